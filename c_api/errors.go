@@ -0,0 +1,79 @@
+package main
+
+import "C"
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
+)
+
+// The error codes that returned to the caller of C API.
+//
+// For the functions that returns a new object ID, the negative
+// of the error code is returned when failed. For the functions
+// that returns a string, the returned string is in the format of
+// "code|message", or an empty string when success.
+const (
+	ErrCodeNone = iota
+	ErrCodeUnknown
+	ErrCodeNotFound
+	ErrCodeEmpty
+	ErrCodeOutOfRange
+	ErrCodeClosed
+	ErrCodeCorrupt
+	ErrCodeBusy
+)
+
+var (
+	errTimelineDBNotFound     = errors.New("Timeline database not found")
+	errChunkTimelineNotFound  = errors.New("Chunk timeline not found")
+	errPlayerTimelineNotFound = errors.New("Player timeline not found")
+)
+
+// errorCode returns the error code of err.
+func errorCode(err error) int {
+	switch {
+	case err == nil:
+		return ErrCodeNone
+	case errors.Is(err, errTimelineDBNotFound), errors.Is(err, errChunkTimelineNotFound), errors.Is(err, errPlayerTimelineNotFound):
+		return ErrCodeNotFound
+	case errors.Is(err, timeline.ErrEmpty):
+		return ErrCodeEmpty
+	case errors.Is(err, timeline.ErrOutOfRange):
+		return ErrCodeOutOfRange
+	case errors.Is(err, timeline.ErrClosed):
+		return ErrCodeClosed
+	case errors.Is(err, timeline.ErrCorrupt):
+		return ErrCodeCorrupt
+	case errors.Is(err, timeline.ErrBusy):
+		return ErrCodeBusy
+	default:
+		return ErrCodeUnknown
+	}
+}
+
+// asCError converts err to a C string that in the
+// format of "code|message". If err is nil, then
+// returns an empty string.
+func asCError(err error) *C.char {
+	if err == nil {
+		return C.CString("")
+	}
+	return C.CString(fmt.Sprintf("%d|%v", errorCode(err), err))
+}
+
+// asCErrorID returns the negative of the
+// error code of err, which used as the
+// result of an invalid object ID.
+func asCErrorID(err error) C.longlong {
+	return C.longlong(-errorCode(err))
+}
+
+// asCErrorBytes converts err to the payload that
+// returned by the complex functions when failed.
+// The payload is the error code in one byte and
+// then follows the error message.
+func asCErrorBytes(err error) *C.char {
+	return asCbytes(append([]byte{byte(errorCode(err))}, err.Error()...))
+}
@@ -6,10 +6,12 @@ import (
 	"encoding/binary"
 	"fmt"
 
+	diff_define "github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
 	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
 	"github.com/TriM-Organization/bedrock-world-operator/chunk"
 	"github.com/TriM-Organization/bedrock-world-operator/define"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
 )
 
 var savedChunkTimeline = NewSimpleManager[*timeline.ChunkTimeline]()
@@ -17,7 +19,7 @@ var savedChunkTimeline = NewSimpleManager[*timeline.ChunkTimeline]()
 // appendChunk ..
 func appendChunk(
 	id C.longlong,
-	chunkPayload *C.char, nbtPayload *C.char,
+	chunkPayload *C.char, nbtPayload *C.char, entityPayload *C.char, biomePayload *C.char,
 	rangeStart C.int, rangeEnd C.int,
 	NOPWhenNoChange C.int,
 	e chunk.Encoding,
@@ -25,41 +27,155 @@ func appendChunk(
 	subChunks := unpackChunks(asGoBytes(chunkPayload))
 	nbts, err := unpackNBTs(asGoBytes(nbtPayload))
 	if err != nil {
-		return C.CString(fmt.Sprintf("append: %v", err))
+		return asCError(fmt.Errorf("append: %w", err))
+	}
+	entities, err := unpackNBTs(asGoBytes(entityPayload))
+	if err != nil {
+		return asCError(fmt.Errorf("append: %w", err))
 	}
 
 	c, err := utils.FromChunkPayload(subChunks, define.Range{int(rangeStart), int(rangeEnd)}, e)
 	if err != nil {
-		return C.CString(fmt.Sprintf("append: %v", err))
+		return asCError(fmt.Errorf("append: %w", err))
+	}
+	if biomes := asGoBytes(biomePayload); len(biomes) > 0 {
+		err = chunk.DecodeBiomes(bytes.NewBuffer(biomes), c, e)
+		if err != nil {
+			return asCError(fmt.Errorf("append: %w", err))
+		}
 	}
 
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return C.CString("append: Chunk timeline not found")
+		return asCError(fmt.Errorf("append: %w", errChunkTimelineNotFound))
+	}
+
+	err = (*ctl).Append(c, nbts, entities, asGoBool(NOPWhenNoChange))
+	if err != nil {
+		return asCError(fmt.Errorf("append: %w", err))
 	}
 
-	(*ctl).Append(c, nbts, asGoBool(NOPWhenNoChange))
 	return C.CString("")
 }
 
 //export AppendDiskChunk
 func AppendDiskChunk(
 	id C.longlong,
-	chunkPayload *C.char, nbtPayload *C.char,
+	chunkPayload *C.char, nbtPayload *C.char, entityPayload *C.char, biomePayload *C.char,
 	rangeStart C.int, rangeEnd C.int,
 	NOPWhenNoChange C.int,
 ) *C.char {
-	return appendChunk(id, chunkPayload, nbtPayload, rangeStart, rangeEnd, NOPWhenNoChange, chunk.DiskEncoding)
+	return appendChunk(id, chunkPayload, nbtPayload, entityPayload, biomePayload, rangeStart, rangeEnd, NOPWhenNoChange, chunk.DiskEncoding)
 }
 
 //export AppendNetworkChunk
 func AppendNetworkChunk(
 	id C.longlong,
+	chunkPayload *C.char, nbtPayload *C.char, entityPayload *C.char, biomePayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+	NOPWhenNoChange C.int,
+) *C.char {
+	return appendChunk(id, chunkPayload, nbtPayload, entityPayload, biomePayload, rangeStart, rangeEnd, NOPWhenNoChange, chunk.NetworkEncoding)
+}
+
+// hashChunk ..
+func hashChunk(
 	chunkPayload *C.char, nbtPayload *C.char,
 	rangeStart C.int, rangeEnd C.int,
+	chunkPosX C.int, chunkPosZ C.int,
+	e chunk.Encoding,
+) *C.char {
+	subChunks := unpackChunks(asGoBytes(chunkPayload))
+	nbts, err := unpackNBTs(asGoBytes(nbtPayload))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("hashChunk: %w", err))
+	}
+
+	c, err := utils.FromChunkPayload(subChunks, define.Range{int(rangeStart), int(rangeEnd)}, e)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("hashChunk: %w", err))
+	}
+
+	hashes := diff_define.HashChunk(c, diff_define.FromChunkNBT(define.ChunkPos{int32(chunkPosX), int32(chunkPosZ)}, nbts))
+	return asCbytes(append([]byte{ErrCodeNone}, packHashes(hashes)...))
+}
+
+//export HashDiskChunk
+func HashDiskChunk(
+	chunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+	chunkPosX C.int, chunkPosZ C.int,
+) *C.char {
+	return hashChunk(chunkPayload, nbtPayload, rangeStart, rangeEnd, chunkPosX, chunkPosZ, chunk.DiskEncoding)
+}
+
+//export HashNetworkChunk
+func HashNetworkChunk(
+	chunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+	chunkPosX C.int, chunkPosZ C.int,
+) *C.char {
+	return hashChunk(chunkPayload, nbtPayload, rangeStart, rangeEnd, chunkPosX, chunkPosZ, chunk.NetworkEncoding)
+}
+
+// appendSubChunks ..
+func appendSubChunks(
+	id C.longlong,
+	subChunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
 	NOPWhenNoChange C.int,
+	e chunk.Encoding,
 ) *C.char {
-	return appendChunk(id, chunkPayload, nbtPayload, rangeStart, rangeEnd, NOPWhenNoChange, chunk.NetworkEncoding)
+	nbts, err := unpackNBTs(asGoBytes(nbtPayload))
+	if err != nil {
+		return asCError(fmt.Errorf("appendSubChunks: %w", err))
+	}
+
+	r := define.Range{int(rangeStart), int(rangeEnd)}
+	subChunks := make(map[int16]*chunk.SubChunk)
+	for _, value := range unpackChunks(asGoBytes(subChunkPayload)) {
+		if len(value) < 2 {
+			return asCError(fmt.Errorf("appendSubChunks: Sub chunk payload is broken"))
+		}
+		y := int16(binary.LittleEndian.Uint16(value))
+		subChunk, _, err := chunk.DecodeSubChunk(bytes.NewBuffer(value[2:]), r, e)
+		if err != nil {
+			return asCError(fmt.Errorf("appendSubChunks: %w", err))
+		}
+		subChunks[y] = subChunk
+	}
+
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCError(fmt.Errorf("appendSubChunks: %w", errChunkTimelineNotFound))
+	}
+
+	err = (*ctl).AppendSubChunks(subChunks, nbts, asGoBool(NOPWhenNoChange))
+	if err != nil {
+		return asCError(fmt.Errorf("appendSubChunks: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export AppendDiskSubChunks
+func AppendDiskSubChunks(
+	id C.longlong,
+	subChunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+	NOPWhenNoChange C.int,
+) *C.char {
+	return appendSubChunks(id, subChunkPayload, nbtPayload, rangeStart, rangeEnd, NOPWhenNoChange, chunk.DiskEncoding)
+}
+
+//export AppendNetworkSubChunks
+func AppendNetworkSubChunks(
+	id C.longlong,
+	subChunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+	NOPWhenNoChange C.int,
+) *C.char {
+	return appendSubChunks(id, subChunkPayload, nbtPayload, rangeStart, rangeEnd, NOPWhenNoChange, chunk.NetworkEncoding)
 }
 
 //export Empty
@@ -93,7 +209,7 @@ func Pointer(id C.longlong) C.int {
 func ResetPointer(id C.longlong) *C.char {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return C.CString("ResetPointer: Chunk timeline not found")
+		return asCError(fmt.Errorf("ResetPointer: %w", errChunkTimelineNotFound))
 	}
 	(*ctl).ResetPointer()
 	return C.CString("")
@@ -131,27 +247,64 @@ func AllTimePointLen(id C.longlong) C.int {
 func SetMaxLimit(id C.longlong, maxLimit C.int) *C.char {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return C.CString("SetMaxLimit: Chunk timeline not found")
+		return asCError(fmt.Errorf("SetMaxLimit: %w", errChunkTimelineNotFound))
 	}
 
 	err := (*ctl).SetMaxLimit(uint(maxLimit))
 	if err != nil {
-		return C.CString(fmt.Sprintf("SetMaxLimit: %v", err))
+		return asCError(fmt.Errorf("SetMaxLimit: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export BestEffort
+func BestEffort(id C.longlong) C.int {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return -1
 	}
+	return asCbool((*ctl).BestEffort())
+}
 
+//export SetBestEffort
+func SetBestEffort(id C.longlong, enabled C.int) *C.char {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCError(fmt.Errorf("SetBestEffort: %w", errChunkTimelineNotFound))
+	}
+	(*ctl).SetBestEffort(asGoBool(enabled))
 	return C.CString("")
 }
 
+//export SkippedNBTs
+func SkippedNBTs(id C.longlong) C.int {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return -1
+	}
+	return C.int((*ctl).SkippedNBTs())
+}
+
+//export IgnoredDeltas
+func IgnoredDeltas(id C.longlong) C.int {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return -1
+	}
+	return C.int((*ctl).IgnoredDeltas())
+}
+
 //export Compact
 func Compact(id C.longlong) *C.char {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return C.CString("Compact: Chunk timeline not found")
+		return asCError(fmt.Errorf("Compact: %w", errChunkTimelineNotFound))
 	}
 
 	err := (*ctl).Compact()
 	if err != nil {
-		return C.CString(fmt.Sprintf("Compact: %v", err))
+		return asCError(fmt.Errorf("Compact: %w", err))
 	}
 
 	return C.CString("")
@@ -161,15 +314,15 @@ func Compact(id C.longlong) *C.char {
 func next(id C.longlong, e chunk.Encoding) (complexReturn *C.char) {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return asCbytes(nil)
+		return asCErrorBytes(errChunkTimelineNotFound)
 	}
 
-	c, nbts, updateUnixTime, isLastElement, err := (*ctl).Next()
+	c, nbts, entities, updateUnixTime, isLastElement, err := (*ctl).Next()
 	if err != nil {
-		return asCbytes(nil)
+		return asCErrorBytes(err)
 	}
 
-	return packNextOrLast(c, e, nbts, updateUnixTime, &isLastElement)
+	return packNextOrLast(c, e, nbts, entities, updateUnixTime, &isLastElement)
 }
 
 //export NextDiskChunk
@@ -186,15 +339,15 @@ func NextNetworkChunk(id C.longlong) (complexReturn *C.char) {
 func jumpTo(id C.longlong, index C.int, e chunk.Encoding) (complexReturn *C.char) {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return asCbytes(nil)
+		return asCErrorBytes(errChunkTimelineNotFound)
 	}
 
-	c, nbts, updateUnixTime, err := (*ctl).JumpTo(uint(index))
+	c, nbts, entities, updateUnixTime, err := (*ctl).JumpTo(uint(index))
 	if err != nil {
-		return asCbytes(nil)
+		return asCErrorBytes(err)
 	}
 
-	return packNextOrLast(c, e, nbts, updateUnixTime, nil)
+	return packNextOrLast(c, e, nbts, entities, updateUnixTime, nil)
 }
 
 //export JumpToDiskChunk
@@ -211,15 +364,15 @@ func JumpToNetworkChunk(id C.longlong, index C.int) (complexReturn *C.char) {
 func last(id C.longlong, e chunk.Encoding) (complexReturn *C.char) {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return asCbytes(nil)
+		return asCErrorBytes(errChunkTimelineNotFound)
 	}
 
-	c, nbts, updateUnixTime, err := (*ctl).Last()
+	c, nbts, entities, updateUnixTime, err := (*ctl).Last()
 	if err != nil {
-		return asCbytes(nil)
+		return asCErrorBytes(err)
 	}
 
-	return packNextOrLast(c, e, nbts, updateUnixTime, nil)
+	return packNextOrLast(c, e, nbts, entities, updateUnixTime, nil)
 }
 
 //export LastDiskChunk
@@ -232,16 +385,295 @@ func LastNetworkChunk(id C.longlong) (complexReturn *C.char) {
 	return last(id, chunk.NetworkEncoding)
 }
 
+//export BlockHistory
+func BlockHistory(id C.longlong, x C.int, y C.int, z C.int) *C.char {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCErrorBytes(fmt.Errorf("BlockHistory: %w", errChunkTimelineNotFound))
+	}
+	if x < 0 || x > 15 || z < 0 || z > 15 {
+		return asCErrorBytes(fmt.Errorf("BlockHistory: %w (block (%d, %d, %d) is not in this chunk)", timeline.ErrOutOfRange, x, y, z))
+	}
+
+	changes, err := (*ctl).BlockHistory(uint8(x), int16(y), uint8(z))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("BlockHistory: %w", err))
+	}
+
+	result := make([]map[string]any, 0, len(changes))
+	for _, change := range changes {
+		oldBlocks := make([]any, 0, len(change.OldBlocks))
+		newBlocks := make([]any, 0, len(change.NewBlocks))
+		for index := range change.OldBlocks {
+			oldBlocks = append(oldBlocks, (*ctl).BlockRegistry().EncodeBlockState(change.OldBlocks[index]))
+			newBlocks = append(newBlocks, (*ctl).BlockRegistry().EncodeBlockState(change.NewBlocks[index]))
+		}
+
+		m := map[string]any{
+			"time_point": int32(change.TimePoint),
+			"unix_time":  change.UpdateUnixTime,
+			"old_blocks": oldBlocks,
+			"new_blocks": newBlocks,
+		}
+		if change.OldNBT != nil {
+			m["old_nbt"] = change.OldNBT
+		}
+		if change.NewNBT != nil {
+			m["new_nbt"] = change.NewNBT
+		}
+		result = append(result, m)
+	}
+
+	payload, err := packNBTs(result)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("BlockHistory: %w", err))
+	}
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
+// packChunkDiff encodes diff as a little endian TAG_Compound,
+// and the block states are found from registry.
+func packChunkDiff(registry *diff_define.BlockRegistry, diff *timeline.ChunkDiff) (payload []byte, err error) {
+	blocks := make([]map[string]any, 0, len(diff.Blocks))
+	for _, value := range diff.Blocks {
+		blocks = append(blocks, map[string]any{
+			"x":         int32(value.X),
+			"y":         int32(value.Y),
+			"z":         int32(value.Z),
+			"layer":     int32(value.Layer),
+			"old_block": registry.EncodeBlockState(value.OldBlock),
+			"new_block": registry.EncodeBlockState(value.NewBlock),
+		})
+	}
+
+	addedNBT := make([]map[string]any, 0, len(diff.AddedNBT))
+	for _, value := range diff.AddedNBT {
+		addedNBT = append(addedNBT, value.NewNBT)
+	}
+	removedNBT := make([]map[string]any, 0, len(diff.RemovedNBT))
+	for _, value := range diff.RemovedNBT {
+		removedNBT = append(removedNBT, value.OldNBT)
+	}
+	modifiedNBT := make([]map[string]any, 0, len(diff.ModifiedNBT))
+	for _, value := range diff.ModifiedNBT {
+		modifiedNBT = append(modifiedNBT, map[string]any{
+			"x":       int32(value.X),
+			"y":       int32(value.Y),
+			"z":       int32(value.Z),
+			"old_nbt": value.OldNBT,
+			"new_nbt": value.NewNBT,
+		})
+	}
+
+	buf := bytes.NewBuffer(nil)
+	err = nbt.NewEncoderWithEncoding(buf, nbt.LittleEndian).Encode(map[string]any{
+		"blocks":       blocks,
+		"added_nbt":    addedNBT,
+		"removed_nbt":  removedNBT,
+		"modified_nbt": modifiedNBT,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("packChunkDiff: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+//export DiffBetween
+func DiffBetween(id C.longlong, i C.int, j C.int) *C.char {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCErrorBytes(fmt.Errorf("DiffBetween: %w", errChunkTimelineNotFound))
+	}
+	if i < 0 || j < 0 {
+		return asCErrorBytes(fmt.Errorf("DiffBetween: %w (index %d or %d is negative)", timeline.ErrOutOfRange, i, j))
+	}
+
+	diff, err := (*ctl).DiffBetween(uint(i), uint(j))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("DiffBetween: %w", err))
+	}
+
+	payload, err := packChunkDiff((*ctl).BlockRegistry(), diff)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("DiffBetween: %w", err))
+	}
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
+// diffLatest ..
+func diffLatest(
+	id C.longlong,
+	chunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+	e chunk.Encoding,
+) *C.char {
+	subChunks := unpackChunks(asGoBytes(chunkPayload))
+	nbts, err := unpackNBTs(asGoBytes(nbtPayload))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("diffLatest: %w", err))
+	}
+
+	c, err := utils.FromChunkPayload(subChunks, define.Range{int(rangeStart), int(rangeEnd)}, e)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("diffLatest: %w", err))
+	}
+
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCErrorBytes(fmt.Errorf("diffLatest: %w", errChunkTimelineNotFound))
+	}
+
+	diff, err := (*ctl).DiffLatest(c, nbts)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("diffLatest: %w", err))
+	}
+
+	payload, err := packChunkDiff((*ctl).BlockRegistry(), diff)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("diffLatest: %w", err))
+	}
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
+//export DiffLatestDiskChunk
+func DiffLatestDiskChunk(
+	id C.longlong,
+	chunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+) *C.char {
+	return diffLatest(id, chunkPayload, nbtPayload, rangeStart, rangeEnd, chunk.DiskEncoding)
+}
+
+//export DiffLatestNetworkChunk
+func DiffLatestNetworkChunk(
+	id C.longlong,
+	chunkPayload *C.char, nbtPayload *C.char,
+	rangeStart C.int, rangeEnd C.int,
+) *C.char {
+	return diffLatest(id, chunkPayload, nbtPayload, rangeStart, rangeEnd, chunk.NetworkEncoding)
+}
+
+//export Summaries
+func Summaries(id C.longlong) *C.char {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCErrorBytes(fmt.Errorf("Summaries: %w", errChunkTimelineNotFound))
+	}
+
+	summaries, err := (*ctl).Summaries()
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("Summaries: %w", err))
+	}
+
+	payload := []byte{ErrCodeNone}
+	for _, value := range summaries {
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(value.TimePoint))
+		payload = binary.LittleEndian.AppendUint64(payload, uint64(value.UpdateUnixTime))
+		payload = binary.LittleEndian.AppendUint32(payload, uint32(len(value.BlockChanges)))
+		for _, count := range value.BlockChanges {
+			payload = binary.LittleEndian.AppendUint32(payload, count)
+		}
+		payload = binary.LittleEndian.AppendUint32(payload, value.NBTAdded)
+		payload = binary.LittleEndian.AppendUint32(payload, value.NBTRemoved)
+		payload = binary.LittleEndian.AppendUint32(payload, value.NBTModified)
+		payload = binary.LittleEndian.AppendUint32(payload, value.BlockDeltaSize)
+		payload = binary.LittleEndian.AppendUint32(payload, value.NBTDeltaSize)
+	}
+
+	return asCbytes(payload)
+}
+
+//export NBTModifications
+func NBTModifications(id C.longlong, index C.int) *C.char {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCErrorBytes(fmt.Errorf("NBTModifications: %w", errChunkTimelineNotFound))
+	}
+
+	modifications, err := (*ctl).NBTModifications(uint(index))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("NBTModifications: %w", err))
+	}
+
+	result := make([]map[string]any, 0, len(modifications))
+	for _, modification := range modifications {
+		patches := make([]map[string]any, 0, len(modification.Patches))
+		for _, patch := range modification.Patches {
+			path := make([]map[string]any, 0, len(patch.Path))
+			for _, value := range patch.Path {
+				switch value := value.(type) {
+				case string:
+					path = append(path, map[string]any{"key": value})
+				case int:
+					path = append(path, map[string]any{"index": int32(value)})
+				}
+			}
+
+			m := map[string]any{
+				"op":   patch.Op,
+				"path": path,
+			}
+			if patch.Op == diff_define.NBTPatchSet {
+				m["value"] = patch.Value
+			}
+			patches = append(patches, m)
+		}
+
+		result = append(result, map[string]any{
+			"x":       int32(modification.X),
+			"y":       int32(modification.Y),
+			"z":       int32(modification.Z),
+			"kind":    modification.Kind,
+			"patches": patches,
+		})
+	}
+
+	payload, err := packNBTs(result)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("NBTModifications: %w", err))
+	}
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
+//export SearchBlocks
+func SearchBlocks(id C.longlong, statesPayload *C.char) *C.char {
+	ctl := savedChunkTimeline.LoadObject(int(id))
+	if ctl == nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocks: %w", errChunkTimelineNotFound))
+	}
+
+	predicate, err := unpackBlockPredicate(asGoBytes(statesPayload))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocks: %w", err))
+	}
+
+	events, err := (*ctl).SearchBlocks(predicate)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocks: %w", err))
+	}
+
+	result := make([]map[string]any, 0, len(events))
+	for _, event := range events {
+		result = append(result, blockEventToNBT((*ctl).BlockRegistry(), event))
+	}
+
+	payload, err := packNBTs(result)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocks: %w", err))
+	}
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
 //export Pop
 func Pop(id C.longlong) *C.char {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return C.CString("Pop: Chunk timeline not found")
+		return asCError(fmt.Errorf("Pop: %w", errChunkTimelineNotFound))
 	}
 
 	err := (*ctl).Pop()
 	if err != nil {
-		return C.CString(fmt.Sprintf("Pop: %v", err))
+		return asCError(fmt.Errorf("Pop: %w", err))
 	}
 
 	return C.CString("")
@@ -251,12 +683,12 @@ func Pop(id C.longlong) *C.char {
 func Save(id C.longlong) *C.char {
 	ctl := savedChunkTimeline.LoadObject(int(id))
 	if ctl == nil {
-		return C.CString("Save: Chunk timeline not found")
+		return asCError(fmt.Errorf("Save: %w", errChunkTimelineNotFound))
 	}
 
 	err := (*ctl).Save()
 	if err != nil {
-		return C.CString(fmt.Sprintf("Save: %v", err))
+		return asCError(fmt.Errorf("Save: %w", err))
 	}
 
 	return C.CString("")
@@ -0,0 +1,174 @@
+package main
+
+import "C"
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+var savedPlayerTimeline = NewSimpleManager[*timeline.PlayerTimeline]()
+
+// packPlayer encodes the player data and its update unix time.
+// The payload is the update unix time in 8 bytes and then follows
+// the player data as a little endian TAG_Compound (empty if no data).
+func packPlayer(player map[string]any, updateUnixTime int64) *C.char {
+	payload := binary.LittleEndian.AppendUint64([]byte{ErrCodeNone}, uint64(updateUnixTime))
+
+	if len(player) > 0 {
+		buf := bytes.NewBuffer(nil)
+		if err := nbt.NewEncoderWithEncoding(buf, nbt.LittleEndian).Encode(player); err != nil {
+			return asCErrorBytes(fmt.Errorf("packPlayer: %v", err))
+		}
+		payload = append(payload, buf.Bytes()...)
+	}
+
+	return asCbytes(payload)
+}
+
+//export PlayerAppend
+func PlayerAppend(id C.longlong, playerPayload *C.char, NOPWhenNoChange C.int) *C.char {
+	var player map[string]any
+
+	if payload := asGoBytes(playerPayload); len(payload) > 0 {
+		err := nbt.NewDecoderWithEncoding(bytes.NewBuffer(payload), nbt.LittleEndian).Decode(&player)
+		if err != nil {
+			return asCError(fmt.Errorf("PlayerAppend: %v", err))
+		}
+	}
+
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCError(fmt.Errorf("PlayerAppend: %w", errPlayerTimelineNotFound))
+	}
+
+	err := (*ptl).Append(player, asGoBool(NOPWhenNoChange))
+	if err != nil {
+		return asCError(fmt.Errorf("PlayerAppend: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export PlayerEmpty
+func PlayerEmpty(id C.longlong) C.int {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return -1
+	}
+	return asCbool((*ptl).Empty())
+}
+
+//export PlayerReadOnly
+func PlayerReadOnly(id C.longlong) C.int {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return -1
+	}
+	return asCbool((*ptl).ReadOnly())
+}
+
+//export PlayerAllTimePoint
+func PlayerAllTimePoint(id C.longlong) *C.char {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCbytes(nil)
+	}
+
+	payload := make([]byte, 0, (*ptl).AllTimePointLen()*8)
+	for _, value := range (*ptl).AllTimePoint() {
+		payload = binary.LittleEndian.AppendUint64(payload, uint64(value))
+	}
+
+	return asCbytes(payload)
+}
+
+//export PlayerAllTimePointLen
+func PlayerAllTimePointLen(id C.longlong) C.int {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return -1
+	}
+	return C.int((*ptl).AllTimePointLen())
+}
+
+//export PlayerSetMaxLimit
+func PlayerSetMaxLimit(id C.longlong, maxLimit C.int) *C.char {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCError(fmt.Errorf("PlayerSetMaxLimit: %w", errPlayerTimelineNotFound))
+	}
+
+	err := (*ptl).SetMaxLimit(uint(maxLimit))
+	if err != nil {
+		return asCError(fmt.Errorf("PlayerSetMaxLimit: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export PlayerJumpTo
+func PlayerJumpTo(id C.longlong, index C.int) (complexReturn *C.char) {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCErrorBytes(errPlayerTimelineNotFound)
+	}
+	if index < 0 {
+		return asCErrorBytes(fmt.Errorf("PlayerJumpTo: %w (index %d is negative)", timeline.ErrOutOfRange, index))
+	}
+
+	player, updateUnixTime, err := (*ptl).JumpTo(uint(index))
+	if err != nil {
+		return asCErrorBytes(err)
+	}
+
+	return packPlayer(player, updateUnixTime)
+}
+
+//export PlayerLast
+func PlayerLast(id C.longlong) (complexReturn *C.char) {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCErrorBytes(errPlayerTimelineNotFound)
+	}
+
+	player, updateUnixTime, err := (*ptl).Last()
+	if err != nil {
+		return asCErrorBytes(err)
+	}
+
+	return packPlayer(player, updateUnixTime)
+}
+
+//export PlayerPop
+func PlayerPop(id C.longlong) *C.char {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCError(fmt.Errorf("PlayerPop: %w", errPlayerTimelineNotFound))
+	}
+
+	err := (*ptl).Pop()
+	if err != nil {
+		return asCError(fmt.Errorf("PlayerPop: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export PlayerSave
+func PlayerSave(id C.longlong) *C.char {
+	ptl := savedPlayerTimeline.LoadObject(int(id))
+	if ptl == nil {
+		return asCError(fmt.Errorf("PlayerSave: %w", errPlayerTimelineNotFound))
+	}
+
+	err := (*ptl).Save()
+	if err != nil {
+		return asCError(fmt.Errorf("PlayerSave: %w", err))
+	}
+
+	return C.CString("")
+}
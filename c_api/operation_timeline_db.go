@@ -2,10 +2,12 @@ package main
 
 import "C"
 import (
+	"encoding/binary"
 	"fmt"
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
 	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
 )
 
@@ -15,8 +17,55 @@ var savedTimelineDB = NewSimpleManager[timeline.TimelineDatabase]()
 func NewTimelineDB(path *C.char, noGrowSync C.int, noSync C.int) C.longlong {
 	tldb, err := timeline.Open(C.GoString(path), asGoBool(noGrowSync), asGoBool(noSync))
 	if err != nil {
-		return -1
+		return asCErrorID(err)
+	}
+	return C.longlong(savedTimelineDB.AddObject(tldb))
+}
+
+//export NewLevelTimelineDB
+func NewLevelTimelineDB(path *C.char) C.longlong {
+	tldb, err := timeline.OpenLevel(C.GoString(path))
+	if err != nil {
+		return asCErrorID(err)
+	}
+	return C.longlong(savedTimelineDB.AddObject(tldb))
+}
+
+//export NewMemoryTimelineDB
+func NewMemoryTimelineDB() C.longlong {
+	tldb, err := timeline.OpenMemory()
+	if err != nil {
+		return asCErrorID(err)
+	}
+	return C.longlong(savedTimelineDB.AddObject(tldb))
+}
+
+//export NewEncryptedTimelineDB
+func NewEncryptedTimelineDB(
+	path *C.char, noGrowSync C.int, noSync C.int,
+	currentKeyID C.int, keys *C.char, hmacKey *C.char,
+) C.longlong {
+	allKeys, err := unpackKeys(asGoBytes(keys))
+	if err != nil {
+		return asCErrorID(err)
+	}
+	keyProvider, err := timeline.NewStaticKeyProvider(uint32(currentKeyID), allKeys)
+	if err != nil {
+		return asCErrorID(err)
+	}
+
+	db, err := timeline.OpenBBoltDB(C.GoString(path), asGoBool(noGrowSync), asGoBool(noSync))
+	if err != nil {
+		return asCErrorID(err)
+	}
+	tldb, err := timeline.OpenEncrypted(db, timeline.EncryptionOptions{
+		KeyProvider: keyProvider,
+		HMACKey:     asGoBytes(hmacKey),
+	})
+	if err != nil {
+		return asCErrorID(err)
 	}
+
 	return C.longlong(savedTimelineDB.AddObject(tldb))
 }
 
@@ -29,12 +78,12 @@ func ReleaseTimelineDB(id C.longlong) {
 func CloseTimelineDB(id C.longlong) *C.char {
 	tldb := savedTimelineDB.LoadObject(int(id))
 	if tldb == nil {
-		return C.CString("CloseTimelineDB: Timeline database not found")
+		return asCError(fmt.Errorf("CloseTimelineDB: %w", errTimelineDBNotFound))
 	}
 
 	err := (*tldb).CloseTimelineDB()
 	if err != nil {
-		return C.CString(fmt.Sprintf("CloseTimelineDB: %v", err))
+		return asCError(fmt.Errorf("CloseTimelineDB: %w", err))
 	}
 
 	return C.CString("")
@@ -44,7 +93,7 @@ func CloseTimelineDB(id C.longlong) *C.char {
 func NewChunkTimeline(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int, readOnly C.int) C.longlong {
 	tldb := savedTimelineDB.LoadObject(int(id))
 	if tldb == nil {
-		return -1
+		return asCErrorID(errTimelineDBNotFound)
 	}
 
 	result, err := (*tldb).NewChunkTimeline(
@@ -55,7 +104,28 @@ func NewChunkTimeline(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int,
 		asGoBool(readOnly),
 	)
 	if err != nil {
-		return -1
+		return asCErrorID(err)
+	}
+
+	return C.longlong(savedChunkTimeline.AddObject(result))
+}
+
+//export TryNewChunkTimeline
+func TryNewChunkTimeline(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int, readOnly C.int) C.longlong {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorID(errTimelineDBNotFound)
+	}
+
+	result, err := (*tldb).TryNewChunkTimeline(
+		define.DimChunk{
+			Dimension: operator_define.Dimension(dm),
+			ChunkPos:  operator_define.ChunkPos{int32(chunkPosX), int32(chunkPosZ)},
+		},
+		asGoBool(readOnly),
+	)
+	if err != nil {
+		return asCErrorID(err)
 	}
 
 	return C.longlong(savedChunkTimeline.AddObject(result))
@@ -70,7 +140,7 @@ func ReleaseChunkTimeline(id C.longlong) {
 func DeleteChunkTimeline(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int) *C.char {
 	tldb := savedTimelineDB.LoadObject(int(id))
 	if tldb == nil {
-		return C.CString("DeleteChunkTimeline: Timeline database not found")
+		return asCError(fmt.Errorf("DeleteChunkTimeline: %w", errTimelineDBNotFound))
 	}
 
 	err := (*tldb).DeleteChunkTimeline(
@@ -80,7 +150,7 @@ func DeleteChunkTimeline(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.i
 		},
 	)
 	if err != nil {
-		return C.CString(fmt.Sprintf("DeleteChunkTimeline: %v", err))
+		return asCError(fmt.Errorf("DeleteChunkTimeline: %w", err))
 	}
 
 	return C.CString("")
@@ -107,7 +177,7 @@ func LoadLatestTimePointUnixTime(id C.longlong, dm C.int, chunkPosX C.int, chunk
 func SaveLatestTimePointUnixTime(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int, timeStamp C.longlong) *C.char {
 	tldb := savedTimelineDB.LoadObject(int(id))
 	if tldb == nil {
-		return C.CString("SaveLatestTimePointUnixTime: Timeline database not found")
+		return asCError(fmt.Errorf("SaveLatestTimePointUnixTime: %w", errTimelineDBNotFound))
 	}
 
 	err := (*tldb).SaveLatestTimePointUnixTime(
@@ -118,8 +188,347 @@ func SaveLatestTimePointUnixTime(id C.longlong, dm C.int, chunkPosX C.int, chunk
 		int64(timeStamp),
 	)
 	if err != nil {
-		return C.CString(fmt.Sprintf("SaveLatestTimePointUnixTime: %v", err))
+		return asCError(fmt.Errorf("SaveLatestTimePointUnixTime: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export SetCompression
+func SetCompression(id C.longlong, compressionID C.int, level C.int) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("SetCompression: %w", errTimelineDBNotFound))
+	}
+
+	compression, err := utils.NewCompression(byte(compressionID), int(level))
+	if err != nil {
+		return asCError(fmt.Errorf("SetCompression: %w", err))
+	}
+
+	err = (*tldb).SetCompression(compression)
+	if err != nil {
+		return asCError(fmt.Errorf("SetCompression: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export RewriteChunkTimeline
+func RewriteChunkTimeline(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("RewriteChunkTimeline: %w", errTimelineDBNotFound))
+	}
+
+	err := (*tldb).RewriteChunkTimeline(
+		define.DimChunk{
+			Dimension: operator_define.Dimension(dm),
+			ChunkPos:  operator_define.ChunkPos{int32(chunkPosX), int32(chunkPosZ)},
+		},
+	)
+	if err != nil {
+		return asCError(fmt.Errorf("RewriteChunkTimeline: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export RotateEncryptionKey
+func RotateEncryptionKey(id C.longlong) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("RotateEncryptionKey: %w", errTimelineDBNotFound))
+	}
+
+	err := (*tldb).RotateEncryptionKey()
+	if err != nil {
+		return asCError(fmt.Errorf("RotateEncryptionKey: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export SharedPaletteEnabled
+func SharedPaletteEnabled(id C.longlong) C.int {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return -1
+	}
+	return asCbool((*tldb).SharedPaletteEnabled())
+}
+
+//export SetSharedPalette
+func SetSharedPalette(id C.longlong, enabled C.int) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("SetSharedPalette: %w", errTimelineDBNotFound))
+	}
+
+	err := (*tldb).SetSharedPalette(asGoBool(enabled))
+	if err != nil {
+		return asCError(fmt.Errorf("SetSharedPalette: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export GCSharedPalette
+func GCSharedPalette(id C.longlong) C.longlong {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorID(errTimelineDBNotFound)
+	}
+
+	removed, err := (*tldb).GCSharedPalette()
+	if err != nil {
+		return asCErrorID(err)
+	}
+
+	return C.longlong(removed)
+}
+
+//export SubChunkDedupEnabled
+func SubChunkDedupEnabled(id C.longlong) C.int {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return -1
+	}
+	return asCbool((*tldb).SubChunkDedupEnabled())
+}
+
+//export SetSubChunkDedup
+func SetSubChunkDedup(id C.longlong, enabled C.int) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("SetSubChunkDedup: %w", errTimelineDBNotFound))
+	}
+
+	err := (*tldb).SetSubChunkDedup(asGoBool(enabled))
+	if err != nil {
+		return asCError(fmt.Errorf("SetSubChunkDedup: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export RegisterCustomBlocks
+func RegisterCustomBlocks(id C.longlong, payload *C.char) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("RegisterCustomBlocks: %w", errTimelineDBNotFound))
+	}
+
+	nbts, err := unpackNBTs(asGoBytes(payload))
+	if err != nil {
+		return asCError(fmt.Errorf("RegisterCustomBlocks: %w", err))
+	}
+
+	states := make([]operator_define.BlockState, 0, len(nbts))
+	for _, value := range nbts {
+		state := operator_define.BlockState{}
+		state.Name, _ = value["name"].(string)
+		state.Properties, _ = value["states"].(map[string]any)
+		state.Version, _ = value["version"].(int32)
+		states = append(states, state)
+	}
+
+	_, err = (*tldb).RegisterCustomBlocks(states...)
+	if err != nil {
+		return asCError(fmt.Errorf("RegisterCustomBlocks: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export CustomBlocks
+func CustomBlocks(id C.longlong) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorBytes(fmt.Errorf("CustomBlocks: %w", errTimelineDBNotFound))
+	}
+
+	states, err := (*tldb).CustomBlocks()
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("CustomBlocks: %w", err))
+	}
+
+	nbts := make([]map[string]any, 0, len(states))
+	for _, state := range states {
+		nbts = append(nbts, map[string]any{
+			"name":    state.Name,
+			"states":  state.Properties,
+			"version": state.Version,
+		})
+	}
+
+	payload, err := packNBTs(nbts)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("CustomBlocks: %w", err))
+	}
+
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
+//export SubChunkHashes
+func SubChunkHashes(id C.longlong, dm C.int, chunkPosX C.int, chunkPosZ C.int) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorBytes(fmt.Errorf("SubChunkHashes: %w", errTimelineDBNotFound))
+	}
+
+	hashes, err := (*tldb).SubChunkHashes(
+		define.DimChunk{
+			Dimension: operator_define.Dimension(dm),
+			ChunkPos:  operator_define.ChunkPos{int32(chunkPosX), int32(chunkPosZ)},
+		},
+	)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SubChunkHashes: %w", err))
+	}
+
+	return asCbytes(append([]byte{ErrCodeNone}, packHashes(hashes)...))
+}
+
+//export SearchBlocksInChunks
+func SearchBlocksInChunks(id C.longlong, positionsPayload *C.char, statesPayload *C.char) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocksInChunks: %w", errTimelineDBNotFound))
+	}
+
+	positionsBytes := asGoBytes(positionsPayload)
+	if len(positionsBytes)%12 != 0 {
+		return asCErrorBytes(fmt.Errorf("SearchBlocksInChunks: Invalid positions payload (length %d)", len(positionsBytes)))
+	}
+	positions := make([]define.DimChunk, 0, len(positionsBytes)/12)
+	for ptr := 0; ptr < len(positionsBytes); ptr += 12 {
+		positions = append(positions, define.DimChunk{
+			Dimension: operator_define.Dimension(int32(binary.LittleEndian.Uint32(positionsBytes[ptr:]))),
+			ChunkPos: operator_define.ChunkPos{
+				int32(binary.LittleEndian.Uint32(positionsBytes[ptr+4:])),
+				int32(binary.LittleEndian.Uint32(positionsBytes[ptr+8:])),
+			},
+		})
+	}
+
+	predicate, err := unpackBlockPredicate(asGoBytes(statesPayload))
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocksInChunks: %w", err))
+	}
+
+	found, err := (*tldb).SearchBlocks(positions, predicate)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocksInChunks: %w", err))
+	}
+
+	result := make([]map[string]any, 0)
+	for pos, events := range found {
+		for _, event := range events {
+			m := blockEventToNBT((*tldb).BlockRegistry(), event)
+			m["dm"] = int32(pos.Dimension)
+			m["chunk_x"] = pos.ChunkPos[0]
+			m["chunk_z"] = pos.ChunkPos[1]
+			result = append(result, m)
+		}
+	}
+
+	payload, err := packNBTs(result)
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("SearchBlocksInChunks: %w", err))
+	}
+	return asCbytes(append([]byte{ErrCodeNone}, payload...))
+}
+
+//export NewPlayerTimeline
+func NewPlayerTimeline(id C.longlong, playerID *C.char, readOnly C.int) C.longlong {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorID(errTimelineDBNotFound)
+	}
+
+	result, err := (*tldb).NewPlayerTimeline(C.GoString(playerID), asGoBool(readOnly))
+	if err != nil {
+		return asCErrorID(err)
+	}
+
+	return C.longlong(savedPlayerTimeline.AddObject(result))
+}
+
+//export TryNewPlayerTimeline
+func TryNewPlayerTimeline(id C.longlong, playerID *C.char, readOnly C.int) C.longlong {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorID(errTimelineDBNotFound)
+	}
+
+	result, err := (*tldb).TryNewPlayerTimeline(C.GoString(playerID), asGoBool(readOnly))
+	if err != nil {
+		return asCErrorID(err)
+	}
+
+	return C.longlong(savedPlayerTimeline.AddObject(result))
+}
+
+//export ReleasePlayerTimeline
+func ReleasePlayerTimeline(id C.longlong) {
+	savedPlayerTimeline.ReleaseObject(int(id))
+}
+
+//export DeletePlayerTimeline
+func DeletePlayerTimeline(id C.longlong, playerID *C.char) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("DeletePlayerTimeline: %w", errTimelineDBNotFound))
+	}
+
+	err := (*tldb).DeletePlayerTimeline(C.GoString(playerID))
+	if err != nil {
+		return asCError(fmt.Errorf("DeletePlayerTimeline: %w", err))
 	}
 
 	return C.CString("")
 }
+
+//export RewritePlayerTimeline
+func RewritePlayerTimeline(id C.longlong, playerID *C.char) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCError(fmt.Errorf("RewritePlayerTimeline: %w", errTimelineDBNotFound))
+	}
+
+	err := (*tldb).RewritePlayerTimeline(C.GoString(playerID))
+	if err != nil {
+		return asCError(fmt.Errorf("RewritePlayerTimeline: %w", err))
+	}
+
+	return C.CString("")
+}
+
+//export HasPlayerTimeline
+func HasPlayerTimeline(id C.longlong, playerID *C.char) C.int {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return -1
+	}
+	return asCbool((*tldb).HasPlayerTimeline(C.GoString(playerID)))
+}
+
+//export AllPlayerTimeline
+func AllPlayerTimeline(id C.longlong) *C.char {
+	tldb := savedTimelineDB.LoadObject(int(id))
+	if tldb == nil {
+		return asCErrorBytes(fmt.Errorf("AllPlayerTimeline: %w", errTimelineDBNotFound))
+	}
+
+	ids := make([][]byte, 0)
+	err := (*tldb).ForEachPlayerTimeline(func(id string) error {
+		ids = append(ids, []byte(id))
+		return nil
+	})
+	if err != nil {
+		return asCErrorBytes(fmt.Errorf("AllPlayerTimeline: %w", err))
+	}
+
+	return asCbytes(append([]byte{ErrCodeNone}, packChunks(ids)...))
+}
@@ -7,8 +7,11 @@ import (
 	"fmt"
 	"unsafe"
 
+	diff_define "github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
 	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
 	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
 	"github.com/sandertv/gophertunnel/minecraft/nbt"
 )
 
@@ -55,6 +58,14 @@ func unpackChunks(payload []byte) (subChunks [][]byte) {
 	return
 }
 
+func packHashes(hashes []uint64) (payload []byte) {
+	payload = make([]byte, 0, len(hashes)*8)
+	for _, value := range hashes {
+		payload = binary.LittleEndian.AppendUint64(payload, value)
+	}
+	return
+}
+
 func packNBTs(nbts []map[string]any) (payload []byte, err error) {
 	buf := bytes.NewBuffer(nil)
 
@@ -87,13 +98,60 @@ func unpackNBTs(payload []byte) (nbts []map[string]any, err error) {
 	return
 }
 
+// unpackBlockPredicate decodes the block states in payload, which
+// is the same as unpackNBTs, as a predicate that matches any of them.
+// The "states" of each block state could only hold a part of properties.
+func unpackBlockPredicate(payload []byte) (predicate timeline.BlockPredicate, err error) {
+	nbts, err := unpackNBTs(payload)
+	if err != nil {
+		return nil, fmt.Errorf("unpackBlockPredicate: %v", err)
+	}
+
+	states := make([]operator_define.BlockState, 0, len(nbts))
+	for _, value := range nbts {
+		state := operator_define.BlockState{}
+		state.Name, _ = value["name"].(string)
+		state.Properties, _ = value["states"].(map[string]any)
+		states = append(states, state)
+	}
+
+	return timeline.MatchBlockStates(states...), nil
+}
+
+// blockEventToNBT encodes event as a NBT compound,
+// and the block states are found from registry.
+func blockEventToNBT(registry *diff_define.BlockRegistry, event timeline.BlockEvent) map[string]any {
+	return map[string]any{
+		"time_point": int32(event.TimePoint),
+		"unix_time":  event.UpdateUnixTime,
+		"x":          int32(event.X),
+		"y":          int32(event.Y),
+		"z":          int32(event.Z),
+		"layer":      int32(event.Layer),
+		"appeared":   asNBTBool(event.Appeared),
+		"old_block":  registry.EncodeBlockState(event.OldBlock),
+		"new_block":  registry.EncodeBlockState(event.NewBlock),
+	}
+}
+
+// asNBTBool converts b to a TAG_Byte.
+func asNBTBool(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
 func packNextOrLast(
-	c *chunk.Chunk, e chunk.Encoding, nbts []map[string]any,
+	c *chunk.Chunk, e chunk.Encoding, nbts []map[string]any, entities []map[string]any,
 	updateUnixTime int64,
 	isLastElement *bool,
 ) *C.char {
 	result := bytes.NewBuffer(nil)
 
+	// error code
+	result.WriteByte(ErrCodeNone)
+
 	// c
 	{
 		chunkPayload, r := utils.ChunkPayload(c, e)
@@ -118,7 +176,7 @@ func packNextOrLast(
 	{
 		nbtPayload, err := packNBTs(nbts)
 		if err != nil {
-			return asCbytes(nil)
+			return asCErrorBytes(err)
 		}
 
 		length := make([]byte, 4)
@@ -127,6 +185,29 @@ func packNextOrLast(
 		result.Write(nbtPayload)
 	}
 
+	// entities
+	{
+		entityPayload, err := packNBTs(entities)
+		if err != nil {
+			return asCErrorBytes(err)
+		}
+
+		length := make([]byte, 4)
+		binary.LittleEndian.PutUint32(length, uint32(len(entityPayload)))
+		result.Write(length)
+		result.Write(entityPayload)
+	}
+
+	// biomes
+	{
+		biomePayload := chunk.EncodeBiomes(c, e)
+
+		length := make([]byte, 4)
+		binary.LittleEndian.PutUint32(length, uint32(len(biomePayload)))
+		result.Write(length)
+		result.Write(biomePayload)
+	}
+
 	// updateUnixTime
 	unixTimeBytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(unixTimeBytes, uint64(updateUnixTime))
@@ -143,3 +224,20 @@ func packNextOrLast(
 
 	return asCbytes(result.Bytes())
 }
+
+func unpackKeys(payload []byte) (keys map[uint32][]byte, err error) {
+	keys = make(map[uint32][]byte)
+	for len(payload) > 0 {
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("unpackKeys: Keys payload is broken")
+		}
+		id := binary.LittleEndian.Uint32(payload)
+		length := binary.LittleEndian.Uint32(payload[4:])
+		if uint32(len(payload)-8) < length {
+			return nil, fmt.Errorf("unpackKeys: Keys payload is broken")
+		}
+		keys[id] = bytes.Clone(payload[8 : 8+length])
+		payload = payload[8+length:]
+	}
+	return
+}
@@ -2,7 +2,6 @@ package main
 
 import (
 	"log"
-	"slices"
 	"sync"
 	"time"
 
@@ -10,7 +9,6 @@ import (
 	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
 	"github.com/TriM-Organization/bedrock-world-operator/world"
 	"github.com/pterm/pterm"
-	"go.etcd.io/bbolt"
 )
 
 func IterEntireDatabase(
@@ -20,6 +18,7 @@ func IterEntireDatabase(
 	maxConcurrent int,
 	providedUnixTime int64,
 	ensureExistOne bool,
+	bestEffort bool,
 ) {
 	startTime := time.Now()
 	counter := 0
@@ -28,45 +27,30 @@ func IterEntireDatabase(
 		pterm.Success.Println("Found chunks:", counter)
 	}()
 
-	err := db.UnderlyingDatabase().View(func(tx *bbolt.Tx) error {
-		var foundKeyChunkCount bool
-		var startGoRoutines = 0
-		bucket := tx.Bucket(timeline.DatabaseKeyChunkIndex)
-		waiter := new(sync.WaitGroup)
+	var startGoRoutines = 0
+	waiter := new(sync.WaitGroup)
 
-		err := bucket.ForEach(func(k, v []byte) error {
-			if !foundKeyChunkCount && slices.Equal(k, timeline.DatabaseKeyChunkCount) {
-				foundKeyChunkCount = true
-				return nil
-			}
-
-			pos := define.IndexInv(k)
-			counter++
+	err := db.ForEachChunkTimeline(func(pos define.DimChunk) error {
+		counter++
 
-			if maxConcurrent == 0 {
-				waiter.Add(1)
-				SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, waiter, pos)
-			} else {
-				if startGoRoutines > maxConcurrent {
-					waiter.Wait()
-					startGoRoutines = 0
-				}
-				startGoRoutines++
-				waiter.Add(1)
-				go SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, waiter, pos)
+		if maxConcurrent == 0 {
+			waiter.Add(1)
+			SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, bestEffort, waiter, pos)
+		} else {
+			if startGoRoutines > maxConcurrent {
+				waiter.Wait()
+				startGoRoutines = 0
 			}
-
-			return nil
-		})
-		if err != nil {
-			return err
+			startGoRoutines++
+			waiter.Add(1)
+			go SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, bestEffort, waiter, pos)
 		}
 
-		if maxConcurrent != 0 {
-			waiter.Wait()
-		}
 		return nil
 	})
+	if maxConcurrent != 0 {
+		waiter.Wait()
+	}
 	if err != nil {
 		log.Fatalln(err)
 	}
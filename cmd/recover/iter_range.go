@@ -1,7 +1,6 @@
 package main
 
 import (
-	"log"
 	"sync"
 	"time"
 
@@ -9,7 +8,6 @@ import (
 	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
 	"github.com/TriM-Organization/bedrock-world-operator/world"
 	"github.com/pterm/pterm"
-	"go.etcd.io/bbolt"
 )
 
 func IterRange(
@@ -20,6 +18,7 @@ func IterRange(
 	maxConcurrent int,
 	providedUnixTime int64,
 	ensureExistOne bool,
+	bestEffort bool,
 ) {
 	startTime := time.Now()
 	counter := 0
@@ -28,38 +27,30 @@ func IterRange(
 		pterm.Success.Println("Found chunks:", counter)
 	}()
 
-	err := db.UnderlyingDatabase().View(func(tx *bbolt.Tx) error {
-		var startGoRoutines = 0
+	var startGoRoutines = 0
+	waiter := new(sync.WaitGroup)
 
-		bucket := tx.Bucket(timeline.DatabaseKeyChunkIndex)
-		waiter := new(sync.WaitGroup)
-
-		for _, pos := range enumChunks {
-			if bucket.Get(define.Index(pos)) == nil {
-				continue
-			}
-			counter++
-
-			if maxConcurrent == 0 {
-				waiter.Add(1)
-				SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, waiter, pos)
-			} else {
-				if startGoRoutines > maxConcurrent {
-					waiter.Wait()
-					startGoRoutines = 0
-				}
-				startGoRoutines++
-				waiter.Add(1)
-				go SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, waiter, pos)
+	for _, pos := range enumChunks {
+		if !db.HasChunkTimeline(pos) {
+			continue
+		}
+		counter++
+
+		if maxConcurrent == 0 {
+			waiter.Add(1)
+			SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, bestEffort, waiter, pos)
+		} else {
+			if startGoRoutines > maxConcurrent {
+				waiter.Wait()
+				startGoRoutines = 0
 			}
+			startGoRoutines++
+			waiter.Add(1)
+			go SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, bestEffort, waiter, pos)
 		}
+	}
 
-		if maxConcurrent != 0 {
-			waiter.Wait()
-		}
-		return nil
-	})
-	if err != nil {
-		log.Fatalln(err)
+	if maxConcurrent != 0 {
+		waiter.Wait()
 	}
 }
@@ -2,7 +2,6 @@ package main
 
 import (
 	"log"
-	"slices"
 	"sync"
 	"time"
 
@@ -10,7 +9,6 @@ import (
 	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
 	"github.com/TriM-Organization/bedrock-world-operator/world"
 	"github.com/pterm/pterm"
-	"go.etcd.io/bbolt"
 )
 
 func IterRangeEntireDatabase(
@@ -21,6 +19,7 @@ func IterRangeEntireDatabase(
 	maxConcurrent int,
 	providedUnixTime int64,
 	ensureExistOne bool,
+	bestEffort bool,
 ) {
 	startTime := time.Now()
 	counter := 0
@@ -34,48 +33,33 @@ func IterRangeEntireDatabase(
 		mapping[value] = true
 	}
 
-	err := db.UnderlyingDatabase().View(func(tx *bbolt.Tx) error {
-		var foundKeyChunkCount bool
-		var startGoRoutines = 0
-		bucket := tx.Bucket(timeline.DatabaseKeyChunkIndex)
-		waiter := new(sync.WaitGroup)
-
-		err := bucket.ForEach(func(k, v []byte) error {
-			if !foundKeyChunkCount && slices.Equal(k, timeline.DatabaseKeyChunkCount) {
-				foundKeyChunkCount = true
-				return nil
-			}
-
-			pos := define.IndexInv(k)
-			if !mapping[pos] {
-				return nil
-			}
-			counter++
-
-			if maxConcurrent == 0 {
-				waiter.Add(1)
-				SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, waiter, pos)
-			} else {
-				if startGoRoutines > maxConcurrent {
-					waiter.Wait()
-					startGoRoutines = 0
-				}
-				startGoRoutines++
-				waiter.Add(1)
-				go SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, waiter, pos)
-			}
+	var startGoRoutines = 0
+	waiter := new(sync.WaitGroup)
 
+	err := db.ForEachChunkTimeline(func(pos define.DimChunk) error {
+		if !mapping[pos] {
 			return nil
-		})
-		if err != nil {
-			return err
 		}
+		counter++
 
-		if maxConcurrent != 0 {
-			waiter.Wait()
+		if maxConcurrent == 0 {
+			waiter.Add(1)
+			SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, bestEffort, waiter, pos)
+		} else {
+			if startGoRoutines > maxConcurrent {
+				waiter.Wait()
+				startGoRoutines = 0
+			}
+			startGoRoutines++
+			waiter.Add(1)
+			go SingleChunkRunner(db, w, doCompact, providedUnixTime, ensureExistOne, bestEffort, waiter, pos)
 		}
+
 		return nil
 	})
+	if maxConcurrent != 0 {
+		waiter.Wait()
+	}
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -1,21 +1,21 @@
 package main
 
 import (
-	"encoding/binary"
 	"flag"
 	"log"
 	"time"
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
+	"github.com/TriM-Organization/bedrock-world-operator/block"
 	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
 	"github.com/TriM-Organization/bedrock-world-operator/world"
 	"github.com/pterm/pterm"
-	"go.etcd.io/bbolt"
 )
 
 var (
 	path             *string
+	backend          *string
 	output           *string
 	maxConcurrent    *int
 	doCompact        *bool
@@ -27,12 +27,15 @@ var (
 	rangeEndZ        *int
 	providedUnixTime *int64
 	ensureExistOne   *bool
+	bestEffort       *bool
+	recoverPlayers   *bool
 	noGrowSync       *bool
 	noSync           *bool
 )
 
 func init() {
 	path = flag.String("path", "", "The path of your timeline database.")
+	backend = flag.String("backend", "bbolt", "The storage backend of your timeline database (bbolt or leveldb).")
 	output = flag.String("output", "", "The path to output your Minecraft world.")
 	doCompact = flag.Bool("do-compact", false, "Compact the output mcworld to make it as small as possible.")
 	maxConcurrent = flag.Int("max-concurrent", 4096, "The maximum concurrent quantity. Set 0 to disable. Note that set to 1 is slow than when set to 0.")
@@ -57,6 +60,22 @@ func init() {
 			"chunk meet the given time conditions, ensure that at least the closest one can be selected.",
 	)
 
+	bestEffort = flag.Bool(
+		"best-effort",
+		false,
+		""+
+			"Skip the broken block NBTs (block entities) instead of skipping the whole chunk. "+
+			"The skipped ones come back when the timeline resync from the next full block NBT snapshot.",
+	)
+
+	recoverPlayers = flag.Bool(
+		"recover-players",
+		false,
+		""+
+			"Also restore the player data (e.g. the inventory) as of the same time. "+
+			"The player data is saved by the \"player_server_<UUID>\" keys of the output world.",
+	)
+
 	noGrowSync = flag.Bool("no-grow-sync", true, "Database settings: No grow sync.")
 	noSync = flag.Bool("no-sync", true, "Database settings: No Sync.")
 
@@ -67,18 +86,33 @@ func init() {
 	if len(*output) == 0 {
 		log.Fatalln("Please provide the path to output your Minecraft world.\n\te.g. -output \"mcworld\"")
 	}
+	if *backend != "bbolt" && *backend != "leveldb" {
+		log.Fatalln("backend must be bbolt or leveldb.")
+	}
 	if *maxConcurrent < 0 {
 		log.Fatalln("max-concurrent can't less than 0.")
 	}
 }
 
 func main() {
-	db, err := timeline.Open(*path, *noGrowSync, *noSync)
+	var db timeline.TimelineDatabase
+	var err error
+
+	if *backend == "leveldb" {
+		db, err = timeline.OpenLevel(*path)
+	} else {
+		db, err = timeline.Open(*path, *noGrowSync, *noSync)
+	}
 	if err != nil {
 		log.Fatalln(err)
 	}
 	defer db.CloseTimelineDB()
 
+	// The recovered chunks are encoded by bedrock-world-operator, so
+	// let it know the custom blocks that registered to this database.
+	registry := db.BlockRegistry()
+	block.RuntimeIDToState, block.StateToRuntimeID = registry.RuntimeIDToState, registry.StateToRuntimeID
+
 	w, err := world.Open(*output)
 	if err != nil {
 		log.Fatalln(err)
@@ -103,27 +137,28 @@ func main() {
 			}
 		}
 
-		err = db.UnderlyingDatabase().View(func(tx *bbolt.Tx) error {
-			countBytes := tx.Bucket(timeline.DatabaseKeyChunkIndex).Get(timeline.DatabaseKeyChunkCount)
-			if len(countBytes) < 4 {
-				countBytes = make([]byte, 4)
-			}
-			if binary.LittleEndian.Uint32(countBytes) < uint32(len(enumChunks)) {
-				shouldIterEntire = true
-			}
-			return nil
-		})
-		if err != nil {
-			log.Fatalln(err)
+		if db.ChunkCount() < uint32(len(enumChunks)) {
+			shouldIterEntire = true
 		}
 
 		if shouldIterEntire {
-			IterRangeEntireDatabase(db, w, *doCompact, enumChunks, *maxConcurrent, *providedUnixTime, *ensureExistOne)
+			IterRangeEntireDatabase(db, w, *doCompact, enumChunks, *maxConcurrent, *providedUnixTime, *ensureExistOne, *bestEffort)
 		} else {
-			IterRange(db, w, *doCompact, enumChunks, *maxConcurrent, *providedUnixTime, *ensureExistOne)
+			IterRange(db, w, *doCompact, enumChunks, *maxConcurrent, *providedUnixTime, *ensureExistOne, *bestEffort)
 		}
 	} else {
-		IterEntireDatabase(db, w, *doCompact, *maxConcurrent, *providedUnixTime, *ensureExistOne)
+		IterEntireDatabase(db, w, *doCompact, *maxConcurrent, *providedUnixTime, *ensureExistOne, *bestEffort)
+	}
+
+	if *recoverPlayers {
+		err = SavePlayers(db, w, *providedUnixTime, *ensureExistOne)
+		if err != nil {
+			// The deferred functions are not called by log.Fatalln,
+			// so the chunks that already restored are saved here.
+			_ = w.CloseWorld()
+			_ = db.CloseTimelineDB()
+			log.Fatalln(err)
+		}
 	}
 
 	pterm.Success.Println("ALL DOWN :)")
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-world-operator/define"
+	"github.com/TriM-Organization/bedrock-world-operator/world"
+	world_define "github.com/TriM-Organization/bedrock-world-operator/world/define"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+// SaveEntities saves entities to the chunk at position of w,
+// and the entities that already in this chunk are removed.
+//
+// The entities who have a "UniqueID" tag are saved in the modern format, that
+// is, a "digp" key lists the unique IDs of all these entities, and each entity
+// is saved by an "actorprefix" key. The others are saved in the legacy format,
+// which will be upgraded by Minecraft when this chunk is loaded.
+func SaveEntities(w world.World, dm define.Dimension, position define.ChunkPos, entities []map[string]any) error {
+	identifiersKey := world_define.Sum(dm, position, []byte(world_define.KeyEntityIdentifiers)...)
+	legacyKey := world_define.Sum(dm, position, world_define.KeyEntities)
+
+	// Remove the old entities
+	has, err := w.Has(identifiersKey)
+	if err != nil {
+		return fmt.Errorf("SaveEntities: %w", err)
+	}
+	if has {
+		identifiers, err := w.Get(identifiersKey)
+		if err != nil {
+			return fmt.Errorf("SaveEntities: %w", err)
+		}
+		for len(identifiers) >= 8 {
+			err = w.Delete(append([]byte(world_define.KeyEntity), identifiers[:8]...))
+			if err != nil {
+				return fmt.Errorf("SaveEntities: %w", err)
+			}
+			identifiers = identifiers[8:]
+		}
+	}
+
+	// Save the new entities
+	identifiers := bytes.NewBuffer(nil)
+	legacy := bytes.NewBuffer(nil)
+	for _, entity := range entities {
+		buf := bytes.NewBuffer(nil)
+		if err = nbt.NewEncoderWithEncoding(buf, nbt.LittleEndian).Encode(entity); err != nil {
+			return fmt.Errorf("SaveEntities: %w", err)
+		}
+
+		uniqueID, ok := entity["UniqueID"].(int64)
+		if !ok {
+			legacy.Write(buf.Bytes())
+			continue
+		}
+
+		identifier := binary.LittleEndian.AppendUint64(nil, uint64(uniqueID))
+		identifiers.Write(identifier)
+		err = w.Put(append([]byte(world_define.KeyEntity), identifier...), buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("SaveEntities: %w", err)
+		}
+	}
+
+	for key, value := range map[string][]byte{
+		string(identifiersKey): identifiers.Bytes(),
+		string(legacyKey):      legacy.Bytes(),
+	} {
+		if len(value) == 0 {
+			err = w.Delete([]byte(key))
+		} else {
+			err = w.Put([]byte(key), value)
+		}
+		if err != nil {
+			return fmt.Errorf("SaveEntities: %w", err)
+		}
+	}
+
+	return nil
+}
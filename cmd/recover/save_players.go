@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
+	"github.com/TriM-Organization/bedrock-world-operator/world"
+	"github.com/pterm/pterm"
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+// SavePlayers restores the data of all the players that have timeline in db,
+// and saves them to w by the "player_server_<UUID>" keys.
+//
+// The time point of each player is chosen in the same way as the chunks,
+// so the players are restored as of the same time of the world.
+//
+// A player that failed to restore doesn't stop the others, and all the
+// failures are joined into the returned error.
+func SavePlayers(db timeline.TimelineDatabase, w world.World, providedUnixTime int64, ensureExistOne bool) error {
+	ids := make([]string, 0)
+	err := db.ForEachPlayerTimeline(func(id string) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("SavePlayers: %w", err)
+	}
+
+	failures := make([]error, 0)
+	for _, id := range ids {
+		err = SinglePlayerRunner(db, w, providedUnixTime, ensureExistOne, id)
+		if err != nil {
+			pterm.Warning.Printf("SavePlayers: %v\n", err)
+			failures = append(failures, fmt.Errorf("player %s: %w", id, err))
+			continue
+		}
+		pterm.Info.Printf("Player %s is down.\n", id)
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("SavePlayers: %d of %d players failed to restore: %w", len(failures), len(ids), errors.Join(failures...))
+	}
+	return nil
+}
+
+// SinglePlayerRunner restores the data of the player whose identifier is id.
+func SinglePlayerRunner(db timeline.TimelineDatabase, w world.World, providedUnixTime int64, ensureExistOne bool, id string) error {
+	var player map[string]any
+
+	tl, err := db.NewPlayerTimeline(id, true)
+	if err != nil {
+		return fmt.Errorf("SinglePlayerRunner: %w", err)
+	}
+	defer tl.Save()
+
+	if tl.Empty() {
+		return nil
+	}
+
+	index, hit := slices.BinarySearch(tl.AllTimePoint(), providedUnixTime)
+	if !hit {
+		index--
+	}
+
+	if index < 0 {
+		if !ensureExistOne {
+			return nil
+		}
+		index = 0
+	}
+
+	if index >= tl.AllTimePointLen() {
+		player, _, err = tl.Last()
+	} else {
+		player, _, err = tl.JumpTo(uint(index))
+	}
+	if err != nil {
+		return fmt.Errorf("SinglePlayerRunner: %w", err)
+	}
+
+	key := []byte("player_server_" + id)
+	if len(player) == 0 {
+		err = w.Delete(key)
+		if err != nil {
+			return fmt.Errorf("SinglePlayerRunner: %w", err)
+		}
+		return nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	if err = nbt.NewEncoderWithEncoding(buf, nbt.LittleEndian).Encode(player); err != nil {
+		return fmt.Errorf("SinglePlayerRunner: %w", err)
+	}
+	err = w.Put(key, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("SinglePlayerRunner: %w", err)
+	}
+
+	return nil
+}
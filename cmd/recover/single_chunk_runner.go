@@ -17,11 +17,13 @@ func SingleChunkRunner(
 	doCompact bool,
 	providedUnixTime int64,
 	ensureExistOne bool,
+	bestEffort bool,
 	waiter *sync.WaitGroup,
 	pos define.DimChunk,
 ) {
 	var c *chunk.Chunk
 	var nbts []map[string]any
+	var entities []map[string]any
 
 	defer func() {
 		waiter.Done()
@@ -34,6 +36,7 @@ func SingleChunkRunner(
 		return
 	}
 	defer tl.Save()
+	tl.SetBestEffort(bestEffort)
 
 	if tl.Empty() {
 		return
@@ -52,19 +55,26 @@ func SingleChunkRunner(
 	}
 
 	if index >= tl.AllTimePointLen() {
-		c, nbts, _, err = tl.Last()
+		c, nbts, entities, _, err = tl.Last()
 		if err != nil {
 			pterm.Warning.Printf("SingleChunkRunner: %v\n", err)
 			return
 		}
 	} else {
-		c, nbts, _, err = tl.JumpTo(uint(index))
+		c, nbts, entities, _, err = tl.JumpTo(uint(index))
 		if err != nil {
 			pterm.Warning.Printf("SingleChunkRunner: %v\n", err)
 			return
 		}
 	}
 
+	if tl.SkippedNBTs() > 0 || tl.IgnoredDeltas() > 0 {
+		pterm.Warning.Printf(
+			"SingleChunkRunner: Chunk (%d, %d) in dim %d is restored with %d block NBTs skipped and %d broken deltas ignored\n",
+			pos.ChunkPos[0], pos.ChunkPos[1], pos.Dimension, tl.SkippedNBTs(), tl.IgnoredDeltas(),
+		)
+	}
+
 	if doCompact {
 		c.Compact()
 	}
@@ -79,4 +89,10 @@ func SingleChunkRunner(
 		pterm.Warning.Printf("SingleChunkRunner: %v\n", err)
 		return
 	}
+
+	err = SaveEntities(w, pos.Dimension, pos.ChunkPos, entities)
+	if err != nil {
+		pterm.Warning.Printf("SingleChunkRunner: %v\n", err)
+		return
+	}
 }
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/timeline"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	"github.com/pterm/pterm"
+)
+
+var (
+	path       *string
+	backend    *string
+	codec      *string
+	level      *int
+	trainDict  *bool
+	dictSample *int
+	dictSize   *int
+	palette    *string
+	dedup      *string
+	noGrowSync *bool
+	noSync     *bool
+)
+
+var codecMapping = map[string]byte{
+	"none":   utils.CompressionNone,
+	"gzip":   utils.CompressionGzip,
+	"zstd":   utils.CompressionZstd,
+	"snappy": utils.CompressionSnappy,
+}
+
+func init() {
+	path = flag.String("path", "", "The path of your timeline database.")
+	backend = flag.String("backend", "bbolt", "The storage backend of your timeline database (bbolt or leveldb).")
+	codec = flag.String("codec", "zstd", "The codec to re-encode all the data (none, gzip, zstd or snappy).")
+	level = flag.Int("level", 3, "The compression level of the codec. For gzip, it is ranging from -2 to 9, and for zstd, it is ranging from 1 to 22.")
+
+	trainDict = flag.Bool("train-dict", false, "Train a zstd dictionary from the existing deltas, and use it to re-encode all the deltas.")
+	dictSample = flag.Int("dict-samples", 10000, "The max count of deltas that used to train the dictionary.")
+	dictSize = flag.Int("dict-size", 112640, "The max size of the trained dictionary.")
+
+	palette = flag.String("palette", "", "Migrate the block palette of all the chunks to the database-wide shared palette (shared) or back to each chunk (chunk). Keep the current setting if empty.")
+
+	dedup = flag.String("dedup", "", "Deduplicate the sub chunk layers of the latest chunks (on) or save them directly (off). Keep the current setting if empty.")
+
+	noGrowSync = flag.Bool("no-grow-sync", true, "Database settings: No grow sync.")
+	noSync = flag.Bool("no-sync", true, "Database settings: No Sync.")
+
+	flag.Parse()
+	if len(*path) == 0 {
+		log.Fatalln("Please provide the path of your timeline database.\n\te.g. -path \"test\"")
+	}
+	if *backend != "bbolt" && *backend != "leveldb" {
+		log.Fatalln("backend must be bbolt or leveldb.")
+	}
+	if _, ok := codecMapping[*codec]; !ok {
+		log.Fatalln("codec must be none, gzip, zstd or snappy.")
+	}
+	if *palette != "" && *palette != "shared" && *palette != "chunk" {
+		log.Fatalln("palette must be shared or chunk.")
+	}
+	if *dedup != "" && *dedup != "on" && *dedup != "off" {
+		log.Fatalln("dedup must be on or off.")
+	}
+}
+
+func main() {
+	var db timeline.TimelineDatabase
+	var err error
+
+	compression, err := utils.NewCompression(codecMapping[*codec], *level)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *backend == "leveldb" {
+		db, err = timeline.OpenLevel(*path)
+	} else {
+		db, err = timeline.Open(*path, *noGrowSync, *noSync)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.CloseTimelineDB()
+
+	err = db.SetCompression(compression)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	if *palette != "" {
+		err = db.SetSharedPalette(*palette == "shared")
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if *dedup != "" {
+		err = db.SetSubChunkDedup(*dedup == "on")
+		if err != nil {
+			log.Fatalln(err)
+		}
+	}
+
+	if *trainDict {
+		version, err := db.TrainDictionary(*dictSample, *dictSize, max(*level, 1))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		pterm.Info.Printf("Dictionary %d is trained.\n", version)
+	}
+
+	startTime := time.Now()
+	allChunks := make([]define.DimChunk, 0)
+	err = db.ForEachChunkTimeline(func(pos define.DimChunk) error {
+		allChunks = append(allChunks, pos)
+		return nil
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for index, pos := range allChunks {
+		err = db.RewriteChunkTimeline(pos)
+		if err != nil {
+			pterm.Warning.Printf("Chunk (%d, %d) in dim %d: %v\n", pos.ChunkPos[0], pos.ChunkPos[1], pos.Dimension, err)
+			continue
+		}
+		pterm.Info.Printf("Chunk (%d, %d) in dim %d is down (%d/%d).\n", pos.ChunkPos[0], pos.ChunkPos[1], pos.Dimension, index+1, len(allChunks))
+	}
+
+	allPlayers := make([]string, 0)
+	err = db.ForEachPlayerTimeline(func(id string) error {
+		allPlayers = append(allPlayers, id)
+		return nil
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	for index, id := range allPlayers {
+		err = db.RewritePlayerTimeline(id)
+		if err != nil {
+			pterm.Warning.Printf("Player %s: %v\n", id, err)
+			continue
+		}
+		pterm.Info.Printf("Player %s is down (%d/%d).\n", id, index+1, len(allPlayers))
+	}
+
+	removed, err := db.GCSharedPalette()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if removed > 0 {
+		pterm.Info.Printf("%d unused shared palette entries are removed.\n", removed)
+	}
+
+	pterm.Success.Println("Time used:", time.Since(startTime))
+	pterm.Success.Println("Found chunks:", len(allChunks))
+	pterm.Success.Println("Found players:", len(allPlayers))
+	pterm.Success.Println("ALL DOWN :)")
+}
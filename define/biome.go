@@ -0,0 +1,81 @@
+package define
+
+import "github.com/TriM-Organization/bedrock-world-operator/chunk"
+
+type (
+	// ChunkBiomeMatrix represents the biomes of all sub chunks in a chunk.
+	//
+	// Each element is a BlockMatrix that holds the biome IDs of a sub chunk
+	// (not the block palette indexes), and a nil one means all the biome IDs
+	// in this sub chunk are 0.
+	ChunkBiomeMatrix []BlockMatrix
+	// ChunkBiomeDiffMatrix represents the difference of biomes for
+	// all sub chunks in the target chunk between two different times.
+	ChunkBiomeDiffMatrix []DiffMatrix
+)
+
+// ChunkToBiomeMatrix converts the biomes of c to its chunk biome matrix represents.
+// Time complexity: O(4096×n), n is the sub chunk count of this chunk.
+func ChunkToBiomeMatrix(c *chunk.Chunk) (result ChunkBiomeMatrix) {
+	result = make(ChunkBiomeMatrix, len(c.Sub()))
+
+	for index, biomes := range c.Biomes() {
+		if index >= len(result) {
+			break
+		}
+
+		var empty = true
+		for _, value := range biomes {
+			if value != 0 {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			continue
+		}
+
+		result[index] = NewBlockMatrix()
+		copy(result[index][:], biomes)
+	}
+
+	return
+}
+
+// BiomeDifference computes the difference between older and newer.
+// We assume len(older) = len(newer).
+//
+// Time complexity: O(4096×n), n=len(older).
+func BiomeDifference(older ChunkBiomeMatrix, newer ChunkBiomeMatrix) ChunkBiomeDiffMatrix {
+	result := make(ChunkBiomeDiffMatrix, len(older))
+	for i := range result {
+		result[i] = BlockDifference(older[i], newer[i])
+	}
+	return result
+}
+
+// BiomeRestore use old and diff to compute the newer chunk biome matrix.
+// We assume len(old) = len(diff).
+//
+// The same as ChunkRestore, the biome matrix of each sub chunk in the
+// returned chunk biome matrix is the same one that come from old.
+//
+// Time complexity: O(n×L), n=len(old).
+// L is the average count of changes that each sub chunk have.
+func BiomeRestore(old ChunkBiomeMatrix, diff ChunkBiomeDiffMatrix) ChunkBiomeMatrix {
+	result := make(ChunkBiomeMatrix, len(old))
+	for i := range result {
+		result[i] = BlockRestore(old[i], diff[i])
+	}
+	return result
+}
+
+// BiomeNoChange reports diff is empty or not.
+func BiomeNoChange(diff ChunkBiomeDiffMatrix) bool {
+	for _, value := range diff {
+		if !BlockNoChange(value) {
+			return false
+		}
+	}
+	return true
+}
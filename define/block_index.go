@@ -3,6 +3,7 @@ package define
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 )
 
 // BlockIndex is a integer that ranging from 0 to 4095,
@@ -40,10 +41,22 @@ func (b BlockIndex) Marshal(buf *bytes.Buffer) {
 }
 
 // Unmarshal decode a BlockIndex from the underlying bytes buffer.
-func (b *BlockIndex) Unmarshal(buf *bytes.Buffer) {
-	temp := make([]byte, 2)
-	_, _ = buf.Read(temp)
-	*b = BlockIndex(binary.LittleEndian.Uint16(temp))
+// If the bytes buffer is truncated or the decoded index is not
+// ranging from 0 to 4095, then returns an error that wraps
+// ErrMalformed.
+func (b *BlockIndex) Unmarshal(buf *bytes.Buffer) error {
+	temp := buf.Next(2)
+	if len(temp) != 2 {
+		return fmt.Errorf("Unmarshal: %w (block index is truncated)", ErrMalformed)
+	}
+
+	index := binary.LittleEndian.Uint16(temp)
+	if index >= 4096 {
+		return fmt.Errorf("Unmarshal: %w (block index %d is out of range)", ErrMalformed, index)
+	}
+
+	*b = BlockIndex(index)
+	return nil
 }
 
 // ChunkBlockIndex holds two (u)int16 integers
@@ -91,9 +104,18 @@ func (c ChunkBlockIndex) Marshal(buf *bytes.Buffer) {
 }
 
 // Unmarshal decode a ChunkBlockIndex from the underlying bytes buffer.
-func (c *ChunkBlockIndex) Unmarshal(buf *bytes.Buffer) {
-	c.blockIndex.Unmarshal(buf)
-	temp := make([]byte, 2)
-	_, _ = buf.Read(temp)
+// If the bytes buffer is truncated or the decoded block index is out
+// of range, then returns an error that wraps ErrMalformed.
+func (c *ChunkBlockIndex) Unmarshal(buf *bytes.Buffer) error {
+	if err := c.blockIndex.Unmarshal(buf); err != nil {
+		return fmt.Errorf("Unmarshal: %w", err)
+	}
+
+	temp := buf.Next(2)
+	if len(temp) != 2 {
+		return fmt.Errorf("Unmarshal: %w (sub chunk index is truncated)", ErrMalformed)
+	}
 	c.inWhichSubChunk = int16(binary.LittleEndian.Uint16(temp))
+
+	return nil
 }
@@ -9,9 +9,14 @@ type (
 	// BlockMatrix represents a block matrix at a specific point in time.
 	BlockMatrix *[MatrixSize]uint32
 	// SingleBlockDiff represents a single block change who in a sub chunk.
+	//
+	// If RunLength is not 0, then it represents a run of block changes,
+	// and the RunLength blocks that follow are also changed to NewPaletteID.
+	// IndexDelta is always relative to the last block of the previous one.
 	SingleBlockDiff struct {
 		IndexDelta   uint32
 		NewPaletteID uint32
+		RunLength    uint32
 	}
 	// DiffMatrix is a matrix that holds the difference of
 	// BlockMatrix between time i-1 and time i.
@@ -62,7 +67,8 @@ func BlockDifference(older BlockMatrix, newer BlockMatrix) DiffMatrix {
 // BlockRestore use old and diff to compute the newer block matrix.
 // Note that the returned block martix is the same object of old when
 // old is not empty.
-// Time complexity: O(l), l=len(diff).
+// The runs in diff (see SingleBlockDiff) are applied directly.
+// Time complexity: O(l), l is the count of changed blocks in diff.
 //
 // Note that you could do this operation for all difference array,
 // then you will get the final block matrix that represents the latest one.
@@ -81,13 +87,80 @@ func BlockRestore(old BlockMatrix, diff DiffMatrix) BlockMatrix {
 	index := uint32(0)
 	for _, value := range diff {
 		index += value.IndexDelta
-		old[index] = value.NewPaletteID
+		if value.RunLength == 0 {
+			old[index] = value.NewPaletteID
+			continue
+		}
+		run := old[index : index+value.RunLength+1]
+		for i := range run {
+			run[i] = value.NewPaletteID
+		}
+		index += value.RunLength
 	}
 
 	return old
 }
 
+// DiffMatrixBlock finds the block at index in diff, and returns the
+// block palette index that it changed to. If this block is not changed
+// in diff, then changed is false.
+// The runs in diff (see SingleBlockDiff) are also considered.
+//
+// Time complexity: O(l), l is the length of diff.
+func DiffMatrixBlock(diff DiffMatrix, index uint32) (newPaletteID uint32, changed bool) {
+	current := uint32(0)
+	for _, value := range diff {
+		current += value.IndexDelta
+		if current > index {
+			break
+		}
+		if index <= current+value.RunLength {
+			return value.NewPaletteID, true
+		}
+		current += value.RunLength
+	}
+	return 0, false
+}
+
+// BlockChangeCount returns the count of changed blocks in diff,
+// and the runs in diff (see SingleBlockDiff) are also counted.
+//
+// Time complexity: O(l), l is the length of diff.
+func BlockChangeCount(diff DiffMatrix) (result int) {
+	for _, value := range diff {
+		result += int(value.RunLength) + 1
+	}
+	return
+}
+
 // BlockNoChange reports diff is empty or not.
 func BlockNoChange(diff DiffMatrix) bool {
 	return (len(diff) == 0)
 }
+
+// RemapBlockMatrix replaces each block palette index in matrix
+// by remap (see BlockPalette.Deduplicate), that is, index i will
+// become remap[i]. The indices that out of the range of remap are
+// not changed.
+// Time complexity: O(4096).
+func RemapBlockMatrix(matrix BlockMatrix, remap []uint32) {
+	if BlockMatrixIsEmpty(matrix) {
+		return
+	}
+	for index, value := range matrix {
+		if uint64(value) < uint64(len(remap)) {
+			matrix[index] = remap[value]
+		}
+	}
+}
+
+// RemapDiffMatrix is the same as RemapBlockMatrix,
+// but replaces the block palette indices in diff.
+// Time complexity: O(l), l is the length of diff.
+func RemapDiffMatrix(diff DiffMatrix, remap []uint32) {
+	for index, value := range diff {
+		if uint64(value.NewPaletteID) < uint64(len(remap)) {
+			diff[index].NewPaletteID = remap[value.NewPaletteID]
+		}
+	}
+}
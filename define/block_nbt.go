@@ -15,6 +15,7 @@ import (
 const (
 	ModifiedNBTBSDiff byte = iota
 	ModifiedNBTOrigin
+	ModifiedNBTStructural
 )
 
 // NBTWithIndex represents a single NBT block entity data who in a chunk.
@@ -69,16 +70,51 @@ type DiffNBTWithIndex struct {
 // NewDiffNBT returns the difference between between olderNBT and newerNBT.
 // Note that olderNBT and newerNBT must represents the NBt block in the same position.
 //
+// The difference is a list of NBTPatch (see NewStructuralDiffNBT) that could be inspected
+// by DiffNBTWithIndex.Patches. If the encoded patches is larger than newerNBT, then newerNBT
+// is saved entirely (ModifiedNBTOrigin) instead.
+//
+// Time complexity: O(N), N is the count of the values in olderNBT and newerNBT.
+func NewDiffNBT(olderNBT *NBTWithIndex, newerNBT *NBTWithIndex) (result *DiffNBTWithIndex, err error) {
+	if olderNBT == nil || newerNBT == nil {
+		return nil, fmt.Errorf("NewDiffNBT: olderNBT or newerNBT is nil")
+	}
+	if olderNBT.Index != newerNBT.Index {
+		return nil, fmt.Errorf("NewDiffNBT: Can't do difference operation between two blocks in different position")
+	}
+	result = &DiffNBTWithIndex{Index: olderNBT.Index}
+
+	patchesBytes := NBTPatchesBytes(NewStructuralDiffNBT(olderNBT.NBT, newerNBT.NBT))
+
+	buf := bytes.NewBuffer(nil)
+	utils.MarshalNBT(buf, newerNBT.NBT, "")
+	if buf.Len() < len(patchesBytes) {
+		result.DiffNBT = append([]byte{ModifiedNBTOrigin}, buf.Bytes()...)
+		return
+	}
+
+	result.DiffNBT = append([]byte{ModifiedNBTStructural}, patchesBytes...)
+	return
+}
+
+// NewBSDiffNBT returns the difference between between olderNBT and newerNBT as a bsdiff patch.
+// Note that olderNBT and newerNBT must represents the NBt block in the same position.
+//
+// The patch is computed over the bytes that encoded by utils.MarshalNBT, so it could not be
+// inspected, and could only be restored if the encoding of olderNBT is not changed.
+// NewDiffNBT is used by default, and NewBSDiffNBT is kept for the time points that saved
+// by the older versions.
+//
 // Time complexity: O(C).
 // Note that C is not very small and is little big due to
 //   - Use bsdiff to do restore to reduce bytes use.
 //   - Use xxhash to ensure when user do restore operation, they can verify the data they get is correct.
-func NewDiffNBT(olderNBT *NBTWithIndex, newerNBT *NBTWithIndex) (result *DiffNBTWithIndex, err error) {
+func NewBSDiffNBT(olderNBT *NBTWithIndex, newerNBT *NBTWithIndex) (result *DiffNBTWithIndex, err error) {
 	if olderNBT == nil || newerNBT == nil {
-		return nil, fmt.Errorf("NewDiffNBT: olderNBT or newerNBT is nil")
+		return nil, fmt.Errorf("NewBSDiffNBT: olderNBT or newerNBT is nil")
 	}
 	if olderNBT.Index != newerNBT.Index {
-		return nil, fmt.Errorf("NewDiffNBT: Can't do difference operation between two blocks in different position")
+		return nil, fmt.Errorf("NewBSDiffNBT: Can't do difference operation between two blocks in different position")
 	}
 	result = &DiffNBTWithIndex{Index: olderNBT.Index}
 
@@ -93,7 +129,7 @@ func NewDiffNBT(olderNBT *NBTWithIndex, newerNBT *NBTWithIndex) (result *DiffNBT
 	buf = bytes.NewBuffer(nil)
 	err = binarydist.Diff(bytes.NewBuffer(olderNBTBytes), bytes.NewBuffer(newerNBTBytes), buf)
 	if err != nil {
-		return nil, fmt.Errorf("NewDiffNBT: %v", err)
+		return nil, fmt.Errorf("NewBSDiffNBT: %v", err)
 	}
 
 	if buf.Len() > len(newerNBTBytes) {
@@ -126,8 +162,9 @@ func NewDiffNBT(olderNBT *NBTWithIndex, newerNBT *NBTWithIndex) (result *DiffNBT
 // then you will get the final block NBT data that represents the latest one.
 //
 // In this case, the time complexity is O(C×n) where n is the length of these difference array.
-// Note that C is not very small and is little big due to we use bsdiff to do restore and use
-// xxhash to ensure the data we get is correct.
+// For ModifiedNBTBSDiff, C is not very small and is little big due to we use bsdiff to do restore
+// and use xxhash to ensure the data we get is correct. For ModifiedNBTStructural, olderNBT is deep
+// copied and then the patches are applied, so C is relevant to the size of olderNBT.
 func (d DiffNBTWithIndex) Restore(olderNBT NBTWithIndex) (result *NBTWithIndex, err error) {
 	if d.Index != olderNBT.Index {
 		return nil, fmt.Errorf("Restore: Can't do restore operation between two blocks in different position")
@@ -137,12 +174,29 @@ func (d DiffNBTWithIndex) Restore(olderNBT NBTWithIndex) (result *NBTWithIndex,
 	}
 	result = &NBTWithIndex{Index: olderNBT.Index}
 
-	if d.DiffNBT[0] == ModifiedNBTOrigin {
-		err = nbt.NewDecoderWithEncoding(bytes.NewBuffer(d.DiffNBT[1:]), nbt.LittleEndian).Decode(&result.NBT)
+	switch d.DiffNBT[0] {
+	case ModifiedNBTOrigin:
+		result.NBT, err = ReadNBT(bytes.NewBuffer(d.DiffNBT[1:]))
 		if err != nil {
-			return nil, fmt.Errorf("Restore: %v", err)
+			return nil, fmt.Errorf("Restore: %w", err)
 		}
 		return
+	case ModifiedNBTStructural:
+		patches, err := BytesToNBTPatches(d.DiffNBT[1:])
+		if err != nil {
+			return nil, fmt.Errorf("Restore: %w", err)
+		}
+		olderCopy, err := NBTDeepCopy([]NBTWithIndex{olderNBT})
+		if err != nil {
+			return nil, fmt.Errorf("Restore: %v", err)
+		}
+		if result.NBT, err = ApplyNBTPatches(olderCopy[0].NBT, patches); err != nil {
+			return nil, fmt.Errorf("Restore: %w", err)
+		}
+		return result, nil
+	case ModifiedNBTBSDiff:
+	default:
+		return nil, fmt.Errorf("Restore: %w (unknown NBT delta kind %d)", ErrMalformed, d.DiffNBT[0])
 	}
 
 	if len(d.DiffNBT) < 17 {
@@ -168,13 +222,48 @@ func (d DiffNBTWithIndex) Restore(olderNBT NBTWithIndex) (result *NBTWithIndex,
 		return nil, fmt.Errorf("Restore: Data changed")
 	}
 
-	err = nbt.NewDecoderWithEncoding(bytes.NewBuffer(newerNBTBytes), nbt.LittleEndian).Decode(&result.NBT)
+	result.NBT, err = ReadNBT(bytes.NewBuffer(newerNBTBytes))
 	if err != nil {
-		return nil, fmt.Errorf("Restore: %v", err)
+		return nil, fmt.Errorf("Restore: %w", err)
 	}
 	return
 }
 
+// Kind returns the kind of this NBT delta, which is one of ModifiedNBTBSDiff,
+// ModifiedNBTOrigin and ModifiedNBTStructural. Returns 0xff if it is broken.
+func (d DiffNBTWithIndex) Kind() byte {
+	if len(d.DiffNBT) < 1 {
+		return 0xff
+	}
+	return d.DiffNBT[0]
+}
+
+// Patches returns the patches that hold by this NBT delta.
+//
+// For ModifiedNBTOrigin, a single NBTPatchSet with an empty path is returned,
+// which replaces the NBT entirely. ModifiedNBTBSDiff could not be inspected,
+// so an error is returned.
+func (d DiffNBTWithIndex) Patches() (patches []NBTPatch, err error) {
+	switch d.Kind() {
+	case ModifiedNBTStructural:
+		patches, err = BytesToNBTPatches(d.DiffNBT[1:])
+		if err != nil {
+			return nil, fmt.Errorf("Patches: %w", err)
+		}
+		return patches, nil
+	case ModifiedNBTOrigin:
+		m, err := ReadNBT(bytes.NewBuffer(d.DiffNBT[1:]))
+		if err != nil {
+			return nil, fmt.Errorf("Patches: %w", err)
+		}
+		return []NBTPatch{{Op: NBTPatchSet, Value: m}}, nil
+	case ModifiedNBTBSDiff:
+		return nil, fmt.Errorf("Patches: The NBT delta is a bsdiff patch that can't be inspected")
+	default:
+		return nil, fmt.Errorf("Patches: %w (unknown NBT delta kind %d)", ErrMalformed, d.Kind())
+	}
+}
+
 // MultipleDiffNBT represents the difference between NBT blocks in the same chunk but different times.
 // All the NBT blocks should in the same position in this chunk, and MultipleDiffNBT just refer to the
 // states (add/remove/modify) of these blocks in different times.
@@ -189,8 +278,8 @@ type MultipleDiffNBT struct {
 //
 // Time complexity: O(C×k + (a+b)), a=len(older), b=len(newer).
 //
-// k is the number that shown the counts of changed (modified) NBT blocks.
-// Note that C is not very small and is little big due to we use bsdiff and xxhash for each modified NBT block.
+// k is the number that shown the counts of changed (modified) NBT blocks,
+// and C is relevant to the size of each modified NBT block (see NewDiffNBT).
 func NBTDifference(older []NBTWithIndex, newer []NBTWithIndex) (result *MultipleDiffNBT, err error) {
 	olderSet := make(map[ChunkBlockIndex]*NBTWithIndex)
 	newerSet := make(map[ChunkBlockIndex]*NBTWithIndex)
@@ -246,7 +335,7 @@ func NBTDifference(older []NBTWithIndex, newer []NBTWithIndex) (result *Multiple
 // NBTRestore computes the newer block NBT data of this chunk by given old and diff.
 //
 // Time complexity: O(a+C×b), a=len(old), b=len(diff.Modified).
-// Note that C is not very small and is little big due to we use bsdiff and xxhash for each modified NBT block.
+// C is relevant to the kind of each modified NBT block (see DiffNBTWithIndex.Restore).
 func NBTRestore(old []NBTWithIndex, diff MultipleDiffNBT) (result []NBTWithIndex, err error) {
 	// Deep copy
 	oldCopy, err := NBTDeepCopy(old)
@@ -287,6 +376,58 @@ func NBTRestore(old []NBTWithIndex, diff MultipleDiffNBT) (result []NBTWithIndex
 	return
 }
 
+// NBTRestoreBestEffort is like NBTRestore, but the modified NBT blocks that failed to restore
+// (e.g. the older NBT is wrong or the delta is broken) are removed from result instead of failing
+// the whole chunk, and their indexes are returned as broken.
+//
+// Time complexity: O(a+C×b), a=len(old), b=len(diff.Modified).
+func NBTRestoreBestEffort(old []NBTWithIndex, diff MultipleDiffNBT) (result []NBTWithIndex, broken []ChunkBlockIndex, err error) {
+	oldCopy, err := NBTDeepCopy(old)
+	if err != nil {
+		return nil, nil, fmt.Errorf("NBTRestoreBestEffort: %v", err)
+	}
+
+	olderSet := make(map[ChunkBlockIndex]NBTWithIndex)
+	for _, value := range oldCopy {
+		olderSet[value.Index] = value
+	}
+
+	changedSet := make(map[ChunkBlockIndex]bool)
+	for _, value := range diff.Added {
+		changedSet[value.Index] = true
+		result = append(result, value)
+	}
+	for _, value := range diff.Removed {
+		changedSet[value] = true
+	}
+	for _, value := range diff.Modified {
+		if changedSet[value.Index] {
+			continue
+		}
+		changedSet[value.Index] = true
+
+		older, ok := olderSet[value.Index]
+		if !ok {
+			broken = append(broken, value.Index)
+			continue
+		}
+		newer, err := value.Restore(older)
+		if err != nil {
+			broken = append(broken, value.Index)
+			continue
+		}
+		result = append(result, *newer)
+	}
+
+	for _, value := range oldCopy {
+		if !changedSet[value.Index] {
+			result = append(result, value)
+		}
+	}
+
+	return result, broken, nil
+}
+
 // NBTNoChange reports diff is empty or not.
 func NBTNoChange(diff MultipleDiffNBT) bool {
 	return (len(diff.Removed) == 0 && len(diff.Added) == 0 && len(diff.Modified) == 0)
@@ -2,23 +2,42 @@ package define
 
 import (
 	"github.com/TriM-Organization/bedrock-world-operator/block"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
 )
 
 // BlockPalette is the block palette for a chunk timeline.
 // All time point in this line will share the same palette.
 type BlockPalette struct {
-	bp      []uint32
-	mapping map[uint32]uint32
+	bp       []uint32
+	mapping  map[uint32]uint32
+	registry *BlockRegistry
 }
 
 // NewBlockPalette creates a new block palette that only have a air block.
 // Technically speaking, air does not actually exist, but it can be found by agreement.
+//
+// The returned block palette only knows the standard block table,
+// use NewBlockPaletteWithRegistry if there are custom blocks.
 func NewBlockPalette() *BlockPalette {
+	return NewBlockPaletteWithRegistry(nil)
+}
+
+// NewBlockPaletteWithRegistry is the same as NewBlockPalette, but the
+// block states of the blocks in the returned palette are found from
+// registry, so the custom blocks that registered to it are known.
+func NewBlockPaletteWithRegistry(registry *BlockRegistry) *BlockPalette {
 	return &BlockPalette{
-		mapping: make(map[uint32]uint32),
+		mapping:  make(map[uint32]uint32),
+		registry: registry,
 	}
 }
 
+// Registry returns the block registry that used by this block palette.
+// If the palette only knows the standard block table, then nil is returned.
+func (b *BlockPalette) Registry() *BlockRegistry {
+	return b.registry
+}
+
 // AddBlock adds the block whose block runtime id is blockRuntimeID
 // to the underlying block palette.
 // If is exist or the given block is air, then do no operation.
@@ -36,6 +55,57 @@ func (b *BlockPalette) AddBlock(blockRuntimeID uint32) {
 	b.mapping[blockRuntimeID] = uint32(len(b.bp))
 }
 
+// AppendBlock appends the block whose block runtime id is blockRuntimeID
+// to the end of the underlying block palette, even if it is exist or is air.
+//
+// AppendBlock is used when loading a saved block palette, which ensure the
+// index of each entry is not changed when two saved entries are decoded to
+// the same block (e.g. they are upgraded to the same block state).
+// Note that BlockPaletteIndex will always return the first one, so you should
+// call Deduplicate after all the saved entries are appended.
+func (b *BlockPalette) AppendBlock(blockRuntimeID uint32) {
+	b.bp = append(b.bp, blockRuntimeID)
+	if blockRuntimeID == block.AirRuntimeID {
+		return
+	}
+	if _, ok := b.mapping[blockRuntimeID]; !ok {
+		b.mapping[blockRuntimeID] = uint32(len(b.bp))
+	}
+}
+
+// Deduplicate removes the duplicate entries and the air entries
+// (which are appended by AppendBlock) from the underlying block palette.
+//
+// The returned remap maps the old block palette index to the new one,
+// that is, remap[i] is the new index of the block whose index is i.
+// remap[0] is always 0 due to air block is not saved in block palette.
+// If there is nothing to remove, then remap is nil.
+func (b *BlockPalette) Deduplicate() (remap []uint32) {
+	newPalette := make([]uint32, 0, len(b.bp))
+	mapping := make(map[uint32]uint32)
+	remap = make([]uint32, len(b.bp)+1)
+
+	for index, blockRuntimeID := range b.bp {
+		if blockRuntimeID == block.AirRuntimeID {
+			continue
+		}
+		newIndex, ok := mapping[blockRuntimeID]
+		if !ok {
+			newPalette = append(newPalette, blockRuntimeID)
+			newIndex = uint32(len(newPalette))
+			mapping[blockRuntimeID] = newIndex
+		}
+		remap[index+1] = newIndex
+	}
+
+	if len(newPalette) == len(b.bp) {
+		return nil
+	}
+	b.bp = newPalette
+	b.mapping = mapping
+	return remap
+}
+
 // BlockPaletteIndex finds the index of blockRuntimeID in block palette.
 // If not exist, then added it the underlying block palette.
 //
@@ -65,6 +135,13 @@ func (b *BlockPalette) BlockRuntimeID(blockPaletteIndex uint32) uint32 {
 	return b.bp[blockPaletteIndex-1]
 }
 
+// BlockState returns the block state of the block that crresponding to
+// blockPaletteIndex, which is found from the registry of this palette.
+// Will not check if blockPaletteIndex is out of index (see BlockRuntimeID).
+func (b *BlockPalette) BlockState(blockPaletteIndex uint32) operator_define.BlockState {
+	return b.registry.BlockStateOf(b.BlockRuntimeID(blockPaletteIndex))
+}
+
 // BlockPaletteLen returns the length of underlying block palette.
 func (b *BlockPalette) BlockPaletteLen() int {
 	return len(b.bp)
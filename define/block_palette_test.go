@@ -0,0 +1,38 @@
+package define
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+)
+
+func TestBlockPaletteDeduplicate(t *testing.T) {
+	stone, _ := block.StateToRuntimeID("minecraft:stone", nil)
+	gold, _ := block.StateToRuntimeID("minecraft:gold_block", nil)
+
+	palette := NewBlockPalette()
+	for _, blockRuntimeID := range []uint32{stone, gold, stone, block.AirRuntimeID, gold} {
+		palette.AppendBlock(blockRuntimeID)
+	}
+
+	remap := palette.Deduplicate()
+	if !slices.Equal(remap, []uint32{0, 1, 2, 1, 0, 2}) {
+		t.Fatalf("Deduplicate: expected remap [0 1 2 1 0 2], but got %v", remap)
+	}
+	if !slices.Equal(palette.BlockPalette(), []uint32{stone, gold}) {
+		t.Fatalf("Deduplicate: expected palette [%d %d], but got %v", stone, gold, palette.BlockPalette())
+	}
+	if index := palette.BlockPaletteIndex(gold); index != 2 {
+		t.Fatalf("BlockPaletteIndex: expected 2, but got %d", index)
+	}
+	if remap = palette.Deduplicate(); remap != nil {
+		t.Fatalf("Deduplicate: expected nil remap, but got %v", remap)
+	}
+
+	diff := DiffMatrix{{NewPaletteID: 3}, {IndexDelta: 1, NewPaletteID: 5, RunLength: 2}, {IndexDelta: 3, NewPaletteID: 7}}
+	RemapDiffMatrix(diff, []uint32{0, 1, 2, 1, 0, 2})
+	if diff[0].NewPaletteID != 1 || diff[1].NewPaletteID != 2 || diff[2].NewPaletteID != 7 {
+		t.Fatalf("RemapDiffMatrix: got %v", diff)
+	}
+}
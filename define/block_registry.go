@@ -0,0 +1,160 @@
+package define
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// BlockRegistry holds the block states that the standard block
+// table of bedrock-world-operator don't know, such as the custom
+// blocks or the blocks that come from newer game version.
+//
+// They are mapped to the runtime ID that computed by the hash of
+// their name and states, which is the same as the network runtime
+// ID of these blocks.
+//
+// Each timeline database owns its own registry, so the block states
+// registered to one database are not visible to the others.
+// A nil registry only knows the standard block table.
+type BlockRegistry struct {
+	mu     *sync.RWMutex
+	states map[uint32]operator_define.BlockState
+}
+
+// NewBlockRegistry creates a new block registry
+// that only knows the standard block table.
+func NewBlockRegistry() *BlockRegistry {
+	return &BlockRegistry{
+		mu:     new(sync.RWMutex),
+		states: make(map[uint32]operator_define.BlockState),
+	}
+}
+
+// Register registers state which is not in the standard block
+// table, and returns the runtime ID that it mapped to.
+// If the state is already registered, then the registered one is kept.
+func (r *BlockRegistry) Register(state operator_define.BlockState) (runtimeID uint32) {
+	if state.Properties == nil {
+		state.Properties = make(map[string]any)
+	}
+	runtimeID = block.ComputeBlockHash(state.Name, state.Properties)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.states[runtimeID]; !ok {
+		r.states[runtimeID] = state
+	}
+
+	return runtimeID
+}
+
+// ExtraBlockState returns the block state whose runtime ID is runtimeID,
+// and the block state is registered to r but not in the standard block table.
+func (r *BlockRegistry) ExtraBlockState(runtimeID uint32) (state operator_define.BlockState, found bool) {
+	if r == nil {
+		return state, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	state, found = r.states[runtimeID]
+	return
+}
+
+// RuntimeIDToState has the same signature as block.RuntimeIDToState,
+// but it could also find the block states that registered to r.
+func (r *BlockRegistry) RuntimeIDToState(runtimeID uint32) (name string, properties map[string]any, found bool) {
+	if name, properties, found = standardRuntimeIDToState(runtimeID); found {
+		return
+	}
+	if state, ok := r.ExtraBlockState(runtimeID); ok {
+		return state.Name, state.Properties, true
+	}
+	return "", nil, false
+}
+
+// StateToRuntimeID has the same signature as block.StateToRuntimeID,
+// but it could also find the block states that registered to r.
+func (r *BlockRegistry) StateToRuntimeID(name string, properties map[string]any) (runtimeID uint32, found bool) {
+	runtimeID = block.ComputeBlockHash(name, properties)
+	if _, ok := r.ExtraBlockState(runtimeID); ok {
+		return runtimeID, true
+	}
+	return standardStateToRuntimeID(name, properties)
+}
+
+// BlockStateOf returns the block state of the block
+// whose runtime ID is blockRuntimeID.
+//
+// For the blocks that registered to r, the original name,
+// states and version are returned.
+// For the unknown blocks, minecraft:unknown is returned.
+func (r *BlockRegistry) BlockStateOf(blockRuntimeID uint32) operator_define.BlockState {
+	if state, ok := r.ExtraBlockState(blockRuntimeID); ok {
+		return state
+	}
+
+	name, states, found := standardRuntimeIDToState(blockRuntimeID)
+	if !found {
+		name, states = "minecraft:unknown", make(map[string]any)
+	}
+	return operator_define.BlockState{
+		Name:       name,
+		Properties: states,
+		Version:    chunk.CurrentBlockVersion,
+	}
+}
+
+// EncodeBlockState returns the NBT represent of the block
+// whose runtime ID is blockRuntimeID (see BlockStateOf).
+func (r *BlockRegistry) EncodeBlockState(blockRuntimeID uint32) map[string]any {
+	state := r.BlockStateOf(blockRuntimeID)
+	return map[string]any{
+		"name":    state.Name,
+		"states":  state.Properties,
+		"version": state.Version,
+	}
+}
+
+// DecodeBlockState decodes the NBT represent of a block that
+// encoded by EncodeBlockState, and returns its runtime ID.
+//
+// The block state is upgraded by UpgradeBlockState first if its
+// version is older than chunk.CurrentBlockVersion, and upgraded
+// is true if the stored NBT is outdated and should be written back.
+//
+// If the block state is not in the standard block table, then it
+// will be registered to r, so the original (or the upgraded) block
+// state could be encoded again verbatim.
+func (r *BlockRegistry) DecodeBlockState(m map[string]any) (blockRuntimeID uint32, upgraded bool, err error) {
+	name, _ := m["name"].(string)
+	version, _ := m["version"].(int32)
+	if len(name) == 0 {
+		return 0, false, fmt.Errorf("DecodeBlockState: %w (block name is missing)", ErrMalformed)
+	}
+
+	states := make(map[string]any)
+	if statesAny, ok := m["states"]; ok {
+		if states, ok = statesAny.(map[string]any); !ok {
+			return 0, false, fmt.Errorf("DecodeBlockState: %w (invalid states %#v of block %s)", ErrMalformed, statesAny, name)
+		}
+	}
+
+	original := operator_define.BlockState{
+		Name:       name,
+		Properties: states,
+		Version:    version,
+	}
+	state, changed := UpgradeBlockState(original)
+
+	if blockRuntimeID, ok := standardRuntimeID(state.Name, state.Properties); ok {
+		return blockRuntimeID, version != chunk.CurrentBlockVersion, nil
+	}
+	if changed {
+		return r.Register(state), true, nil
+	}
+	return r.Register(original), false, nil
+}
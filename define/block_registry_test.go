@@ -0,0 +1,49 @@
+package define
+
+import (
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+func TestBlockRegistry(t *testing.T) {
+	registry, other := NewBlockRegistry(), NewBlockRegistry()
+
+	unregistered := map[string]any{"color": "red"}
+	if _, found := registry.StateToRuntimeID("test:unregistered", unregistered); found {
+		t.Fatal("unregistered block state is found")
+	}
+	if state := registry.BlockStateOf(block.ComputeBlockHash("test:unregistered", unregistered)); state.Name != "minecraft:unknown" {
+		t.Fatalf("expected minecraft:unknown for unregistered block state, but got %s", state.Name)
+	}
+
+	state := operator_define.BlockState{Name: "test:custom", Properties: map[string]any{"color": "red"}, Version: 1}
+	runtimeID := registry.Register(state)
+
+	if result, found := registry.StateToRuntimeID(state.Name, state.Properties); !found || result != runtimeID {
+		t.Fatalf("StateToRuntimeID: expected %d, but got %d (found = %v)", runtimeID, result, found)
+	}
+	if name, _, found := registry.RuntimeIDToState(runtimeID); !found || name != state.Name {
+		t.Fatalf("RuntimeIDToState: expected %s, but got %s (found = %v)", state.Name, name, found)
+	}
+	if result := registry.BlockStateOf(runtimeID); result.Name != state.Name || result.Version != state.Version {
+		t.Fatalf("BlockStateOf: expected %v, but got %v", state, result)
+	}
+	palette := NewBlockPaletteWithRegistry(registry)
+	if result := palette.BlockState(palette.BlockPaletteIndex(runtimeID)); result.Name != state.Name {
+		t.Fatalf("BlockState: expected %s, but got %s", state.Name, result.Name)
+	}
+
+	// The registered block states are only known by
+	// this registry, but not the others or the process.
+	if _, found := other.StateToRuntimeID(state.Name, state.Properties); found {
+		t.Fatal("other.StateToRuntimeID: the block state of another registry is found")
+	}
+	if _, _, found := block.RuntimeIDToState(runtimeID); found {
+		t.Fatal("block.RuntimeIDToState: the block state of a registry is found")
+	}
+	if result := (*BlockRegistry)(nil).BlockStateOf(runtimeID); result.Name != "minecraft:unknown" {
+		t.Fatalf("BlockStateOf: expected minecraft:unknown for nil registry, but got %s", result.Name)
+	}
+}
@@ -0,0 +1,115 @@
+package define
+
+import (
+	"maps"
+	"strings"
+	"sync"
+
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+	"github.com/df-mc/worldupgrader/blockupgrader"
+)
+
+// BlockStateUpgrader upgrades state to a newer version. It returns
+// the original state if nothing need to be upgraded.
+//
+// BlockStateUpgrader must not modify the properties of state.
+type BlockStateUpgrader func(state operator_define.BlockState) operator_define.BlockState
+
+// blockStateUpgraders is the upgrade pipeline that used by
+// UpgradeBlockState, and worldupgrader is always the first one.
+var blockStateUpgraders = struct {
+	mu        *sync.RWMutex
+	upgraders []BlockStateUpgrader
+}{
+	mu:        new(sync.RWMutex),
+	upgraders: []BlockStateUpgrader{worldUpgrader},
+}
+
+// standardRuntimeIDToState and standardStateToRuntimeID are the lookups
+// of the standard block table, which are taken before any application
+// could replace block.RuntimeIDToState and block.StateToRuntimeID (e.g.
+// by the functions of a BlockRegistry).
+var (
+	standardRuntimeIDToState = block.RuntimeIDToState
+	standardStateToRuntimeID = block.StateToRuntimeID
+)
+
+// RegisterBlockStateUpgrader adds upgrader to the end of the
+// upgrade pipeline, which could be used to upgrade the custom
+// blocks or the blocks that df-mc/worldupgrader don't know.
+//
+// Note that the schemas of df-mc/worldupgrader could also be
+// extended by blockupgrader.RegisterSchema.
+func RegisterBlockStateUpgrader(upgrader BlockStateUpgrader) {
+	blockStateUpgraders.mu.Lock()
+	defer blockStateUpgraders.mu.Unlock()
+	blockStateUpgraders.upgraders = append(blockStateUpgraders.upgraders, upgrader)
+}
+
+// UpgradeBlockState runs state through the upgrade pipeline, and
+// returns the upgraded state. changed is false if nothing upgraded.
+//
+// The block states whose version is chunk.CurrentBlockVersion or
+// newer are returned directly, because the standard block table
+// could not know the blocks that come from newer game version.
+func UpgradeBlockState(state operator_define.BlockState) (result operator_define.BlockState, changed bool) {
+	if state.Version >= chunk.CurrentBlockVersion {
+		return state, false
+	}
+
+	blockStateUpgraders.mu.RLock()
+	upgraders := blockStateUpgraders.upgraders
+	blockStateUpgraders.mu.RUnlock()
+
+	result = state
+	for _, upgrader := range upgraders {
+		result = upgrader(result)
+	}
+
+	changed = result.Name != state.Name ||
+		result.Version != state.Version ||
+		block.ComputeBlockHash(result.Name, result.Properties) != block.ComputeBlockHash(state.Name, state.Properties)
+	return result, changed
+}
+
+// worldUpgrader upgrades state by the schemas of df-mc/worldupgrader.
+func worldUpgrader(state operator_define.BlockState) operator_define.BlockState {
+	// The upgrader of df-mc/worldupgrader may modify the properties
+	// in place, and the upgraded properties may shared with its schemas,
+	// so we copy them at both sides.
+	upgraded := blockupgrader.Upgrade(blockupgrader.BlockState{
+		Name:       state.Name,
+		Properties: maps.Clone(state.Properties),
+		Version:    state.Version,
+	})
+	return operator_define.BlockState{
+		Name:       upgraded.Name,
+		Properties: maps.Clone(upgraded.Properties),
+		Version:    upgraded.Version,
+	}
+}
+
+// standardRuntimeID finds the runtime ID of the block whose name is
+// name and states is properties from the standard block table.
+//
+// Different to block.StateToRuntimeID, found is false when the block
+// states is not exactly the same as the one in standard block table.
+func standardRuntimeID(name string, properties map[string]any) (runtimeID uint32, found bool) {
+	if !strings.Contains(name, ":") {
+		name = "minecraft:" + name
+	}
+
+	runtimeID, found = standardStateToRuntimeID(name, properties)
+	if !found {
+		return 0, false
+	}
+
+	standardName, standardProperties, found := standardRuntimeIDToState(runtimeID)
+	if !found || block.ComputeBlockHash(standardName, standardProperties) != block.ComputeBlockHash(name, properties) {
+		return 0, false
+	}
+
+	return runtimeID, true
+}
@@ -0,0 +1,184 @@
+package define
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	"github.com/TriM-Organization/bedrock-world-operator/define"
+	"github.com/cespare/xxhash/v2"
+)
+
+// subChunkHasher computes the content hash of sub chunks.
+//
+// The blocks are hashed by the hash of their name and states
+// but not their runtime ID, so the hash is stable even if the
+// standard block table is changed.
+// The layers that only have air are skipped, so a sub chunk
+// have no layer and the one that full of air are the same.
+type subChunkHasher struct {
+	digest *xxhash.Digest
+	buf    []byte
+	states map[uint32]uint32
+}
+
+// newSubChunkHasher creates a new subChunkHasher.
+func newSubChunkHasher() *subChunkHasher {
+	return &subChunkHasher{
+		digest: xxhash.New(),
+		buf:    make([]byte, 0, MatrixSize*4),
+		states: make(map[uint32]uint32),
+	}
+}
+
+// stateHash returns the hash of the name and states of
+// the block whose block runtime ID is blockRuntimeID.
+func (h *subChunkHasher) stateHash(blockRuntimeID uint32) uint32 {
+	if result, ok := h.states[blockRuntimeID]; ok {
+		return result
+	}
+
+	result := blockRuntimeID
+	if name, properties, found := standardRuntimeIDToState(blockRuntimeID); found {
+		result = block.ComputeBlockHash(name, properties)
+	}
+	h.states[blockRuntimeID] = result
+
+	return result
+}
+
+// sum computes the hash of a sub chunk, whose layers are
+// the block runtime IDs of each layer, and nbts is the block
+// NBTs in this sub chunk.
+func (h *subChunkHasher) sum(layers [][]uint32, nbts []NBTWithIndex) uint64 {
+	h.digest.Reset()
+
+	for index, layer := range layers {
+		if !slices.ContainsFunc(layer, func(blockRuntimeID uint32) bool {
+			return blockRuntimeID != block.AirRuntimeID
+		}) {
+			continue
+		}
+
+		h.buf = binary.LittleEndian.AppendUint32(h.buf[:0], uint32(index))
+		for _, blockRuntimeID := range layer {
+			h.buf = binary.LittleEndian.AppendUint32(h.buf, h.stateHash(blockRuntimeID))
+		}
+		_, _ = h.digest.Write(h.buf)
+	}
+
+	nbts = slices.Clone(nbts)
+	slices.SortFunc(nbts, func(a NBTWithIndex, b NBTWithIndex) int {
+		if a.Index.inWhichSubChunk != b.Index.inWhichSubChunk {
+			return int(a.Index.inWhichSubChunk) - int(b.Index.inWhichSubChunk)
+		}
+		return int(a.Index.blockIndex) - int(b.Index.blockIndex)
+	})
+
+	for _, value := range nbts {
+		buf := bytes.NewBuffer(h.buf[:0])
+		value.Index.Marshal(buf)
+		utils.MarshalNBT(buf, value.NBT, "")
+		_, _ = h.digest.Write(buf.Bytes())
+	}
+
+	return h.digest.Sum64()
+}
+
+// HashSubChunk returns the content hash of subChunk, and nbts
+// is the block NBTs in this sub chunk.
+//
+// HashSubChunk and HashLayers return the same hash for the same
+// blocks and block NBTs, so it could be used to check whether a
+// sub chunk is changed without computing its difference.
+func HashSubChunk(subChunk *chunk.SubChunk, nbts []NBTWithIndex) uint64 {
+	return newSubChunkHasher().sumSubChunk(subChunk, nbts)
+}
+
+// sumSubChunk is an internal implement detail of HashSubChunk.
+func (h *subChunkHasher) sumSubChunk(subChunk *chunk.SubChunk, nbts []NBTWithIndex) uint64 {
+	layers := make([][]uint32, 0, len(subChunk.Layers()))
+	for index := range subChunk.Layers() {
+		layers = append(layers, subChunk.Blocks(uint8(index)))
+	}
+	return h.sum(layers, nbts)
+}
+
+// HashLayers returns the content hash of the sub chunk whose
+// layers is layers, and nbts is the block NBTs in this sub chunk.
+// blockPalette is the block palette that layers used.
+func HashLayers(layers Layers, blockPalette *BlockPalette, nbts []NBTWithIndex) uint64 {
+	return newSubChunkHasher().sumLayers(layers, blockPalette, nbts)
+}
+
+// sumLayers is an internal implement detail of HashLayers.
+func (h *subChunkHasher) sumLayers(layers Layers, blockPalette *BlockPalette, nbts []NBTWithIndex) uint64 {
+	blocks := make([][]uint32, 0, len(layers))
+	for _, blockMatrix := range layers {
+		if BlockMatrixIsEmpty(blockMatrix) {
+			blocks = append(blocks, nil)
+			continue
+		}
+
+		layer := make([]uint32, MatrixSize)
+		for index, value := range blockMatrix {
+			layer[index] = blockPalette.BlockRuntimeID(value)
+		}
+		blocks = append(blocks, layer)
+	}
+	return h.sum(blocks, nbts)
+}
+
+// HashChunk returns the content hash of each sub chunk of c,
+// and nbts is the block NBTs of c (see FromChunkNBT).
+func HashChunk(c *chunk.Chunk, nbts []NBTWithIndex) (result []uint64) {
+	h := newSubChunkHasher()
+	groups := GroupNBTBySubChunk(nbts, c.Range())
+
+	for index, subChunk := range c.Sub() {
+		result = append(result, h.sumSubChunk(subChunk, groups[index]))
+	}
+	return
+}
+
+// HashChunkMatrix returns the content hash of each sub chunk of
+// matrix, and nbts is the block NBTs of this chunk. r is the range
+// of this chunk, and blockPalette is the block palette that matrix used.
+func HashChunkMatrix(matrix ChunkMatrix, r define.Range, blockPalette *BlockPalette, nbts []NBTWithIndex) (result []uint64) {
+	h := newSubChunkHasher()
+	groups := GroupNBTBySubChunk(nbts, r)
+
+	for index, layers := range matrix {
+		var group []NBTWithIndex
+		if index < len(groups) {
+			group = groups[index]
+		}
+		result = append(result, h.sumLayers(layers, blockPalette, group))
+	}
+	return
+}
+
+// GroupNBTBySubChunk groups nbts by the sub chunk that they in,
+// and r is the range of this chunk.
+//
+// The NBTs that out of r are grouped into the lowest or the highest
+// sub chunk, so they are also considered when hashing.
+func GroupNBTBySubChunk(nbts []NBTWithIndex, r define.Range) (result [][]NBTWithIndex) {
+	result = make([][]NBTWithIndex, (r.Height()>>4)+1)
+	for _, value := range nbts {
+		index := SubChunkIndexOf(value.Index.Y(), r)
+		result[index] = append(result[index], value)
+	}
+	return
+}
+
+// SubChunkIndexOf returns the index of the sub chunk which the block
+// at y is in, and r is the range of this chunk.
+// If y is out of r, then returns the lowest or the highest one.
+func SubChunkIndexOf(y int16, r define.Range) int {
+	index := int(y>>4) - (r[0] >> 4)
+	return max(min(index, r.Height()>>4), 0)
+}
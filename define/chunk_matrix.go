@@ -1,5 +1,7 @@
 package define
 
+import "fmt"
+
 type (
 	// ChunkMatrix represents the chunk matrix that holds all
 	// block of this chunk. A chunk can have multiple sub chunks,
@@ -57,3 +59,73 @@ func ChunkNoChange(diff ChunkDiffMatrix) bool {
 	}
 	return true
 }
+
+// ValidateChunkMatrix checks whether all the block palette
+// index in matrix is valid for a block palette whose length
+// is paletteLen. If not, then returns an error that wraps
+// ErrMalformed.
+//
+// Time complexity: O(4096×n), n=len(matrix).
+func ValidateChunkMatrix(matrix ChunkMatrix, paletteLen int) error {
+	for subChunkIndex, layers := range matrix {
+		for layerIndex, blockMatrix := range layers {
+			if BlockMatrixIsEmpty(blockMatrix) {
+				continue
+			}
+			for _, value := range blockMatrix {
+				if uint64(value) > uint64(paletteLen) {
+					return fmt.Errorf(
+						"ValidateChunkMatrix: %w (block palette index %d in sub chunk %d layer %d is out of range)",
+						ErrMalformed, value, subChunkIndex, layerIndex,
+					)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateChunkDiffMatrix checks whether all the block palette
+// index in diff is valid for a block palette whose length is
+// paletteLen. If not, then returns an error that wraps ErrMalformed.
+//
+// Time complexity: O(C), C is the count of block changes in diff.
+func ValidateChunkDiffMatrix(diff ChunkDiffMatrix, paletteLen int) error {
+	for subChunkIndex, layersDiff := range diff {
+		for layerIndex, diffMatrix := range layersDiff {
+			for _, value := range diffMatrix {
+				if uint64(value.NewPaletteID) > uint64(paletteLen) {
+					return fmt.Errorf(
+						"ValidateChunkDiffMatrix: %w (block palette index %d in sub chunk %d layer %d is out of range)",
+						ErrMalformed, value.NewPaletteID, subChunkIndex, layerIndex,
+					)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// RemapChunkMatrix replaces each block palette index in
+// matrix by remap (see RemapBlockMatrix).
+//
+// Time complexity: O(4096×n), n=len(matrix).
+func RemapChunkMatrix(matrix ChunkMatrix, remap []uint32) {
+	for _, layers := range matrix {
+		for _, blockMatrix := range layers {
+			RemapBlockMatrix(blockMatrix, remap)
+		}
+	}
+}
+
+// RemapChunkDiffMatrix replaces each block palette index
+// in diff by remap (see RemapBlockMatrix).
+//
+// Time complexity: O(C), C is the count of block changes in diff.
+func RemapChunkDiffMatrix(diff ChunkDiffMatrix, remap []uint32) {
+	for _, layersDiff := range diff {
+		for _, diffMatrix := range layersDiff {
+			RemapDiffMatrix(diffMatrix, remap)
+		}
+	}
+}
@@ -1,53 +1,64 @@
 package define
 
 import (
+	"bytes"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
 	"github.com/TriM-Organization/bedrock-world-operator/block"
 	"github.com/TriM-Organization/bedrock-world-operator/chunk"
 	"github.com/TriM-Organization/bedrock-world-operator/define"
+	"github.com/cespare/xxhash/v2"
 )
 
 // ChunkToMatrix converts a chunk to its chunk martix represents.
 // blockPalette is the block palette of this chunk timeline used.
 func ChunkToMatrix(c *chunk.Chunk, blockPalette *BlockPalette) (result ChunkMatrix) {
 	for _, value := range c.Sub() {
-		l := Layers{}
+		result = append(result, SubChunkToLayers(value, blockPalette))
+	}
+	return
+}
 
-		if value.Empty() {
-			if len(value.Layers()) == 0 {
-				result = append(result, l)
-				continue
-			}
-			_ = l.Layer(0)
-			result = append(result, l)
-			continue
+// SubChunkToLayers converts a sub chunk to its layers represents.
+// blockPalette is the block palette that the returned layers used.
+func SubChunkToLayers(subChunk *chunk.SubChunk, blockPalette *BlockPalette) (result Layers) {
+	result = Layers{}
+
+	if subChunk.Empty() {
+		if len(subChunk.Layers()) > 0 {
+			_ = result.Layer(0)
 		}
+		return result
+	}
 
-		for index, layer := range value.Layers() {
-			newerBlockMartrix := NewBlockMatrix()
+	for index, layer := range subChunk.Layers() {
+		newerBlockMartrix := NewBlockMatrix()
 
-			ptr := 0
-			for x := range uint8(16) {
-				for y := range uint8(16) {
-					for z := range uint8(16) {
-						newerBlockMartrix[ptr] = blockPalette.BlockPaletteIndex(layer.At(x, y, z))
-						ptr++
-					}
+		ptr := 0
+		for x := range uint8(16) {
+			for y := range uint8(16) {
+				for z := range uint8(16) {
+					newerBlockMartrix[ptr] = blockPalette.BlockPaletteIndex(layer.At(x, y, z))
+					ptr++
 				}
 			}
-
-			_ = l.Layer(index)
-			l[index] = newerBlockMartrix
 		}
 
-		result = append(result, l)
+		_ = result.Layer(index)
+		result[index] = newerBlockMartrix
 	}
 
-	return
+	return result
 }
 
 // MatrixToChunk converts the chunk matrix to its chunk represents.
+// biomes is the biomes of this chunk, and the nil sub chunks in it are kept as 0.
 // r is the range of this chunk, and blockPalette is this chunk matrix used.
-func MatrixToChunk(matrix ChunkMatrix, r define.Range, blockPalette *BlockPalette) (c *chunk.Chunk) {
+//
+// The custom blocks in the returned chunk are kept as the runtime IDs that
+// given by the registry of blockPalette, so use blockPalette.Registry() to
+// find their block states (e.g. when encoding the returned chunk).
+func MatrixToChunk(matrix ChunkMatrix, biomes ChunkBiomeMatrix, r define.Range, blockPalette *BlockPalette) (c *chunk.Chunk) {
 	c = chunk.NewChunk(block.AirRuntimeID, r)
 	sub := c.Sub()
 
@@ -73,6 +84,15 @@ func MatrixToChunk(matrix ChunkMatrix, r define.Range, blockPalette *BlockPalett
 		}
 	}
 
+	biomeIDs := c.Biomes()
+	for index, biomeMatrix := range biomes {
+		if index >= len(biomeIDs) || BlockMatrixIsEmpty(biomeMatrix) {
+			continue
+		}
+		copy(biomeIDs[index], biomeMatrix[:])
+	}
+	c.SetBiomes(biomeIDs)
+
 	return
 }
 
@@ -115,3 +135,42 @@ func ToChunkNBT(nbts []NBTWithIndex) (result []map[string]any) {
 	}
 	return
 }
+
+// FromChunkEntities converts entities to []EntityWithID.
+//
+// The unique ID of each entity is its "UniqueID" tag. For the entity who don't
+// have this tag, the hash of its NBT is used instead, so it could still be matched
+// when it is not changed. If multiple entities have the same unique ID, then only
+// the last one is kept.
+//
+// Note that the returned slice is not the deep copied NBTs.
+func FromChunkEntities(entities []map[string]any) (result []EntityWithID) {
+	indexes := make(map[int64]int)
+
+	for _, value := range entities {
+		uniqueID, ok := value["UniqueID"].(int64)
+		if !ok {
+			buf := bytes.NewBuffer(nil)
+			utils.MarshalNBT(buf, value, "")
+			uniqueID = int64(xxhash.Sum64(buf.Bytes()))
+		}
+
+		if index, ok := indexes[uniqueID]; ok {
+			result[index].NBT = value
+			continue
+		}
+		indexes[uniqueID] = len(result)
+		result = append(result, EntityWithID{UniqueID: uniqueID, NBT: value})
+	}
+
+	return
+}
+
+// ToChunkEntities converts entities to []map[string]any.
+// Note that the returned slice is not the deep copied NBTs.
+func ToChunkEntities(entities []EntityWithID) (result []map[string]any) {
+	for _, value := range entities {
+		result = append(result, value.NBT)
+	}
+	return
+}
@@ -33,3 +33,47 @@ func IndexNBTDu(pos DimChunk, timeID uint) []byte {
 		timeIDBytes...,
 	)
 }
+
+// IndexSummary returns a bytes holding the written index of the chunk position passed,
+// but specially for the summary of each time point used key to index.
+func IndexSummary(pos DimChunk, timeID uint) []byte {
+	timeIDBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(timeIDBytes, uint32(timeID))
+	return append(
+		Sum(pos, []byte(KeySummary)...),
+		timeIDBytes...,
+	)
+}
+
+// IndexNBTSnapshot returns a bytes holding the written index of the chunk position passed,
+// but specially for the full block NBT snapshot of some time points used key to index.
+func IndexNBTSnapshot(pos DimChunk, timeID uint) []byte {
+	timeIDBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(timeIDBytes, uint32(timeID))
+	return append(
+		Sum(pos, []byte(KeyNBTSnapshot)...),
+		timeIDBytes...,
+	)
+}
+
+// IndexEntityDu returns a bytes holding the written index of the chunk position passed,
+// but specially for entities delta update used key to index.
+func IndexEntityDu(pos DimChunk, timeID uint) []byte {
+	timeIDBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(timeIDBytes, uint32(timeID))
+	return append(
+		Sum(pos, []byte(KeyEntityDeltaUpdate)...),
+		timeIDBytes...,
+	)
+}
+
+// IndexBiomeDu returns a bytes holding the written index of the chunk position passed,
+// but specially for biomes delta update used key to index.
+func IndexBiomeDu(pos DimChunk, timeID uint) []byte {
+	timeIDBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(timeIDBytes, uint32(timeID))
+	return append(
+		Sum(pos, []byte(KeyBiomeDeltaUpdate)...),
+		timeIDBytes...,
+	)
+}
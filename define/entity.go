@@ -0,0 +1,184 @@
+package define
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// EntityWithID represents a single entity (mob) data who in a chunk.
+// UniqueID is the unique ID of this entity, which is used to match
+// the same entity on different time.
+type EntityWithID struct {
+	UniqueID int64
+	NBT      map[string]any
+}
+
+// EntityDeepCopy return the deep copy of src.
+func EntityDeepCopy(src []EntityWithID) (result []EntityWithID, err error) {
+	for _, value := range src {
+		nbts, err := NBTDeepCopy([]NBTWithIndex{{NBT: value.NBT}})
+		if err != nil {
+			return nil, fmt.Errorf("EntityDeepCopy: %v", err)
+		}
+		result = append(result, EntityWithID{
+			UniqueID: value.UniqueID,
+			NBT:      nbts[0].NBT,
+		})
+	}
+	return
+}
+
+// DiffEntityWithID represents the difference between the same entity but on different time.
+// DiffNBT is the same as the one of DiffNBTWithIndex, which is computed by NewDiffNBT.
+type DiffEntityWithID struct {
+	UniqueID int64
+	DiffNBT  []byte
+}
+
+// NewDiffEntity returns the difference between between olderEntity and newerEntity.
+// Note that olderEntity and newerEntity must represents the same entity.
+//
+// Time complexity: O(N), N is the count of the values in olderEntity and newerEntity.
+func NewDiffEntity(olderEntity *EntityWithID, newerEntity *EntityWithID) (result *DiffEntityWithID, err error) {
+	if olderEntity == nil || newerEntity == nil {
+		return nil, fmt.Errorf("NewDiffEntity: olderEntity or newerEntity is nil")
+	}
+	if olderEntity.UniqueID != newerEntity.UniqueID {
+		return nil, fmt.Errorf("NewDiffEntity: Can't do difference operation between two different entities")
+	}
+
+	diff, err := NewDiffNBT(&NBTWithIndex{NBT: olderEntity.NBT}, &NBTWithIndex{NBT: newerEntity.NBT})
+	if err != nil {
+		return nil, fmt.Errorf("NewDiffEntity: %v", err)
+	}
+
+	return &DiffEntityWithID{UniqueID: olderEntity.UniqueID, DiffNBT: diff.DiffNBT}, nil
+}
+
+// Restore use olderEntity and DiffEntityWithID it self to compute the newer
+// entity data, and return the restore result. olderEntity is not modified.
+func (d DiffEntityWithID) Restore(olderEntity EntityWithID) (result *EntityWithID, err error) {
+	if d.UniqueID != olderEntity.UniqueID {
+		return nil, fmt.Errorf("Restore: Can't do restore operation between two different entities")
+	}
+
+	newer, err := DiffNBTWithIndex{DiffNBT: d.DiffNBT}.Restore(NBTWithIndex{NBT: olderEntity.NBT})
+	if err != nil {
+		return nil, fmt.Errorf("Restore: %w", err)
+	}
+
+	return &EntityWithID{UniqueID: olderEntity.UniqueID, NBT: newer.NBT}, nil
+}
+
+// MultipleDiffEntity represents the difference between the entities in the same chunk but
+// different times. It is the same as MultipleDiffNBT, but the entities are matched by their
+// unique ID instead of the position.
+type MultipleDiffEntity struct {
+	Removed  []int64
+	Added    []EntityWithID
+	Modified []DiffEntityWithID
+}
+
+// EntityDifference computes the difference between multiple entity changes in one single chunk.
+// older and newer are represents the different time of these entities in the same chunk.
+//
+// Time complexity: O(C×k + (a+b)), a=len(older), b=len(newer).
+//
+// k is the number that shown the counts of changed (modified) entities,
+// and C is relevant to the size of each modified entity (see NewDiffNBT).
+func EntityDifference(older []EntityWithID, newer []EntityWithID) (result *MultipleDiffEntity, err error) {
+	olderSet := make(map[int64]*EntityWithID)
+	newerSet := make(map[int64]*EntityWithID)
+	for _, value := range older {
+		olderSet[value.UniqueID] = &value
+	}
+	for _, value := range newer {
+		newerSet[value.UniqueID] = &value
+	}
+
+	result = &MultipleDiffEntity{
+		Removed:  make([]int64, 0),
+		Added:    make([]EntityWithID, 0),
+		Modified: make([]DiffEntityWithID, 0),
+	}
+
+	for key := range olderSet {
+		if newerSet[key] == nil {
+			result.Removed = append(result.Removed, key)
+		}
+	}
+
+	for key, value := range newerSet {
+		if olderSet[key] == nil {
+			result.Added = append(result.Added, *value)
+		}
+	}
+
+	for key, value := range olderSet {
+		if newerSet[key] == nil || reflect.DeepEqual(value.NBT, newerSet[key].NBT) {
+			continue
+		}
+
+		diff, err := NewDiffEntity(value, newerSet[key])
+		if err != nil {
+			return nil, fmt.Errorf("EntityDifference: %v", err)
+		}
+
+		result.Modified = append(result.Modified, *diff)
+	}
+
+	return result, nil
+}
+
+// EntityRestore computes the newer entity data of this chunk by given old and diff.
+//
+// Time complexity: O(a+C×b), a=len(old), b=len(diff.Modified).
+// C is relevant to the size of each modified entity (see DiffEntityWithID.Restore).
+func EntityRestore(old []EntityWithID, diff MultipleDiffEntity) (result []EntityWithID, err error) {
+	// Deep copy
+	oldCopy, err := EntityDeepCopy(old)
+	if err != nil {
+		return nil, fmt.Errorf("EntityRestore: %v", err)
+	}
+
+	// Added
+	result = append(result, diff.Added...)
+
+	// Modified
+	olderSet := make(map[int64]EntityWithID)
+	for _, value := range oldCopy {
+		olderSet[value.UniqueID] = value
+	}
+	for _, value := range diff.Modified {
+		older, ok := olderSet[value.UniqueID]
+		if !ok {
+			return nil, fmt.Errorf("EntityRestore: %w (modified entity %d is not found)", ErrMalformed, value.UniqueID)
+		}
+		newer, err := value.Restore(older)
+		if err != nil {
+			return nil, fmt.Errorf("EntityRestore: %w", err)
+		}
+		result = append(result, *newer)
+	}
+
+	// No change
+	changedSet := make(map[int64]bool)
+	for _, value := range diff.Removed {
+		changedSet[value] = true
+	}
+	for _, value := range diff.Modified {
+		changedSet[value.UniqueID] = true
+	}
+	for _, value := range oldCopy {
+		if !changedSet[value.UniqueID] {
+			result = append(result, value)
+		}
+	}
+
+	return
+}
+
+// EntityNoChange reports diff is empty or not.
+func EntityNoChange(diff MultipleDiffEntity) bool {
+	return (len(diff.Removed) == 0 && len(diff.Added) == 0 && len(diff.Modified) == 0)
+}
@@ -0,0 +1,7 @@
+package define
+
+import "errors"
+
+// ErrMalformed is returned when decoding a data
+// that is truncated, corrupted or out of range.
+var ErrMalformed = errors.New("malformed data")
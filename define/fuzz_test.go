@@ -0,0 +1,54 @@
+package define
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// malformedNBT makes the NBT decoder of gophertunnel
+// panics with "makeslice: len out of range".
+var malformedNBT = []byte("\f\x04\x0000000000")
+
+func FuzzReadNBT(f *testing.F) {
+	f.Add(malformedNBT)
+	f.Add([]byte{0})
+	f.Add([]byte{10, 0, 0, 3, 1, 0, 'a', 1, 0, 0, 0, 0})
+	f.Add([]byte{10, 0, 0, 9, 1, 0, 'l', 10, 0xff, 0xff, 0xff, 0x7f, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		buf := bytes.NewBuffer(data)
+		_, err := ReadNBT(buf)
+		if err != nil && !errors.Is(err, ErrMalformed) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func FuzzBytesToNBTPatches(f *testing.F) {
+	f.Add(NBTPatchesBytes(NewStructuralDiffNBT(
+		map[string]any{"a": int32(1), "l": []any{int32(1), int32(2)}},
+		map[string]any{"b": "x", "l": []any{int32(1)}},
+	)))
+	f.Add(append([]byte{1, NBTPatchSet, 0}, malformedNBT...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToNBTPatches(data)
+		if err != nil && !errors.Is(err, ErrMalformed) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+}
+
+func FuzzDiffNBTRestore(f *testing.F) {
+	older := map[string]any{"id": "Chest", "Items": []any{map[string]any{"Count": byte(1)}}}
+	diff, err := NewDiffNBT(&NBTWithIndex{NBT: older}, &NBTWithIndex{NBT: map[string]any{"id": "Chest"}})
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(diff.DiffNBT)
+	f.Add(append([]byte{ModifiedNBTOrigin}, malformedNBT...))
+	f.Add(append([]byte{ModifiedNBTStructural, 1, NBTPatchSet, 0}, malformedNBT...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, _ = DiffNBTWithIndex{DiffNBT: data}.Restore(NBTWithIndex{NBT: older})
+		_, _ = DiffNBTWithIndex{DiffNBT: data}.Patches()
+	})
+}
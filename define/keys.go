@@ -11,12 +11,28 @@ import (
 const (
 	KeyChunkGlobalData = "tbplrg"
 
-	KeyBlockDeltaUpdate = "du"
-	KeyNBTDeltaUpdate   = "du'"
+	KeyBlockDeltaUpdate  = "du"
+	KeyNBTDeltaUpdate    = "du'"
+	KeySummary           = "du#"
+	KeyNBTSnapshot       = "du!"
+	KeyEntityDeltaUpdate = "du$"
+	KeyBiomeDeltaUpdate  = "du%"
 
 	KeyLatestTimePointUnixTime = 'T'
 	KeyLatestChunk             = 'm'
 	KeyLatestNBT               = "m'"
+	KeyLatestHash              = "m#"
+	KeyLatestEntity            = "m$"
+	KeyLatestBiome             = "m%"
+)
+
+// Keys on a per-player basis.
+// These are prefixed by only the player index (see IndexPlayer),
+// and they are saved in the bucket of the player timelines.
+const (
+	KeyPlayerGlobalData  = "tbplrg"
+	KeyPlayerDeltaUpdate = "du"
+	KeyLatestPlayer      = "m"
 )
 
 // Index returns a bytes holding the written index of the chunk position passed.
@@ -0,0 +1,300 @@
+package define
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+const (
+	// NBTPatchSet sets the value at the path. If the last element of
+	// the path is a list index that equals to the length of this list,
+	// then the value is appended to this list.
+	NBTPatchSet byte = iota
+	// NBTPatchRemove removes the value at the path, which
+	// is a key of a compound or an index of a list.
+	NBTPatchRemove
+)
+
+// NBTPatch is a single operation of a structural NBT delta.
+//
+// Path is the path from the root compound to the target value,
+// and each element of it is a string (the key of a compound) or
+// an int (the index of a list). An empty path refers to the root
+// compound itself, which means the NBT is replaced entirely.
+type NBTPatch struct {
+	Op    byte
+	Path  []any
+	Value any
+}
+
+// NewStructuralDiffNBT returns the structural patches that could
+// change olderNBT to newerNBT. The compounds are compared key by key,
+// and the lists (TAG_List) who hold the same type of elements are
+// compared index by index. Other values (including the arrays) are
+// set entirely if they are changed.
+//
+// The keys of compounds are visited in sorted order, so
+// the same two NBTs always produce the same patches.
+func NewStructuralDiffNBT(olderNBT map[string]any, newerNBT map[string]any) (patches []NBTPatch) {
+	return diffNBTCompound(nil, olderNBT, newerNBT, nil)
+}
+
+// diffNBTValue appends the patches that change older to newer at path.
+func diffNBTValue(path []any, older any, newer any, patches []NBTPatch) []NBTPatch {
+	olderMap, ok1 := older.(map[string]any)
+	newerMap, ok2 := newer.(map[string]any)
+	if ok1 && ok2 {
+		return diffNBTCompound(path, olderMap, newerMap, patches)
+	}
+
+	olderList, ok1 := older.([]any)
+	newerList, ok2 := newer.([]any)
+	if ok1 && ok2 && nbtListSameType(olderList, newerList) {
+		return diffNBTList(path, olderList, newerList, patches)
+	}
+
+	if !reflect.DeepEqual(older, newer) {
+		patches = append(patches, NBTPatch{Op: NBTPatchSet, Path: slices.Clone(path), Value: newer})
+	}
+	return patches
+}
+
+// diffNBTCompound appends the patches that change
+// the compound older to newer at path.
+func diffNBTCompound(path []any, older map[string]any, newer map[string]any, patches []NBTPatch) []NBTPatch {
+	keys := make([]string, 0, len(older)+len(newer))
+	for key := range older {
+		keys = append(keys, key)
+	}
+	for key := range newer {
+		if _, ok := older[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		olderValue, inOlder := older[key]
+		newerValue, inNewer := newer[key]
+		subPath := append(slices.Clip(path), key)
+
+		switch {
+		case !inNewer:
+			patches = append(patches, NBTPatch{Op: NBTPatchRemove, Path: subPath})
+		case !inOlder:
+			patches = append(patches, NBTPatch{Op: NBTPatchSet, Path: subPath, Value: newerValue})
+		default:
+			patches = diffNBTValue(subPath, olderValue, newerValue, patches)
+		}
+	}
+
+	return patches
+}
+
+// diffNBTList appends the patches that change the list older to newer
+// at path. The extra elements are removed from the end of the list, so
+// the indexes of the patches are always valid when applying in order.
+func diffNBTList(path []any, older []any, newer []any, patches []NBTPatch) []NBTPatch {
+	for index := range min(len(older), len(newer)) {
+		patches = diffNBTValue(append(slices.Clip(path), index), older[index], newer[index], patches)
+	}
+	for index := len(older); index < len(newer); index++ {
+		patches = append(patches, NBTPatch{Op: NBTPatchSet, Path: append(slices.Clip(path), index), Value: newer[index]})
+	}
+	for index := len(older) - 1; index >= len(newer); index-- {
+		patches = append(patches, NBTPatch{Op: NBTPatchRemove, Path: append(slices.Clip(path), index)})
+	}
+	return patches
+}
+
+// nbtListSameType reports whether all the elements
+// of older and newer have the same type.
+// An empty list is never the same as others, because
+// we could not know the element type of it.
+func nbtListSameType(older []any, newer []any) bool {
+	if len(older) == 0 || len(newer) == 0 {
+		return false
+	}
+
+	elementType := reflect.TypeOf(older[0])
+	for _, value := range older {
+		if reflect.TypeOf(value) != elementType {
+			return false
+		}
+	}
+	for _, value := range newer {
+		if reflect.TypeOf(value) != elementType {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyNBTPatches applies patches to olderNBT in order and returns the result.
+// olderNBT is modified in place, so deep copy it first if it is still in use.
+func ApplyNBTPatches(olderNBT map[string]any, patches []NBTPatch) (result map[string]any, err error) {
+	var root any = olderNBT
+	for _, patch := range patches {
+		root, err = applyNBTPatch(root, patch.Path, patch)
+		if err != nil {
+			return nil, fmt.Errorf("ApplyNBTPatches: %w", err)
+		}
+	}
+
+	result, ok := root.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("ApplyNBTPatches: %w (the root of NBT is not a compound)", ErrMalformed)
+	}
+	return result, nil
+}
+
+// applyNBTPatch applies patch to the value at path of
+// node, and returns the node after applying.
+func applyNBTPatch(node any, path []any, patch NBTPatch) (result any, err error) {
+	if len(path) == 0 {
+		if patch.Op != NBTPatchSet {
+			return nil, fmt.Errorf("applyNBTPatch: %w (can't remove the root compound)", ErrMalformed)
+		}
+		return patch.Value, nil
+	}
+
+	switch key := path[0].(type) {
+	case string:
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("applyNBTPatch: %w (%q is not in a compound)", ErrMalformed, key)
+		}
+		if len(path) == 1 && patch.Op == NBTPatchRemove {
+			delete(m, key)
+			return m, nil
+		}
+		if len(path) > 1 {
+			if _, ok := m[key]; !ok {
+				return nil, fmt.Errorf("applyNBTPatch: %w (%q is not found)", ErrMalformed, key)
+			}
+		}
+		if m[key], err = applyNBTPatch(m[key], path[1:], patch); err != nil {
+			return nil, err
+		}
+		return m, nil
+	case int:
+		list, ok := node.([]any)
+		if !ok {
+			return nil, fmt.Errorf("applyNBTPatch: %w (index %d is not in a list)", ErrMalformed, key)
+		}
+		if len(path) == 1 && patch.Op == NBTPatchSet && key == len(list) {
+			return append(list, patch.Value), nil
+		}
+		if key < 0 || key >= len(list) {
+			return nil, fmt.Errorf("applyNBTPatch: %w (index %d is out of range)", ErrMalformed, key)
+		}
+		if len(path) == 1 && patch.Op == NBTPatchRemove {
+			return slices.Delete(list, key, key+1), nil
+		}
+		if list[key], err = applyNBTPatch(list[key], path[1:], patch); err != nil {
+			return nil, err
+		}
+		return list, nil
+	default:
+		return nil, fmt.Errorf("applyNBTPatch: %w (unknown path element %T)", ErrMalformed, key)
+	}
+}
+
+// NBTPatchesBytes return the bytes represents of patches,
+// which is not prefixed by the kind of this NBT delta.
+func NBTPatchesBytes(patches []NBTPatch) []byte {
+	buf := bytes.NewBuffer(nil)
+	buf.Write(binary.AppendUvarint(nil, uint64(len(patches))))
+
+	for _, patch := range patches {
+		buf.WriteByte(patch.Op)
+		buf.Write(binary.AppendUvarint(nil, uint64(len(patch.Path))))
+
+		for _, value := range patch.Path {
+			switch value := value.(type) {
+			case string:
+				buf.WriteByte(0)
+				buf.Write(binary.AppendUvarint(nil, uint64(len(value))))
+				buf.WriteString(value)
+			case int:
+				buf.WriteByte(1)
+				buf.Write(binary.AppendUvarint(nil, uint64(value)))
+			}
+		}
+
+		if patch.Op == NBTPatchSet {
+			utils.MarshalNBT(buf, map[string]any{"": patch.Value}, "")
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// BytesToNBTPatches decode the patches from in, which is
+// the same as the one that returned by NBTPatchesBytes.
+func BytesToNBTPatches(in []byte) (patches []NBTPatch, err error) {
+	buf := bytes.NewBuffer(in)
+
+	length, err := binary.ReadUvarint(buf)
+	if err != nil || length > uint64(buf.Len()) {
+		return nil, fmt.Errorf("BytesToNBTPatches: %w (patches are truncated)", ErrMalformed)
+	}
+
+	patches = make([]NBTPatch, length)
+	for i := range patches {
+		op, err := buf.ReadByte()
+		if err != nil || op > NBTPatchRemove {
+			return nil, fmt.Errorf("BytesToNBTPatches: %w (invalid operation)", ErrMalformed)
+		}
+		patches[i].Op = op
+
+		pathLen, err := binary.ReadUvarint(buf)
+		if err != nil || pathLen > uint64(buf.Len()) {
+			return nil, fmt.Errorf("BytesToNBTPatches: %w (path is truncated)", ErrMalformed)
+		}
+
+		patches[i].Path = make([]any, pathLen)
+		for j := range patches[i].Path {
+			kind, err := buf.ReadByte()
+			if err != nil {
+				return nil, fmt.Errorf("BytesToNBTPatches: %w (path is truncated)", ErrMalformed)
+			}
+
+			value, err := binary.ReadUvarint(buf)
+			if err != nil {
+				return nil, fmt.Errorf("BytesToNBTPatches: %w (path is truncated)", ErrMalformed)
+			}
+
+			switch kind {
+			case 0:
+				if value > uint64(buf.Len()) {
+					return nil, fmt.Errorf("BytesToNBTPatches: %w (path is truncated)", ErrMalformed)
+				}
+				patches[i].Path[j] = string(buf.Next(int(value)))
+			case 1:
+				if value > math.MaxInt32 {
+					return nil, fmt.Errorf("BytesToNBTPatches: %w (list index overflow)", ErrMalformed)
+				}
+				patches[i].Path[j] = int(value)
+			default:
+				return nil, fmt.Errorf("BytesToNBTPatches: %w (unknown path element)", ErrMalformed)
+			}
+		}
+
+		if op == NBTPatchSet {
+			m, err := ReadNBT(buf)
+			if err != nil {
+				return nil, fmt.Errorf("BytesToNBTPatches: %w", err)
+			}
+			patches[i].Value = m[""]
+		}
+	}
+
+	return patches, nil
+}
@@ -0,0 +1,128 @@
+package define
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// testNBTPairs returns the pairs of the older
+// and newer NBTs that used by the patch tests.
+func testNBTPairs() [][2]map[string]any {
+	chest := func(counts ...byte) map[string]any {
+		items := make([]any, 0)
+		for slot, count := range counts {
+			items = append(items, map[string]any{"Name": "minecraft:apple", "Count": count, "Slot": byte(slot)})
+		}
+		return map[string]any{"id": "Chest", "x": int32(1), "y": int32(-40), "z": int32(2), "Items": items}
+	}
+	return [][2]map[string]any{
+		{chest(1), chest(2)},
+		{chest(1, 2, 3), chest(1)},
+		{chest(1), chest(1, 2, 3)},
+		{chest(1), chest(1)},
+		{{}, chest(1)},
+		{chest(1), {}},
+		{
+			{"id": "Sign", "Text": "hello", "Color": int32(1), "Lines": []any{"a", "b"}},
+			{"id": "Sign", "Text": "world", "Glowing": byte(1), "Lines": []int32{1}},
+		},
+		{
+			{"id": "Beehive", "Flower": map[string]any{"x": int32(1), "pos": []int32{1, 2, 3}}},
+			{"id": "Beehive", "Flower": map[string]any{"x": int32(2), "pos": []int32{1, 2, 4}, "ticks": int64(7)}},
+		},
+	}
+}
+
+func TestNBTPatchRoundTrip(t *testing.T) {
+	for index, pair := range testNBTPairs() {
+		patches := NewStructuralDiffNBT(pair[0], pair[1])
+		if reflect.DeepEqual(pair[0], pair[1]) && len(patches) != 0 {
+			t.Fatalf("pair %d: expected no patch for the same NBTs, but got %v", index, patches)
+		}
+
+		encoded := NBTPatchesBytes(patches)
+		decoded, err := BytesToNBTPatches(encoded)
+		if err != nil {
+			t.Fatalf("pair %d: BytesToNBTPatches: %v", index, err)
+		}
+		if !bytes.Equal(NBTPatchesBytes(decoded), encoded) {
+			t.Fatalf("pair %d: decoded patches %v are not the same as %v", index, decoded, patches)
+		}
+
+		older := testNBTPairs()[index][0]
+		result, err := ApplyNBTPatches(older, decoded)
+		if err != nil {
+			t.Fatalf("pair %d: ApplyNBTPatches: %v", index, err)
+		}
+		if !reflect.DeepEqual(result, pair[1]) {
+			t.Fatalf("pair %d: expected %v, but got %v", index, pair[1], result)
+		}
+	}
+}
+
+func TestDiffNBTRestore(t *testing.T) {
+	for index, pair := range testNBTPairs() {
+		older, newer := &NBTWithIndex{NBT: pair[0]}, &NBTWithIndex{NBT: pair[1]}
+		older.Index.UpdateIndex(1, -40, 2)
+		newer.Index = older.Index
+
+		structural, err := NewDiffNBT(older, newer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		legacy, err := NewBSDiffNBT(older, newer)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, diff := range []*DiffNBTWithIndex{structural, legacy} {
+			result, err := diff.Restore(*older)
+			if err != nil {
+				t.Fatalf("pair %d: Restore: %v", index, err)
+			}
+			if result.Index != older.Index || !reflect.DeepEqual(result.NBT, newer.NBT) {
+				t.Fatalf("pair %d: expected %v, but got %v", index, newer.NBT, result.NBT)
+			}
+		}
+	}
+
+	// A small change of a large NBT is saved as patches, and
+	// the one that replaced entirely is saved as the newer NBT
+	pairs := testNBTPairs()
+	diff, err := NewDiffNBT(&NBTWithIndex{NBT: pairs[0][0]}, &NBTWithIndex{NBT: pairs[0][1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Kind() != ModifiedNBTStructural {
+		t.Fatalf("expected a structural delta, but got kind %d", diff.Kind())
+	}
+	diff, err = NewDiffNBT(&NBTWithIndex{NBT: pairs[5][0]}, &NBTWithIndex{NBT: pairs[5][1]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patches, err := diff.Patches()
+	if diff.Kind() != ModifiedNBTOrigin || err != nil || len(patches) != 1 || len(patches[0].Path) != 0 {
+		t.Fatalf("expected the newer NBT is saved entirely, but got kind %d and patches %v (%v)", diff.Kind(), patches, err)
+	}
+
+	// The bsdiff delta of a large NBT could not be inspected
+	older, newer := map[string]any{"Items": []any{}}, map[string]any{"Items": []any{}}
+	for slot := range 27 {
+		older["Items"] = append(older["Items"].([]any), map[string]any{"Name": "minecraft:apple", "Count": byte(1), "Slot": byte(slot)})
+		newer["Items"] = append(newer["Items"].([]any), map[string]any{"Name": "minecraft:apple", "Count": byte(slot/26 + 1), "Slot": byte(slot)})
+	}
+	diff, err = NewBSDiffNBT(&NBTWithIndex{NBT: older}, &NBTWithIndex{NBT: newer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Kind() != ModifiedNBTBSDiff {
+		t.Fatalf("expected a bsdiff delta, but got kind %d", diff.Kind())
+	}
+	if _, err = diff.Patches(); err == nil {
+		t.Fatal("Patches: expected an error for bsdiff delta")
+	}
+	if result, err := diff.Restore(NBTWithIndex{NBT: older}); err != nil || !reflect.DeepEqual(result.NBT, newer) {
+		t.Fatalf("Restore: expected %v, but got %v and %v", newer, result, err)
+	}
+}
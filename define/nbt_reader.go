@@ -0,0 +1,184 @@
+package define
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/sandertv/gophertunnel/minecraft/nbt"
+)
+
+// maxNBTDepth is the maximum nesting depth of a NBT,
+// which is the same as the one of the NBT decoder.
+const maxNBTDepth = 512
+
+// ReadNBT reads a little endian NBT compound from buf.
+// It is compatible with utils.MarshalNBT, and a single
+// TAG_End is decoded as an empty compound.
+//
+// The NBT decoder allocates the arrays and lists by their length
+// prefix and panics when the length is invalid. So the whole NBT
+// is walked first to check each length prefix against the bytes
+// that left, and any panic is also recovered. Both of them result
+// in an error that wraps ErrMalformed.
+func ReadNBT(buf *bytes.Buffer) (result map[string]any, err error) {
+	size, err := nbtSize(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("ReadNBT: %w", err)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("ReadNBT: %w (%v)", ErrMalformed, r)
+		}
+	}()
+
+	decoder := nbt.NewDecoderWithEncoding(bytes.NewBuffer(buf.Next(size)), nbt.LittleEndian)
+	decoder.AllowZero = true
+
+	result = make(map[string]any)
+	if err = decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("ReadNBT: %w (%v)", ErrMalformed, err)
+	}
+	return result, nil
+}
+
+// nbtSize returns the size of the little endian NBT compound
+// at the start of data, without decoding (and allocating) it.
+func nbtSize(data []byte) (size int, err error) {
+	r := nbtWalker{data: data}
+
+	tagType, ok := r.byte()
+	if !ok {
+		return 0, fmt.Errorf("nbtSize: %w (NBT is truncated)", ErrMalformed)
+	}
+	switch tagType {
+	case 0:
+		return r.off, nil
+	case 10:
+	default:
+		return 0, fmt.Errorf("nbtSize: %w (root tag %d is not a compound)", ErrMalformed, tagType)
+	}
+
+	if !r.string() || !r.payload(tagType, 0) {
+		return 0, fmt.Errorf("nbtSize: %w (NBT is truncated or broken)", ErrMalformed)
+	}
+	return r.off, nil
+}
+
+// nbtWalker walks a little endian NBT
+// and checks the bounds of each tag.
+type nbtWalker struct {
+	data []byte
+	off  int
+}
+
+// left returns the count of the bytes that not walked.
+func (r *nbtWalker) left() int {
+	return len(r.data) - r.off
+}
+
+// skip skips n bytes.
+func (r *nbtWalker) skip(n int64) bool {
+	if n < 0 || n > int64(r.left()) {
+		return false
+	}
+	r.off += int(n)
+	return true
+}
+
+// byte reads a byte.
+func (r *nbtWalker) byte() (result byte, ok bool) {
+	if r.left() < 1 {
+		return 0, false
+	}
+	r.off++
+	return r.data[r.off-1], true
+}
+
+// int32 reads a little endian int32.
+func (r *nbtWalker) int32() (result int32, ok bool) {
+	if r.left() < 4 {
+		return 0, false
+	}
+	r.off += 4
+	return int32(binary.LittleEndian.Uint32(r.data[r.off-4:])), true
+}
+
+// string skips a string that prefixed by its length.
+func (r *nbtWalker) string() bool {
+	if r.left() < 2 {
+		return false
+	}
+	length := binary.LittleEndian.Uint16(r.data[r.off:])
+	r.off += 2
+	return r.skip(int64(length))
+}
+
+// payload skips the payload of a tag whose type is tagType.
+func (r *nbtWalker) payload(tagType byte, depth int) bool {
+	if depth >= maxNBTDepth {
+		return false
+	}
+
+	switch tagType {
+	case 1:
+		return r.skip(1)
+	case 2:
+		return r.skip(2)
+	case 3, 5:
+		return r.skip(4)
+	case 4, 6:
+		return r.skip(8)
+	case 8:
+		return r.string()
+	case 7, 11, 12:
+		length, ok := r.int32()
+		if !ok || length < 0 {
+			return false
+		}
+		switch tagType {
+		case 7:
+			return r.skip(int64(length))
+		case 11:
+			return r.skip(int64(length) * 4)
+		default:
+			return r.skip(int64(length) * 8)
+		}
+	case 9:
+		elementType, ok := r.byte()
+		if !ok || elementType > 12 {
+			return false
+		}
+		length, ok := r.int32()
+		if !ok || length < 0 || (elementType == 0 && length > 0) {
+			return false
+		}
+		// Each element takes at least one byte, so a length
+		// bigger than the bytes that left must be broken.
+		if int64(length) > int64(r.left()) {
+			return false
+		}
+		for range length {
+			if !r.payload(elementType, depth+1) {
+				return false
+			}
+		}
+		return true
+	case 10:
+		for {
+			fieldType, ok := r.byte()
+			if !ok || fieldType > 12 {
+				return false
+			}
+			if fieldType == 0 {
+				return true
+			}
+			if !r.string() || !r.payload(fieldType, depth+1) {
+				return false
+			}
+		}
+	default:
+		return false
+	}
+}
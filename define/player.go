@@ -0,0 +1,67 @@
+package define
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DiffPlayer represents the difference between the data of the same player but on different time.
+// DiffNBT is the same as the one of DiffNBTWithIndex, which is computed by NewDiffNBT.
+// If DiffNBT is empty, then the player data is not changed.
+type DiffPlayer struct {
+	DiffNBT []byte
+}
+
+// PlayerDifference returns the difference between olderPlayer and newerPlayer.
+// Note that olderPlayer and newerPlayer must represents the same player.
+// A nil player data is treated as an empty one.
+//
+// Time complexity: O(N), N is the count of the values in olderPlayer and newerPlayer.
+func PlayerDifference(olderPlayer map[string]any, newerPlayer map[string]any) (result DiffPlayer, err error) {
+	if reflect.DeepEqual(olderPlayer, newerPlayer) || (len(olderPlayer) == 0 && len(newerPlayer) == 0) {
+		return
+	}
+	if olderPlayer == nil {
+		olderPlayer = make(map[string]any)
+	}
+	if newerPlayer == nil {
+		newerPlayer = make(map[string]any)
+	}
+
+	diff, err := NewDiffNBT(&NBTWithIndex{NBT: olderPlayer}, &NBTWithIndex{NBT: newerPlayer})
+	if err != nil {
+		return result, fmt.Errorf("PlayerDifference: %v", err)
+	}
+
+	return DiffPlayer{DiffNBT: diff.DiffNBT}, nil
+}
+
+// PlayerRestore computes the newer player data by given olderPlayer and diff.
+// olderPlayer is not modified.
+//
+// Time complexity: O(C), C is relevant to the size of olderPlayer (see DiffNBTWithIndex.Restore).
+func PlayerRestore(olderPlayer map[string]any, diff DiffPlayer) (result map[string]any, err error) {
+	if olderPlayer == nil {
+		olderPlayer = make(map[string]any)
+	}
+
+	if PlayerNoChange(diff) {
+		olderCopy, err := NBTDeepCopy([]NBTWithIndex{{NBT: olderPlayer}})
+		if err != nil {
+			return nil, fmt.Errorf("PlayerRestore: %v", err)
+		}
+		return olderCopy[0].NBT, nil
+	}
+
+	newer, err := DiffNBTWithIndex{DiffNBT: diff.DiffNBT}.Restore(NBTWithIndex{NBT: olderPlayer})
+	if err != nil {
+		return nil, fmt.Errorf("PlayerRestore: %w", err)
+	}
+
+	return newer.NBT, nil
+}
+
+// PlayerNoChange reports diff is empty or not.
+func PlayerNoChange(diff DiffPlayer) bool {
+	return len(diff.DiffNBT) == 0
+}
@@ -0,0 +1,31 @@
+package define
+
+import "encoding/binary"
+
+// IndexPlayer returns a bytes holding the written index of
+// the player whose identifier is id (e.g. the UUID of this player).
+//
+// We write the length of id first (2 bytes), and then id itself,
+// so the index of a player is never the prefix of another one.
+func IndexPlayer(id string) []byte {
+	b := make([]byte, 2, 2+len(id))
+	binary.LittleEndian.PutUint16(b, uint16(len(id)))
+	return append(b, id...)
+}
+
+// SumPlayer converts IndexPlayer(id) to its []byte representation and appends p.
+// See Sum for more information.
+func SumPlayer(id string, p ...byte) []byte {
+	return append(IndexPlayer(id), p...)
+}
+
+// IndexPlayerDu returns a bytes holding the written index of the player passed,
+// but specially for player data delta update used key to index.
+func IndexPlayerDu(id string, timeID uint) []byte {
+	timeIDBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(timeIDBytes, uint32(timeID))
+	return append(
+		SumPlayer(id, []byte(KeyPlayerDeltaUpdate)...),
+		timeIDBytes...,
+	)
+}
@@ -0,0 +1,47 @@
+package define
+
+// TimePointSummary is the summary of the changes
+// of a time point, compared with the previous one.
+type TimePointSummary struct {
+	// BlockChanges is the count of changed blocks
+	// of each sub chunk, including all the layers.
+	BlockChanges []uint32
+	// NBTAdded, NBTRemoved and NBTModified are the count
+	// of added, removed and modified block NBTs.
+	NBTAdded    uint32
+	NBTRemoved  uint32
+	NBTModified uint32
+	// BlockDeltaSize and NBTDeltaSize are the size
+	// (in bytes) of the stored block delta and NBT
+	// delta of this time point.
+	BlockDeltaSize uint32
+	NBTDeltaSize   uint32
+}
+
+// NewTimePointSummary returns the summary of the time point whose
+// block delta is chunkDiff and NBT delta is nbtDiff. The size of
+// the stored deltas should be set by the caller.
+//
+// Time complexity: O(L), L is the length of all the DiffMatrix in chunkDiff.
+func NewTimePointSummary(chunkDiff ChunkDiffMatrix, nbtDiff MultipleDiffNBT) TimePointSummary {
+	result := TimePointSummary{
+		BlockChanges: make([]uint32, len(chunkDiff)),
+		NBTAdded:     uint32(len(nbtDiff.Added)),
+		NBTRemoved:   uint32(len(nbtDiff.Removed)),
+		NBTModified:  uint32(len(nbtDiff.Modified)),
+	}
+	for index, layersDiff := range chunkDiff {
+		for _, diff := range layersDiff {
+			result.BlockChanges[index] += uint32(BlockChangeCount(diff))
+		}
+	}
+	return result
+}
+
+// TotalBlockChanges returns the count of changed blocks of all sub chunks.
+func (t TimePointSummary) TotalBlockChanges() (result int) {
+	for _, value := range t.BlockChanges {
+		result += int(value)
+	}
+	return
+}
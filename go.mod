@@ -4,6 +4,9 @@ go 1.24
 
 require (
 	github.com/TriM-Organization/bedrock-world-operator v1.1.3
+	github.com/df-mc/goleveldb v1.1.9
+	github.com/golang/snappy v0.0.4
+	github.com/klauspost/compress v1.17.11
 	github.com/sandertv/gophertunnel v1.45.1
 )
 
@@ -12,10 +15,7 @@ require (
 	atomicgo.dev/keyboard v0.2.9 // indirect
 	atomicgo.dev/schedule v0.1.0 // indirect
 	github.com/containerd/console v1.0.3 // indirect
-	github.com/df-mc/goleveldb v1.1.9 // indirect
-	github.com/golang/snappy v0.0.4 // indirect
 	github.com/gookit/color v1.5.4 // indirect
-	github.com/klauspost/compress v1.17.11 // indirect
 	github.com/lithammer/fuzzysearch v1.1.8 // indirect
 	github.com/mattn/go-runewidth v0.0.16 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
@@ -28,7 +28,7 @@ require (
 
 require (
 	github.com/cespare/xxhash/v2 v2.3.0
-	github.com/df-mc/worldupgrader v1.0.11 // indirect
+	github.com/df-mc/worldupgrader v1.0.11
 	github.com/go-gl/mathgl v1.1.0 // indirect
 	github.com/google/uuid v1.6.0 // indirect
 	github.com/kr/binarydist v0.1.0
@@ -0,0 +1,128 @@
+package marshal
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// ChunkBiomeMatrixToBytes return the bytes represents of biomes.
+// codec is used to compress the returned bytes, and a xxhash64
+// checksum trailer is appended to the result.
+//
+// If all the sub chunks of biomes are empty, then returns nil.
+func ChunkBiomeMatrixToBytes(biomes define.ChunkBiomeMatrix, codec *utils.Codec) (result []byte, err error) {
+	empty := true
+	for _, value := range biomes {
+		if !define.BlockMatrixIsEmpty(value) {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return nil, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, value := range biomes {
+		BlockMatrixToBytes(buf, value)
+	}
+
+	result, err = codec.Encode(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("ChunkBiomeMatrixToBytes: %v", err)
+	}
+	return utils.WithChecksum(result), nil
+}
+
+// BytesToChunkBiomeMatrix decode ChunkBiomeMatrix from bytes.
+// r is the range of this chunk, and codec is used to decompress in.
+//
+// If the checksum trailer of in is not match, then
+// the returned error wraps utils.ErrChecksumMismatch.
+func BytesToChunkBiomeMatrix(in []byte, r operator_define.Range, codec *utils.Codec) (result define.ChunkBiomeMatrix, err error) {
+	result = make(define.ChunkBiomeMatrix, (r.Height()>>4)+1)
+
+	if len(in) == 0 {
+		return result, nil
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToChunkBiomeMatrix: %w", err)
+	}
+
+	ptr := 0
+	buf := bytes.NewBuffer(originBytes)
+	for buf.Len() > 0 {
+		if ptr >= len(result) {
+			return nil, fmt.Errorf("BytesToChunkBiomeMatrix: %w (too many sub chunks)", define.ErrMalformed)
+		}
+		result[ptr], err = BytesToBlockMatrix(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToChunkBiomeMatrix: %w", err)
+		}
+		ptr++
+	}
+
+	return result, nil
+}
+
+// ChunkBiomeDiffMatrixToBytes return the bytes represents of diff.
+// codec is used to compress the returned bytes, and its dictionary
+// will be used if have. A xxhash64 checksum trailer is appended to
+// the result.
+//
+// If diff is empty (see define.BiomeNoChange), then returns nil.
+func ChunkBiomeDiffMatrixToBytes(diff define.ChunkBiomeDiffMatrix, codec *utils.Codec) (result []byte, err error) {
+	if define.BiomeNoChange(diff) {
+		return nil, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	for _, value := range diff {
+		DiffMatrixToBytes(buf, value)
+	}
+
+	result, err = codec.EncodeDelta(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("ChunkBiomeDiffMatrixToBytes: %v", err)
+	}
+	return utils.WithChecksum(result), nil
+}
+
+// BytesToChunkBiomeDiffMatrix decode ChunkBiomeDiffMatrix from bytes.
+// r is the range of this chunk, and codec is used to decompress in.
+//
+// If the checksum trailer of in is not match, then
+// the returned error wraps utils.ErrChecksumMismatch.
+func BytesToChunkBiomeDiffMatrix(in []byte, r operator_define.Range, codec *utils.Codec) (result define.ChunkBiomeDiffMatrix, err error) {
+	result = make(define.ChunkBiomeDiffMatrix, (r.Height()>>4)+1)
+
+	if len(in) == 0 {
+		return result, nil
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToChunkBiomeDiffMatrix: %w", err)
+	}
+
+	ptr := 0
+	buf := bytes.NewBuffer(originBytes)
+	for buf.Len() > 0 {
+		if ptr >= len(result) {
+			return nil, fmt.Errorf("BytesToChunkBiomeDiffMatrix: %w (too many sub chunks)", define.ErrMalformed)
+		}
+		result[ptr], err = BytesToDiffMatrix(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToChunkBiomeDiffMatrix: %w", err)
+		}
+		ptr++
+	}
+
+	return result, nil
+}
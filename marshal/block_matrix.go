@@ -2,82 +2,467 @@ package marshal
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 )
 
 // BlockMatrixToBytes write the bytes represents of blockMatrix into a bytes buffer.
+//
+// blockMatrix is written as a local palette and the bit-packed indices
+// of this palette, which is like the paletted storage of the sub chunks
+// in Bedrock Edition. If the whole matrix is the same value, then only
+// this value is written.
 func BlockMatrixToBytes(buf *bytes.Buffer, blockMatrix define.BlockMatrix) {
 	if define.BlockMatrixIsEmpty(blockMatrix) {
 		buf.WriteByte(MatrixStateEmpty)
 		return
 	}
-	buf.WriteByte(MatrixStateNotEmpty)
+
+	palette := make([]uint32, 0)
+	mapping := make(map[uint32]uint32)
+	for _, value := range blockMatrix {
+		if _, ok := mapping[value]; !ok {
+			mapping[value] = uint32(len(palette))
+			palette = append(palette, value)
+		}
+	}
 
 	w := protocol.NewWriter(buf, 0)
-	for i := range define.MatrixSize {
-		w.Varuint32(&blockMatrix[i])
+	if len(palette) == 1 {
+		buf.WriteByte(MatrixStateSingleValue)
+		w.Varuint32(&palette[0])
+		return
+	}
+	buf.WriteByte(MatrixStatePaletted)
+
+	paletteLen := uint32(len(palette))
+	w.Varuint32(&paletteLen)
+	for i := range palette {
+		w.Varuint32(&palette[i])
+	}
+
+	bitsPerIndex := paletteBitsPerIndex(len(palette))
+	indicesPerWord := 32 / bitsPerIndex
+	words := make([]uint32, (define.MatrixSize+indicesPerWord-1)/indicesPerWord)
+	for i, value := range blockMatrix {
+		words[i/indicesPerWord] |= mapping[value] << (uint32(i%indicesPerWord) * uint32(bitsPerIndex))
+	}
+
+	buf.WriteByte(byte(bitsPerIndex))
+	for _, word := range words {
+		buf.Write(binary.LittleEndian.AppendUint32(nil, word))
 	}
 }
 
 // BytesToBlockMatrix decode BlockMatrix from bytes buffer.
-func BytesToBlockMatrix(buf *bytes.Buffer) define.BlockMatrix {
-	b, _ := buf.ReadByte()
-	if b == MatrixStateEmpty {
-		return nil
+// If the bytes buffer is truncated or corrupted, then returns
+// an error that wraps define.ErrMalformed.
+//
+// The legacy format (MatrixStateNotEmpty) is also supported,
+// but the references (MatrixStateReference) are not supported.
+func BytesToBlockMatrix(buf *bytes.Buffer) (result define.BlockMatrix, err error) {
+	return bytesToBlockMatrix(buf, nil)
+}
+
+// bytesToBlockMatrix decode BlockMatrix from bytes buffer,
+// and resolver is used to resolve the references.
+// If resolver is nil, then the references are not supported.
+func bytesToBlockMatrix(buf *bytes.Buffer, resolver LayerResolver) (result define.BlockMatrix, err error) {
+	b, err := buf.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("BytesToBlockMatrix: %w (matrix state is truncated)", define.ErrMalformed)
 	}
 
-	r := protocol.NewReader(buf, 0, false)
-	result := define.NewBlockMatrix()
+	switch b {
+	case MatrixStateEmpty:
+		return nil, nil
+	case MatrixStateNotEmpty:
+		result, err = bytesToLegacyBlockMatrix(buf)
+	case MatrixStatePaletted:
+		result, err = bytesToPalettedBlockMatrix(buf)
+	case MatrixStateSingleValue:
+		var value uint32
+		if value, err = readVaruint32(buf); err == nil {
+			result = define.NewBlockMatrix()
+			for i := range result {
+				result[i] = value
+			}
+		}
+	case MatrixStateReference:
+		ref := buf.Next(ReferenceSize)
+		switch {
+		case len(ref) != ReferenceSize:
+			err = fmt.Errorf("%w (reference is truncated)", define.ErrMalformed)
+		case resolver == nil:
+			err = fmt.Errorf("%w (reference is not supported here)", define.ErrMalformed)
+		default:
+			result, err = resolver(bytes.Clone(ref))
+		}
+	default:
+		return nil, fmt.Errorf("BytesToBlockMatrix: %w (unknown matrix state %d)", define.ErrMalformed, b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("BytesToBlockMatrix: %w", err)
+	}
+
+	return result, nil
+}
 
+// bytesToLegacyBlockMatrix decode BlockMatrix from bytes buffer,
+// which is written as 4096 Varuint32 by the older versions.
+func bytesToLegacyBlockMatrix(buf *bytes.Buffer) (result define.BlockMatrix, err error) {
+	result = define.NewBlockMatrix()
 	for i := range define.MatrixSize {
-		r.Varuint32(&result[i])
+		result[i], err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToLegacyBlockMatrix: %w", err)
+		}
+	}
+	return result, nil
+}
+
+// bytesToPalettedBlockMatrix decode BlockMatrix from bytes buffer,
+// which is written as a local palette and the bit-packed indices.
+func bytesToPalettedBlockMatrix(buf *bytes.Buffer) (result define.BlockMatrix, err error) {
+	paletteLen, err := readVaruint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w", err)
+	}
+	if paletteLen == 0 || paletteLen > define.MatrixSize || int(paletteLen) > buf.Len() {
+		return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w (palette length %d is invalid)", define.ErrMalformed, paletteLen)
+	}
+
+	palette := make([]uint32, paletteLen)
+	for i := range palette {
+		palette[i], err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w", err)
+		}
 	}
 
-	return result
+	bitsPerIndex, err := buf.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w (bits per index is truncated)", define.ErrMalformed)
+	}
+	if bitsPerIndex == 0 || bitsPerIndex > 16 || 1<<bitsPerIndex < int(paletteLen) {
+		return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w (bits per index %d is invalid)", define.ErrMalformed, bitsPerIndex)
+	}
+
+	indicesPerWord := 32 / int(bitsPerIndex)
+	wordCount := (define.MatrixSize + indicesPerWord - 1) / indicesPerWord
+	words := buf.Next(wordCount * 4)
+	if len(words) != wordCount*4 {
+		return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w (indices is truncated)", define.ErrMalformed)
+	}
+
+	mask := uint32(1)<<bitsPerIndex - 1
+	result = define.NewBlockMatrix()
+	for i := 0; len(words) > 0; words = words[4:] {
+		word := binary.LittleEndian.Uint32(words)
+		for j := 0; j < indicesPerWord && i < define.MatrixSize; j++ {
+			index := word & mask
+			if index >= paletteLen {
+				return nil, fmt.Errorf("bytesToPalettedBlockMatrix: %w (palette index %d is out of range)", define.ErrMalformed, index)
+			}
+			result[i] = palette[index]
+			word >>= bitsPerIndex
+			i++
+		}
+	}
+
+	return result, nil
+}
+
+// paletteBitsPerIndex returns the count of bits that used to store
+// a single palette index, which is the same as the paletted storage
+// of Bedrock Edition (1, 2, 3, 4, 5, 6, 8 or 16).
+func paletteBitsPerIndex(paletteLen int) int {
+	for _, bits := range []int{1, 2, 3, 4, 5, 6, 8} {
+		if paletteLen <= 1<<bits {
+			return bits
+		}
+	}
+	return 16
 }
 
 // DiffMatrixToBytes writes the bytes represents of diffMatrix into a bytes buffer.
+//
+// diffMatrix is written as the index and palette ID pairs, the runs of
+// consecutive indices that share the same palette ID, or a bitmap of
+// the changed indices plus their palette IDs. The smallest one is used.
 func DiffMatrixToBytes(buf *bytes.Buffer, diffMatrix define.DiffMatrix) {
-	length := uint16(len(diffMatrix))
-
-	if length == 0 {
+	if len(diffMatrix) == 0 {
 		buf.WriteByte(MatrixStateEmpty)
 		return
 	}
+
+	runs, blockCount, increasing := diffRuns(diffMatrix)
+	candidates := []*bytes.Buffer{diffRunsToBytes(runs)}
+	if blockCount <= math.MaxUint16 {
+		candidates = append(candidates, diffPairsToBytes(runs, blockCount))
+	}
+	if increasing {
+		candidates = append(candidates, diffBitmapToBytes(runs, blockCount))
+	}
+
+	best := candidates[0]
+	for _, value := range candidates[1:] {
+		if value.Len() < best.Len() {
+			best = value
+		}
+	}
+	buf.Write(best.Bytes())
+}
+
+// BytesToDiffMatrix decode DiffMatrix from bytes buffer.
+// If the bytes buffer is truncated or corrupted (e.g. the block
+// index is out of range), then returns an error that wraps
+// define.ErrMalformed.
+//
+// The runs of consecutive indices are decoded as SingleBlockDiff
+// whose RunLength is not 0, so they could be applied directly.
+func BytesToDiffMatrix(buf *bytes.Buffer) (result define.DiffMatrix, err error) {
+	b, err := buf.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("BytesToDiffMatrix: %w (matrix state is truncated)", define.ErrMalformed)
+	}
+
+	switch b {
+	case MatrixStateEmpty:
+		return nil, nil
+	case MatrixStateNotEmpty:
+		result, err = bytesToDiffPairs(buf)
+	case MatrixStateRuns:
+		result, err = bytesToDiffRuns(buf)
+	case MatrixStateBitmap:
+		result, err = bytesToDiffBitmap(buf)
+	default:
+		return nil, fmt.Errorf("BytesToDiffMatrix: %w (unknown matrix state %d)", define.ErrMalformed, b)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("BytesToDiffMatrix: %w", err)
+	}
+
+	return result, nil
+}
+
+// diffRuns merges the consecutive block changes in diffMatrix that
+// share the same new palette ID to runs, and returns the count of
+// changed blocks. increasing is false if some block index of
+// diffMatrix is not bigger than the previous one.
+func diffRuns(diffMatrix define.DiffMatrix) (runs define.DiffMatrix, blockCount int, increasing bool) {
+	runs = make(define.DiffMatrix, 0, len(diffMatrix))
+	increasing = true
+
+	for i, value := range diffMatrix {
+		blockCount += int(value.RunLength) + 1
+		if i > 0 && value.IndexDelta == 0 {
+			increasing = false
+		}
+
+		if last := len(runs) - 1; last >= 0 && value.IndexDelta == 1 && runs[last].NewPaletteID == value.NewPaletteID {
+			runs[last].RunLength += value.RunLength + 1
+			continue
+		}
+		runs = append(runs, value)
+	}
+
+	return runs, blockCount, increasing
+}
+
+// diffPairsToBytes writes runs as the index and palette ID pairs,
+// which is the format that used by the older versions.
+func diffPairsToBytes(runs define.DiffMatrix, blockCount int) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
 	buf.WriteByte(MatrixStateNotEmpty)
 
 	w := protocol.NewWriter(buf, 0)
+	length := uint16(blockCount)
 	w.Uint16(&length)
 
-	for _, value := range diffMatrix {
+	one := uint32(1)
+	for _, value := range runs {
 		w.Varuint32(&value.IndexDelta)
+		for range value.RunLength {
+			w.Varuint32(&one)
+		}
+	}
+	for _, value := range runs {
+		for range value.RunLength + 1 {
+			w.Varuint32(&value.NewPaletteID)
+		}
 	}
-	for _, value := range diffMatrix {
+
+	return buf
+}
+
+// diffRunsToBytes writes runs as the runs of consecutive
+// indices that share the same palette ID.
+func diffRunsToBytes(runs define.DiffMatrix) *bytes.Buffer {
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte(MatrixStateRuns)
+
+	w := protocol.NewWriter(buf, 0)
+	length := uint32(len(runs))
+	w.Varuint32(&length)
+
+	for _, value := range runs {
+		w.Varuint32(&value.IndexDelta)
+	}
+	for _, value := range runs {
+		w.Varuint32(&value.RunLength)
+	}
+	for _, value := range runs {
 		w.Varuint32(&value.NewPaletteID)
 	}
+
+	return buf
 }
 
-// BytesToDiffMatrix decode DiffMatrix from bytes buffer.
-func BytesToDiffMatrix(buf *bytes.Buffer) (result define.DiffMatrix) {
-	var length uint16
+// diffBitmapToBytes writes runs as a bitmap of the changed
+// indices, and then the palette IDs of these indices.
+// The indices of runs must be increasing.
+func diffBitmapToBytes(runs define.DiffMatrix, blockCount int) *bytes.Buffer {
+	bitmap := make([]byte, define.MatrixSize/8)
+	ids := make([]uint32, 0, blockCount)
+
+	index := uint32(0)
+	for _, value := range runs {
+		index += value.IndexDelta
+		for i := index; i <= index+value.RunLength; i++ {
+			bitmap[i/8] |= 1 << (i % 8)
+			ids = append(ids, value.NewPaletteID)
+		}
+		index += value.RunLength
+	}
+
+	buf := bytes.NewBuffer(nil)
+	buf.WriteByte(MatrixStateBitmap)
+	buf.Write(bitmap)
 
-	b, _ := buf.ReadByte()
-	if b == MatrixStateEmpty {
-		return nil
+	w := protocol.NewWriter(buf, 0)
+	for i := range ids {
+		w.Varuint32(&ids[i])
 	}
 
-	r := protocol.NewReader(buf, 0, false)
-	r.Uint16(&length)
+	return buf
+}
+
+// bytesToDiffPairs decode DiffMatrix from bytes buffer,
+// which is written as the index and palette ID pairs.
+func bytesToDiffPairs(buf *bytes.Buffer) (result define.DiffMatrix, err error) {
+	length, err := readUint16(buf)
+	if err != nil {
+		return nil, fmt.Errorf("bytesToDiffPairs: %w", err)
+	}
+	if int(length)*2 > buf.Len() {
+		return nil, fmt.Errorf("bytesToDiffPairs: %w (diff matrix is truncated)", define.ErrMalformed)
+	}
+	result = make([]define.SingleBlockDiff, length)
+
+	index := uint64(0)
+	for i := range length {
+		result[i].IndexDelta, err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToDiffPairs: %w", err)
+		}
+
+		index += uint64(result[i].IndexDelta)
+		if index >= define.MatrixSize {
+			return nil, fmt.Errorf("bytesToDiffPairs: %w (block index %d is out of range)", define.ErrMalformed, index)
+		}
+	}
+	for i := range length {
+		result[i].NewPaletteID, err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToDiffPairs: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// bytesToDiffRuns decode DiffMatrix from bytes buffer, which is
+// written as the runs of consecutive indices that share the same
+// palette ID.
+func bytesToDiffRuns(buf *bytes.Buffer) (result define.DiffMatrix, err error) {
+	length, err := readVaruint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("bytesToDiffRuns: %w", err)
+	}
+	if uint64(length)*3 > uint64(buf.Len()) {
+		return nil, fmt.Errorf("bytesToDiffRuns: %w (diff matrix is truncated)", define.ErrMalformed)
+	}
 	result = make([]define.SingleBlockDiff, length)
 
-	for index := range length {
-		r.Varuint32(&result[index].IndexDelta)
+	for i := range result {
+		result[i].IndexDelta, err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToDiffRuns: %w", err)
+		}
+	}
+
+	index := uint64(0)
+	for i := range result {
+		result[i].RunLength, err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToDiffRuns: %w", err)
+		}
+
+		index += uint64(result[i].IndexDelta) + uint64(result[i].RunLength)
+		if index >= define.MatrixSize {
+			return nil, fmt.Errorf("bytesToDiffRuns: %w (block index %d is out of range)", define.ErrMalformed, index)
+		}
+	}
+
+	for i := range result {
+		result[i].NewPaletteID, err = readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToDiffRuns: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// bytesToDiffBitmap decode DiffMatrix from bytes buffer, which is
+// written as a bitmap of the changed indices and their palette IDs.
+// The consecutive indices that share the same palette ID are merged
+// to runs.
+func bytesToDiffBitmap(buf *bytes.Buffer) (result define.DiffMatrix, err error) {
+	bitmap := buf.Next(define.MatrixSize / 8)
+	if len(bitmap) != define.MatrixSize/8 {
+		return nil, fmt.Errorf("bytesToDiffBitmap: %w (bitmap is truncated)", define.ErrMalformed)
 	}
-	for index := range length {
-		r.Varuint32(&result[index].NewPaletteID)
+
+	lastIndex := uint32(0)
+	for index := range uint32(define.MatrixSize) {
+		if bitmap[index/8]&(1<<(index%8)) == 0 {
+			continue
+		}
+
+		newPaletteID, err := readVaruint32(buf)
+		if err != nil {
+			return nil, fmt.Errorf("bytesToDiffBitmap: %w", err)
+		}
+
+		if last := len(result) - 1; last >= 0 && index == lastIndex+1 && result[last].NewPaletteID == newPaletteID {
+			result[last].RunLength++
+		} else {
+			result = append(result, define.SingleBlockDiff{
+				IndexDelta:   index - lastIndex,
+				NewPaletteID: newPaletteID,
+			})
+		}
+		lastIndex = index
 	}
 
-	return
+	if len(result) == 0 {
+		return nil, fmt.Errorf("bytesToDiffBitmap: %w (bitmap is empty)", define.ErrMalformed)
+	}
+	return result, nil
 }
@@ -0,0 +1,104 @@
+package marshal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+)
+
+// testBlockMatrix returns a block matrix whose block at
+// index i is the i%paletteLen+1 palette ID.
+func testBlockMatrix(paletteLen int) define.BlockMatrix {
+	result := define.NewBlockMatrix()
+	for i := range result {
+		result[i] = uint32(i%paletteLen + 1)
+	}
+	return result
+}
+
+func TestBlockMatrixRoundTrip(t *testing.T) {
+	cases := []struct {
+		name   string
+		matrix define.BlockMatrix
+		state  uint8
+	}{
+		{"empty", nil, MatrixStateEmpty},
+		{"air", define.NewBlockMatrix(), MatrixStateSingleValue},
+		{"single value", testBlockMatrix(1), MatrixStateSingleValue},
+		{"paletted", testBlockMatrix(3), MatrixStatePaletted},
+		{"large palette", testBlockMatrix(define.MatrixSize), MatrixStatePaletted},
+	}
+
+	for _, value := range cases {
+		buf := bytes.NewBuffer(nil)
+		BlockMatrixToBytes(buf, value.matrix)
+		if state := buf.Bytes()[0]; state != value.state {
+			t.Fatalf("%s: expected matrix state %d, but got %d", value.name, value.state, state)
+		}
+
+		result, err := BytesToBlockMatrix(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", value.name, err)
+		}
+		if define.BlockMatrixIsEmpty(value.matrix) {
+			if !define.BlockMatrixIsEmpty(result) {
+				t.Fatalf("%s: expected an empty matrix", value.name)
+			}
+			continue
+		}
+		if *result != *value.matrix {
+			t.Fatalf("%s: decoded matrix is not the same as the original one", value.name)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("%s: %d bytes are not consumed", value.name, buf.Len())
+		}
+	}
+}
+
+func TestDiffMatrixRoundTrip(t *testing.T) {
+	older := testBlockMatrix(2)
+
+	run := testBlockMatrix(2)
+	for i := 100; i < 600; i++ {
+		run[i] = 7
+	}
+	scattered := testBlockMatrix(2)
+	for i := 0; i < define.MatrixSize; i += 3 {
+		scattered[i] = uint32(i%5 + 3)
+	}
+	few := testBlockMatrix(2)
+	few[10], few[4000] = 9, 10
+
+	cases := []struct {
+		name  string
+		newer define.BlockMatrix
+		state uint8
+	}{
+		{"no change", testBlockMatrix(2), MatrixStateEmpty},
+		{"pairs", few, MatrixStateNotEmpty},
+		{"runs", run, MatrixStateRuns},
+		{"bitmap", scattered, MatrixStateBitmap},
+	}
+
+	for _, value := range cases {
+		buf := bytes.NewBuffer(nil)
+		DiffMatrixToBytes(buf, define.BlockDifference(older, value.newer))
+		if state := buf.Bytes()[0]; state != value.state {
+			t.Fatalf("%s: expected matrix state %d, but got %d", value.name, value.state, state)
+		}
+
+		diff, err := BytesToDiffMatrix(buf)
+		if err != nil {
+			t.Fatalf("%s: %v", value.name, err)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("%s: %d bytes are not consumed", value.name, buf.Len())
+		}
+
+		base := testBlockMatrix(2)
+		if result := define.BlockRestore(base, diff); *result != *value.newer {
+			t.Fatalf("%s: restored matrix is not the same as the newer one", value.name)
+		}
+	}
+}
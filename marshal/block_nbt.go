@@ -6,14 +6,15 @@ import (
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
-	"github.com/sandertv/gophertunnel/minecraft/nbt"
 	"github.com/sandertv/gophertunnel/minecraft/protocol"
 )
 
 // BlockNBTBytes return the bytes represents of blockNBT.
 // blockNBT must contains all NBT blocks from the same chunk
 // and in the same time.
-func BlockNBTBytes(blockNBT []define.NBTWithIndex) (result []byte, err error) {
+//
+// codec is used to compress the returned bytes.
+func BlockNBTBytes(blockNBT []define.NBTWithIndex, codec *utils.Codec) (result []byte, err error) {
 	if len(blockNBT) == 0 {
 		return nil, nil
 	}
@@ -25,7 +26,7 @@ func BlockNBTBytes(blockNBT []define.NBTWithIndex) (result []byte, err error) {
 		utils.MarshalNBT(buf, value.NBT, "")
 	}
 
-	result, err = utils.Gzip(buf.Bytes())
+	result, err = codec.Encode(buf.Bytes())
 	if err != nil {
 		return nil, fmt.Errorf("BlockNBTBytes: %v", err)
 	}
@@ -35,12 +36,15 @@ func BlockNBTBytes(blockNBT []define.NBTWithIndex) (result []byte, err error) {
 // BytesToBlockNBT decode multiple NBTWithIndex from bytes.
 // Ensure all element in returned slice all represents the NBT blocks
 // in the same chunk and in the same time.
-func BytesToBlockNBT(in []byte) (result []define.NBTWithIndex, err error) {
+//
+// codec is used to decompress in. If in is truncated or corrupted,
+// then returns an error that wraps define.ErrMalformed.
+func BytesToBlockNBT(in []byte, codec *utils.Codec) (result []define.NBTWithIndex, err error) {
 	if len(in) == 0 {
 		return
 	}
 
-	originBytes, err := utils.Ungzip(in)
+	originBytes, err := codec.Decode(in)
 	if err != nil {
 		err = fmt.Errorf("BytesToBlockNBT: %v", err)
 		return
@@ -48,14 +52,16 @@ func BytesToBlockNBT(in []byte) (result []define.NBTWithIndex, err error) {
 	result = make([]define.NBTWithIndex, 0)
 
 	buf := bytes.NewBuffer(originBytes)
-	r := protocol.NewReader(buf, 0, false)
-
 	for buf.Len() > 0 {
 		var index define.ChunkBlockIndex
-		var m map[string]any
 
-		index.Unmarshal(buf)
-		r.NBT(&m, nbt.LittleEndian)
+		if err = index.Unmarshal(buf); err != nil {
+			return nil, fmt.Errorf("BytesToBlockNBT: %w", err)
+		}
+		m, err := readNBT(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToBlockNBT: %w", err)
+		}
 
 		result = append(result, define.NBTWithIndex{
 			Index: index,
@@ -67,7 +73,9 @@ func BytesToBlockNBT(in []byte) (result []define.NBTWithIndex, err error) {
 }
 
 // MultipleDiffNBTBytes return the bytes represents of diff.
-func MultipleDiffNBTBytes(diff define.MultipleDiffNBT) (result []byte, err error) {
+// codec is used to compress the returned bytes, and its
+// dictionary will be used if have.
+func MultipleDiffNBTBytes(diff define.MultipleDiffNBT, codec *utils.Codec) (result []byte, err error) {
 	if define.NBTNoChange(diff) {
 		return nil, nil
 	}
@@ -93,7 +101,7 @@ func MultipleDiffNBTBytes(diff define.MultipleDiffNBT) (result []byte, err error
 		w.ByteSlice(&value.DiffNBT)
 	}
 
-	result, err = utils.Gzip(buf.Bytes())
+	result, err = codec.EncodeDelta(buf.Bytes())
 	if err != nil {
 		return nil, fmt.Errorf("MultipleDiffNBTBytes: %v", err)
 	}
@@ -101,49 +109,125 @@ func MultipleDiffNBTBytes(diff define.MultipleDiffNBT) (result []byte, err error
 }
 
 // BytesToMultipleDiffNBT decode MultipleDiffNBT from bytes.
-func BytesToMultipleDiffNBT(in []byte) (result define.MultipleDiffNBT, err error) {
+// codec is used to decompress in. If in is truncated or corrupted,
+// then returns an error that wraps define.ErrMalformed.
+func BytesToMultipleDiffNBT(in []byte, codec *utils.Codec) (result define.MultipleDiffNBT, err error) {
 	var length uint32
 
 	if len(in) == 0 {
 		return
 	}
 
-	originBytes, err := utils.Ungzip(in)
+	originBytes, err := codec.Decode(in)
 	if err != nil {
 		err = fmt.Errorf("BytesToMultipleDiffNBT: %v", err)
 		return
 	}
 
 	buf := bytes.NewBuffer(originBytes)
-	r := protocol.NewReader(buf, 0, false)
 
-	r.Varuint32(&length)
+	length, err = readVaruint32(buf)
+	if err != nil {
+		return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+	}
+	if uint64(length)*4 > uint64(buf.Len()) {
+		return result, fmt.Errorf("BytesToMultipleDiffNBT: %w (removed NBT blocks are truncated)", define.ErrMalformed)
+	}
 	result.Removed = make([]define.ChunkBlockIndex, length)
 	for i := range length {
-		var index define.ChunkBlockIndex
-		index.Unmarshal(buf)
-		result.Removed[i] = index
+		if err = result.Removed[i].Unmarshal(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+		}
 	}
 
-	r.Varuint32(&length)
+	length, err = readVaruint32(buf)
+	if err != nil {
+		return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+	}
+	if uint64(length)*5 > uint64(buf.Len()) {
+		return result, fmt.Errorf("BytesToMultipleDiffNBT: %w (added NBT blocks are truncated)", define.ErrMalformed)
+	}
 	result.Added = make([]define.NBTWithIndex, length)
 	for i := range length {
-		var object define.NBTWithIndex
-		var index define.ChunkBlockIndex
-		index.Unmarshal(buf)
-		r.NBT(&object.NBT, nbt.LittleEndian)
-		object.Index = index
-		result.Added[i] = object
+		if err = result.Added[i].Index.Unmarshal(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+		}
+		if result.Added[i].NBT, err = readNBT(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+		}
 	}
 
 	for buf.Len() > 0 {
 		var object define.DiffNBTWithIndex
-		var index define.ChunkBlockIndex
-		index.Unmarshal(buf)
-		r.ByteSlice(&object.DiffNBT)
-		object.Index = define.ChunkBlockIndex(index)
+		if err = object.Index.Unmarshal(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+		}
+		if object.DiffNBT, err = readByteSlice(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffNBT: %w", err)
+		}
 		result.Modified = append(result.Modified, object)
 	}
 
+	return result, nil
+}
+
+// NBTSnapshotBytes return the bytes represents of the full block NBT
+// snapshot nbts, which is prefixed by the count of the NBT blocks, so
+// the returned bytes is never empty even if there is no NBT block.
+//
+// codec is used to compress the returned bytes.
+func NBTSnapshotBytes(nbts []define.NBTWithIndex, codec *utils.Codec) (result []byte, err error) {
+	buf := bytes.NewBuffer(nil)
+	w := protocol.NewWriter(buf, 0)
+
+	length := uint32(len(nbts))
+	w.Varuint32(&length)
+	for _, value := range nbts {
+		value.Index.Marshal(buf)
+		utils.MarshalNBT(buf, value.NBT, "")
+	}
+
+	result, err = codec.Encode(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("NBTSnapshotBytes: %v", err)
+	}
 	return
 }
+
+// BytesToNBTSnapshot decode the full block NBT snapshot from bytes.
+// codec is used to decompress in. If in is empty, truncated or
+// corrupted, then returns an error that wraps define.ErrMalformed.
+func BytesToNBTSnapshot(in []byte, codec *utils.Codec) (result []define.NBTWithIndex, err error) {
+	if len(in) == 0 {
+		return nil, fmt.Errorf("BytesToNBTSnapshot: %w (snapshot is empty)", define.ErrMalformed)
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToNBTSnapshot: %v", err)
+	}
+	buf := bytes.NewBuffer(originBytes)
+
+	length, err := readVaruint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToNBTSnapshot: %w", err)
+	}
+	if uint64(length)*5 > uint64(buf.Len()) {
+		return nil, fmt.Errorf("BytesToNBTSnapshot: %w (NBT blocks are truncated)", define.ErrMalformed)
+	}
+
+	result = make([]define.NBTWithIndex, length)
+	for i := range result {
+		if err = result[i].Index.Unmarshal(buf); err != nil {
+			return nil, fmt.Errorf("BytesToNBTSnapshot: %w", err)
+		}
+		if result[i].NBT, err = readNBT(buf); err != nil {
+			return nil, fmt.Errorf("BytesToNBTSnapshot: %w", err)
+		}
+	}
+	if buf.Len() > 0 {
+		return nil, fmt.Errorf("BytesToNBTSnapshot: %w (%d trailing bytes)", define.ErrMalformed, buf.Len())
+	}
+
+	return result, nil
+}
@@ -10,51 +10,111 @@ import (
 )
 
 // ChunkMatrixToBytes return the bytes represents of chunkMatrix.
-func ChunkMatrixToBytes(chunkMatrix define.ChunkMatrix) (result []byte, err error) {
+// codec is used to compress the returned bytes, and a xxhash64
+// checksum trailer is appended to the result.
+func ChunkMatrixToBytes(chunkMatrix define.ChunkMatrix, codec *utils.Codec) (result []byte, err error) {
+	return ChunkMatrixToBytesWithReference(chunkMatrix, codec, nil)
+}
+
+// ChunkMatrixToBytesWithReference is the same as ChunkMatrixToBytes,
+// but referencer is used to get the references of the non-empty layers,
+// and only the references are written for these layers.
+//
+// If referencer returns a nil reference for a layer, or referencer
+// is nil, then this layer is written directly.
+func ChunkMatrixToBytesWithReference(
+	chunkMatrix define.ChunkMatrix,
+	codec *utils.Codec,
+	referencer LayerReferencer,
+) (result []byte, err error) {
 	buf := bytes.NewBuffer(nil)
 
 	for _, value := range chunkMatrix {
-		LayersToBytes(buf, value)
+		if err = layersToBytes(buf, value, referencer); err != nil {
+			return nil, fmt.Errorf("ChunkMatrixToBytes: %w", err)
+		}
 	}
 
 	if buf.Len() == 0 {
 		return nil, nil
 	}
 
-	result, err = utils.Gzip(buf.Bytes())
+	result, err = codec.Encode(buf.Bytes())
 	if err != nil {
 		return nil, fmt.Errorf("ChunkMatrixToBytes: %v", err)
 	}
-	return
+	return utils.WithChecksum(result), nil
 }
 
 // BytesToChunkMatrix decode ChunkMatrix from bytes.
-// r is the count of sub chunks that this chunk have.
-func BytesToChunkMatrix(in []byte, r operator_define.Range) (result define.ChunkMatrix, err error) {
+// r is the count of sub chunks that this chunk have,
+// and codec is used to decompress in.
+//
+// If the checksum trailer of in is not match, then
+// the returned error wraps utils.ErrChecksumMismatch.
+func BytesToChunkMatrix(in []byte, r operator_define.Range, codec *utils.Codec) (result define.ChunkMatrix, err error) {
+	return BytesToChunkMatrixWithResolver(in, r, codec, nil)
+}
+
+// BytesToChunkMatrixWithResolver is the same as BytesToChunkMatrix,
+// but resolver is used to resolve the references of the layers.
+// If resolver is nil, then the references are not supported.
+func BytesToChunkMatrixWithResolver(
+	in []byte,
+	r operator_define.Range,
+	codec *utils.Codec,
+	resolver LayerResolver,
+) (result define.ChunkMatrix, err error) {
 	result = make(define.ChunkMatrix, (r.Height()>>4)+1)
 
 	if len(in) == 0 {
 		return result, nil
 	}
 
-	originBytes, err := utils.Ungzip(in)
+	originBytes, err := codec.Decode(in)
 	if err != nil {
-		err = fmt.Errorf("BytesToChunkMatrix: %v", err)
+		err = fmt.Errorf("BytesToChunkMatrix: %w", err)
 		return
 	}
 
 	ptr := 0
 	buf := bytes.NewBuffer(originBytes)
 	for buf.Len() > 0 {
-		result[ptr] = BytesToLayers(buf)
+		if ptr >= len(result) {
+			return nil, fmt.Errorf("BytesToChunkMatrix: %w (too many sub chunks)", define.ErrMalformed)
+		}
+		result[ptr], err = bytesToLayers(buf, resolver)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToChunkMatrix: %w", err)
+		}
 		ptr++
 	}
 
 	return result, nil
 }
 
+// ChunkMatrixReferences returns all the references of the layers
+// that saved in in, which is encoded by ChunkMatrixToBytesWithReference.
+// A reference is returned multiple times if it is used by multiple layers.
+func ChunkMatrixReferences(in []byte, codec *utils.Codec) (refs [][]byte, err error) {
+	_, err = BytesToChunkMatrixWithResolver(
+		in, operator_define.Range{0, MaxSubChunkCount<<4 - 1}, codec,
+		func(ref []byte) (define.BlockMatrix, error) {
+			refs = append(refs, ref)
+			return nil, nil
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ChunkMatrixReferences: %w", err)
+	}
+	return refs, nil
+}
+
 // ChunkDiffMatrixToBytes return the bytes represents of chunkDiffMatrix.
-func ChunkDiffMatrixToBytes(chunkDiffMatrix define.ChunkDiffMatrix) (result []byte, err error) {
+// codec is used to compress the returned bytes, and its
+// dictionary will be used if have. A xxhash64 checksum
+// trailer is appended to the result.
+func ChunkDiffMatrixToBytes(chunkDiffMatrix define.ChunkDiffMatrix, codec *utils.Codec) (result []byte, err error) {
 	buf := bytes.NewBuffer(nil)
 
 	for _, value := range chunkDiffMatrix {
@@ -65,34 +125,75 @@ func ChunkDiffMatrixToBytes(chunkDiffMatrix define.ChunkDiffMatrix) (result []by
 		return nil, nil
 	}
 
-	result, err = utils.Gzip(buf.Bytes())
+	result, err = codec.EncodeDelta(buf.Bytes())
 	if err != nil {
 		return nil, fmt.Errorf("ChunkDiffMatrixToBytes: %v", err)
 	}
-	return
+	return utils.WithChecksum(result), nil
 }
 
 // BytesToChunkDiffMatrix decode ChunkDiffMatrix from bytes.
-// r is the count of sub chunks that this chunk have.
-func BytesToChunkDiffMatrix(in []byte, r operator_define.Range) (result define.ChunkDiffMatrix, err error) {
+// r is the count of sub chunks that this chunk have,
+// and codec is used to decompress in.
+//
+// If the checksum trailer of in is not match, then
+// the returned error wraps utils.ErrChecksumMismatch.
+func BytesToChunkDiffMatrix(in []byte, r operator_define.Range, codec *utils.Codec) (result define.ChunkDiffMatrix, err error) {
 	result = make(define.ChunkDiffMatrix, (r.Height()>>4)+1)
 
 	if len(in) == 0 {
 		return result, nil
 	}
 
-	originBytes, err := utils.Ungzip(in)
+	originBytes, err := codec.Decode(in)
 	if err != nil {
-		err = fmt.Errorf("BytesToChunkDiffMatrix: %v", err)
+		err = fmt.Errorf("BytesToChunkDiffMatrix: %w", err)
 		return
 	}
 
 	ptr := 0
 	buf := bytes.NewBuffer(originBytes)
 	for buf.Len() > 0 {
-		result[ptr] = BytesToLayersDiff(buf)
+		if ptr >= len(result) {
+			return nil, fmt.Errorf("BytesToChunkDiffMatrix: %w (too many sub chunks)", define.ErrMalformed)
+		}
+		result[ptr], err = BytesToLayersDiff(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToChunkDiffMatrix: %w", err)
+		}
 		ptr++
 	}
 
 	return result, nil
 }
+
+// BytesToSubChunkDiff is the same as BytesToChunkDiffMatrix, but only
+// the LayersDiff of the sub chunk at index is returned. The sub chunks
+// after index are not decoded.
+//
+// If the sub chunk at index is not saved in in (e.g. in is empty),
+// then returns an empty LayersDiff.
+// The checksum trailer of in is also checked.
+func BytesToSubChunkDiff(in []byte, index int, codec *utils.Codec) (result define.LayersDiff, err error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToSubChunkDiff: %w", err)
+	}
+
+	buf := bytes.NewBuffer(originBytes)
+	for ptr := 0; ptr <= index && buf.Len() > 0; ptr++ {
+		result, err = BytesToLayersDiff(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToSubChunkDiff: %w", err)
+		}
+		if ptr == index {
+			return result, nil
+		}
+	}
+
+	return nil, nil
+}
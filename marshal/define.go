@@ -1,6 +1,47 @@
 package marshal
 
+import "github.com/TriM-Organization/bedrock-chunk-diff/define"
+
+// The state of a matrix, which is written as the first
+// byte of each matrix and also used as the format tag.
+//
+// MatrixStateNotEmpty is the legacy format of BlockMatrix,
+// which is still supported when decoding. New BlockMatrix
+// are written as MatrixStatePaletted or MatrixStateSingleValue.
+//
+// DiffMatrix is written as MatrixStateNotEmpty (index and palette
+// ID pairs), MatrixStateRuns or MatrixStateBitmap, depends on which
+// one is the smallest.
+//
+// MatrixStateReference is only used by the BlockMatrix that saved
+// in other place, and only the reference of it is written.
 const (
 	MatrixStateEmpty uint8 = iota
 	MatrixStateNotEmpty
+	MatrixStatePaletted
+	MatrixStateSingleValue
+	MatrixStateRuns
+	MatrixStateBitmap
+	MatrixStateReference
+)
+
+// MaxLayerCount is the max count of
+// layers that a sub chunk could have.
+const MaxLayerCount = 256
+
+// MaxSubChunkCount is the max count of
+// sub chunks that a chunk could have.
+const MaxSubChunkCount = 256
+
+// ReferenceSize is the size of the reference of a
+// layer, which is the content hash of this layer.
+const ReferenceSize = 16
+
+type (
+	// LayerReferencer returns the reference of blockMatrix, which
+	// is a non-empty layer that will be saved in other place.
+	// If ref is nil, then blockMatrix will be written directly.
+	LayerReferencer func(blockMatrix define.BlockMatrix) (ref []byte, err error)
+	// LayerResolver resolves ref to the layer that it refers to.
+	LayerResolver func(ref []byte) (blockMatrix define.BlockMatrix, err error)
 )
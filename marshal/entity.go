@@ -0,0 +1,173 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// EntitiesBytes return the bytes represents of entities.
+// entities must contains all entities from the same chunk
+// and in the same time.
+//
+// codec is used to compress the returned bytes.
+func EntitiesBytes(entities []define.EntityWithID, codec *utils.Codec) (result []byte, err error) {
+	if len(entities) == 0 {
+		return nil, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	for _, value := range entities {
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(value.UniqueID)))
+		utils.MarshalNBT(buf, value.NBT, "")
+	}
+
+	result, err = codec.Encode(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("EntitiesBytes: %v", err)
+	}
+	return
+}
+
+// BytesToEntities decode multiple EntityWithID from bytes.
+// Ensure all element in returned slice all represents the
+// entities in the same chunk and in the same time.
+//
+// codec is used to decompress in. If in is truncated or corrupted,
+// then returns an error that wraps define.ErrMalformed.
+func BytesToEntities(in []byte, codec *utils.Codec) (result []define.EntityWithID, err error) {
+	if len(in) == 0 {
+		return
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToEntities: %v", err)
+	}
+	result = make([]define.EntityWithID, 0)
+
+	buf := bytes.NewBuffer(originBytes)
+	for buf.Len() > 0 {
+		uniqueID, err := readUint64(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToEntities: %w", err)
+		}
+		m, err := readNBT(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToEntities: %w", err)
+		}
+
+		result = append(result, define.EntityWithID{
+			UniqueID: int64(uniqueID),
+			NBT:      m,
+		})
+	}
+
+	return result, nil
+}
+
+// MultipleDiffEntityBytes return the bytes represents of diff.
+// codec is used to compress the returned bytes, and its
+// dictionary will be used if have.
+func MultipleDiffEntityBytes(diff define.MultipleDiffEntity, codec *utils.Codec) (result []byte, err error) {
+	if define.EntityNoChange(diff) {
+		return nil, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	w := protocol.NewWriter(buf, 0)
+
+	length := uint32(len(diff.Removed))
+	w.Varuint32(&length)
+	for _, value := range diff.Removed {
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(value)))
+	}
+
+	length = uint32(len(diff.Added))
+	w.Varuint32(&length)
+	for _, value := range diff.Added {
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(value.UniqueID)))
+		utils.MarshalNBT(buf, value.NBT, "")
+	}
+
+	for _, value := range diff.Modified {
+		buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(value.UniqueID)))
+		w.ByteSlice(&value.DiffNBT)
+	}
+
+	result, err = codec.EncodeDelta(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("MultipleDiffEntityBytes: %v", err)
+	}
+	return
+}
+
+// BytesToMultipleDiffEntity decode MultipleDiffEntity from bytes.
+// codec is used to decompress in. If in is truncated or corrupted,
+// then returns an error that wraps define.ErrMalformed.
+func BytesToMultipleDiffEntity(in []byte, codec *utils.Codec) (result define.MultipleDiffEntity, err error) {
+	if len(in) == 0 {
+		return
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return result, fmt.Errorf("BytesToMultipleDiffEntity: %v", err)
+	}
+	buf := bytes.NewBuffer(originBytes)
+
+	length, err := readVaruint32(buf)
+	if err != nil {
+		return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+	}
+	if uint64(length)*8 > uint64(buf.Len()) {
+		return result, fmt.Errorf("BytesToMultipleDiffEntity: %w (removed entities are truncated)", define.ErrMalformed)
+	}
+	result.Removed = make([]int64, length)
+	for i := range length {
+		uniqueID, err := readUint64(buf)
+		if err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+		}
+		result.Removed[i] = int64(uniqueID)
+	}
+
+	length, err = readVaruint32(buf)
+	if err != nil {
+		return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+	}
+	if uint64(length)*9 > uint64(buf.Len()) {
+		return result, fmt.Errorf("BytesToMultipleDiffEntity: %w (added entities are truncated)", define.ErrMalformed)
+	}
+	result.Added = make([]define.EntityWithID, length)
+	for i := range length {
+		uniqueID, err := readUint64(buf)
+		if err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+		}
+		result.Added[i].UniqueID = int64(uniqueID)
+		if result.Added[i].NBT, err = readNBT(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+		}
+	}
+
+	for buf.Len() > 0 {
+		var object define.DiffEntityWithID
+		uniqueID, err := readUint64(buf)
+		if err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+		}
+		object.UniqueID = int64(uniqueID)
+		if object.DiffNBT, err = readByteSlice(buf); err != nil {
+			return result, fmt.Errorf("BytesToMultipleDiffEntity: %w", err)
+		}
+		result.Modified = append(result.Modified, object)
+	}
+
+	return result, nil
+}
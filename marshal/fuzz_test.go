@@ -0,0 +1,197 @@
+package marshal
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// fuzzRange is the range of the chunks that used by the fuzz targets.
+var fuzzRange = operator_define.Range{-64, 319}
+
+// malformedNBT makes the NBT decoder of gophertunnel
+// panics with "makeslice: len out of range".
+var malformedNBT = []byte("\f\x04\x0000000000")
+
+// fuzzCodec returns a codec that don't compress anything,
+// so the fuzz input could reach the decoders directly.
+func fuzzCodec() *utils.Codec {
+	return utils.NewCodec(utils.NoneCompression{})
+}
+
+// fuzzEncode encodes data by codec, so the result
+// could be passed to the decoders that need a codec.
+func fuzzEncode(t *testing.T, codec *utils.Codec, data []byte) []byte {
+	result, err := codec.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// checkMalformed fails t if err is not nil but not wraps define.ErrMalformed
+// or utils.ErrChecksumMismatch, which means the decoder returned an
+// unexpected kind of error.
+func checkMalformed(t *testing.T, err error) {
+	if err == nil || errors.Is(err, define.ErrMalformed) || errors.Is(err, utils.ErrChecksumMismatch) {
+		return
+	}
+	t.Fatalf("unexpected error: %v", err)
+}
+
+func FuzzBytesToBlockNBT(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add(append([]byte{0, 0, 0, 0}, malformedNBT...))
+	f.Add([]byte{1, 0, 2, 0, 10, 0, 0, 1, 2, 0, 'i', 'd', 3, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToBlockNBT(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToMultipleDiffNBT(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{1, 0, 0, 0, 0, 0})
+	f.Add(append([]byte{0, 1, 0, 0, 0, 0}, malformedNBT...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToMultipleDiffNBT(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToNBTSnapshot(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add(append([]byte{1, 0, 0, 0, 0, 0}, malformedNBT...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToNBTSnapshot(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToEntities(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add(append([]byte{1, 0, 0, 0, 0, 0, 0, 0, 0}, malformedNBT...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToEntities(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToMultipleDiffEntity(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add(append([]byte{0, 1, 0, 0, 0, 0, 0, 0, 0}, malformedNBT...))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToMultipleDiffEntity(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToPlayer(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add(malformedNBT)
+	f.Add([]byte{10, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToPlayer(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToDiffPlayer(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{2, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToDiffPlayer(fuzzEncode(t, codec, data), codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToChunkMatrix(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{1, 0, 0, 0, 1, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToChunkMatrix(fuzzEncode(t, codec, data), fuzzRange, codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToChunkDiffMatrix(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{1, 0, 0, 0, 1, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToChunkDiffMatrix(fuzzEncode(t, codec, data), fuzzRange, codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToSubChunkDiff(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{1, 0, 0, 0, 1, 0}, 0)
+	f.Fuzz(func(t *testing.T, data []byte, index int) {
+		_, err := BytesToSubChunkDiff(fuzzEncode(t, codec, data), index, codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToChunkBiomeMatrix(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{1, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToChunkBiomeMatrix(fuzzEncode(t, codec, data), fuzzRange, codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToChunkBiomeDiffMatrix(f *testing.F) {
+	codec := fuzzCodec()
+	f.Add([]byte{1, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToChunkBiomeDiffMatrix(fuzzEncode(t, codec, data), fuzzRange, codec)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToTimePointSummary(f *testing.F) {
+	f.Add(TimePointSummaryToBytes(define.TimePointSummary{}))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToTimePointSummary(data)
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToLayers(f *testing.F) {
+	f.Add([]byte{1, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToLayers(bytes.NewBuffer(data))
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToLayersDiff(f *testing.F) {
+	f.Add([]byte{1, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToLayersDiff(bytes.NewBuffer(data))
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToBlockMatrix(f *testing.F) {
+	f.Add([]byte{0})
+	f.Add([]byte{1, 1, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToBlockMatrix(bytes.NewBuffer(data))
+		checkMalformed(t, err)
+	})
+}
+
+func FuzzBytesToDiffMatrix(f *testing.F) {
+	f.Add([]byte{0})
+	f.Add([]byte{1, 1, 0, 0, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		_, err := BytesToDiffMatrix(bytes.NewBuffer(data))
+		checkMalformed(t, err)
+	})
+}
@@ -3,33 +3,74 @@ package marshal
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 )
 
 // LayersToBytes writes the bytes represents of layers into a bytes buffer.
 func LayersToBytes(buf *bytes.Buffer, layers define.Layers) {
+	_ = layersToBytes(buf, layers, nil)
+}
+
+// layersToBytes writes the bytes represents of layers into a bytes buffer.
+// referencer is used to get the references of the non-empty layers, and
+// if it is nil, then all the layers are written directly.
+func layersToBytes(buf *bytes.Buffer, layers define.Layers, referencer LayerReferencer) error {
 	lengthBytes := make([]byte, 4)
 	binary.LittleEndian.PutUint32(lengthBytes, uint32(len(layers)))
 	buf.Write(lengthBytes)
+
 	for _, value := range layers {
-		BlockMatrixToBytes(buf, value)
+		if referencer == nil || define.BlockMatrixIsEmpty(value) {
+			BlockMatrixToBytes(buf, value)
+			continue
+		}
+
+		ref, err := referencer(value)
+		if err != nil {
+			return fmt.Errorf("layersToBytes: %w", err)
+		}
+		if ref == nil {
+			BlockMatrixToBytes(buf, value)
+			continue
+		}
+		if len(ref) != ReferenceSize {
+			return fmt.Errorf("layersToBytes: The length of reference is %d but expected %d", len(ref), ReferenceSize)
+		}
+
+		buf.WriteByte(MatrixStateReference)
+		buf.Write(ref)
 	}
+
+	return nil
 }
 
 // BytesToLayers decode Layers from bytes buffer.
-func BytesToLayers(buf *bytes.Buffer) define.Layers {
-	lengthBytes := make([]byte, 4)
-	_, _ = buf.Read(lengthBytes)
-	length := int(binary.LittleEndian.Uint32(lengthBytes))
+// If the bytes buffer is truncated or corrupted, then
+// returns an error that wraps define.ErrMalformed.
+func BytesToLayers(buf *bytes.Buffer) (result define.Layers, err error) {
+	return bytesToLayers(buf, nil)
+}
 
-	result := define.Layers{}
+// bytesToLayers decode Layers from bytes buffer, and
+// resolver is used to resolve the references.
+func bytesToLayers(buf *bytes.Buffer, resolver LayerResolver) (result define.Layers, err error) {
+	length, err := readLayersLength(buf)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToLayers: %w", err)
+	}
+
+	result = define.Layers{}
 	for i := range length {
 		result.Layer(i)
-		result[i] = BytesToBlockMatrix(buf)
+		result[i], err = bytesToBlockMatrix(buf, resolver)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToLayers: %w", err)
+		}
 	}
 
-	return result
+	return result, nil
 }
 
 // LayersDiffToBytes writes the bytes represents of layersDiff into a bytes buffer.
@@ -43,16 +84,36 @@ func LayersDiffToBytes(buf *bytes.Buffer, layersDiff define.LayersDiff) {
 }
 
 // BytesToLayersDiff decode LayersDiff from bytes buffer.
-func BytesToLayersDiff(buf *bytes.Buffer) define.LayersDiff {
-	lengthBytes := make([]byte, 4)
-	_, _ = buf.Read(lengthBytes)
-	length := int(binary.LittleEndian.Uint32(lengthBytes))
+// If the bytes buffer is truncated or corrupted, then
+// returns an error that wraps define.ErrMalformed.
+func BytesToLayersDiff(buf *bytes.Buffer) (result define.LayersDiff, err error) {
+	length, err := readLayersLength(buf)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToLayersDiff: %w", err)
+	}
 
-	result := define.LayersDiff{}
+	result = define.LayersDiff{}
 	for i := range length {
 		result.Layer(i)
-		result[i] = BytesToDiffMatrix(buf)
+		result[i], err = BytesToDiffMatrix(buf)
+		if err != nil {
+			return nil, fmt.Errorf("BytesToLayersDiff: %w", err)
+		}
 	}
 
-	return result
+	return result, nil
+}
+
+// readLayersLength reads the count of layers from buf,
+// and ensure it is not larger than MaxLayerCount and
+// the remaining bytes in buf.
+func readLayersLength(buf *bytes.Buffer) (length int, err error) {
+	lengthUint32, err := readUint32(buf)
+	if err != nil {
+		return 0, fmt.Errorf("readLayersLength: %w", err)
+	}
+	if lengthUint32 > MaxLayerCount || int(lengthUint32) > buf.Len() {
+		return 0, fmt.Errorf("readLayersLength: %w (layer count %d is invalid)", define.ErrMalformed, lengthUint32)
+	}
+	return int(lengthUint32), nil
 }
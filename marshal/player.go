@@ -0,0 +1,84 @@
+package marshal
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// PlayerBytes return the bytes represents of the player data.
+// codec is used to compress the returned bytes.
+//
+// If player is empty, then returns nil.
+func PlayerBytes(player map[string]any, codec *utils.Codec) (result []byte, err error) {
+	if len(player) == 0 {
+		return nil, nil
+	}
+
+	buf := bytes.NewBuffer(nil)
+	utils.MarshalNBT(buf, player, "")
+
+	result, err = codec.Encode(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("PlayerBytes: %v", err)
+	}
+	return
+}
+
+// BytesToPlayer decode the player data from bytes.
+// codec is used to decompress in. If in is truncated or corrupted,
+// then returns an error that wraps define.ErrMalformed.
+func BytesToPlayer(in []byte, codec *utils.Codec) (result map[string]any, err error) {
+	if len(in) == 0 {
+		return
+	}
+
+	originBytes, err := codec.Decode(in)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToPlayer: %v", err)
+	}
+
+	buf := bytes.NewBuffer(originBytes)
+	result, err = readNBT(buf)
+	if err != nil {
+		return nil, fmt.Errorf("BytesToPlayer: %w", err)
+	}
+	if buf.Len() > 0 {
+		return nil, fmt.Errorf("BytesToPlayer: %w (%d bytes left)", define.ErrMalformed, buf.Len())
+	}
+
+	return result, nil
+}
+
+// DiffPlayerBytes return the bytes represents of diff.
+// codec is used to compress the returned bytes, and its
+// dictionary will be used if have.
+//
+// If diff is empty (see define.PlayerNoChange), then returns nil.
+func DiffPlayerBytes(diff define.DiffPlayer, codec *utils.Codec) (result []byte, err error) {
+	if define.PlayerNoChange(diff) {
+		return nil, nil
+	}
+
+	result, err = codec.EncodeDelta(diff.DiffNBT)
+	if err != nil {
+		return nil, fmt.Errorf("DiffPlayerBytes: %v", err)
+	}
+	return
+}
+
+// BytesToDiffPlayer decode DiffPlayer from bytes.
+// codec is used to decompress in.
+func BytesToDiffPlayer(in []byte, codec *utils.Codec) (result define.DiffPlayer, err error) {
+	if len(in) == 0 {
+		return
+	}
+
+	result.DiffNBT, err = codec.Decode(in)
+	if err != nil {
+		return result, fmt.Errorf("BytesToDiffPlayer: %v", err)
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package marshal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+)
+
+// readVaruint32 reads a variable-length encoded uint32 from buf.
+// It is compatible with protocol.Writer.Varuint32.
+func readVaruint32(buf *bytes.Buffer) (result uint32, err error) {
+	for i := 0; i < 35; i += 7 {
+		b, err := buf.ReadByte()
+		if err != nil {
+			return 0, fmt.Errorf("readVaruint32: %w (varuint32 is truncated)", define.ErrMalformed)
+		}
+
+		result |= uint32(b&0x7f) << i
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+	return 0, fmt.Errorf("readVaruint32: %w (varuint32 overflow)", define.ErrMalformed)
+}
+
+// readUint16 reads a little endian uint16 from buf.
+func readUint16(buf *bytes.Buffer) (result uint16, err error) {
+	temp := buf.Next(2)
+	if len(temp) != 2 {
+		return 0, fmt.Errorf("readUint16: %w (uint16 is truncated)", define.ErrMalformed)
+	}
+	return binary.LittleEndian.Uint16(temp), nil
+}
+
+// readUint32 reads a little endian uint32 from buf.
+func readUint32(buf *bytes.Buffer) (result uint32, err error) {
+	temp := buf.Next(4)
+	if len(temp) != 4 {
+		return 0, fmt.Errorf("readUint32: %w (uint32 is truncated)", define.ErrMalformed)
+	}
+	return binary.LittleEndian.Uint32(temp), nil
+}
+
+// readUint64 reads a little endian uint64 from buf.
+func readUint64(buf *bytes.Buffer) (result uint64, err error) {
+	temp := buf.Next(8)
+	if len(temp) != 8 {
+		return 0, fmt.Errorf("readUint64: %w (uint64 is truncated)", define.ErrMalformed)
+	}
+	return binary.LittleEndian.Uint64(temp), nil
+}
+
+// readByteSlice reads a byte slice that prefixed by its
+// length from buf. It is compatible with protocol.Writer.ByteSlice.
+func readByteSlice(buf *bytes.Buffer) (result []byte, err error) {
+	length, err := readVaruint32(buf)
+	if err != nil {
+		return nil, fmt.Errorf("readByteSlice: %w", err)
+	}
+	if uint64(length) > uint64(buf.Len()) {
+		return nil, fmt.Errorf("readByteSlice: %w (byte slice is truncated)", define.ErrMalformed)
+	}
+	return bytes.Clone(buf.Next(int(length))), nil
+}
+
+// readNBT reads a little endian NBT compound from buf.
+// It is compatible with protocol.Writer.NBT and utils.MarshalNBT.
+// Malformed input never panics, see define.ReadNBT.
+func readNBT(buf *bytes.Buffer) (result map[string]any, err error) {
+	result, err = define.ReadNBT(buf)
+	if err != nil {
+		return nil, fmt.Errorf("readNBT: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,65 @@
+package marshal
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/sandertv/gophertunnel/minecraft/protocol"
+)
+
+// TimePointSummaryToBytes returns the bytes represents of summary.
+//
+// The summary is small, so it is not compressed, and could be read
+// without decoding any delta.
+func TimePointSummaryToBytes(summary define.TimePointSummary) []byte {
+	buf := bytes.NewBuffer(nil)
+	w := protocol.NewWriter(buf, 0)
+
+	length := uint32(len(summary.BlockChanges))
+	w.Varuint32(&length)
+	for index := range summary.BlockChanges {
+		w.Varuint32(&summary.BlockChanges[index])
+	}
+
+	w.Varuint32(&summary.NBTAdded)
+	w.Varuint32(&summary.NBTRemoved)
+	w.Varuint32(&summary.NBTModified)
+	w.Varuint32(&summary.BlockDeltaSize)
+	w.Varuint32(&summary.NBTDeltaSize)
+
+	return buf.Bytes()
+}
+
+// BytesToTimePointSummary decode TimePointSummary from bytes.
+// If in is truncated or corrupted, then returns an error that
+// wraps define.ErrMalformed.
+func BytesToTimePointSummary(in []byte) (result define.TimePointSummary, err error) {
+	buf := bytes.NewBuffer(in)
+
+	length, err := readVaruint32(buf)
+	if err != nil {
+		return result, fmt.Errorf("BytesToTimePointSummary: %w", err)
+	}
+	if length > MaxSubChunkCount {
+		return result, fmt.Errorf("BytesToTimePointSummary: %w (too many sub chunks)", define.ErrMalformed)
+	}
+
+	result.BlockChanges = make([]uint32, length)
+	for index := range result.BlockChanges {
+		if result.BlockChanges[index], err = readVaruint32(buf); err != nil {
+			return result, fmt.Errorf("BytesToTimePointSummary: %w", err)
+		}
+	}
+
+	for _, value := range []*uint32{
+		&result.NBTAdded, &result.NBTRemoved, &result.NBTModified,
+		&result.BlockDeltaSize, &result.NBTDeltaSize,
+	} {
+		if *value, err = readVaruint32(buf); err != nil {
+			return result, fmt.Errorf("BytesToTimePointSummary: %w", err)
+		}
+	}
+
+	return result, nil
+}
@@ -1,6 +1,16 @@
 from .timeline.define import Range, Dimension, ChunkPos
 from .timeline.constant import RANGE_OVERWORLD, RANGE_NETHER, RANGE_END
 from .timeline.constant import DIMENSION_OVERWORLD, DIMENSION_NETHER, DIMENSION_END
+from .timeline.constant import ERROR_CODE_NONE, ERROR_CODE_UNKNOWN, ERROR_CODE_NOT_FOUND
+from .timeline.constant import ERROR_CODE_EMPTY, ERROR_CODE_OUT_OF_RANGE, ERROR_CODE_CLOSED
+from .timeline.constant import ERROR_CODE_CORRUPT, ERROR_CODE_BUSY
 
-from .timeline.define import ChunkData
+from .timeline.errors import TimelineError, NotFoundError, EmptyError, OutOfRangeError
+from .timeline.errors import ClosedError, CorruptError, BusyError
+
+from .timeline.define import ChunkData, TimePointSummary
 from .timeline.timeline_database import new_timeline_database
+from .timeline.timeline_database import new_level_timeline_database
+from .timeline.timeline_database import new_memory_timeline_database
+from .timeline.timeline_database import new_encrypted_timeline_database
+from .timeline.chunk_timeline import hash_disk_chunk, hash_network_chunk
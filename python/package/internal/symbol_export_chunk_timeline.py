@@ -1,12 +1,17 @@
 import numpy
+import struct
 from .types import LIB
 from .types import CInt, CLongLong, CString, CSlice
 from .types import as_c_bytes, as_python_bytes, as_python_string
-from .utils import pack_bytes_list, unpack_next_or_last
+from .utils import pack_bytes_list, unpack_bytes_list, unpack_next_or_last
 
 
-LIB.AppendDiskChunk.argtypes = [CLongLong, CSlice, CSlice, CInt, CInt, CInt]
-LIB.AppendNetworkChunk.argtypes = [CLongLong, CSlice, CSlice, CInt, CInt, CInt]
+LIB.AppendDiskChunk.argtypes = [CLongLong, CSlice, CSlice, CSlice, CSlice, CInt, CInt, CInt]
+LIB.AppendNetworkChunk.argtypes = [CLongLong, CSlice, CSlice, CSlice, CSlice, CInt, CInt, CInt]
+LIB.AppendDiskSubChunks.argtypes = [CLongLong, CSlice, CSlice, CInt, CInt, CInt]
+LIB.AppendNetworkSubChunks.argtypes = [CLongLong, CSlice, CSlice, CInt, CInt, CInt]
+LIB.HashDiskChunk.argtypes = [CSlice, CSlice, CInt, CInt, CInt, CInt]
+LIB.HashNetworkChunk.argtypes = [CSlice, CSlice, CInt, CInt, CInt, CInt]
 LIB.Empty.argtypes = [CLongLong]
 LIB.ReadOnly.argtypes = [CLongLong]
 LIB.Pointer.argtypes = [CLongLong]
@@ -14,6 +19,10 @@ LIB.ResetPointer.argtypes = [CLongLong]
 LIB.AllTimePoint.argtypes = [CLongLong]
 LIB.AllTimePointLen.argtypes = [CLongLong]
 LIB.SetMaxLimit.argtypes = [CLongLong, CInt]
+LIB.BestEffort.argtypes = [CLongLong]
+LIB.SetBestEffort.argtypes = [CLongLong, CInt]
+LIB.SkippedNBTs.argtypes = [CLongLong]
+LIB.IgnoredDeltas.argtypes = [CLongLong]
 LIB.Compact.argtypes = [CLongLong]
 LIB.NextDiskChunk.argtypes = [CLongLong]
 LIB.NextNetworkChunk.argtypes = [CLongLong]
@@ -21,11 +30,22 @@ LIB.JumpToDiskChunk.argtypes = [CLongLong, CInt]
 LIB.JumpToNetworkChunk.argtypes = [CLongLong, CInt]
 LIB.LastDiskChunk.argtypes = [CLongLong]
 LIB.LastNetworkChunk.argtypes = [CLongLong]
+LIB.BlockHistory.argtypes = [CLongLong, CInt, CInt, CInt]
+LIB.DiffBetween.argtypes = [CLongLong, CInt, CInt]
+LIB.DiffLatestDiskChunk.argtypes = [CLongLong, CSlice, CSlice, CInt, CInt]
+LIB.DiffLatestNetworkChunk.argtypes = [CLongLong, CSlice, CSlice, CInt, CInt]
+LIB.Summaries.argtypes = [CLongLong]
+LIB.NBTModifications.argtypes = [CLongLong, CInt]
+LIB.SearchBlocks.argtypes = [CLongLong, CSlice]
 LIB.Pop.argtypes = [CLongLong]
 LIB.Save.argtypes = [CLongLong]
 
 LIB.AppendDiskChunk.restype = CString
 LIB.AppendNetworkChunk.restype = CString
+LIB.AppendDiskSubChunks.restype = CString
+LIB.AppendNetworkSubChunks.restype = CString
+LIB.HashDiskChunk.restype = CSlice
+LIB.HashNetworkChunk.restype = CSlice
 LIB.Empty.restype = CInt
 LIB.ReadOnly.restype = CInt
 LIB.Pointer.restype = CInt
@@ -33,6 +53,10 @@ LIB.ResetPointer.restype = CString
 LIB.AllTimePoint.restype = CSlice
 LIB.AllTimePointLen.restype = CInt
 LIB.SetMaxLimit.restype = CString
+LIB.BestEffort.restype = CInt
+LIB.SetBestEffort.restype = CString
+LIB.SkippedNBTs.restype = CInt
+LIB.IgnoredDeltas.restype = CInt
 LIB.Compact.restype = CString
 LIB.NextDiskChunk.restype = CSlice
 LIB.NextNetworkChunk.restype = CSlice
@@ -40,6 +64,13 @@ LIB.JumpToDiskChunk.restype = CSlice
 LIB.JumpToNetworkChunk.restype = CSlice
 LIB.LastDiskChunk.restype = CSlice
 LIB.LastNetworkChunk.restype = CSlice
+LIB.BlockHistory.restype = CSlice
+LIB.DiffBetween.restype = CSlice
+LIB.DiffLatestDiskChunk.restype = CSlice
+LIB.DiffLatestNetworkChunk.restype = CSlice
+LIB.Summaries.restype = CSlice
+LIB.NBTModifications.restype = CSlice
+LIB.SearchBlocks.restype = CSlice
 LIB.Pop.restype = CString
 LIB.Save.restype = CString
 
@@ -48,6 +79,8 @@ def ctl_append_disk_chunk(
     id: int,
     chunk_payload: list[bytes],
     nbt_payload: list[bytes],
+    entity_payload: list[bytes],
+    biome_payload: bytes,
     range_start: int,
     range_end: int,
     nop_when_no_change: bool,
@@ -57,6 +90,8 @@ def ctl_append_disk_chunk(
             CLongLong(id),
             as_c_bytes(pack_bytes_list(chunk_payload)),
             as_c_bytes(b"".join(nbt_payload)),
+            as_c_bytes(b"".join(entity_payload)),
+            as_c_bytes(biome_payload),
             CInt(range_start),
             CInt(range_end),
             CInt(nop_when_no_change),
@@ -68,6 +103,8 @@ def ctl_append_network_chunk(
     id: int,
     chunk_payload: list[bytes],
     nbt_payload: list[bytes],
+    entity_payload: list[bytes],
+    biome_payload: bytes,
     range_start: int,
     range_end: int,
     nop_when_no_change: bool,
@@ -77,6 +114,8 @@ def ctl_append_network_chunk(
             CLongLong(id),
             as_c_bytes(pack_bytes_list(chunk_payload)),
             as_c_bytes(b"".join(nbt_payload)),
+            as_c_bytes(b"".join(entity_payload)),
+            as_c_bytes(biome_payload),
             CInt(range_start),
             CInt(range_end),
             CInt(nop_when_no_change),
@@ -84,6 +123,102 @@ def ctl_append_network_chunk(
     )
 
 
+def pack_sub_chunks(sub_chunks: dict[int, bytes]) -> list[bytes]:
+    return [struct.pack("<h", y) + payload for y, payload in sub_chunks.items()]
+
+
+def ctl_append_disk_sub_chunks(
+    id: int,
+    sub_chunks: dict[int, bytes],
+    nbt_payload: list[bytes],
+    range_start: int,
+    range_end: int,
+    nop_when_no_change: bool,
+) -> str:
+    return as_python_string(
+        LIB.AppendDiskSubChunks(
+            CLongLong(id),
+            as_c_bytes(pack_bytes_list(pack_sub_chunks(sub_chunks))),
+            as_c_bytes(b"".join(nbt_payload)),
+            CInt(range_start),
+            CInt(range_end),
+            CInt(nop_when_no_change),
+        )
+    )
+
+
+def ctl_append_network_sub_chunks(
+    id: int,
+    sub_chunks: dict[int, bytes],
+    nbt_payload: list[bytes],
+    range_start: int,
+    range_end: int,
+    nop_when_no_change: bool,
+) -> str:
+    return as_python_string(
+        LIB.AppendNetworkSubChunks(
+            CLongLong(id),
+            as_c_bytes(pack_bytes_list(pack_sub_chunks(sub_chunks))),
+            as_c_bytes(b"".join(nbt_payload)),
+            CInt(range_start),
+            CInt(range_end),
+            CInt(nop_when_no_change),
+        )
+    )
+
+
+def unpack_hashes(payload: bytes) -> tuple[list[int], str]:
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return list(struct.unpack(f"<{(len(payload) - 1) // 8}Q", payload[1:])), ""
+
+
+def ctl_hash_disk_chunk(
+    chunk_payload: list[bytes],
+    nbt_payload: list[bytes],
+    range_start: int,
+    range_end: int,
+    posx: int,
+    posz: int,
+) -> tuple[list[int], str]:
+    return unpack_hashes(
+        as_python_bytes(
+            LIB.HashDiskChunk(
+                as_c_bytes(pack_bytes_list(chunk_payload)),
+                as_c_bytes(b"".join(nbt_payload)),
+                CInt(range_start),
+                CInt(range_end),
+                CInt(posx),
+                CInt(posz),
+            )
+        )
+    )
+
+
+def ctl_hash_network_chunk(
+    chunk_payload: list[bytes],
+    nbt_payload: list[bytes],
+    range_start: int,
+    range_end: int,
+    posx: int,
+    posz: int,
+) -> tuple[list[int], str]:
+    return unpack_hashes(
+        as_python_bytes(
+            LIB.HashNetworkChunk(
+                as_c_bytes(pack_bytes_list(chunk_payload)),
+                as_c_bytes(b"".join(nbt_payload)),
+                CInt(range_start),
+                CInt(range_end),
+                CInt(posx),
+                CInt(posz),
+            )
+        )
+    )
+
+
 def ctl_empty(id: int) -> int:
     return int(LIB.Empty(CLongLong(id)))
 
@@ -114,19 +249,39 @@ def ctl_set_max_limit(id: int, max_limit: int) -> str:
     return as_python_string(LIB.SetMaxLimit(CLongLong(id), CInt(max_limit)))
 
 
+def ctl_best_effort(id: int) -> int:
+    return int(LIB.BestEffort(CLongLong(id)))
+
+
+def ctl_set_best_effort(id: int, enabled: bool) -> str:
+    return as_python_string(LIB.SetBestEffort(CLongLong(id), CInt(enabled)))
+
+
+def ctl_skipped_nbts(id: int) -> int:
+    return int(LIB.SkippedNBTs(CLongLong(id)))
+
+
+def ctl_ignored_deltas(id: int) -> int:
+    return int(LIB.IgnoredDeltas(CLongLong(id)))
+
+
 def ctl_compact(id: int) -> str:
     return as_python_string(LIB.Compact(CLongLong(id)))
 
 
 def ctl_next_disk_chunk(
     id: int,
-) -> tuple[list[bytes], int, int, list[bytes], int, bool, bool]:
+) -> tuple[
+    list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, bool, str
+]:
     return unpack_next_or_last(as_python_bytes(LIB.NextDiskChunk(CLongLong(id))), True)
 
 
 def ctl_next_network_chunk(
     id: int,
-) -> tuple[list[bytes], int, int, list[bytes], int, bool, bool]:
+) -> tuple[
+    list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, bool, str
+]:
     return unpack_next_or_last(
         as_python_bytes(LIB.NextNetworkChunk(CLongLong(id))), True
     )
@@ -134,7 +289,9 @@ def ctl_next_network_chunk(
 
 def ctl_jump_to_disk_chunk(
     id: int, index: int
-) -> tuple[list[bytes], int, int, list[bytes], int, bool, bool]:
+) -> tuple[
+    list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, bool, str
+]:
     return unpack_next_or_last(
         as_python_bytes(LIB.JumpToDiskChunk(CLongLong(id), CInt(index))), False
     )
@@ -142,7 +299,9 @@ def ctl_jump_to_disk_chunk(
 
 def ctl_jump_to_network_chunk(
     id: int, index: int
-) -> tuple[list[bytes], int, int, list[bytes], int, bool, bool]:
+) -> tuple[
+    list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, bool, str
+]:
     return unpack_next_or_last(
         as_python_bytes(LIB.JumpToNetworkChunk(CLongLong(id), CInt(index))), False
     )
@@ -150,20 +309,186 @@ def ctl_jump_to_network_chunk(
 
 def ctl_last_disk_chunk(
     id: int,
-) -> tuple[list[bytes], int, int, list[bytes], int, bool]:
-    sub_chunks, range_start, range_end, nbts, update_unix_time, _, success = (
+) -> tuple[list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, str]:
+    (
+        sub_chunks,
+        range_start,
+        range_end,
+        nbts,
+        entities,
+        biomes,
+        update_unix_time,
+        _,
+        success,
+        err,
+    ) = (
         unpack_next_or_last(as_python_bytes(LIB.LastDiskChunk(CLongLong(id))), False)
     )
-    return sub_chunks, range_start, range_end, nbts, update_unix_time, success
+    return (
+        sub_chunks,
+        range_start,
+        range_end,
+        nbts,
+        entities,
+        biomes,
+        update_unix_time,
+        success,
+        err,
+    )
 
 
 def ctl_last_network_chunk(
     id: int,
-) -> tuple[list[bytes], int, int, list[bytes], int, bool]:
-    sub_chunks, range_start, range_end, nbts, update_unix_time, _, success = (
+) -> tuple[list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, str]:
+    (
+        sub_chunks,
+        range_start,
+        range_end,
+        nbts,
+        entities,
+        biomes,
+        update_unix_time,
+        _,
+        success,
+        err,
+    ) = (
         unpack_next_or_last(as_python_bytes(LIB.LastNetworkChunk(CLongLong(id))), False)
     )
-    return sub_chunks, range_start, range_end, nbts, update_unix_time, success
+    return (
+        sub_chunks,
+        range_start,
+        range_end,
+        nbts,
+        entities,
+        biomes,
+        update_unix_time,
+        success,
+        err,
+    )
+
+
+def ctl_block_history(id: int, x: int, y: int, z: int) -> tuple[list[bytes], str]:
+    payload = as_python_bytes(
+        LIB.BlockHistory(CLongLong(id), CInt(x), CInt(y), CInt(z))
+    )
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return unpack_bytes_list(payload[1:]), ""
+
+
+def unpack_chunk_diff(payload: bytes) -> tuple[bytes, str]:
+    if len(payload) == 0:
+        return b"", ""
+    if payload[0] != 0:
+        return b"", str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return payload[1:], ""
+
+
+def ctl_diff_between(id: int, i: int, j: int) -> tuple[bytes, str]:
+    return unpack_chunk_diff(
+        as_python_bytes(LIB.DiffBetween(CLongLong(id), CInt(i), CInt(j)))
+    )
+
+
+def ctl_diff_latest_disk_chunk(
+    id: int,
+    chunk_payload: list[bytes],
+    nbt_payload: list[bytes],
+    range_start: int,
+    range_end: int,
+) -> tuple[bytes, str]:
+    return unpack_chunk_diff(
+        as_python_bytes(
+            LIB.DiffLatestDiskChunk(
+                CLongLong(id),
+                as_c_bytes(pack_bytes_list(chunk_payload)),
+                as_c_bytes(b"".join(nbt_payload)),
+                CInt(range_start),
+                CInt(range_end),
+            )
+        )
+    )
+
+
+def ctl_diff_latest_network_chunk(
+    id: int,
+    chunk_payload: list[bytes],
+    nbt_payload: list[bytes],
+    range_start: int,
+    range_end: int,
+) -> tuple[bytes, str]:
+    return unpack_chunk_diff(
+        as_python_bytes(
+            LIB.DiffLatestNetworkChunk(
+                CLongLong(id),
+                as_c_bytes(pack_bytes_list(chunk_payload)),
+                as_c_bytes(b"".join(nbt_payload)),
+                CInt(range_start),
+                CInt(range_end),
+            )
+        )
+    )
+
+
+def ctl_summaries(
+    id: int,
+) -> tuple[list[tuple[int, int, list[int], int, int, int, int, int]], str]:
+    payload = as_python_bytes(LIB.Summaries(CLongLong(id)))
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+
+    result = []
+    ptr = 1
+    while ptr < len(payload):
+        time_point, update_unix_time, length = struct.unpack(
+            "<IqI", payload[ptr : ptr + 16]
+        )
+        ptr += 16
+        block_changes = list(
+            struct.unpack(f"<{length}I", payload[ptr : ptr + length * 4])
+        )
+        ptr += length * 4
+        nbt_added, nbt_removed, nbt_modified, block_delta_size, nbt_delta_size = (
+            struct.unpack("<5I", payload[ptr : ptr + 20])
+        )
+        ptr += 20
+        result.append(
+            (
+                time_point,
+                update_unix_time,
+                block_changes,
+                nbt_added,
+                nbt_removed,
+                nbt_modified,
+                block_delta_size,
+                nbt_delta_size,
+            )
+        )
+    return result, ""
+
+
+def ctl_nbt_modifications(id: int, index: int) -> tuple[list[bytes], str]:
+    payload = as_python_bytes(LIB.NBTModifications(CLongLong(id), CInt(index)))
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return unpack_bytes_list(payload[1:]), ""
+
+
+def ctl_search_blocks(id: int, block_states: list[bytes]) -> tuple[list[bytes], str]:
+    payload = as_python_bytes(
+        LIB.SearchBlocks(CLongLong(id), as_c_bytes(b"".join(block_states)))
+    )
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return unpack_bytes_list(payload[1:]), ""
 
 
 def ctl_pop(id: int) -> str:
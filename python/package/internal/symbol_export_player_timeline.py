@@ -0,0 +1,84 @@
+import numpy
+import struct
+from .types import LIB
+from .types import CInt, CLongLong, CString, CSlice
+from .types import as_c_bytes, as_python_bytes, as_python_string
+
+
+LIB.PlayerAppend.argtypes = [CLongLong, CSlice, CInt]
+LIB.PlayerEmpty.argtypes = [CLongLong]
+LIB.PlayerReadOnly.argtypes = [CLongLong]
+LIB.PlayerAllTimePoint.argtypes = [CLongLong]
+LIB.PlayerAllTimePointLen.argtypes = [CLongLong]
+LIB.PlayerSetMaxLimit.argtypes = [CLongLong, CInt]
+LIB.PlayerJumpTo.argtypes = [CLongLong, CInt]
+LIB.PlayerLast.argtypes = [CLongLong]
+LIB.PlayerPop.argtypes = [CLongLong]
+LIB.PlayerSave.argtypes = [CLongLong]
+
+LIB.PlayerAppend.restype = CString
+LIB.PlayerEmpty.restype = CInt
+LIB.PlayerReadOnly.restype = CInt
+LIB.PlayerAllTimePoint.restype = CSlice
+LIB.PlayerAllTimePointLen.restype = CInt
+LIB.PlayerSetMaxLimit.restype = CString
+LIB.PlayerJumpTo.restype = CSlice
+LIB.PlayerLast.restype = CSlice
+LIB.PlayerPop.restype = CString
+LIB.PlayerSave.restype = CString
+
+
+def unpack_player(payload: bytes) -> tuple[bytes, int, bool, str]:
+    if len(payload) == 0:
+        return b"", 0, False, ""
+    if payload[0] != 0:
+        err = str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+        return b"", 0, False, err
+    update_unix_time: int = struct.unpack("<q", payload[1:9])[0]
+    return payload[9:], update_unix_time, True, ""
+
+
+def ptl_append(id: int, player_payload: bytes, nop_when_no_change: bool) -> str:
+    return as_python_string(
+        LIB.PlayerAppend(
+            CLongLong(id), as_c_bytes(player_payload), CInt(nop_when_no_change)
+        )
+    )
+
+
+def ptl_empty(id: int) -> int:
+    return int(LIB.PlayerEmpty(CLongLong(id)))
+
+
+def ptl_read_only(id: int) -> int:
+    return int(LIB.PlayerReadOnly(CLongLong(id)))
+
+
+def ptl_all_time_point(id: int) -> numpy.ndarray:
+    return numpy.frombuffer(
+        as_python_bytes(LIB.PlayerAllTimePoint(CLongLong(id))), dtype="<i8"
+    )
+
+
+def ptl_all_time_point_len(id: int) -> int:
+    return int(LIB.PlayerAllTimePointLen(CLongLong(id)))
+
+
+def ptl_set_max_limit(id: int, max_limit: int) -> str:
+    return as_python_string(LIB.PlayerSetMaxLimit(CLongLong(id), CInt(max_limit)))
+
+
+def ptl_jump_to(id: int, index: int) -> tuple[bytes, int, bool, str]:
+    return unpack_player(as_python_bytes(LIB.PlayerJumpTo(CLongLong(id), CInt(index))))
+
+
+def ptl_last(id: int) -> tuple[bytes, int, bool, str]:
+    return unpack_player(as_python_bytes(LIB.PlayerLast(CLongLong(id))))
+
+
+def ptl_pop(id: int) -> str:
+    return as_python_string(LIB.PlayerPop(CLongLong(id)))
+
+
+def ptl_save(id: int) -> str:
+    return as_python_string(LIB.PlayerSave(CLongLong(id)))
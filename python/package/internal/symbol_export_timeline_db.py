@@ -1,31 +1,110 @@
 from .types import LIB
-from .types import as_c_string, as_python_string
-from .types import CInt, CLongLong, CString
+import struct
+from .types import as_c_bytes, as_c_string, as_python_bytes, as_python_string
+from .utils import unpack_bytes_list
+from .types import CInt, CLongLong, CSlice, CString
 
 
 LIB.NewTimelineDB.argtypes = [CString, CInt, CInt]
+LIB.NewLevelTimelineDB.argtypes = [CString]
+LIB.NewMemoryTimelineDB.argtypes = []
+LIB.NewEncryptedTimelineDB.argtypes = [CString, CInt, CInt, CInt, CSlice, CSlice]
 LIB.ReleaseTimelineDB.argtypes = [CLongLong]
 LIB.CloseTimelineDB.argtypes = [CLongLong]
 LIB.NewChunkTimeline.argtypes = [CLongLong, CInt, CInt, CInt, CInt]
+LIB.TryNewChunkTimeline.argtypes = [CLongLong, CInt, CInt, CInt, CInt]
 LIB.ReleaseChunkTimeline.argtypes = [CLongLong]
 LIB.DeleteChunkTimeline.argtypes = [CLongLong, CInt, CInt, CInt]
 LIB.LoadLatestTimePointUnixTime.argtypes = [CLongLong, CInt, CInt, CInt]
 LIB.SaveLatestTimePointUnixTime.argtypes = [CLongLong, CInt, CInt, CInt, CLongLong]
+LIB.SetCompression.argtypes = [CLongLong, CInt, CInt]
+LIB.RewriteChunkTimeline.argtypes = [CLongLong, CInt, CInt, CInt]
+LIB.RotateEncryptionKey.argtypes = [CLongLong]
+LIB.SharedPaletteEnabled.argtypes = [CLongLong]
+LIB.SetSharedPalette.argtypes = [CLongLong, CInt]
+LIB.GCSharedPalette.argtypes = [CLongLong]
+LIB.SubChunkDedupEnabled.argtypes = [CLongLong]
+LIB.SetSubChunkDedup.argtypes = [CLongLong, CInt]
+LIB.RegisterCustomBlocks.argtypes = [CLongLong, CSlice]
+LIB.CustomBlocks.argtypes = [CLongLong]
+LIB.SubChunkHashes.argtypes = [CLongLong, CInt, CInt, CInt]
+LIB.SearchBlocksInChunks.argtypes = [CLongLong, CSlice, CSlice]
+LIB.NewPlayerTimeline.argtypes = [CLongLong, CString, CInt]
+LIB.TryNewPlayerTimeline.argtypes = [CLongLong, CString, CInt]
+LIB.ReleasePlayerTimeline.argtypes = [CLongLong]
+LIB.DeletePlayerTimeline.argtypes = [CLongLong, CString]
+LIB.RewritePlayerTimeline.argtypes = [CLongLong, CString]
+LIB.HasPlayerTimeline.argtypes = [CLongLong, CString]
+LIB.AllPlayerTimeline.argtypes = [CLongLong]
 
 LIB.NewTimelineDB.restype = CLongLong
+LIB.NewLevelTimelineDB.restype = CLongLong
+LIB.NewMemoryTimelineDB.restype = CLongLong
+LIB.NewEncryptedTimelineDB.restype = CLongLong
 LIB.ReleaseTimelineDB.restype = None
 LIB.CloseTimelineDB.restype = CString
 LIB.NewChunkTimeline.restype = CLongLong
+LIB.TryNewChunkTimeline.restype = CLongLong
 LIB.ReleaseChunkTimeline.restype = None
 LIB.DeleteChunkTimeline.restype = CString
 LIB.LoadLatestTimePointUnixTime.restype = CLongLong
 LIB.SaveLatestTimePointUnixTime.restype = CString
+LIB.SetCompression.restype = CString
+LIB.RewriteChunkTimeline.restype = CString
+LIB.RotateEncryptionKey.restype = CString
+LIB.SharedPaletteEnabled.restype = CInt
+LIB.SetSharedPalette.restype = CString
+LIB.GCSharedPalette.restype = CLongLong
+LIB.SubChunkDedupEnabled.restype = CInt
+LIB.SetSubChunkDedup.restype = CString
+LIB.RegisterCustomBlocks.restype = CString
+LIB.CustomBlocks.restype = CSlice
+LIB.SubChunkHashes.restype = CSlice
+LIB.SearchBlocksInChunks.restype = CSlice
+LIB.NewPlayerTimeline.restype = CLongLong
+LIB.TryNewPlayerTimeline.restype = CLongLong
+LIB.ReleasePlayerTimeline.restype = None
+LIB.DeletePlayerTimeline.restype = CString
+LIB.RewritePlayerTimeline.restype = CString
+LIB.HasPlayerTimeline.restype = CInt
+LIB.AllPlayerTimeline.restype = CSlice
 
 
 def new_timeline_db(path: str, no_grow_sync: bool, no_sync: bool) -> int:
     return int(LIB.NewTimelineDB(as_c_string(path), CInt(no_grow_sync), CInt(no_sync)))
 
 
+def new_encrypted_timeline_db(
+    path: str,
+    no_grow_sync: bool,
+    no_sync: bool,
+    current_key_id: int,
+    keys: dict[int, bytes],
+    hmac_key: bytes,
+) -> int:
+    keys_payload = b"".join(
+        struct.pack("<II", key_id, len(key)) + key for key_id, key in keys.items()
+    )
+    return int(
+        LIB.NewEncryptedTimelineDB(
+            as_c_string(path),
+            CInt(no_grow_sync),
+            CInt(no_sync),
+            CInt(current_key_id),
+            as_c_bytes(keys_payload),
+            as_c_bytes(hmac_key),
+        )
+    )
+
+
+def new_level_timeline_db(path: str) -> int:
+    return int(LIB.NewLevelTimelineDB(as_c_string(path)))
+
+
+def new_memory_timeline_db() -> int:
+    return int(LIB.NewMemoryTimelineDB())
+
+
 def release_timeline_db(id: int) -> None:
     LIB.ReleaseTimelineDB(CLongLong(id))
 
@@ -44,6 +123,16 @@ def tldb_new_chunk_timeline(
     )
 
 
+def tldb_try_new_chunk_timeline(
+    id: int, dm: int, posx: int, posz: int, read_only: bool
+) -> int:
+    return int(
+        LIB.TryNewChunkTimeline(
+            CLongLong(id), CInt(dm), CInt(posx), CInt(posz), CInt(read_only)
+        )
+    )
+
+
 def release_chunk_timeline(id: int) -> None:
     LIB.ReleaseChunkTimeline(CLongLong(id))
 
@@ -70,3 +159,128 @@ def tldb_save_latest_time_point_unix_time(
             CLongLong(id), CInt(dm), CInt(posx), CInt(posz), CLongLong(time_stamp)
         )
     )
+
+
+def tldb_set_compression(id: int, compression_id: int, level: int) -> str:
+    return as_python_string(
+        LIB.SetCompression(CLongLong(id), CInt(compression_id), CInt(level))
+    )
+
+
+def tldb_rewrite_chunk_timeline(id: int, dm: int, posx: int, posz: int) -> str:
+    return as_python_string(
+        LIB.RewriteChunkTimeline(CLongLong(id), CInt(dm), CInt(posx), CInt(posz))
+    )
+
+
+def tldb_rotate_encryption_key(id: int) -> str:
+    return as_python_string(LIB.RotateEncryptionKey(CLongLong(id)))
+
+
+def tldb_shared_palette_enabled(id: int) -> int:
+    return int(LIB.SharedPaletteEnabled(CLongLong(id)))
+
+
+def tldb_set_shared_palette(id: int, enabled: bool) -> str:
+    return as_python_string(LIB.SetSharedPalette(CLongLong(id), CInt(enabled)))
+
+
+def tldb_gc_shared_palette(id: int) -> int:
+    return int(LIB.GCSharedPalette(CLongLong(id)))
+
+
+def tldb_sub_chunk_dedup_enabled(id: int) -> int:
+    return int(LIB.SubChunkDedupEnabled(CLongLong(id)))
+
+
+def tldb_set_sub_chunk_dedup(id: int, enabled: bool) -> str:
+    return as_python_string(LIB.SetSubChunkDedup(CLongLong(id), CInt(enabled)))
+
+
+def tldb_register_custom_blocks(id: int, block_states: list[bytes]) -> str:
+    return as_python_string(
+        LIB.RegisterCustomBlocks(CLongLong(id), as_c_bytes(b"".join(block_states)))
+    )
+
+
+def tldb_custom_blocks(id: int) -> tuple[list[bytes], str]:
+    payload = as_python_bytes(LIB.CustomBlocks(CLongLong(id)))
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return unpack_bytes_list(payload[1:]), ""
+
+
+def tldb_sub_chunk_hashes(
+    id: int, dm: int, posx: int, posz: int
+) -> tuple[list[int], str]:
+    payload = as_python_bytes(
+        LIB.SubChunkHashes(CLongLong(id), CInt(dm), CInt(posx), CInt(posz))
+    )
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return list(struct.unpack(f"<{(len(payload) - 1) // 8}Q", payload[1:])), ""
+
+
+def tldb_search_blocks_in_chunks(
+    id: int, positions: list[tuple[int, int, int]], block_states: list[bytes]
+) -> tuple[list[bytes], str]:
+    positions_payload = b"".join(struct.pack("<3i", *i) for i in positions)
+    payload = as_python_bytes(
+        LIB.SearchBlocksInChunks(
+            CLongLong(id),
+            as_c_bytes(positions_payload),
+            as_c_bytes(b"".join(block_states)),
+        )
+    )
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return unpack_bytes_list(payload[1:]), ""
+
+
+def tldb_new_player_timeline(id: int, player_id: str, read_only: bool) -> int:
+    return int(
+        LIB.NewPlayerTimeline(CLongLong(id), as_c_string(player_id), CInt(read_only))
+    )
+
+
+def tldb_try_new_player_timeline(id: int, player_id: str, read_only: bool) -> int:
+    return int(
+        LIB.TryNewPlayerTimeline(
+            CLongLong(id), as_c_string(player_id), CInt(read_only)
+        )
+    )
+
+
+def release_player_timeline(id: int) -> None:
+    LIB.ReleasePlayerTimeline(CLongLong(id))
+
+
+def tldb_delete_player_timeline(id: int, player_id: str) -> str:
+    return as_python_string(
+        LIB.DeletePlayerTimeline(CLongLong(id), as_c_string(player_id))
+    )
+
+
+def tldb_rewrite_player_timeline(id: int, player_id: str) -> str:
+    return as_python_string(
+        LIB.RewritePlayerTimeline(CLongLong(id), as_c_string(player_id))
+    )
+
+
+def tldb_has_player_timeline(id: int, player_id: str) -> int:
+    return int(LIB.HasPlayerTimeline(CLongLong(id), as_c_string(player_id)))
+
+
+def tldb_all_player_timeline(id: int) -> tuple[list[str], str]:
+    payload = as_python_bytes(LIB.AllPlayerTimeline(CLongLong(id)))
+    if len(payload) == 0:
+        return [], ""
+    if payload[0] != 0:
+        return [], str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+    return [i.decode(encoding="utf-8") for i in unpack_bytes_list(payload[1:])], ""
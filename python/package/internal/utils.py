@@ -24,10 +24,15 @@ def pack_bytes_list(sub_chunks: list[bytes]) -> bytes:
 
 def unpack_next_or_last(
     payload: bytes, read_is_last_element: bool
-) -> tuple[list[bytes], int, int, list[bytes], int, bool, bool]:
+) -> tuple[
+    list[bytes], int, int, list[bytes], list[bytes], bytes, int, bool, bool, str
+]:
     if len(payload) == 0:
-        return [], 0, 0, [], 0, False, False
-    r = BytesIO(payload)
+        return [], 0, 0, [], [], b"", 0, False, False, ""
+    if payload[0] != 0:
+        err = str(payload[0]) + "|" + payload[1:].decode(encoding="utf-8")
+        return [], 0, 0, [], [], b"", 0, False, False, err
+    r = BytesIO(payload[1:])
 
     length: int = struct.unpack("<I", r.read(4))[0]
     chunk_payload_bytes = r.read(length)
@@ -40,18 +45,28 @@ def unpack_next_or_last(
     nbt_payload = r.read(length)
     nbts = unpack_bytes_list(nbt_payload)
 
+    length = struct.unpack("<I", r.read(4))[0]
+    entity_payload = r.read(length)
+    entities = unpack_bytes_list(entity_payload)
+
+    length = struct.unpack("<I", r.read(4))[0]
+    biomes = r.read(length)
+
     update_unix_time: int = struct.unpack("<q", r.read(8))[0]
 
     is_last_element = False
     if read_is_last_element:
-        is_last_element = bool(int(r.read(1)))
+        is_last_element = bool(int(r.read(1)[0]))
 
     return (
         sub_chunks,
         range_start,
         range_end,
         nbts,
+        entities,
+        biomes,
         update_unix_time,
         is_last_element,
         True,
+        "",
     )
@@ -1,14 +1,24 @@
 import numpy
 from dataclasses import dataclass
-from .define import Range, ChunkData
+from .define import Range, ChunkData, ChunkPos, TimePointSummary
+from .constant import ERROR_CODE_NONE
+from .errors import TimelineError, parse_error, raise_if_error
 from ..internal.symbol_export_timeline_db import release_chunk_timeline
 from ..internal.symbol_export_chunk_timeline import (
     ctl_all_time_point,
     ctl_all_time_point_len,
     ctl_append_disk_chunk,
     ctl_append_network_chunk,
+    ctl_append_disk_sub_chunks,
+    ctl_append_network_sub_chunks,
+    ctl_block_history,
     ctl_compact,
+    ctl_diff_between,
+    ctl_diff_latest_disk_chunk,
+    ctl_diff_latest_network_chunk,
     ctl_empty,
+    ctl_hash_disk_chunk,
+    ctl_hash_network_chunk,
     ctl_jump_to_disk_chunk,
     ctl_jump_to_network_chunk,
     ctl_last_disk_chunk,
@@ -21,6 +31,13 @@ from ..internal.symbol_export_chunk_timeline import (
     ctl_reset_pointer,
     ctl_save,
     ctl_set_max_limit,
+    ctl_best_effort,
+    ctl_set_best_effort,
+    ctl_skipped_nbts,
+    ctl_ignored_deltas,
+    ctl_summaries,
+    ctl_nbt_modifications,
+    ctl_search_blocks,
 )
 
 
@@ -49,6 +66,7 @@ class ChunkTimeline:
     """
 
     _chunk_timeline_id: int = -1
+    _last_error: TimelineError | None = None
 
     def __del__(self):
         if self._chunk_timeline_id >= 0 and release_chunk_timeline is not None:
@@ -69,13 +87,39 @@ class ChunkTimeline:
         """
         return self._chunk_timeline_id >= 0
 
+    def error_code(self) -> int:
+        """
+        error_code returns the reason why this chunk timeline is not valid.
+
+        Returns:
+            int: One of the ERROR_CODE_* constants.
+                 If this chunk timeline is valid, then return ERROR_CODE_NONE.
+        """
+        if self._chunk_timeline_id >= 0:
+            return ERROR_CODE_NONE
+        return -self._chunk_timeline_id
+
+    def last_error(self) -> TimelineError | None:
+        """
+        last_error returns the error that met by the last
+        call of the functions who return None when failed,
+        for example, next_disk_chunk and last_disk_chunk.
+
+        Returns:
+            TimelineError | None:
+                The error of the last failed call.
+                If there is no failed call, then return None.
+        """
+        return self._last_error
+
     def append_disk_chunk(
         self, chunk_data: ChunkData, nop_when_no_change: bool = False
     ):
         """
         append_disk_chunk tries append a new chunk whose is disk
         encoding to the timeline of current chunk. Additionally,
-        we will also append the block entities of this chunk.
+        we will also append the block entities, the entities and
+        the biomes of this chunk.
 
         Calling append_disk_chunk will make sure there is exist at
         least one empty space to place the new time point, whether
@@ -96,18 +140,19 @@ class ChunkTimeline:
 
 
         Raises:
-            Exception: When failed to append the chunk.
+            TimelineError: When failed to append the chunk.
         """
         err = ctl_append_disk_chunk(
             self._chunk_timeline_id,
             chunk_data.sub_chunks,
             chunk_data.nbts,
+            chunk_data.entities,
+            chunk_data.biomes,
             chunk_data.chunk_range.start_range,
             chunk_data.chunk_range.end_range,
             nop_when_no_change,
         )
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
 
     def append_network_chunk(
         self, chunk_data: ChunkData, nop_when_no_change: bool = False
@@ -115,7 +160,8 @@ class ChunkTimeline:
         """
         append_network_chunk tries append a new chunk whose is network
         encoding to the timeline of current chunk.
-        Additionally, we will also append the block entities of this chunk.
+        Additionally, we will also append the block entities, the entities
+        and the biomes of this chunk.
 
         Calling append_network_chunk will make sure there is exist at least
         one empty space to place the new time point, whether new time point
@@ -135,18 +181,111 @@ class ChunkTimeline:
                 Defaults to False.
 
         Raises:
-            Exception: When failed to append the chunk.
+            TimelineError: When failed to append the chunk.
         """
         err = ctl_append_network_chunk(
             self._chunk_timeline_id,
             chunk_data.sub_chunks,
             chunk_data.nbts,
+            chunk_data.entities,
+            chunk_data.biomes,
             chunk_data.chunk_range.start_range,
             chunk_data.chunk_range.end_range,
             nop_when_no_change,
         )
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
+
+    def append_disk_sub_chunks(
+        self,
+        sub_chunks: dict[int, bytes],
+        nbts: list[bytes],
+        chunk_range: Range,
+        nop_when_no_change: bool = False,
+    ):
+        """
+        append_disk_sub_chunks is the same as append_disk_chunk,
+        but only the sub chunks in sub_chunks (whose are disk encoding)
+        are changed, and the others are treated as unchanged.
+
+        So only these sub chunks are diffed, which is much faster than
+        append_disk_chunk if you know exactly which sub chunks are changed.
+
+        The block entities in the other sub chunks are kept, and the ones
+        in nbts that not in these sub chunks are ignored.
+
+        If current timeline is read only, then calling append_disk_sub_chunks
+        will do no operation.
+
+        Args:
+            sub_chunks (dict[int, bytes]):
+                The changed sub chunks, and the key is the Y index of the sub chunk
+                (e.g. -4 is the lowest sub chunk of Overworld).
+            nbts (list[bytes]):
+                The block entities (little endian NBT) in these sub chunks.
+            chunk_range (Range): The range of this chunk.
+            nop_when_no_change (bool, optional):
+                Specific if the append one have no difference between the latest one,
+                then don't append anything to the current chunk timeline.
+                Defaults to False.
+
+        Raises:
+            TimelineError: When failed to append the sub chunks.
+        """
+        err = ctl_append_disk_sub_chunks(
+            self._chunk_timeline_id,
+            sub_chunks,
+            nbts,
+            chunk_range.start_range,
+            chunk_range.end_range,
+            nop_when_no_change,
+        )
+        raise_if_error(err)
+
+    def append_network_sub_chunks(
+        self,
+        sub_chunks: dict[int, bytes],
+        nbts: list[bytes],
+        chunk_range: Range,
+        nop_when_no_change: bool = False,
+    ):
+        """
+        append_network_sub_chunks is the same as append_network_chunk,
+        but only the sub chunks in sub_chunks (whose are network encoding)
+        are changed, and the others are treated as unchanged.
+
+        So only these sub chunks are diffed, which is much faster than
+        append_network_chunk if you know exactly which sub chunks are changed.
+
+        The block entities in the other sub chunks are kept, and the ones
+        in nbts that not in these sub chunks are ignored.
+
+        If current timeline is read only, then calling append_network_sub_chunks
+        will do no operation.
+
+        Args:
+            sub_chunks (dict[int, bytes]):
+                The changed sub chunks, and the key is the Y index of the sub chunk
+                (e.g. -4 is the lowest sub chunk of Overworld).
+            nbts (list[bytes]):
+                The block entities (little endian NBT) in these sub chunks.
+            chunk_range (Range): The range of this chunk.
+            nop_when_no_change (bool, optional):
+                Specific if the append one have no difference between the latest one,
+                then don't append anything to the current chunk timeline.
+                Defaults to False.
+
+        Raises:
+            TimelineError: When failed to append the sub chunks.
+        """
+        err = ctl_append_network_sub_chunks(
+            self._chunk_timeline_id,
+            sub_chunks,
+            nbts,
+            chunk_range.start_range,
+            chunk_range.end_range,
+            nop_when_no_change,
+        )
+        raise_if_error(err)
 
     def empty(self) -> bool:
         """
@@ -189,11 +328,10 @@ class ChunkTimeline:
         reset_pointer is always successful if there even have no time point.
 
         Raises:
-            Exception: When this timeline is not exist.
+            TimelineError: When this timeline is not exist.
         """
         err = ctl_reset_pointer(self._chunk_timeline_id)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
 
     def all_time_point(self) -> numpy.ndarray:
         """
@@ -244,11 +382,72 @@ class ChunkTimeline:
             max_limit (int): The max limit of this timeline.
 
         Raises:
-            Exception: When failed to update the max limit.
+            TimelineError: When failed to update the max limit.
         """
         err = ctl_set_max_limit(self._chunk_timeline_id, max_limit)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
+
+    def best_effort(self) -> bool:
+        """
+        best_effort returns whether this timeline is in best effort mode.
+
+        Returns:
+            bool: Return True for this timeline is in best effort mode.
+                  Return False for not, or this timeline is not exist.
+        """
+        return ctl_best_effort(self._chunk_timeline_id) == 1
+
+    def set_best_effort(self, enabled: bool):
+        """
+        set_best_effort sets whether this timeline is in best effort mode.
+        It is disabled by default.
+
+        In best effort mode, the block NBTs that failed to restore are skipped
+        instead of failing the whole chunk, and the broken block NBT deltas are
+        ignored. The skipped block NBTs come back when there is a full block NBT
+        snapshot, which is saved every 16 time points. The blocks are always
+        restored strictly.
+
+        Note that best effort mode only used when reading,
+        the data that saved to the database is never affected.
+
+        Args:
+            enabled (bool): Whether to enable best effort mode.
+
+        Raises:
+            TimelineError: When this timeline is not exist.
+        """
+        err = ctl_set_best_effort(self._chunk_timeline_id, enabled)
+        raise_if_error(err)
+
+    def skipped_nbts(self) -> int:
+        """
+        skipped_nbts returns how many block NBTs are skipped because
+        they failed to restore in best effort mode. The block NBTs
+        that lost with an ignored delta are not counted.
+
+        The count is accumulated by all the reading since this
+        timeline is loaded or set_best_effort is called.
+
+        Returns:
+            int: The count of the skipped block NBTs.
+                 Return -1 for this timeline is not exist.
+        """
+        return ctl_skipped_nbts(self._chunk_timeline_id)
+
+    def ignored_deltas(self) -> int:
+        """
+        ignored_deltas returns how many broken deltas (or full block NBT
+        snapshots) are ignored in best effort mode, including the deltas
+        of block NBTs, entities and biomes.
+
+        The count is accumulated in the same way as skipped_nbts.
+
+        Returns:
+            int: The count of the ignored deltas.
+                 Return -1 for this timeline is not exist.
+        """
+        return ctl_ignored_deltas(self._chunk_timeline_id)
 
     def compact(self):
         """
@@ -268,15 +467,14 @@ class ChunkTimeline:
             - C is a little big (bigger than 2) due to there are multiple operations need to do.
 
         Raises:
-            Exception: When failed to compact the underlying block palette.
+            TimelineError: When failed to compact the underlying block palette.
         """
         err = ctl_compact(self._chunk_timeline_id)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
 
     def next_disk_chunk(self) -> tuple[ChunkData, int, bool] | None:
         """
-        next_disk_chunk gets the next time point of current chunk and the NBT blocks in it.
+        next_disk_chunk gets the next time point of current chunk and the NBT blocks and entities in it.
         Note that the returned ChunkData is in disk encoding.
 
         With the call to next_disk_chunk, we granted that the returned time keeps increasing
@@ -300,30 +498,37 @@ class ChunkTimeline:
                 Returned int is the update unix time of this time point.
                 The returned bool can inform whether the element obtained after the
                 current call to next_disk_chunk is at the end of the time series.
-                If meet error, then return None.
+                If meet error, then return None, and
+                the error could be got by last_error.
         """
         (
             sub_chunks,
             range_start,
             range_end,
             nbts,
+            entities,
+            biomes,
             update_unix_time,
             is_last_element,
             success,
+            err,
         ) = ctl_next_disk_chunk(self._chunk_timeline_id)
 
         if not success:
+            self._last_error = parse_error(err)
             return None
 
         return (
-            ChunkData(sub_chunks, nbts, Range(range_start, range_end)),
+            ChunkData(
+                sub_chunks, nbts, Range(range_start, range_end), entities, biomes
+            ),
             update_unix_time,
             is_last_element,
         )
 
     def next_network_chunk(self) -> tuple[ChunkData, int, bool] | None:
         """
-        next_network_chunk gets the next time point of current chunk and the NBT blocks in it.
+        next_network_chunk gets the next time point of current chunk and the NBT blocks and entities in it.
         Note that the returned ChunkData is in network encoding.
 
         With the call to next_network_chunk, we granted that the returned time keeps increasing
@@ -346,23 +551,30 @@ class ChunkTimeline:
                 Returned int is the update unix time of this time point.
                 The returned bool can inform whether the element obtained after the
                 current call to next_network_chunk is at the end of the time series.
-                If meet error, then return None.
+                If meet error, then return None, and
+                the error could be got by last_error.
         """
         (
             sub_chunks,
             range_start,
             range_end,
             nbts,
+            entities,
+            biomes,
             update_unix_time,
             is_last_element,
             success,
+            err,
         ) = ctl_next_network_chunk(self._chunk_timeline_id)
 
         if not success:
+            self._last_error = parse_error(err)
             return None
 
         return (
-            ChunkData(sub_chunks, nbts, Range(range_start, range_end)),
+            ChunkData(
+                sub_chunks, nbts, Range(range_start, range_end), entities, biomes
+            ),
             update_unix_time,
             is_last_element,
         )
@@ -391,15 +603,28 @@ class ChunkTimeline:
             tuple[ChunkData, int] | None:
                 The chunk data of target time point.
                 Returned int is the update unix time of this time point.
-                If meet error, then return None.
+                If meet error, then return None, and
+                the error could be got by last_error.
         """
-        sub_chunks, range_start, range_end, nbts, update_unix_time, _, success = (
-            ctl_jump_to_disk_chunk(self._chunk_timeline_id, index)
-        )
+        (
+            sub_chunks,
+            range_start,
+            range_end,
+            nbts,
+            entities,
+            biomes,
+            update_unix_time,
+            _,
+            success,
+            err,
+        ) = ctl_jump_to_disk_chunk(self._chunk_timeline_id, index)
         if not success:
+            self._last_error = parse_error(err)
             return None
         return (
-            ChunkData(sub_chunks, nbts, Range(range_start, range_end)),
+            ChunkData(
+                sub_chunks, nbts, Range(range_start, range_end), entities, biomes
+            ),
             update_unix_time,
         )
 
@@ -427,22 +652,35 @@ class ChunkTimeline:
             tuple[ChunkData, int] | None:
                 The chunk data of target time point.
                 Returned int is the update unix time of this time point.
-                If meet error, then return None.
+                If meet error, then return None, and
+                the error could be got by last_error.
         """
-        sub_chunks, range_start, range_end, nbts, update_unix_time, _, success = (
-            ctl_jump_to_network_chunk(self._chunk_timeline_id, index)
-        )
+        (
+            sub_chunks,
+            range_start,
+            range_end,
+            nbts,
+            entities,
+            biomes,
+            update_unix_time,
+            _,
+            success,
+            err,
+        ) = ctl_jump_to_network_chunk(self._chunk_timeline_id, index)
         if not success:
+            self._last_error = parse_error(err)
             return None
         return (
-            ChunkData(sub_chunks, nbts, Range(range_start, range_end)),
+            ChunkData(
+                sub_chunks, nbts, Range(range_start, range_end), entities, biomes
+            ),
             update_unix_time,
         )
 
     def last_disk_chunk(self) -> tuple[ChunkData, int] | None:
         """
         last_disk_chunk gets the latest time point
-        of current chunk and the NBT blocks in it.
+        of current chunk and the NBT blocks and entities in it.
 
         Time complexity: Time complexity: O(4096×n).
         n is the sub chunk count of this chunk.
@@ -451,22 +689,34 @@ class ChunkTimeline:
             tuple[ChunkData, int, bool] | None:
                 The chunk data who is encoded in disk encoding.
                 Returned int is the update unix time of the time point.
-                If meet error, then return None.
+                If meet error, then return None, and
+                the error could be got by last_error.
         """
-        sub_chunks, range_start, range_end, nbts, update_unix_time, success = (
-            ctl_last_disk_chunk(self._chunk_timeline_id)
-        )
+        (
+            sub_chunks,
+            range_start,
+            range_end,
+            nbts,
+            entities,
+            biomes,
+            update_unix_time,
+            success,
+            err,
+        ) = ctl_last_disk_chunk(self._chunk_timeline_id)
         if not success:
+            self._last_error = parse_error(err)
             return None
         return (
-            ChunkData(sub_chunks, nbts, Range(range_start, range_end)),
+            ChunkData(
+                sub_chunks, nbts, Range(range_start, range_end), entities, biomes
+            ),
             update_unix_time,
         )
 
     def last_network_chunk(self) -> tuple[ChunkData, int] | None:
         """
         last_network_chunk gets the latest time point
-        of current chunk and the NBT blocks in it.
+        of current chunk and the NBT blocks and entities in it.
 
         Time complexity: O(4096×n).
         n is the sub chunk count of this chunk.
@@ -475,18 +725,283 @@ class ChunkTimeline:
             tuple[ChunkData, int, bool] | None:
                 The chunk data who is encoded in network encoding.
                 Returned int is the update unix time of the time point.
-                If meet error, then return None.
+                If meet error, then return None, and
+                the error could be got by last_error.
         """
-        sub_chunks, range_start, range_end, nbts, update_unix_time, success = (
-            ctl_last_network_chunk(self._chunk_timeline_id)
-        )
+        (
+            sub_chunks,
+            range_start,
+            range_end,
+            nbts,
+            entities,
+            biomes,
+            update_unix_time,
+            success,
+            err,
+        ) = ctl_last_network_chunk(self._chunk_timeline_id)
         if not success:
+            self._last_error = parse_error(err)
             return None
         return (
-            ChunkData(sub_chunks, nbts, Range(range_start, range_end)),
+            ChunkData(
+                sub_chunks, nbts, Range(range_start, range_end), entities, biomes
+            ),
             update_unix_time,
         )
 
+    def block_history(self, x: int, y: int, z: int) -> list[bytes]:
+        """
+        block_history returns all the changes of the block at (x, y, z)
+        of this timeline, from the earliest time point to the latest one.
+
+        Only the block deltas of the sub chunk that this block in are
+        decoded, so it is much faster than restoring each time point by
+        next_disk_chunk. The first time point is compared with an empty
+        chunk (full of air). This function don't move the pointer.
+
+        Each change is a little endian TAG_Compound, which contains:
+            - time_point (TAG_Int): The index of the time point that
+              this change happened, which could be used by
+              jump_to_and_get_disk_chunk.
+            - unix_time (TAG_Long): The update unix time of this time point.
+            - old_blocks (TAG_List): The block states of each layer of this
+              block before this time point.
+            - new_blocks (TAG_List): The block states of each layer of this
+              block after this time point.
+            - old_nbt (TAG_Compound): The block NBT before this time point.
+              It is missing if this block have no NBT.
+            - new_nbt (TAG_Compound): The block NBT after this time point.
+              It is missing if this block have no NBT.
+
+        Args:
+            x (int): The relative x coordinate to this chunk (0 to 15).
+            y (int): The world height of this block.
+            z (int): The relative z coordinate to this chunk (0 to 15).
+
+        Raises:
+            TimelineError: When failed to get the block history.
+
+        Returns:
+            list[bytes]: The changes of this block.
+        """
+        result, err = ctl_block_history(self._chunk_timeline_id, x, y, z)
+        raise_if_error(err)
+        return result
+
+    def diff_between(self, i: int, j: int) -> bytes:
+        """
+        diff_between returns the difference of this chunk between the time
+        point i and j. If i is bigger than j, then the returned one is the
+        difference from the newer to the older.
+
+        Only the time point min(i, j) is restored, and the block deltas
+        after it are composed directly, so the full chunks are never built.
+        This function don't move the pointer.
+
+        The returned bytes is a little endian TAG_Compound, which contains:
+            - blocks (TAG_List): The changed blocks, and each of them
+              is a TAG_Compound that contains x, y, z (the relative x
+              and z to this chunk, and the world height y), layer,
+              old_block and new_block (the block states).
+            - added_nbt (TAG_List): The block NBTs that added.
+            - removed_nbt (TAG_List): The block NBTs that removed.
+            - modified_nbt (TAG_List): The block NBTs that modified, and
+              each of them is a TAG_Compound that contains x, y, z,
+              old_nbt and new_nbt.
+
+        Args:
+            i (int): The index of the older time point.
+            j (int): The index of the newer time point.
+
+        Raises:
+            TimelineError: When failed to compute the difference.
+
+        Returns:
+            bytes: The difference from i to j.
+        """
+        result, err = ctl_diff_between(self._chunk_timeline_id, i, j)
+        raise_if_error(err)
+        return result
+
+    def diff_latest_disk_chunk(self, chunk_data: ChunkData) -> bytes:
+        """
+        diff_latest_disk_chunk returns the difference between the latest
+        time point of this timeline and chunk_data, which is encoded in
+        disk encoding and could be a live chunk that not appended.
+
+        The sub chunks whose content hash is the same as the latest one
+        are skipped, and chunk_data will not be appended.
+
+        The returned bytes is a little endian TAG_Compound, which contains:
+            - blocks (TAG_List): The changed blocks, and each of them
+              is a TAG_Compound that contains x, y, z (the relative x
+              and z to this chunk, and the world height y), layer,
+              old_block and new_block (the block states).
+            - added_nbt (TAG_List): The block NBTs that added.
+            - removed_nbt (TAG_List): The block NBTs that removed.
+            - modified_nbt (TAG_List): The block NBTs that modified, and
+              each of them is a TAG_Compound that contains x, y, z,
+              old_nbt and new_nbt.
+
+        Args:
+            chunk_data (ChunkData): The chunk to compare with the latest one.
+
+        Raises:
+            TimelineError: When failed to compute the difference.
+
+        Returns:
+            bytes: The difference from the latest one to chunk_data.
+        """
+        result, err = ctl_diff_latest_disk_chunk(
+            self._chunk_timeline_id,
+            chunk_data.sub_chunks,
+            chunk_data.nbts,
+            chunk_data.chunk_range.start_range,
+            chunk_data.chunk_range.end_range,
+        )
+        raise_if_error(err)
+        return result
+
+    def diff_latest_network_chunk(self, chunk_data: ChunkData) -> bytes:
+        """
+        diff_latest_network_chunk returns the difference between the latest
+        time point of this timeline and chunk_data, which is encoded in
+        network encoding and could be a live chunk that not appended.
+
+        The sub chunks whose content hash is the same as the latest one
+        are skipped, and chunk_data will not be appended.
+
+        The returned bytes is a little endian TAG_Compound, which contains:
+            - blocks (TAG_List): The changed blocks, and each of them
+              is a TAG_Compound that contains x, y, z (the relative x
+              and z to this chunk, and the world height y), layer,
+              old_block and new_block (the block states).
+            - added_nbt (TAG_List): The block NBTs that added.
+            - removed_nbt (TAG_List): The block NBTs that removed.
+            - modified_nbt (TAG_List): The block NBTs that modified, and
+              each of them is a TAG_Compound that contains x, y, z,
+              old_nbt and new_nbt.
+
+        Args:
+            chunk_data (ChunkData): The chunk to compare with the latest one.
+
+        Raises:
+            TimelineError: When failed to compute the difference.
+
+        Returns:
+            bytes: The difference from the latest one to chunk_data.
+        """
+        result, err = ctl_diff_latest_network_chunk(
+            self._chunk_timeline_id,
+            chunk_data.sub_chunks,
+            chunk_data.nbts,
+            chunk_data.chunk_range.start_range,
+            chunk_data.chunk_range.end_range,
+        )
+        raise_if_error(err)
+        return result
+
+    def summaries(self) -> list[TimePointSummary]:
+        """
+        summaries returns the summary of each time point of this timeline,
+        from the earliest one to the latest one.
+
+        The summaries are saved when appending, so this function don't
+        need to decode any delta, and it could be used to show the activity
+        of this chunk.
+
+        Raises:
+            TimelineError: When failed to get the summaries.
+
+        Returns:
+            list[TimePointSummary]: The summary of each time point.
+                                    If this timeline is empty, then
+                                    return an empty list.
+        """
+        result, err = ctl_summaries(self._chunk_timeline_id)
+        raise_if_error(err)
+        return [TimePointSummary(*i) for i in result]
+
+    def nbt_modifications(self, index: int) -> list[bytes]:
+        """
+        nbt_modifications returns the block NBTs that modified at the time
+        point whose index is index, and the patches of each of them. The
+        added and removed block NBTs are not included (see diff_between).
+        This function don't move the pointer.
+
+        Each modification is a little endian TAG_Compound, which contains:
+            - x, y, z (TAG_Int): The position of this block, where x and z
+              are the relative coordinates to this chunk.
+            - kind (TAG_Byte): The kind of the stored delta. 0 is a bsdiff
+              patch, 1 is the whole NBT and 2 is the structural patches.
+            - patches (TAG_List): The patches that change the block NBT from
+              the previous time point to this time point, and should be applied
+              in order. Each of them is a TAG_Compound, which contains:
+                - op (TAG_Byte): 0 to set the value at path, or 1 to remove
+                  it. If the last element of path is a list index that equals
+                  to the length of this list, then the value is appended.
+                - path (TAG_List): The path from the root compound. Each element
+                  is a TAG_Compound that holds "key" (TAG_String) for a key of
+                  compound, or "index" (TAG_Int) for an index of list. An empty
+                  path means the whole NBT is replaced.
+                - value (any): The new value. It is missing if op is 1.
+
+        Args:
+            index (int): The index of the time point.
+
+        Raises:
+            TimelineError: When failed to get the modifications.
+
+        Returns:
+            list[bytes]: The modified block NBTs.
+        """
+        result, err = ctl_nbt_modifications(self._chunk_timeline_id, index)
+        raise_if_error(err)
+        return result
+
+    def search_blocks(self, block_states: list[bytes]) -> list[bytes]:
+        """
+        search_blocks returns all the time points and positions where
+        the blocks that matches any of block_states appeared or disappeared,
+        from the earliest time point to the latest one.
+
+        Only the block palette and the block deltas are used, so the full
+        chunks are never restored. The first time point is compared with
+        an empty chunk (full of air). This function don't move the pointer.
+
+        Each event is a little endian TAG_Compound, which contains:
+            - time_point (TAG_Int): The index of the time point that
+              this event happened, which could be used by
+              jump_to_and_get_disk_chunk.
+            - unix_time (TAG_Long): The update unix time of this time point.
+            - x, y, z (TAG_Int): The position of this block, where x and z
+              are the relative coordinates to this chunk.
+            - layer (TAG_Int): The layer of this block.
+            - appeared (TAG_Byte): 1 if the matching block appeared at this
+              time point, or 0 if it disappeared.
+            - old_block (TAG_Compound): The block state before this time point.
+            - new_block (TAG_Compound): The block state after this time point.
+
+        If both old_block and new_block match, then two events are returned.
+
+        Args:
+            block_states (list[bytes]):
+                The block states to search. Each element is a little endian
+                TAG_Compound NBT that holds "name" (TAG_String) and "states"
+                (TAG_Compound, optional). A block matches if it has the same
+                name, and all the properties in "states" are the same, so
+                "states" could only hold a part of properties.
+
+        Raises:
+            TimelineError: When failed to search the blocks.
+
+        Returns:
+            list[bytes]: The events that found.
+        """
+        result, err = ctl_search_blocks(self._chunk_timeline_id, block_states)
+        raise_if_error(err)
+        return result
+
     def pop(self):
         """
         pop tries to delete the first time point from this timeline.
@@ -495,11 +1010,10 @@ class ChunkTimeline:
         only one time point, then we will do no operation.
 
         Raises:
-            Exception: When failed to pop.
+            TimelineError: When failed to pop.
         """
         err = ctl_pop(self._chunk_timeline_id)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
 
     def save(self):
         """
@@ -522,8 +1036,69 @@ class ChunkTimeline:
         to maintain data consistency.
 
         Raises:
-            Exception: When failed to save this timeline.
+            TimelineError: When failed to save this timeline.
         """
         err = ctl_save(self._chunk_timeline_id)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
+
+
+def hash_disk_chunk(chunk_data: ChunkData, pos: ChunkPos) -> list[int]:
+    """
+    hash_disk_chunk computes the content hash of each sub chunk
+    of chunk_data (whose sub chunks are disk encoding), which
+    covers both the blocks and the block NBTs in this sub chunk.
+
+    The result could be compared with TimelineDatabase.sub_chunk_hashes
+    to know which sub chunks are changed since the latest time point.
+
+    Args:
+        chunk_data (ChunkData): The chunk to compute the hashes.
+        pos (ChunkPos): The chunk position of this chunk.
+
+    Returns:
+        list[int]: The hash of each sub chunk (from the lowest one).
+
+    Raises:
+        TimelineError: When failed to compute the hashes.
+    """
+    result, err = ctl_hash_disk_chunk(
+        chunk_data.sub_chunks,
+        chunk_data.nbts,
+        chunk_data.chunk_range.start_range,
+        chunk_data.chunk_range.end_range,
+        pos.x,
+        pos.z,
+    )
+    raise_if_error(err)
+    return result
+
+
+def hash_network_chunk(chunk_data: ChunkData, pos: ChunkPos) -> list[int]:
+    """
+    hash_network_chunk computes the content hash of each sub chunk
+    of chunk_data (whose sub chunks are network encoding), which
+    covers both the blocks and the block NBTs in this sub chunk.
+
+    The result could be compared with TimelineDatabase.sub_chunk_hashes
+    to know which sub chunks are changed since the latest time point.
+
+    Args:
+        chunk_data (ChunkData): The chunk to compute the hashes.
+        pos (ChunkPos): The chunk position of this chunk.
+
+    Returns:
+        list[int]: The hash of each sub chunk (from the lowest one).
+
+    Raises:
+        TimelineError: When failed to compute the hashes.
+    """
+    result, err = ctl_hash_network_chunk(
+        chunk_data.sub_chunks,
+        chunk_data.nbts,
+        chunk_data.chunk_range.start_range,
+        chunk_data.chunk_range.end_range,
+        pos.x,
+        pos.z,
+    )
+    raise_if_error(err)
+    return result
@@ -12,3 +12,17 @@ RANGE_OVERWORLD = DIMENSION_OVERWORLD.range()
 RANGE_NETHER = DIMENSION_NETHER.range()
 RANGE_END = DIMENSION_END.range()
 RANGE_INVALID = Range(0, -1)
+
+COMPRESSION_NONE = 0
+COMPRESSION_GZIP = 1
+COMPRESSION_ZSTD = 2
+COMPRESSION_SNAPPY = 3
+
+ERROR_CODE_NONE = 0
+ERROR_CODE_UNKNOWN = 1
+ERROR_CODE_NOT_FOUND = 2
+ERROR_CODE_EMPTY = 3
+ERROR_CODE_OUT_OF_RANGE = 4
+ERROR_CODE_CLOSED = 5
+ERROR_CODE_CORRUPT = 6
+ERROR_CODE_BUSY = 7
@@ -124,6 +124,13 @@ class ChunkData:
     the number of block entities within this chunk, so that each element in this list is
     just one little endian TAG_Compound NBT.
 
+    The same is true for the entities field, but each element of it is the NBT data of
+    one entity (e.g. a mob or an armor stand), and the entities are matched by their
+    "UniqueID" tag when they are appended to the timeline.
+
+    The biomes field is the biome storage of this chunk, which is the same encoding as
+    sub_chunks (disk or network). If it is empty, then all the biomes are treated as 0.
+
     Args:
         sub_chunks (list[bytes]): The payload (block matrix data) of this chunk.
                                   The length of this list must equal to 24 if this chunk is from Overworld,
@@ -137,8 +144,43 @@ class ChunkData:
             for a Nether chunk, this is Range(0, 127);
             for a End chunk, this is Range(0, 255).
             Defaults to Range(-64, 319).
+        entities: (list[bytes], optional): The entities NBT data of this chunk.
+                                           Defaults to an empty list.
+        biomes: (bytes, optional): The biomes payload of this chunk.
+                                   Defaults to empty bytes.
     """
 
     sub_chunks: list[bytes] = field(default_factory=lambda: [])
     nbts: list[bytes] = field(default_factory=lambda: [])
     chunk_range: Range = Range(-64, 319)
+    entities: list[bytes] = field(default_factory=lambda: [])
+    biomes: bytes = b""
+
+
+@dataclass(frozen=True)
+class TimePointSummary:
+    """
+    TimePointSummary is the summary of the changes of a time point,
+    compared with the previous one. The first time point is compared
+    with an empty chunk.
+
+    Args:
+        time_point (int): The index of this time point.
+        update_unix_time (int): The update unix time of this time point.
+        block_changes (list[int]): The count of changed blocks of each
+                                   sub chunk, including all the layers.
+        nbt_added (int): The count of added block NBTs.
+        nbt_removed (int): The count of removed block NBTs.
+        nbt_modified (int): The count of modified block NBTs.
+        block_delta_size (int): The size (in bytes) of the stored block delta.
+        nbt_delta_size (int): The size (in bytes) of the stored NBT delta.
+    """
+
+    time_point: int = 0
+    update_unix_time: int = 0
+    block_changes: list[int] = field(default_factory=lambda: [])
+    nbt_added: int = 0
+    nbt_removed: int = 0
+    nbt_modified: int = 0
+    block_delta_size: int = 0
+    nbt_delta_size: int = 0
@@ -0,0 +1,112 @@
+from .constant import (
+    ERROR_CODE_UNKNOWN,
+    ERROR_CODE_NOT_FOUND,
+    ERROR_CODE_EMPTY,
+    ERROR_CODE_OUT_OF_RANGE,
+    ERROR_CODE_CLOSED,
+    ERROR_CODE_CORRUPT,
+    ERROR_CODE_BUSY,
+)
+
+
+class TimelineError(Exception):
+    """
+    TimelineError is the base class of all the
+    errors that returned from the timeline database.
+
+    The code is one of the ERROR_CODE_* constants,
+    and can be used to know the kind of the failure.
+    """
+
+    code: int = ERROR_CODE_UNKNOWN
+
+    def __init__(self, message: str = "", code: int = ERROR_CODE_UNKNOWN):
+        super().__init__(message)
+        self.code = code
+
+
+class NotFoundError(TimelineError):
+    """NotFoundError is raised when the timeline database or chunk timeline is not exist."""
+
+
+class EmptyError(TimelineError):
+    """EmptyError is raised when reading a time point from an empty chunk timeline."""
+
+
+class OutOfRangeError(TimelineError):
+    """OutOfRangeError is raised when the index of the time point is out of range."""
+
+
+class ClosedError(TimelineError):
+    """ClosedError is raised when the underlying database is already closed."""
+
+
+class CorruptError(TimelineError):
+    """CorruptError is raised when the data in the underlying database is broken."""
+
+
+class BusyError(TimelineError):
+    """BusyError is raised when the chunk timeline is still in use."""
+
+
+_ERROR_CLASSES: dict[int, type[TimelineError]] = {
+    ERROR_CODE_NOT_FOUND: NotFoundError,
+    ERROR_CODE_EMPTY: EmptyError,
+    ERROR_CODE_OUT_OF_RANGE: OutOfRangeError,
+    ERROR_CODE_CLOSED: ClosedError,
+    ERROR_CODE_CORRUPT: CorruptError,
+    ERROR_CODE_BUSY: BusyError,
+}
+
+
+def error_from_code(code: int, message: str = "") -> TimelineError:
+    """
+    error_from_code returns the error whose
+    kind is described by the error code.
+
+    Args:
+        code (int): One of the ERROR_CODE_* constants.
+        message (str, optional): The message of the error.
+                                 Defaults to empty string.
+
+    Returns:
+        TimelineError: The error of this code.
+    """
+    return _ERROR_CLASSES.get(code, TimelineError)(message, code)
+
+
+def parse_error(err: str) -> TimelineError | None:
+    """
+    parse_error parses the error string that returned
+    from the C API, which is in the format of "code|message".
+
+    Args:
+        err (str): The error string.
+
+    Returns:
+        TimelineError | None:
+            The parsed error.
+            If err is empty, then return None.
+    """
+    if len(err) == 0:
+        return None
+    code, sep, message = err.partition("|")
+    if len(sep) == 0 or not code.isdigit():
+        return TimelineError(err)
+    return error_from_code(int(code), message)
+
+
+def raise_if_error(err: str):
+    """
+    raise_if_error raises the error that
+    err described if err is not empty.
+
+    Args:
+        err (str): The error string that returned from the C API.
+
+    Raises:
+        TimelineError: When err is not empty.
+    """
+    error = parse_error(err)
+    if error is not None:
+        raise error
@@ -0,0 +1,235 @@
+import numpy
+from dataclasses import dataclass
+from .constant import ERROR_CODE_NONE
+from .errors import TimelineError, parse_error, raise_if_error
+from ..internal.symbol_export_timeline_db import release_player_timeline
+from ..internal.symbol_export_player_timeline import (
+    ptl_all_time_point,
+    ptl_all_time_point_len,
+    ptl_append,
+    ptl_empty,
+    ptl_jump_to,
+    ptl_last,
+    ptl_pop,
+    ptl_read_only,
+    ptl_save,
+    ptl_set_max_limit,
+)
+
+
+@dataclass
+class PlayerTimeline:
+    """
+    PlayerTimeline records the timeline of a player,
+    and it contains the change logs about the data
+    (e.g. the inventory) of this player.
+
+    Different from ChunkTimeline, the player timeline
+    is keyed by the identifier of the player (e.g. the
+    UUID of this player), but not a chunk position.
+
+    Note that it's unsafe for multiple thread to access this
+    struct due to we don't use mutex to ensure the operation
+    is atomic.
+
+    So, it's your responsibility to make ensure there is only
+    one thread is using this object.
+
+    Additionally, before you use this object, please ensure
+    you use PlayerTimeline.is_valid() to check whether the timeline
+    is valid or not.
+    """
+
+    _player_timeline_id: int = -1
+    _last_error: TimelineError | None = None
+
+    def __del__(self):
+        if self._player_timeline_id >= 0 and release_player_timeline is not None:
+            release_player_timeline(self._player_timeline_id)
+
+    def is_valid(self) -> bool:
+        """
+        is_valid check current player timeline is valid or not.
+
+        If not valid, it means the player timeline actually not exist,
+        not only Python but also in Go.
+
+        Returns:
+            bool: Whether the player timeline is valid or not.
+        """
+        return self._player_timeline_id >= 0
+
+    def error_code(self) -> int:
+        """
+        error_code returns the reason why this player timeline is not valid.
+
+        Returns:
+            int: One of the ERROR_CODE_* constants.
+                 If this player timeline is valid, then return ERROR_CODE_NONE.
+        """
+        if self._player_timeline_id >= 0:
+            return ERROR_CODE_NONE
+        return -self._player_timeline_id
+
+    def last_error(self) -> TimelineError | None:
+        """
+        last_error returns the error that met by the last
+        call of the functions who return None when failed,
+        for example, jump_to and last.
+
+        Returns:
+            TimelineError | None:
+                The error of the last failed call.
+                If there is no failed call, then return None.
+        """
+        return self._last_error
+
+    def append(self, player: bytes, nop_when_no_change: bool = False):
+        """
+        append tries append the new data of this player to the timeline.
+        Only the difference to the latest time point is saved.
+
+        Calling append will make sure there is exist at least one empty
+        space to place the new time point, and the way to leave empty
+        space is by calling pop.
+
+        If current timeline is read only, then calling append will do no operation.
+
+        Args:
+            player (bytes):
+                The data of this player, which is a little endian TAG_Compound
+                (e.g. the value of the "player_server_<UUID>" key in the game saves).
+                Empty bytes means this player have no data.
+            nop_when_no_change (bool, optional):
+                Specific if the append one have no difference between the latest one,
+                then don't append anything to the current player timeline.
+                Defaults to False.
+
+        Raises:
+            TimelineError: When failed to append.
+        """
+        err = ptl_append(self._player_timeline_id, player, nop_when_no_change)
+        raise_if_error(err)
+
+    def empty(self) -> bool:
+        """
+        empty returns whether this timeline is empty or not.
+        If is empty, then calling Save will result in no operation.
+
+        Returns:
+            bool: Return True for empty.
+                  Return False for not empty or current timeline is not exist.
+        """
+        return ptl_empty(self._player_timeline_id) == 1
+
+    def read_only(self) -> bool:
+        """
+        read_only returns whether this timeline is read only or not.
+
+        Returns:
+            bool: Return True for this timeline is read only.
+                  Return False for this timeline could be modified,
+                  or this timeline is not exist.
+        """
+        return ptl_read_only(self._player_timeline_id) == 1
+
+    def all_time_point(self) -> numpy.ndarray:
+        """
+        all_time_point returns a list that holds
+        the unix time of all time points this timeline
+        have. Granted the returned array is non-decreasing.
+
+        Returns:
+            numpy.ndarray: The list that holds the unix time
+                           for all time points in this timeline.
+        """
+        return ptl_all_time_point(self._player_timeline_id)
+
+    def all_time_point_len(self) -> int:
+        """
+        all_time_point_len returns the length of
+        the time point that this timeline have.
+
+        Returns:
+            int: The length of this timeline.
+        """
+        return ptl_all_time_point_len(self._player_timeline_id)
+
+    def set_max_limit(self, max_limit: int):
+        """
+        set_max_limit sets the timeline could record how many time point.
+        It is the same as ChunkTimeline.set_max_limit.
+
+        Args:
+            max_limit (int): The max limit of this timeline.
+
+        Raises:
+            TimelineError: When failed to update the max limit.
+        """
+        err = ptl_set_max_limit(self._player_timeline_id, max_limit)
+        raise_if_error(err)
+
+    def jump_to(self, index: int) -> tuple[bytes, int] | None:
+        """
+        jump_to gets the data of this player at the time point who is in index.
+
+        Args:
+            index (int): The index of target time point.
+
+        Returns:
+            tuple[bytes, int] | None:
+                The data of this player as a little endian TAG_Compound
+                (empty bytes if this player have no data).
+                Returned int is the update unix time of this time point.
+                If meet error, then return None, and
+                the error could be got by last_error.
+        """
+        player, update_unix_time, success, err = ptl_jump_to(
+            self._player_timeline_id, index
+        )
+        if not success:
+            self._last_error = parse_error(err)
+            return None
+        return player, update_unix_time
+
+    def last(self) -> tuple[bytes, int] | None:
+        """
+        last gets the data of this player at the latest time point.
+
+        Returns:
+            tuple[bytes, int] | None:
+                The data of this player as a little endian TAG_Compound
+                (empty bytes if this player have no data).
+                Returned int is the update unix time of this time point.
+                If meet error, then return None, and
+                the error could be got by last_error.
+        """
+        player, update_unix_time, success, err = ptl_last(self._player_timeline_id)
+        if not success:
+            self._last_error = parse_error(err)
+            return None
+        return player, update_unix_time
+
+    def pop(self):
+        """
+        pop tries to delete the first time point from this timeline.
+
+        If current timeline is empty, or it is read only, or there is
+        only one time point, then we will do no operation.
+
+        Raises:
+            TimelineError: When failed to pop.
+        """
+        err = ptl_pop(self._player_timeline_id)
+        raise_if_error(err)
+
+    def save(self):
+        """
+        save saves current timeline into the underlying database, and also release current timeline.
+        It is the same as ChunkTimeline.save.
+
+        Raises:
+            TimelineError: When failed to save this timeline.
+        """
+        err = ptl_save(self._player_timeline_id)
+        raise_if_error(err)
@@ -1,15 +1,40 @@
 from .define import Dimension, ChunkPos
 from .constant import DIMENSION_OVERWORLD
 from dataclasses import dataclass
+from .constant import ERROR_CODE_NONE
 from .chunk_timeline import ChunkTimeline
+from .player_timeline import PlayerTimeline
+from .errors import error_from_code, raise_if_error
 from ..internal.symbol_export_timeline_db import (
     new_timeline_db,
+    new_level_timeline_db,
+    new_memory_timeline_db,
+    new_encrypted_timeline_db,
     release_timeline_db,
     tldb_close_timeline_db,
     tldb_delete_chunk_timeline,
     tldb_load_latest_time_point_unix_time,
     tldb_new_chunk_timeline,
+    tldb_try_new_chunk_timeline,
     tldb_save_latest_time_point_unix_time,
+    tldb_set_compression,
+    tldb_rewrite_chunk_timeline,
+    tldb_rotate_encryption_key,
+    tldb_shared_palette_enabled,
+    tldb_set_shared_palette,
+    tldb_gc_shared_palette,
+    tldb_sub_chunk_dedup_enabled,
+    tldb_set_sub_chunk_dedup,
+    tldb_register_custom_blocks,
+    tldb_custom_blocks,
+    tldb_sub_chunk_hashes,
+    tldb_search_blocks_in_chunks,
+    tldb_new_player_timeline,
+    tldb_try_new_player_timeline,
+    tldb_delete_player_timeline,
+    tldb_rewrite_player_timeline,
+    tldb_has_player_timeline,
+    tldb_all_player_timeline,
 )
 
 
@@ -17,7 +42,7 @@ from ..internal.symbol_export_timeline_db import (
 class TimelineDatabase:
     """
     TimelineDatabase wrapper and implements all features from Timeline,
-    and as a provider to provide timeline of chunk related functions.
+    and as a provider to provide timeline of chunk (and player) related functions.
     """
 
     _database_id: int = -1
@@ -41,6 +66,18 @@ class TimelineDatabase:
         """
         return self._database_id >= 0
 
+    def error_code(self) -> int:
+        """
+        error_code returns the reason why this timeline database is not valid.
+
+        Returns:
+            int: One of the ERROR_CODE_* constants.
+                 If this timeline database is valid, then return ERROR_CODE_NONE.
+        """
+        if self._database_id >= 0:
+            return ERROR_CODE_NONE
+        return -self._database_id
+
     def close_timeline_db(self):
         """
         close_timeline_db closes the timeline database.
@@ -49,11 +86,10 @@ class TimelineDatabase:
         released before closing the database.
 
         Raises:
-            Exception: When failed to close the timeline database.
+            TimelineError: When failed to close the timeline database.
         """
         err = tldb_close_timeline_db(self._database_id)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
 
     def new_chunk_timeline(
         self,
@@ -98,6 +134,30 @@ class TimelineDatabase:
             tldb_new_chunk_timeline(self._database_id, int(dm), pos.x, pos.z, read_only)
         )
 
+    def try_new_chunk_timeline(
+        self,
+        pos: ChunkPos,
+        read_only: bool = False,
+        dm: Dimension = DIMENSION_OVERWORLD,
+    ) -> ChunkTimeline:
+        """
+        try_new_chunk_timeline is like new_chunk_timeline, but it will not blocking
+        when there is still some threads are using target chunk.
+
+        Instead, you get a invalid ChunkTimeline whose ChunkTimeline.error_code()
+        is ERROR_CODE_BUSY.
+
+        Args:
+            pos (ChunkPos): The chunk position of the target chunk.
+            read_only (bool, optional): You want to the target timeline is read only or not.
+                                        Defaults to False.
+            dm (Dimension, optional): The dimension of the target chunk.
+                                      Defaults to DIMENSION_OVERWORLD.
+        """
+        return ChunkTimeline(
+            tldb_try_new_chunk_timeline(self._database_id, int(dm), pos.x, pos.z, read_only)
+        )
+
     def delete_chunk_timeline(self, pos: ChunkPos, dm: Dimension = DIMENSION_OVERWORLD):
         """
         delete_chunk_timeline deletes the timeline of chunk who at pos.
@@ -112,11 +172,10 @@ class TimelineDatabase:
                                       Defaults to DIMENSION_OVERWORLD.
 
         Raises:
-            Exception: When failed to delete target timeline.
+            TimelineError: When failed to delete target timeline.
         """
         err = tldb_delete_chunk_timeline(self._database_id, int(dm), pos.x, pos.z)
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
 
     def load_latest_time_point_unix_time(
         self, pos: ChunkPos, dm: Dimension = DIMENSION_OVERWORLD
@@ -159,13 +218,351 @@ class TimelineDatabase:
                                       Defaults to DIMENSION_OVERWORLD.
 
         Raises:
-            Exception: When failed to update the unix time.
+            TimelineError: When failed to update the unix time.
         """
         err = tldb_save_latest_time_point_unix_time(
             self._database_id, int(dm), pos.x, pos.z, time_stamp
         )
-        if len(err) > 0:
-            raise Exception(err)
+        raise_if_error(err)
+
+    def set_compression(self, compression_id: int, level: int = 0):
+        """
+        set_compression sets the compression algorithm that used to
+        compress the values that will be written to the database.
+
+        The values that written before will not be changed, and they
+        could still be read. Use rewrite_chunk_timeline to re-compress
+        them by the new compression algorithm.
+
+        The selected compression algorithm is persisted in the database.
+
+        Args:
+            compression_id (int): The ID of the compression algorithm.
+                                  See COMPRESSION_* for more information.
+            level (int, optional): The compression level.
+                                   It is ignored for the algorithm that have no levels.
+                                   Defaults to 0.
+
+        Raises:
+            TimelineError: When failed to set the compression algorithm.
+        """
+        err = tldb_set_compression(self._database_id, compression_id, level)
+        raise_if_error(err)
+
+    def rewrite_chunk_timeline(
+        self, pos: ChunkPos, dm: Dimension = DIMENSION_OVERWORLD
+    ):
+        """
+        rewrite_chunk_timeline re-compresses all the values that
+        belongs to the timeline of the chunk who at pos by the
+        compression algorithm that currently selected.
+
+        Note that if the timeline of this chunk is using by
+        others, then calling rewrite_chunk_timeline will be
+        blocked until the timeline is released.
+
+        Args:
+            pos (ChunkPos): The chunk position of the target chunk.
+            dm (Dimension, optional): The dimension of the target chunk.
+                                      Defaults to DIMENSION_OVERWORLD.
+
+        Raises:
+            TimelineError: When failed to rewrite the timeline.
+        """
+        err = tldb_rewrite_chunk_timeline(self._database_id, int(dm), pos.x, pos.z)
+        raise_if_error(err)
+
+    def rotate_encryption_key(self):
+        """
+        rotate_encryption_key re-encrypts all the values in
+        the database by the key that currently selected, so
+        the old keys could be dropped after that.
+
+        It's unsafe to modify any timeline when rotating.
+
+        Raises:
+            TimelineError: When the database is not encrypted or failed to rotate.
+        """
+        err = tldb_rotate_encryption_key(self._database_id)
+        raise_if_error(err)
+
+    def shared_palette_enabled(self) -> bool:
+        """
+        shared_palette_enabled returns whether the shared
+        palette is used when saving the chunk timelines.
+
+        Returns:
+            bool: Whether the shared palette is enabled.
+                  If the timeline database is not valid, then return False.
+        """
+        return tldb_shared_palette_enabled(self._database_id) == 1
+
+    def set_shared_palette(self, enabled: bool):
+        """
+        set_shared_palette sets whether to use the shared palette,
+        and save this setting into the underlying database.
+
+        The shared palette maps the block states to stable global IDs
+        for the entire database, and the block palette of each chunk
+        timeline will only save these IDs but not the full block states.
+
+        Existing chunk timelines are migrated when they are saved next
+        time, or use rewrite_chunk_timeline to migrate them immediately.
+        This is also the same when disabling the shared palette.
+
+        Args:
+            enabled (bool): Whether to use the shared palette.
+
+        Raises:
+            TimelineError: When failed to save this setting.
+        """
+        err = tldb_set_shared_palette(self._database_id, enabled)
+        raise_if_error(err)
+
+    def gc_shared_palette(self) -> int:
+        """
+        gc_shared_palette removes the entries of the shared palette
+        that not used by any chunk timeline.
+
+        Returns:
+            int: The count of the removed entries.
+
+        Raises:
+            TimelineError: When failed to remove the unused entries.
+        """
+        result = tldb_gc_shared_palette(self._database_id)
+        if result < 0:
+            raise error_from_code(-result, "gc_shared_palette: Failed to remove the unused entries")
+        return result
+
+    def sub_chunk_dedup_enabled(self) -> bool:
+        """
+        sub_chunk_dedup_enabled returns whether the sub
+        chunk layers of the latest chunks are deduplicated.
+
+        Returns:
+            bool: Whether the sub chunk deduplication is enabled.
+                  If the timeline database is not valid, then return False.
+        """
+        return tldb_sub_chunk_dedup_enabled(self._database_id) == 1
+
+    def set_sub_chunk_dedup(self, enabled: bool):
+        """
+        set_sub_chunk_dedup sets whether to deduplicate the sub chunk
+        layers of the latest chunks, and save this setting into the
+        underlying database.
+
+        When enabled, each non-trivial layer of the latest chunk is
+        saved into a shared bucket by its content hash with a reference
+        count, and the latest chunk only holds the references of them.
+
+        Existing chunk timelines are migrated when they are saved next
+        time, or use rewrite_chunk_timeline to migrate them immediately.
+        This is also the same when disabling the deduplication.
+
+        Args:
+            enabled (bool): Whether to deduplicate the sub chunk layers.
+
+        Raises:
+            TimelineError: When failed to save this setting.
+        """
+        err = tldb_set_sub_chunk_dedup(self._database_id, enabled)
+        raise_if_error(err)
+
+    def register_custom_blocks(self, block_states: list[bytes]):
+        """
+        register_custom_blocks registers the custom block states (e.g. the
+        blocks that come from resource pack) to the timeline database.
+
+        The registered block states are saved into the underlying database,
+        and they are registered again when the database is opened, so the
+        chunks that contains custom blocks could be diffed, saved and restored
+        correctly, even if the database is opened on another machine.
+
+        Args:
+            block_states (list[bytes]):
+                The custom block states. Each element is a little endian TAG_Compound
+                NBT that holds "name" (TAG_String), "states" (TAG_Compound) and
+                "version" (TAG_Int, optional) of a block.
+
+        Raises:
+            TimelineError: When failed to register the custom blocks.
+        """
+        err = tldb_register_custom_blocks(self._database_id, block_states)
+        raise_if_error(err)
+
+    def custom_blocks(self) -> list[bytes]:
+        """
+        custom_blocks returns all the custom block states
+        that registered to this timeline database.
+
+        Returns:
+            list[bytes]: The custom block states. Each element is a little endian
+                         TAG_Compound NBT that holds "name", "states" and "version".
+
+        Raises:
+            TimelineError: When failed to read the custom blocks.
+        """
+        result, err = tldb_custom_blocks(self._database_id)
+        raise_if_error(err)
+        return result
+
+
+    def sub_chunk_hashes(
+        self, pos: ChunkPos, dm: Dimension = DIMENSION_OVERWORLD
+    ) -> list[int]:
+        """
+        sub_chunk_hashes returns the content hash of each sub chunk of the
+        latest time point of the target chunk, which covers both the blocks
+        and the block NBTs in this sub chunk.
+
+        You can compare them with the one that computed by hash_disk_chunk
+        or hash_network_chunk to know which sub chunks are changed, without
+        opening the chunk timeline for writing.
+
+        Args:
+            pos (ChunkPos): The chunk position of the target chunk.
+            dm (Dimension, optional): The dimension of the target chunk.
+                                      Defaults to DIMENSION_OVERWORLD.
+
+        Returns:
+            list[int]: The hash of each sub chunk (from the lowest one).
+                       Return an empty list if the timeline is not exist.
+
+        Raises:
+            TimelineError: When failed to read the hashes.
+        """
+        result, err = tldb_sub_chunk_hashes(self._database_id, int(dm), pos.x, pos.z)
+        raise_if_error(err)
+        return result
+
+    def search_blocks(
+        self,
+        block_states: list[bytes],
+        positions: list[tuple[ChunkPos, Dimension]] | None = None,
+    ) -> list[bytes]:
+        """
+        search_blocks searches the blocks that matches any of block_states
+        in the given chunks, and returns the events that these blocks appeared
+        or disappeared. See ChunkTimeline.search_blocks for more information.
+
+        The timelines are opened as read only one by one, so this function
+        will be blocked if some of them are using by others.
+
+        Args:
+            block_states (list[bytes]):
+                The block states to search, which is the same as
+                ChunkTimeline.search_blocks.
+            positions (list[tuple[ChunkPos, Dimension]] | None, optional):
+                The chunks to search. If it is None or empty, then all the chunk
+                timelines in this database are searched. Defaults to None.
+
+        Raises:
+            TimelineError: When failed to search the blocks.
+
+        Returns:
+            list[bytes]: The events that found. Each event is the same as the one
+                         returned by ChunkTimeline.search_blocks, but it also has
+                         "dm", "chunk_x" and "chunk_z" (TAG_Int).
+        """
+        result, err = tldb_search_blocks_in_chunks(
+            self._database_id,
+            [(int(dm), pos.x, pos.z) for pos, dm in (positions or [])],
+            block_states,
+        )
+        raise_if_error(err)
+        return result
+
+    def new_player_timeline(self, player_id: str, read_only: bool = False) -> PlayerTimeline:
+        """
+        new_player_timeline gets the timeline of a player whose identifier is player_id.
+        It is the same as new_chunk_timeline, but the timeline is keyed by the player.
+
+        Args:
+            player_id (str): The identifier of the target player (e.g. the UUID of this player).
+            read_only (bool, optional): You want to the target timeline is read only or not.
+                                        Defaults to False.
+        """
+        return PlayerTimeline(
+            tldb_new_player_timeline(self._database_id, player_id, read_only)
+        )
+
+    def try_new_player_timeline(
+        self, player_id: str, read_only: bool = False
+    ) -> PlayerTimeline:
+        """
+        try_new_player_timeline is like new_player_timeline, but it will not blocking
+        when there is still some threads are using target player.
+
+        Instead, you get a invalid PlayerTimeline whose PlayerTimeline.error_code()
+        is ERROR_CODE_BUSY.
+
+        Args:
+            player_id (str): The identifier of the target player.
+            read_only (bool, optional): You want to the target timeline is read only or not.
+                                        Defaults to False.
+        """
+        return PlayerTimeline(
+            tldb_try_new_player_timeline(self._database_id, player_id, read_only)
+        )
+
+    def delete_player_timeline(self, player_id: str):
+        """
+        delete_player_timeline deletes the timeline of the player whose identifier is player_id.
+        If timeline is not exist, then do no operation.
+
+        Args:
+            player_id (str): The identifier of the target player.
+
+        Raises:
+            TimelineError: When failed to delete target timeline.
+        """
+        err = tldb_delete_player_timeline(self._database_id, player_id)
+        raise_if_error(err)
+
+    def rewrite_player_timeline(self, player_id: str):
+        """
+        rewrite_player_timeline re-compresses all the values that
+        belongs to the timeline of the player whose identifier is
+        player_id by the compression algorithm that currently selected.
+        It is the same as rewrite_chunk_timeline.
+
+        Args:
+            player_id (str): The identifier of the target player.
+
+        Raises:
+            TimelineError: When failed to rewrite the timeline.
+        """
+        err = tldb_rewrite_player_timeline(self._database_id, player_id)
+        raise_if_error(err)
+
+    def has_player_timeline(self, player_id: str) -> bool:
+        """
+        has_player_timeline reports whether the player
+        whose identifier is player_id have timeline in this database.
+
+        Args:
+            player_id (str): The identifier of the target player.
+
+        Returns:
+            bool: Return True for exist.
+        """
+        return tldb_has_player_timeline(self._database_id, player_id) == 1
+
+    def all_player_timeline(self) -> list[str]:
+        """
+        all_player_timeline returns the identifiers of all
+        the players that have timeline in this database.
+
+        Returns:
+            list[str]: The identifiers of these players.
+
+        Raises:
+            TimelineError: When failed to read the identifiers.
+        """
+        result, err = tldb_all_player_timeline(self._database_id)
+        raise_if_error(err)
+        return result
 
 
 def new_timeline_database(
@@ -200,3 +597,80 @@ def new_timeline_database(
         TimelineDatabase: The opened timeline database.
     """
     return TimelineDatabase(new_timeline_db(path, no_grow_sync, no_sync))
+
+
+def new_level_timeline_database(path: str) -> TimelineDatabase:
+    """
+    new_level_timeline_database open a level database that
+    used for chunk delta update whose at path.
+
+    If not exist, then create a new database.
+
+    Note that you could use TimelineDatabase.is_valid() to check
+    whether the timeline database is valid or not.
+
+    Args:
+        path (str): The path of the timeline database want to open or create.
+
+    Returns:
+        TimelineDatabase: The opened timeline database.
+    """
+    return TimelineDatabase(new_level_timeline_db(path))
+
+
+def new_memory_timeline_database() -> TimelineDatabase:
+    """
+    new_memory_timeline_database opens a timeline database
+    that holds everything in memory.
+
+    All the data will lost after the timeline database is closed,
+    so it is only useful for tests and ephemeral servers.
+
+    Note that you could use TimelineDatabase.is_valid() to check
+    whether the timeline database is valid or not.
+
+    Returns:
+        TimelineDatabase: The opened timeline database.
+    """
+    return TimelineDatabase(new_memory_timeline_db())
+
+
+def new_encrypted_timeline_database(
+    path: str,
+    keys: dict[int, bytes],
+    current_key_id: int,
+    hmac_key: bytes = b"",
+    no_grow_sync: bool = False,
+    no_sync: bool = False,
+) -> TimelineDatabase:
+    """
+    new_encrypted_timeline_database open a timeline database whose
+    at path, and all the values written to it are encrypted by AES-GCM.
+
+    If not exist, then create a new database.
+    If the keys are wrong, then the returned database is not valid.
+
+    Note that you could use TimelineDatabase.is_valid() to check
+    whether the timeline database is valid or not.
+
+    Args:
+        path (str): The path of the timeline database want to open or create.
+        keys (dict[int, bytes]): All the keys that could be used to decrypt the values,
+                                 and the key of this dict is the ID of each key.
+                                 The length of each key must be 16, 24 or 32 bytes.
+        current_key_id (int): The ID of the key that used to encrypt the new values.
+        hmac_key (bytes, optional): If not empty, then the chunk coordinates in the
+                                    database keys will be hidden by HMAC.
+                                    The database must always be opened with the same hmac_key.
+                                    Defaults to b"".
+        no_grow_sync (bool, optional): See new_timeline_database. Defaults to False.
+        no_sync (bool, optional): See new_timeline_database. Defaults to False.
+
+    Returns:
+        TimelineDatabase: The opened timeline database.
+    """
+    return TimelineDatabase(
+        new_encrypted_timeline_db(
+            path, no_grow_sync, no_sync, current_key_id, keys, hmac_key
+        )
+    )
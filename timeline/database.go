@@ -4,48 +4,56 @@ import (
 	"go.etcd.io/bbolt"
 )
 
-// database wrapper a database,
+// database wrapper a bbolt database,
 // and expose some useful functions.
 type database struct {
 	bdb *bbolt.DB
 }
 
+// OpenBBoltDB opens the bbolt database that at path.
+// If not exist, then create a new one.
+//
+// See Open for the meaning of noGrowSync and noSync.
+func OpenBBoltDB(path string, noGrowSync bool, noSync bool) (DB, error) {
+	bdb, err := bbolt.Open(path, 0600, &bbolt.Options{
+		FreelistType: bbolt.FreelistMapType,
+		NoGrowSync:   noGrowSync,
+		NoSync:       noSync,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &database{bdb: bdb}, nil
+}
+
 // Has returns true if the DB does contains the given key.
 func (db *database) Has(key []byte) (has bool) {
-	db.bdb.View(func(tx *bbolt.Tx) error {
-		has = (tx.Bucket(DatabaseKeyRoot).Get(key) != nil)
-		return nil
-	})
-	return
+	return db.Bucket(DatabaseKeyRoot).Has(key)
 }
 
 // Get retrieves the value for a key in the bucket.
 // Returns a nil value if the key does not exist or if the key is a nested bucket.
 func (db *database) Get(key []byte) (value []byte) {
-	db.bdb.View(func(tx *bbolt.Tx) error {
-		result := tx.Bucket(DatabaseKeyRoot).Get(key)
-		value = make([]byte, len(result))
-		copy(value, result)
-		return nil
-	})
-	return
+	return db.Bucket(DatabaseKeyRoot).Get(key)
 }
 
 // Put sets the value for a key in the bucket.
 // If the key exist then its previous value will be overwritten.
 // Returns an error if the key is blank, if the key is too large, or if the value is too large.
 func (db *database) Put(key []byte, value []byte) (err error) {
-	return db.bdb.Update(func(tx *bbolt.Tx) error {
-		return tx.Bucket(DatabaseKeyRoot).Put(key, value)
-	})
+	return db.Bucket(DatabaseKeyRoot).Put(key, value)
 }
 
 // Delete removes a key from the bucket.
 // If the key does not exist then nothing is done and a nil error is returned.
 func (db *database) Delete(key []byte) error {
-	return db.bdb.Update(func(tx *bbolt.Tx) error {
-		return tx.Bucket(DatabaseKeyRoot).Delete(key)
-	})
+	return db.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name.
+// Each operation on the returned bucket will use its own transaction.
+func (db *database) Bucket(name []byte) Bucket {
+	return &databaseBucket{db: db, name: name}
 }
 
 // Close releases all database resources.
@@ -80,6 +88,63 @@ func (db *database) OpenTransaction() (Transaction, error) {
 	return &transaction{tx: tx}, nil
 }
 
+// databaseBucket is a bucket that each
+// operation on it will use its own transaction.
+type databaseBucket struct {
+	db   *database
+	name []byte
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *databaseBucket) Has(key []byte) (has bool) {
+	b.db.bdb.View(func(tx *bbolt.Tx) error {
+		has = (&transactionBucket{tx: tx, name: b.name}).Has(key)
+		return nil
+	})
+	return
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist or if the key is a nested bucket.
+func (b *databaseBucket) Get(key []byte) (value []byte) {
+	b.db.bdb.View(func(tx *bbolt.Tx) error {
+		result := (&transactionBucket{tx: tx, name: b.name}).Get(key)
+		value = make([]byte, len(result))
+		copy(value, result)
+		return nil
+	})
+	return
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+// Returns an error if the key is blank, if the key is too large, or if the value is too large.
+func (b *databaseBucket) Put(key []byte, value []byte) error {
+	return b.db.bdb.Update(func(tx *bbolt.Tx) error {
+		return (&transactionBucket{tx: tx, name: b.name}).Put(key, value)
+	})
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *databaseBucket) Delete(key []byte) error {
+	return b.db.bdb.Update(func(tx *bbolt.Tx) error {
+		return (&transactionBucket{tx: tx, name: b.name}).Delete(key)
+	})
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+//
+// All the key/value pairs are visited in a single read-only transaction,
+// and the key and value passed to fn are only valid during the call.
+func (b *databaseBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	return b.db.bdb.View(func(tx *bbolt.Tx) error {
+		return (&transactionBucket{tx: tx, name: b.name}).ForEach(fn)
+	})
+}
+
 // transaction wrapper a database transaction,
 // and expose some useful functions.
 type transaction struct {
@@ -88,26 +153,32 @@ type transaction struct {
 
 // Has returns true if the DB does contains the given key.
 func (t *transaction) Has(key []byte) (has bool) {
-	return (t.tx.Bucket(DatabaseKeyRoot).Get(key) != nil)
+	return t.Bucket(DatabaseKeyRoot).Has(key)
 }
 
 // Get retrieves the value for a key in the bucket.
 // Returns a nil value if the key does not exist or if the key is a nested bucket.
 func (t *transaction) Get(key []byte) (value []byte) {
-	return t.tx.Bucket(DatabaseKeyRoot).Get(key)
+	return t.Bucket(DatabaseKeyRoot).Get(key)
 }
 
 // Put sets the value for a key in the bucket.
 // If the key exist then its previous value will be overwritten.
 // Returns an error if the key is blank, if the key is too large, or if the value is too large.
 func (t *transaction) Put(key []byte, value []byte) error {
-	return t.tx.Bucket(DatabaseKeyRoot).Put(key, value)
+	return t.Bucket(DatabaseKeyRoot).Put(key, value)
 }
 
 // Delete removes a key from the bucket.
 // If the key does not exist then nothing is done and a nil error is returned.
 func (t *transaction) Delete(key []byte) error {
-	return t.tx.Bucket(DatabaseKeyRoot).Delete(key)
+	return t.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name,
+// and all operations on it are belongs to this transaction.
+func (t *transaction) Bucket(name []byte) Bucket {
+	return &transactionBucket{tx: t.tx, name: name}
 }
 
 // Commit writes all changes to disk, updates the meta page and closes the transaction.
@@ -121,3 +192,69 @@ func (t *transaction) Commit() error {
 func (t *transaction) Discard() error {
 	return t.tx.Rollback()
 }
+
+// transactionBucket is a bucket that
+// belongs to a bbolt transaction.
+//
+// The underlying bbolt bucket will
+// be created when first write to it.
+type transactionBucket struct {
+	tx   *bbolt.Tx
+	name []byte
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *transactionBucket) Has(key []byte) (has bool) {
+	bucket := b.tx.Bucket(b.name)
+	if bucket == nil {
+		return false
+	}
+	return (bucket.Get(key) != nil)
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist or if the key is a nested bucket.
+func (b *transactionBucket) Get(key []byte) (value []byte) {
+	bucket := b.tx.Bucket(b.name)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Get(key)
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+// Returns an error if the key is blank, if the key is too large, or if the value is too large.
+func (b *transactionBucket) Put(key []byte, value []byte) error {
+	bucket, err := b.tx.CreateBucketIfNotExists(b.name)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(key, value)
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *transactionBucket) Delete(key []byte) error {
+	bucket := b.tx.Bucket(b.name)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.Delete(key)
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+func (b *transactionBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	bucket := b.tx.Bucket(b.name)
+	if bucket == nil {
+		return nil
+	}
+	return bucket.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+		return fn(k, v)
+	})
+}
@@ -0,0 +1,567 @@
+package timeline
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+var (
+	DatabaseKeyEncryption      = []byte("encryption")
+	DatabaseKeyEncryptionCheck = []byte("check")
+)
+
+// encryptionCheckPlaintext is the plaintext that used
+// to check whether the encryption key is correct.
+var encryptionCheckPlaintext = []byte("bedrock-chunk-diff")
+
+// KeyProvider provides the keys that used
+// to encrypt and decrypt the stored values.
+//
+// Each key is identified by an ID, and the ID will be
+// written with each encrypted value. So, to rotate the
+// key, just let CurrentKey return a new one and keep
+// the old ones could be found by Key.
+//
+// The length of each key must be 16, 24 or 32 bytes,
+// which selects AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key that used
+	// to encrypt the new values, and its ID.
+	CurrentKey() (id uint32, key []byte, err error)
+	// Key returns the key whose ID is id,
+	// which is used to decrypt the values.
+	Key(id uint32) (key []byte, err error)
+}
+
+// StaticKeyProvider is a KeyProvider
+// that holds all the keys in memory.
+type StaticKeyProvider struct {
+	currentID uint32
+	keys      map[uint32][]byte
+}
+
+// NewStaticKeyProvider returns a new StaticKeyProvider
+// that holds keys, and currentID is the ID of the key
+// that used to encrypt the new values.
+func NewStaticKeyProvider(currentID uint32, keys map[uint32][]byte) (result *StaticKeyProvider, err error) {
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("NewStaticKeyProvider: Current key %d is not found", currentID)
+	}
+	return &StaticKeyProvider{currentID: currentID, keys: keys}, nil
+}
+
+func (s *StaticKeyProvider) CurrentKey() (id uint32, key []byte, err error) {
+	return s.currentID, s.keys[s.currentID], nil
+}
+
+func (s *StaticKeyProvider) Key(id uint32) (key []byte, err error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("Key: Key %d is not found", id)
+	}
+	return key, nil
+}
+
+// EncryptionOptions holds the options of encryption.
+type EncryptionOptions struct {
+	// KeyProvider provides the keys that used
+	// to encrypt and decrypt the stored values.
+	KeyProvider KeyProvider
+	// HMACKey is used to hide the chunk coordinates and
+	// the player identifiers in the database keys. If not
+	// empty, then the chunk coordinates in the keys will be
+	// replaced by their HMAC-SHA256 (truncated to the same
+	// length), and the player identifiers will be replaced
+	// by their HMAC-SHA256 (truncated to 16 bytes).
+	//
+	// Note that HMACKey can't be rotated, and a database
+	// must always be opened with the same HMACKey.
+	HMACKey []byte
+}
+
+// encryptedDatabase wrapper a DB, and encrypts
+// all the values that written to it by AES-GCM.
+//
+// Each encrypted value is consisted of the ID of the key
+// (uint32, little endian), the nonce and the sealed value.
+// The bucket name and the key are used as the additional
+// data, so values can't be moved to other places.
+type encryptedDatabase struct {
+	db      DB
+	options EncryptionOptions
+	mu      *sync.Mutex
+	aeads   map[uint32]cipher.AEAD
+}
+
+// NewEncryptedDB returns a DB that encrypts all the values
+// written to db, and decrypts all the values read from db.
+// The keys stay in plaintext (except the chunk coordinates
+// and the player identifiers if HMACKey is set), so the
+// index still works.
+//
+// If db is encrypted before, then it will be checked that
+// whether the keys (including HMACKey) are correct, and an
+// error that matches ErrCorrupt is returned if not. If it is
+// new, then it will be marked as encrypted.
+func NewEncryptedDB(db DB, options EncryptionOptions) (result DB, err error) {
+	if options.KeyProvider == nil {
+		return nil, fmt.Errorf("NewEncryptedDB: Key provider is not given")
+	}
+
+	edb := &encryptedDatabase{
+		db:      db,
+		options: options,
+		mu:      new(sync.Mutex),
+		aeads:   make(map[uint32]cipher.AEAD),
+	}
+
+	if _, err = edb.currentAEAD(); err != nil {
+		return nil, fmt.Errorf("NewEncryptedDB: %w", err)
+	}
+
+	bucket := edb.Bucket(DatabaseKeyEncryption)
+	if !bucket.Has(DatabaseKeyEncryptionCheck) {
+		if hasPlaintextData(db) {
+			return nil, fmt.Errorf("NewEncryptedDB: Database is not encrypted")
+		}
+
+		err = bucket.Put(DatabaseKeyEncryptionCheck, edb.checkValue())
+		if err != nil {
+			return nil, fmt.Errorf("NewEncryptedDB: %w", err)
+		}
+		return edb, nil
+	}
+
+	plaintext, err := bucket.(*encryptedBucket).getChecked(DatabaseKeyEncryptionCheck)
+	if err != nil {
+		return nil, fmt.Errorf("NewEncryptedDB: %w (wrong encryption key)", err)
+	}
+	if !hmac.Equal(plaintext, edb.checkValue()) {
+		return nil, fmt.Errorf("NewEncryptedDB: %w (wrong HMAC key)", ErrCorrupt)
+	}
+
+	return edb, nil
+}
+
+// hasPlaintextData reports whether db have any value that
+// written by the timeline database, except the chunk count
+// that written when the timeline database is opened.
+func hasPlaintextData(db DB) bool {
+	hasData := false
+	for _, name := range databaseBuckets {
+		_ = db.Bucket(name).ForEach(func(key []byte, value []byte) error {
+			if !bytes.Equal(name, DatabaseKeyChunkIndex) || !bytes.Equal(key, DatabaseKeyChunkCount) {
+				hasData = true
+			}
+			return nil
+		})
+	}
+	return hasData
+}
+
+// checkValue returns the value that saved in the encryption
+// check. It is encrypted by the current key, and it contains
+// the HMAC-SHA256 of DatabaseKeyEncryptionCheck if HMACKey
+// is set, so both the encryption key and HMACKey are checked.
+func (db *encryptedDatabase) checkValue() []byte {
+	if len(db.options.HMACKey) == 0 {
+		return encryptionCheckPlaintext
+	}
+	mac := hmac.New(sha256.New, db.options.HMACKey)
+	mac.Write(DatabaseKeyEncryptionCheck)
+	return mac.Sum(bytes.Clone(encryptionCheckPlaintext))
+}
+
+// aead is an internal implement detail.
+func (db *encryptedDatabase) aead(id uint32, key []byte) (result cipher.AEAD, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if result = db.aeads[id]; result != nil {
+		return result, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("aead: %w", err)
+	}
+	result, err = cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("aead: %w", err)
+	}
+
+	db.aeads[id] = result
+	return result, nil
+}
+
+// currentAEAD is an internal implement detail.
+func (db *encryptedDatabase) currentAEAD() (id uint32, err error) {
+	id, key, err := db.options.KeyProvider.CurrentKey()
+	if err != nil {
+		return 0, fmt.Errorf("currentAEAD: %w", err)
+	}
+	_, err = db.aead(id, key)
+	if err != nil {
+		return 0, fmt.Errorf("currentAEAD: %w", err)
+	}
+	return id, nil
+}
+
+// physicalKey returns the key that really used in the
+// underlying database for the key in bucket name.
+func (db *encryptedDatabase) physicalKey(name []byte, key []byte) []byte {
+	if len(db.options.HMACKey) == 0 {
+		return key
+	}
+
+	mac := hmac.New(sha256.New, db.options.HMACKey)
+	switch {
+	case (bytes.Equal(name, DatabaseKeyRoot) && len(key) >= 10) ||
+		(bytes.Equal(name, DatabaseKeyChunkIndex) && len(key) == 10):
+		mac.Write(key[:10])
+		return append(mac.Sum(nil)[:10], key[10:]...)
+	case bytes.Equal(name, DatabaseKeyPlayer) || bytes.Equal(name, DatabaseKeyPlayerIndex):
+		if len(key) < 2 {
+			return key
+		}
+		idLength := 2 + int(binary.LittleEndian.Uint16(key))
+		if len(key) < idLength {
+			return key
+		}
+		mac.Write(key[:idLength])
+		return append(mac.Sum(nil)[:16], key[idLength:]...)
+	}
+
+	return key
+}
+
+// additionalData is an internal implement detail.
+func (db *encryptedDatabase) additionalData(name []byte, physicalKey []byte) []byte {
+	return levelKey(name, physicalKey)
+}
+
+// encrypt is an internal implement detail.
+func (db *encryptedDatabase) encrypt(name []byte, physicalKey []byte, value []byte) (result []byte, err error) {
+	id, err := db.currentAEAD()
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: %w", err)
+	}
+
+	db.mu.Lock()
+	aead := db.aeads[id]
+	db.mu.Unlock()
+
+	result = make([]byte, 4+aead.NonceSize(), 4+aead.NonceSize()+len(value)+aead.Overhead())
+	binary.LittleEndian.PutUint32(result, id)
+	if _, err = rand.Read(result[4:]); err != nil {
+		return nil, fmt.Errorf("encrypt: %w", err)
+	}
+
+	return aead.Seal(result, result[4:], value, db.additionalData(name, physicalKey)), nil
+}
+
+// decrypt is an internal implement detail.
+// If value is nil, then return nil.
+func (db *encryptedDatabase) decrypt(name []byte, physicalKey []byte, value []byte) (result []byte, err error) {
+	if value == nil {
+		return nil, nil
+	}
+	if len(value) < 4 {
+		return nil, fmt.Errorf("decrypt: Encrypted value is broken")
+	}
+
+	id := binary.LittleEndian.Uint32(value)
+	key, err := db.options.KeyProvider.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	aead, err := db.aead(id, key)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+
+	if len(value) < 4+aead.NonceSize() {
+		return nil, fmt.Errorf("decrypt: Encrypted value is broken")
+	}
+	nonce, sealed := value[4:4+aead.NonceSize()], value[4+aead.NonceSize():]
+
+	result, err = aead.Open(make([]byte, 0, len(sealed)), nonce, sealed, db.additionalData(name, physicalKey))
+	if err != nil {
+		return nil, fmt.Errorf("decrypt: %w", err)
+	}
+	return result, nil
+}
+
+// Rotate re-encrypts all the values in the buckets
+// whose name is in names by the current key.
+//
+// Values that already encrypted by the current key
+// will not be changed. If any value can't be decrypted,
+// then the rotation stops with an error that matches
+// ErrCorrupt, and the batches before it are kept.
+func (db *encryptedDatabase) Rotate(names ...[]byte) error {
+	currentID, err := db.currentAEAD()
+	if err != nil {
+		return fmt.Errorf("Rotate: %w", err)
+	}
+
+	for _, name := range append(slices.Clone(names), DatabaseKeyEncryption) {
+		physicalKeys := make([][]byte, 0)
+		err = db.db.Bucket(name).ForEach(func(key []byte, value []byte) error {
+			// Values shorter than the key ID are broken, and
+			// they will be reported by decrypt in rotateBatch.
+			if len(value) < 4 || binary.LittleEndian.Uint32(value) != currentID {
+				physicalKeys = append(physicalKeys, bytes.Clone(key))
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Rotate: %w", err)
+		}
+
+		for len(physicalKeys) > 0 {
+			batch := physicalKeys[:min(len(physicalKeys), 1024)]
+			physicalKeys = physicalKeys[len(batch):]
+
+			err = db.rotateBatch(name, batch)
+			if err != nil {
+				return fmt.Errorf("Rotate: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// rotateBatch is an internal implement detail.
+func (db *encryptedDatabase) rotateBatch(name []byte, physicalKeys [][]byte) error {
+	var success bool
+
+	tran, err := db.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("rotateBatch: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+
+	bucket := tran.Bucket(name)
+	for _, physicalKey := range physicalKeys {
+		plaintext, err := db.decrypt(name, physicalKey, bucket.Get(physicalKey))
+		if err != nil {
+			return fmt.Errorf("rotateBatch: %w", corruptError(err))
+		}
+		if plaintext == nil {
+			continue
+		}
+
+		value, err := db.encrypt(name, physicalKey, plaintext)
+		if err != nil {
+			return fmt.Errorf("rotateBatch: %w", err)
+		}
+
+		err = bucket.Put(physicalKey, value)
+		if err != nil {
+			return fmt.Errorf("rotateBatch: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("rotateBatch: %w", err)
+	}
+	success = true
+
+	return nil
+}
+
+// Has returns true if the DB does contains the given key.
+func (db *encryptedDatabase) Has(key []byte) (has bool) {
+	return db.Bucket(DatabaseKeyRoot).Has(key)
+}
+
+// Get retrieves the value for a key in the root bucket.
+// Returns a nil value if the key does not exist or can't be decrypted.
+// Use getChecked to know whether the value is failed to decrypt.
+func (db *encryptedDatabase) Get(key []byte) (value []byte) {
+	return db.Bucket(DatabaseKeyRoot).Get(key)
+}
+
+// getChecked is like Get, but returns an error that
+// matches ErrCorrupt if the value can't be decrypted.
+func (db *encryptedDatabase) getChecked(key []byte) (value []byte, err error) {
+	return db.Bucket(DatabaseKeyRoot).(*encryptedBucket).getChecked(key)
+}
+
+// Put sets the value for a key in the root bucket.
+// If the key exist then its previous value will be overwritten.
+func (db *encryptedDatabase) Put(key []byte, value []byte) (err error) {
+	return db.Bucket(DatabaseKeyRoot).Put(key, value)
+}
+
+// Delete removes a key from the root bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (db *encryptedDatabase) Delete(key []byte) error {
+	return db.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name.
+func (db *encryptedDatabase) Bucket(name []byte) Bucket {
+	return &encryptedBucket{edb: db, bucket: db.db.Bucket(name), name: name}
+}
+
+// Close closes the underlying database.
+func (db *encryptedDatabase) Close() error {
+	return db.db.Close()
+}
+
+// OpenTransaction opens a transaction on the underlying
+// database, and all the values written by it are encrypted.
+func (db *encryptedDatabase) OpenTransaction() (Transaction, error) {
+	tran, err := db.db.OpenTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return &encryptedTransaction{edb: db, tran: tran}, nil
+}
+
+// encryptedTransaction is a transaction
+// that belongs to the encrypted database.
+type encryptedTransaction struct {
+	edb  *encryptedDatabase
+	tran Transaction
+}
+
+// Has returns true if the DB does contains the given key.
+func (t *encryptedTransaction) Has(key []byte) (has bool) {
+	return t.Bucket(DatabaseKeyRoot).Has(key)
+}
+
+// Get retrieves the value for a key in the root bucket.
+// Returns a nil value if the key does not exist or can't be decrypted.
+// Use getChecked to know whether the value is failed to decrypt.
+func (t *encryptedTransaction) Get(key []byte) (value []byte) {
+	return t.Bucket(DatabaseKeyRoot).Get(key)
+}
+
+// getChecked is like Get, but returns an error that
+// matches ErrCorrupt if the value can't be decrypted.
+func (t *encryptedTransaction) getChecked(key []byte) (value []byte, err error) {
+	return t.Bucket(DatabaseKeyRoot).(*encryptedBucket).getChecked(key)
+}
+
+// Put sets the value for a key in the root bucket.
+// If the key exist then its previous value will be overwritten.
+func (t *encryptedTransaction) Put(key []byte, value []byte) error {
+	return t.Bucket(DatabaseKeyRoot).Put(key, value)
+}
+
+// Delete removes a key from the root bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (t *encryptedTransaction) Delete(key []byte) error {
+	return t.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name,
+// and all operations on it are belongs to this transaction.
+func (t *encryptedTransaction) Bucket(name []byte) Bucket {
+	return &encryptedBucket{edb: t.edb, bucket: t.tran.Bucket(name), name: name}
+}
+
+// Commit commits the transaction.
+func (t *encryptedTransaction) Commit() error {
+	return t.tran.Commit()
+}
+
+// Discard discards the transaction.
+func (t *encryptedTransaction) Discard() error {
+	return t.tran.Discard()
+}
+
+// encryptedBucket wrapper a bucket, and
+// encrypts all the values written to it.
+type encryptedBucket struct {
+	edb    *encryptedDatabase
+	bucket Bucket
+	name   []byte
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *encryptedBucket) Has(key []byte) (has bool) {
+	return b.bucket.Has(b.edb.physicalKey(b.name, key))
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist or can't be decrypted.
+// Use getChecked to know whether the value is failed to decrypt.
+func (b *encryptedBucket) Get(key []byte) (value []byte) {
+	value, _ = b.getChecked(key)
+	return
+}
+
+// getChecked is like Get, but returns an error that matches
+// ErrCorrupt if the value is exist but can't be decrypted,
+// e.g. it is tampered or its key is not provided anymore.
+func (b *encryptedBucket) getChecked(key []byte) (value []byte, err error) {
+	physicalKey := b.edb.physicalKey(b.name, key)
+	value, err = b.edb.decrypt(b.name, physicalKey, b.bucket.Get(physicalKey))
+	if err != nil {
+		return nil, fmt.Errorf("getChecked: %w", corruptError(err))
+	}
+	return value, nil
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+func (b *encryptedBucket) Put(key []byte, value []byte) error {
+	physicalKey := b.edb.physicalKey(b.name, key)
+	value, err := b.edb.encrypt(b.name, physicalKey, value)
+	if err != nil {
+		return err
+	}
+	return b.bucket.Put(physicalKey, value)
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *encryptedBucket) Delete(key []byte) error {
+	return b.bucket.Delete(b.edb.physicalKey(b.name, key))
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+//
+// Note that the keys passed to fn are the keys used in the underlying database,
+// so the chunk coordinates and the player identifiers in them are hashed if
+// HMACKey is set.
+func (b *encryptedBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	return b.bucket.ForEach(func(key []byte, value []byte) error {
+		plaintext, err := b.edb.decrypt(b.name, key, value)
+		if err != nil {
+			return fmt.Errorf("ForEach: %w", corruptError(err))
+		}
+		return fn(key, plaintext)
+	})
+}
+
+// getValue reads the value of key from reader. If the
+// value is exist but can't be read (e.g. failed to be
+// decrypted), then returns an error that matches ErrCorrupt,
+// instead of a nil value that looks like key is not exist.
+func getValue(reader DatabaseOperation, key []byte) (value []byte, err error) {
+	if r, ok := reader.(checkedReader); ok {
+		return r.getChecked(key)
+	}
+	return reader.Get(key), nil
+}
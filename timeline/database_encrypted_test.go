@@ -0,0 +1,231 @@
+package timeline
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// testKeyProvider returns a StaticKeyProvider that holds the
+// keys whose ID is in ids, and the last one is the current key.
+func testKeyProvider(t *testing.T, ids ...uint32) KeyProvider {
+	t.Helper()
+
+	keys := make(map[uint32][]byte)
+	for _, id := range ids {
+		keys[id] = bytes.Repeat([]byte{byte(id)}, 32)
+	}
+
+	provider, err := NewStaticKeyProvider(ids[len(ids)-1], keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider
+}
+
+// testTamper flips the last byte of the value of key in raw.
+func testTamper(t *testing.T, raw DatabaseOperation, key []byte) {
+	t.Helper()
+
+	value := bytes.Clone(raw.Get(key))
+	if len(value) == 0 {
+		t.Fatalf("value of %v is not found", key)
+	}
+	value[len(value)-1] ^= 1
+
+	if err := raw.Put(key, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEncryptedTamperedValueIsCorrupt(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenEncrypted(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 2)
+
+	testTamper(t, raw, define.Sum(testPos, []byte(define.KeyLatestNBT)...))
+
+	_, err = db.NewChunkTimeline(testPos, true)
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("NewChunkTimeline: expected ErrCorrupt, but got %v", err)
+	}
+
+	err = db.Bucket(DatabaseKeyRoot).ForEach(func(key []byte, value []byte) error {
+		return nil
+	})
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("ForEach: expected ErrCorrupt, but got %v", err)
+	}
+
+	rotated, err := OpenEncrypted(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rotated.RotateEncryptionKey(); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("RotateEncryptionKey: expected ErrCorrupt, but got %v", err)
+	}
+}
+
+func TestEncryptedMissingKeyIsCorrupt(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the encryption check is rotated to key 2,
+	// so the value above still needs key 1.
+	db, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.(*encryptedDatabase).Rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := db.Get([]byte("key")); value != nil {
+		t.Fatalf("Get: expected nil, but got %v", value)
+	}
+	if _, err = getValue(db, []byte("key")); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("getValue: expected ErrCorrupt, but got %v", err)
+	}
+	if value, err := getValue(db, []byte("not exist")); value != nil || err != nil {
+		t.Fatalf("getValue: expected nil value and error, but got %v and %v", value, err)
+	}
+}
+
+func TestEncryptedWrongHMACKeyIsCorrupt(t *testing.T) {
+	raw := OpenMemoryDB()
+	_, err := NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1), HMACKey: []byte("aaaa")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hmacKey := range [][]byte{[]byte("bbbb"), nil} {
+		_, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1), HMACKey: hmacKey})
+		if !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("NewEncryptedDB: expected ErrCorrupt for HMAC key %q, but got %v", hmacKey, err)
+		}
+	}
+
+	// The key that sealed the encryption check is not provided
+	_, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 2), HMACKey: []byte("aaaa")})
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("NewEncryptedDB: expected ErrCorrupt for the wrong encryption key, but got %v", err)
+	}
+
+	// The database that created without HMAC key
+	// can't be opened with a HMAC key either.
+	plain := OpenMemoryDB()
+	if _, err = NewEncryptedDB(plain, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewEncryptedDB(plain, EncryptionOptions{KeyProvider: testKeyProvider(t, 1), HMACKey: []byte("aaaa")})
+	if !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("NewEncryptedDB: expected ErrCorrupt, but got %v", err)
+	}
+
+	if _, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1), HMACKey: []byte("aaaa")}); err != nil {
+		t.Fatalf("NewEncryptedDB: %v", err)
+	}
+}
+
+func TestEncryptedHMACHidesPlayerID(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenEncrypted(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1), HMACKey: []byte("aaaa")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppendPlayer(t, db, DefaultMaxLimit, 1, 2)
+
+	for _, name := range [][]byte{DatabaseKeyPlayer, DatabaseKeyPlayerIndex} {
+		err = raw.Bucket(name).ForEach(func(key []byte, value []byte) error {
+			if bytes.Contains(key, []byte(testPlayerID)) {
+				t.Errorf("player identifier is found in the key %q of bucket %s", key, name)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCheckPlayer(t, db, 1, 2)
+	ids := make([]string, 0)
+	err = db.ForEachPlayerTimeline(func(id string) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil || len(ids) != 1 || ids[0] != testPlayerID {
+		t.Fatalf("ForEachPlayerTimeline: got %v and %v", ids, err)
+	}
+}
+
+func TestEncryptedRefusesPlaintextData(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The chunk count that written when opening is not data
+	if _, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)}); err != nil {
+		t.Fatalf("NewEncryptedDB: %v", err)
+	}
+
+	raw = OpenMemoryDB()
+	if db, err = OpenWithDB(raw); err != nil {
+		t.Fatal(err)
+	}
+	testAppendPlayer(t, db, DefaultMaxLimit, 1)
+	if _, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)}); err == nil {
+		t.Fatal("NewEncryptedDB: expected an error for the database that only have player timelines")
+	}
+
+	raw = OpenMemoryDB()
+	if err = raw.Bucket(DatabaseKeyMeta).Put(DatabaseKeyCodec, []byte{utils.CompressionSnappy, 0, 0, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewEncryptedDB(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)}); err == nil {
+		t.Fatal("NewEncryptedDB: expected an error for the database that have settings")
+	}
+}
+
+func TestEncryptedTamperedSettingIsCorrupt(t *testing.T) {
+	for _, value := range []struct {
+		name []byte
+		key  []byte
+	}{
+		{DatabaseKeyMeta, DatabaseKeyCodec},
+		{DatabaseKeyChunkIndex, DatabaseKeyChunkCount},
+	} {
+		raw := OpenMemoryDB()
+		db, err := OpenEncrypted(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = db.SetCompression(utils.SnappyCompression{}); err != nil {
+			t.Fatal(err)
+		}
+		testAppend(t, db, 1)
+
+		testTamper(t, raw.Bucket(value.name), value.key)
+		_, err = OpenEncrypted(raw, EncryptionOptions{KeyProvider: testKeyProvider(t, 1)})
+		if !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("OpenEncrypted: expected ErrCorrupt for the tampered %s, but got %v", value.key, err)
+		}
+	}
+}
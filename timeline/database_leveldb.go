@@ -0,0 +1,227 @@
+package timeline
+
+import (
+	"slices"
+
+	"github.com/df-mc/goleveldb/leveldb"
+	"github.com/df-mc/goleveldb/leveldb/iterator"
+	"github.com/df-mc/goleveldb/leveldb/opt"
+	"github.com/df-mc/goleveldb/leveldb/util"
+)
+
+// levelKey returns the real key that used in level database
+// for the key in the bucket whose name is name.
+//
+// LevelDB have no bucket, so we prefix each key with the length
+// of the bucket name and the bucket name itself.
+func levelKey(name []byte, key []byte) []byte {
+	result := make([]byte, 0, 1+len(name)+len(key))
+	result = append(result, byte(len(name)))
+	result = append(result, name...)
+	return append(result, key...)
+}
+
+// levelForEach is an internal implement detail.
+func levelForEach(iter iterator.Iterator, prefixLen int, fn func(key []byte, value []byte) error) error {
+	defer iter.Release()
+	for iter.Next() {
+		if err := fn(slices.Clone(iter.Key()[prefixLen:]), slices.Clone(iter.Value())); err != nil {
+			return err
+		}
+	}
+	return iter.Error()
+}
+
+// levelDatabase wrapper a level database,
+// and expose some useful functions.
+type levelDatabase struct {
+	ldb *leveldb.DB
+}
+
+// OpenLevelDB opens the level database that at path.
+// If not exist, then create a new one.
+func OpenLevelDB(path string) (DB, error) {
+	ldb, err := leveldb.OpenFile(path, &opt.Options{
+		BlockSize: 16 * opt.KiB,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &levelDatabase{ldb: ldb}, nil
+}
+
+// Has returns true if the DB does contains the given key.
+func (db *levelDatabase) Has(key []byte) (has bool) {
+	return db.Bucket(DatabaseKeyRoot).Has(key)
+}
+
+// Get retrieves the value for a key in the root bucket.
+// Returns a nil value if the key does not exist.
+func (db *levelDatabase) Get(key []byte) (value []byte) {
+	return db.Bucket(DatabaseKeyRoot).Get(key)
+}
+
+// Put sets the value for a key in the root bucket.
+// If the key exist then its previous value will be overwritten.
+func (db *levelDatabase) Put(key []byte, value []byte) (err error) {
+	return db.Bucket(DatabaseKeyRoot).Put(key, value)
+}
+
+// Delete removes a key from the root bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (db *levelDatabase) Delete(key []byte) error {
+	return db.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name.
+// Each operation on the returned bucket will use its own transaction.
+func (db *levelDatabase) Bucket(name []byte) Bucket {
+	return &levelDatabaseBucket{db: db, name: name}
+}
+
+// Close closes the DB. This will also releases any outstanding snapshot,
+// abort any in-flight compaction and discard open transaction.
+func (db *levelDatabase) Close() error {
+	return db.ldb.Close()
+}
+
+// OpenTransaction opens an atomic DB transaction.
+// Only one transaction can be opened at a time. Subsequent call to write
+// and OpenTransaction will be blocked until in-flight transaction is
+// committed or discarded.
+func (db *levelDatabase) OpenTransaction() (Transaction, error) {
+	tr, err := db.ldb.OpenTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return &levelTransaction{tr: tr}, nil
+}
+
+// levelDatabaseBucket is a bucket that each
+// operation on it will use its own transaction.
+type levelDatabaseBucket struct {
+	db   *levelDatabase
+	name []byte
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *levelDatabaseBucket) Has(key []byte) (has bool) {
+	has, _ = b.db.ldb.Has(levelKey(b.name, key), nil)
+	return
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist.
+func (b *levelDatabaseBucket) Get(key []byte) (value []byte) {
+	value, _ = b.db.ldb.Get(levelKey(b.name, key), nil)
+	return
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+func (b *levelDatabaseBucket) Put(key []byte, value []byte) error {
+	return b.db.ldb.Put(levelKey(b.name, key), value, nil)
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *levelDatabaseBucket) Delete(key []byte) error {
+	return b.db.ldb.Delete(levelKey(b.name, key), nil)
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+func (b *levelDatabaseBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	prefix := levelKey(b.name, nil)
+	return levelForEach(b.db.ldb.NewIterator(util.BytesPrefix(prefix), nil), len(prefix), fn)
+}
+
+// levelTransaction wrapper a level database
+// transaction, and expose some useful functions.
+type levelTransaction struct {
+	tr *leveldb.Transaction
+}
+
+// Has returns true if the DB does contains the given key.
+func (t *levelTransaction) Has(key []byte) (has bool) {
+	return t.Bucket(DatabaseKeyRoot).Has(key)
+}
+
+// Get retrieves the value for a key in the root bucket.
+// Returns a nil value if the key does not exist.
+func (t *levelTransaction) Get(key []byte) (value []byte) {
+	return t.Bucket(DatabaseKeyRoot).Get(key)
+}
+
+// Put sets the value for a key in the root bucket.
+// If the key exist then its previous value will be overwritten.
+func (t *levelTransaction) Put(key []byte, value []byte) error {
+	return t.Bucket(DatabaseKeyRoot).Put(key, value)
+}
+
+// Delete removes a key from the root bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (t *levelTransaction) Delete(key []byte) error {
+	return t.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name,
+// and all operations on it are belongs to this transaction.
+func (t *levelTransaction) Bucket(name []byte) Bucket {
+	return &levelTransactionBucket{tr: t.tr, name: name}
+}
+
+// Commit commits the transaction. If error is not nil, then the transaction is
+// not committed, it can then either be retried or discarded.
+func (t *levelTransaction) Commit() error {
+	return t.tr.Commit()
+}
+
+// Discard discards the transaction.
+func (t *levelTransaction) Discard() error {
+	t.tr.Discard()
+	return nil
+}
+
+// levelTransactionBucket is a bucket
+// that belongs to a level transaction.
+type levelTransactionBucket struct {
+	tr   *leveldb.Transaction
+	name []byte
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *levelTransactionBucket) Has(key []byte) (has bool) {
+	has, _ = b.tr.Has(levelKey(b.name, key), nil)
+	return
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist.
+func (b *levelTransactionBucket) Get(key []byte) (value []byte) {
+	value, _ = b.tr.Get(levelKey(b.name, key), nil)
+	return
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+func (b *levelTransactionBucket) Put(key []byte, value []byte) error {
+	return b.tr.Put(levelKey(b.name, key), value, nil)
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *levelTransactionBucket) Delete(key []byte) error {
+	return b.tr.Delete(levelKey(b.name, key), nil)
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+//
+// The changes that made by this transaction are also visible.
+func (b *levelTransactionBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	prefix := levelKey(b.name, nil)
+	return levelForEach(b.tr.NewIterator(util.BytesPrefix(prefix), nil), len(prefix), fn)
+}
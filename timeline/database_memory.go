@@ -0,0 +1,370 @@
+package timeline
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// memoryValue is a pending write
+// of a memory transaction.
+type memoryValue struct {
+	value   []byte
+	deleted bool
+}
+
+// memoryDatabase is a database that holds
+// everything in memory, and is useful for
+// tests and ephemeral servers.
+//
+// Same as bbolt, only one transaction could
+// be used at a time.
+type memoryDatabase struct {
+	mu      *sync.RWMutex
+	writeMu *sync.Mutex
+	closed  bool
+	buckets map[string]map[string][]byte
+}
+
+// OpenMemoryDB returns a new database that holds
+// everything in memory. All the data will lost
+// after the database is closed.
+func OpenMemoryDB() DB {
+	return &memoryDatabase{
+		mu:      new(sync.RWMutex),
+		writeMu: new(sync.Mutex),
+		buckets: make(map[string]map[string][]byte),
+	}
+}
+
+// get is an internal implement detail.
+func (db *memoryDatabase) get(name []byte, key []byte) (value []byte, found bool) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	value, found = db.buckets[string(name)][string(key)]
+	return
+}
+
+// snapshot is an internal implement detail.
+func (db *memoryDatabase) snapshot(name []byte) map[string][]byte {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return maps.Clone(db.buckets[string(name)])
+}
+
+// apply is an internal implement detail.
+func (db *memoryDatabase) apply(pending map[string]map[string]memoryValue) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return fmt.Errorf("apply: %w", ErrClosed)
+	}
+
+	for name, values := range pending {
+		bucket := db.buckets[name]
+		if bucket == nil {
+			bucket = make(map[string][]byte)
+			db.buckets[name] = bucket
+		}
+		for key, value := range values {
+			if value.deleted {
+				delete(bucket, key)
+				continue
+			}
+			bucket[key] = value.value
+		}
+	}
+
+	return nil
+}
+
+// Has returns true if the DB does contains the given key.
+func (db *memoryDatabase) Has(key []byte) (has bool) {
+	return db.Bucket(DatabaseKeyRoot).Has(key)
+}
+
+// Get retrieves the value for a key in the root bucket.
+// Returns a nil value if the key does not exist.
+func (db *memoryDatabase) Get(key []byte) (value []byte) {
+	return db.Bucket(DatabaseKeyRoot).Get(key)
+}
+
+// Put sets the value for a key in the root bucket.
+// If the key exist then its previous value will be overwritten.
+func (db *memoryDatabase) Put(key []byte, value []byte) (err error) {
+	return db.Bucket(DatabaseKeyRoot).Put(key, value)
+}
+
+// Delete removes a key from the root bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (db *memoryDatabase) Delete(key []byte) error {
+	return db.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name.
+// Each operation on the returned bucket will use its own transaction.
+func (db *memoryDatabase) Bucket(name []byte) Bucket {
+	return &memoryDatabaseBucket{db: db, name: name}
+}
+
+// Close releases all the data that this database holds.
+// It will block waiting for any open transactions to finish
+// before closing the database and returning.
+func (db *memoryDatabase) Close() error {
+	db.writeMu.Lock()
+	defer db.writeMu.Unlock()
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if db.closed {
+		return fmt.Errorf("Close: %w", ErrClosed)
+	}
+	db.closed = true
+	db.buckets = make(map[string]map[string][]byte)
+
+	return nil
+}
+
+// OpenTransaction starts a new transaction.
+// Only one transaction can be used at a time,
+// and starting multiple transactions will cause
+// the calls to block and be serialized until
+// the current transaction finishes.
+func (db *memoryDatabase) OpenTransaction() (Transaction, error) {
+	db.writeMu.Lock()
+
+	db.mu.RLock()
+	closed := db.closed
+	db.mu.RUnlock()
+
+	if closed {
+		db.writeMu.Unlock()
+		return nil, fmt.Errorf("OpenTransaction: %w", ErrClosed)
+	}
+
+	return &memoryTransaction{
+		db:      db,
+		pending: make(map[string]map[string]memoryValue),
+	}, nil
+}
+
+// memoryDatabaseBucket is a bucket that each
+// operation on it will use its own transaction.
+type memoryDatabaseBucket struct {
+	db   *memoryDatabase
+	name []byte
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *memoryDatabaseBucket) Has(key []byte) (has bool) {
+	_, has = b.db.get(b.name, key)
+	return
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist.
+func (b *memoryDatabaseBucket) Get(key []byte) (value []byte) {
+	result, found := b.db.get(b.name, key)
+	if !found {
+		return nil
+	}
+	return slices.Clone(result)
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+func (b *memoryDatabaseBucket) Put(key []byte, value []byte) error {
+	tran, err := b.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+	if err = tran.Bucket(b.name).Put(key, value); err != nil {
+		_ = tran.Discard()
+		return err
+	}
+	return tran.Commit()
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *memoryDatabaseBucket) Delete(key []byte) error {
+	tran, err := b.db.OpenTransaction()
+	if err != nil {
+		return err
+	}
+	if err = tran.Bucket(b.name).Delete(key); err != nil {
+		_ = tran.Discard()
+		return err
+	}
+	return tran.Commit()
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+//
+// The key/value pairs are visited in the order of keys, and come from a
+// snapshot of the bucket that taken when ForEach is called.
+func (b *memoryDatabaseBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	snapshot := b.db.snapshot(b.name)
+	for _, key := range slices.Sorted(maps.Keys(snapshot)) {
+		if err := fn([]byte(key), slices.Clone(snapshot[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// memoryTransaction is a transaction of the memory database.
+// All the writes are buffered, and then applied when commit.
+type memoryTransaction struct {
+	db      *memoryDatabase
+	pending map[string]map[string]memoryValue
+	done    bool
+}
+
+// Has returns true if the DB does contains the given key.
+func (t *memoryTransaction) Has(key []byte) (has bool) {
+	return t.Bucket(DatabaseKeyRoot).Has(key)
+}
+
+// Get retrieves the value for a key in the root bucket.
+// Returns a nil value if the key does not exist.
+func (t *memoryTransaction) Get(key []byte) (value []byte) {
+	return t.Bucket(DatabaseKeyRoot).Get(key)
+}
+
+// Put sets the value for a key in the root bucket.
+// If the key exist then its previous value will be overwritten.
+func (t *memoryTransaction) Put(key []byte, value []byte) error {
+	return t.Bucket(DatabaseKeyRoot).Put(key, value)
+}
+
+// Delete removes a key from the root bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (t *memoryTransaction) Delete(key []byte) error {
+	return t.Bucket(DatabaseKeyRoot).Delete(key)
+}
+
+// Bucket returns the bucket whose name is name,
+// and all operations on it are belongs to this transaction.
+func (t *memoryTransaction) Bucket(name []byte) Bucket {
+	return &memoryTransactionBucket{tran: t, name: string(name)}
+}
+
+// Commit applies all the changes of this transaction to the database.
+func (t *memoryTransaction) Commit() error {
+	if t.done {
+		return fmt.Errorf("Commit: Transaction is closed")
+	}
+	t.done = true
+	defer t.db.writeMu.Unlock()
+	return t.db.apply(t.pending)
+}
+
+// Discard closes the transaction and ignores all previous updates.
+func (t *memoryTransaction) Discard() error {
+	if t.done {
+		return nil
+	}
+	t.done = true
+	t.db.writeMu.Unlock()
+	return nil
+}
+
+// memoryTransactionBucket is a bucket
+// that belongs to a memory transaction.
+type memoryTransactionBucket struct {
+	tran *memoryTransaction
+	name string
+}
+
+// Has returns true if the bucket does contains the given key.
+func (b *memoryTransactionBucket) Has(key []byte) (has bool) {
+	return (b.Get(key) != nil)
+}
+
+// Get retrieves the value for a key in the bucket.
+// Returns a nil value if the key does not exist.
+func (b *memoryTransactionBucket) Get(key []byte) (value []byte) {
+	if pending, ok := b.tran.pending[b.name][string(key)]; ok {
+		if pending.deleted {
+			return nil
+		}
+		return slices.Clone(pending.value)
+	}
+	value, _ = b.tran.db.get([]byte(b.name), key)
+	return slices.Clone(value)
+}
+
+// Put sets the value for a key in the bucket.
+// If the key exist then its previous value will be overwritten.
+func (b *memoryTransactionBucket) Put(key []byte, value []byte) error {
+	if b.tran.done {
+		return fmt.Errorf("Put: Transaction is closed")
+	}
+	if len(key) == 0 {
+		return fmt.Errorf("Put: Key required")
+	}
+
+	pending := b.tran.pending[b.name]
+	if pending == nil {
+		pending = make(map[string]memoryValue)
+		b.tran.pending[b.name] = pending
+	}
+
+	if value == nil {
+		value = make([]byte, 0)
+	}
+	pending[string(key)] = memoryValue{value: slices.Clone(value)}
+
+	return nil
+}
+
+// Delete removes a key from the bucket.
+// If the key does not exist then nothing is done and a nil error is returned.
+func (b *memoryTransactionBucket) Delete(key []byte) error {
+	if b.tran.done {
+		return fmt.Errorf("Delete: Transaction is closed")
+	}
+
+	pending := b.tran.pending[b.name]
+	if pending == nil {
+		pending = make(map[string]memoryValue)
+		b.tran.pending[b.name] = pending
+	}
+	pending[string(key)] = memoryValue{deleted: true}
+
+	return nil
+}
+
+// ForEach executes a function for each key/value pair in the bucket.
+// If the provided function returns an error then the iteration is stopped and
+// the error is returned to the caller.
+//
+// The changes that made by this transaction are also visible.
+func (b *memoryTransactionBucket) ForEach(fn func(key []byte, value []byte) error) error {
+	snapshot := b.tran.db.snapshot([]byte(b.name))
+	if snapshot == nil {
+		snapshot = make(map[string][]byte)
+	}
+
+	for key, value := range b.tran.pending[b.name] {
+		if value.deleted {
+			delete(snapshot, key)
+			continue
+		}
+		snapshot[key] = value.value
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(snapshot)) {
+		if err := fn([]byte(key), snapshot[key]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,188 @@
+package timeline
+
+import (
+	"bytes"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testBackends returns the constructor of
+// each database backend that could be tested.
+func testBackends(t *testing.T) map[string]func() DB {
+	t.Helper()
+
+	return map[string]func() DB{
+		"memory": OpenMemoryDB,
+		"bbolt": func() DB {
+			db, err := OpenBBoltDB(filepath.Join(t.TempDir(), "timeline.db"), false, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		},
+		"leveldb": func() DB {
+			db, err := OpenLevelDB(filepath.Join(t.TempDir(), "timeline"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		},
+		"encrypted": func() DB {
+			db, err := NewEncryptedDB(OpenMemoryDB(), EncryptionOptions{KeyProvider: testKeyProvider(t, 1)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		},
+	}
+}
+
+// testDatabaseOperation checks the basic operations of
+// db, which could be a database, a bucket or a transaction.
+func testDatabaseOperation(t *testing.T, db DatabaseOperation) {
+	t.Helper()
+
+	if err := db.Put([]byte("key"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	if !db.Has([]byte("key")) || !bytes.Equal(db.Get([]byte("key")), []byte("value")) {
+		t.Fatalf("expected the value is put, but got %q", db.Get([]byte("key")))
+	}
+	if err := db.Delete([]byte("key")); err != nil {
+		t.Fatal(err)
+	}
+	if db.Has([]byte("key")) || len(db.Get([]byte("key"))) != 0 {
+		t.Fatal("expected the value is deleted")
+	}
+}
+
+func TestDatabaseBackends(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			db := open()
+			defer db.Close()
+
+			testDatabaseOperation(t, db)
+			testDatabaseOperation(t, db.Bucket([]byte("bucket")))
+
+			// Keys in different buckets are independent
+			if err := db.Put([]byte("key"), []byte("root")); err != nil {
+				t.Fatal(err)
+			}
+			bucket := db.Bucket([]byte("bucket"))
+			for _, key := range []string{"b", "a", "c"} {
+				if err := bucket.Put([]byte(key), []byte(key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if bucket.Has([]byte("key")) || !bytes.Equal(db.Bucket(DatabaseKeyRoot).Get([]byte("key")), []byte("root")) {
+				t.Fatal("expected the keys in different buckets are independent")
+			}
+
+			keys := make([]string, 0)
+			err := bucket.ForEach(func(key []byte, value []byte) error {
+				if !bytes.Equal(key, value) {
+					t.Errorf("ForEach: value of %q is %q", key, value)
+				}
+				keys = append(keys, string(key))
+				return nil
+			})
+			slices.Sort(keys)
+			if err != nil || !slices.Equal(keys, []string{"a", "b", "c"}) {
+				t.Fatalf("ForEach: got %v and %v", keys, err)
+			}
+
+			// Transaction
+			tran, err := db.OpenTransaction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			testDatabaseOperation(t, tran.Bucket([]byte("bucket")))
+			if err = tran.Put([]byte("committed"), []byte("value")); err != nil {
+				t.Fatal(err)
+			}
+			if err = tran.Commit(); err != nil {
+				t.Fatal(err)
+			}
+			if !db.Has([]byte("committed")) {
+				t.Fatal("expected the value is put after committed")
+			}
+
+			tran, err = db.OpenTransaction()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = tran.Put([]byte("discarded"), []byte("value")); err != nil {
+				t.Fatal(err)
+			}
+			if err = tran.Bucket([]byte("bucket")).Delete([]byte("a")); err != nil {
+				t.Fatal(err)
+			}
+			if err = tran.Discard(); err != nil {
+				t.Fatal(err)
+			}
+			if db.Has([]byte("discarded")) || !bucket.Has([]byte("a")) {
+				t.Fatal("expected nothing is changed after discarded")
+			}
+		})
+	}
+}
+
+func TestTimelineOnBackends(t *testing.T) {
+	for name, open := range testBackends(t) {
+		t.Run(name, func(t *testing.T) {
+			raw := open()
+			defer raw.Close()
+
+			db, err := OpenWithDB(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testAppend(t, db, 1, 2, 3)
+
+			if db.ChunkCount() != 1 || !db.HasChunkTimeline(testPos) {
+				t.Fatalf("expected 1 chunk timeline, but got %d", db.ChunkCount())
+			}
+			tl, err := db.NewChunkTimeline(testPos, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			testCheckChunks(t, tl, 1, 2, 3)
+			_ = tl.Save()
+
+			if err = db.DeleteChunkTimeline(testPos); err != nil {
+				t.Fatal(err)
+			}
+			if db.ChunkCount() != 0 || db.HasChunkTimeline(testPos) {
+				t.Fatal("expected the chunk timeline is deleted")
+			}
+		})
+	}
+}
+
+func TestMemoryTransactionGetIsCloned(t *testing.T) {
+	db := OpenMemoryDB()
+	if err := db.Put([]byte("committed"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	tran, err := db.OpenTransaction()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tran.Discard()
+	if err = tran.Put([]byte("pending"), []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"committed", "pending"} {
+		tran.Get([]byte(key))[0] = 'x'
+		if value := tran.Get([]byte(key)); !bytes.Equal(value, []byte("value")) {
+			t.Fatalf("Get: expected the value of %s is not changed, but got %q", key, value)
+		}
+	}
+	if value := db.Get([]byte("committed")); !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("Get: expected the committed value is not changed, but got %q", value)
+	}
+}
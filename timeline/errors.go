@@ -0,0 +1,35 @@
+package timeline
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEmpty is returned when reading a time point
+	// from a chunk timeline that have no time point.
+	ErrEmpty = errors.New("chunk timeline is empty")
+	// ErrOutOfRange is returned when the index of the
+	// time point is out of the range of the chunk timeline.
+	ErrOutOfRange = errors.New("index out of range")
+	// ErrClosed is returned when the underlying
+	// database is already closed.
+	ErrClosed = errors.New("database is closed")
+	// ErrCorrupt is returned when the data read from
+	// the underlying database is broken and could not
+	// be decoded.
+	ErrCorrupt = errors.New("data is corrupt")
+	// ErrBusy is returned when the timeline of a chunk is
+	// still in use and the caller don't want to wait it.
+	ErrBusy = errors.New("chunk timeline is in use")
+)
+
+// corruptError wraps err so it also matches ErrCorrupt.
+// If err is nil or already matches ErrCorrupt, then
+// return err directly.
+func corruptError(err error) error {
+	if err == nil || errors.Is(err, ErrCorrupt) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrCorrupt, err)
+}
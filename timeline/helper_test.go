@@ -0,0 +1,115 @@
+package timeline
+
+import (
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// testPos is the chunk position that used by the tests.
+var testPos = define.DimChunk{ChunkPos: operator_define.ChunkPos{3, -7}}
+
+// testRuntimeID returns the block runtime ID of the block
+// whose name is name and have no block states.
+func testRuntimeID(t *testing.T, name string) uint32 {
+	t.Helper()
+	blockRuntimeID, ok := block.StateToRuntimeID(name, nil)
+	if !ok {
+		t.Fatalf("block %s is not found", name)
+	}
+	return blockRuntimeID
+}
+
+// testChunk returns a chunk that filled with stone under y = -40,
+// and seed gold blocks are placed on the top of the stone.
+// Also returns a chest whose item count is seed.
+func testChunk(t *testing.T, seed int) (c *chunk.Chunk, nbts []map[string]any) {
+	t.Helper()
+
+	stone := testRuntimeID(t, "minecraft:stone")
+	gold := testRuntimeID(t, "minecraft:gold_block")
+
+	c = chunk.NewChunk(block.AirRuntimeID, testPos.Dimension.Range())
+	for x := range uint8(16) {
+		for z := range uint8(16) {
+			for y := int16(-64); y < -40; y++ {
+				c.SetBlock(x, y, z, 0, stone)
+			}
+		}
+	}
+	for i := range seed {
+		c.SetBlock(uint8(i%16), -40, uint8(i/16%16), 0, gold)
+	}
+
+	nbts = []map[string]any{
+		{
+			"id": "Chest",
+			"x":  int32(testPos.ChunkPos[0]*16 + 1),
+			"y":  int32(-40),
+			"z":  int32(testPos.ChunkPos[1]*16 + 2),
+			"Items": []any{
+				map[string]any{"Name": "minecraft:apple", "Count": byte(seed)},
+			},
+		},
+	}
+	return c, nbts
+}
+
+// testAppend appends the chunks that returned by testChunk
+// for each seed in seeds to the timeline of testPos in db.
+// The max limit of the timeline is raised if it can't hold
+// all of them.
+func testAppend(t *testing.T, db TimelineDatabase, seeds ...int) {
+	t.Helper()
+
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.SetMaxLimit(uint(max(tl.AllTimePointLen()+len(seeds), DefaultMaxLimit))); err != nil {
+		t.Fatal(err)
+	}
+	for _, seed := range seeds {
+		c, nbts := testChunk(t, seed)
+		if err = tl.Append(c, nbts, nil, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testEqualChunk reports whether the blocks of a and b are the same.
+func testEqualChunk(a *chunk.Chunk, b *chunk.Chunk) bool {
+	r := testPos.Dimension.Range()
+	for y := int16(r[0]); y <= int16(r[1]); y++ {
+		for x := range uint8(16) {
+			for z := range uint8(16) {
+				if a.Block(x, y, z, 0) != b.Block(x, y, z, 0) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// testCheckChunks checks the timeline tl
+// have the chunks of each seed in seeds.
+func testCheckChunks(t *testing.T, tl *ChunkTimeline, seeds ...int) {
+	t.Helper()
+
+	for index, seed := range seeds {
+		c, _, _, _, err := tl.JumpTo(uint(index))
+		if err != nil {
+			t.Fatalf("JumpTo: %v", err)
+		}
+		if expected, _ := testChunk(t, seed); !testEqualChunk(c, expected) {
+			t.Fatalf("JumpTo: time point %d is not the chunk of seed %d", index, seed)
+		}
+	}
+}
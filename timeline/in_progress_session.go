@@ -5,22 +5,22 @@ import (
 	"sync"
 
 	"maps"
-
-	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 )
 
 // InProgressSession holds the timelines that are still in use.
-type InProgressSession struct {
+// K is the key of these timelines, e.g. define.DimChunk for the
+// chunk timelines and the player identifier for the player ones.
+type InProgressSession[K comparable] struct {
 	mu      *sync.Mutex
 	closed  bool
-	session map[define.DimChunk]context.Context
+	session map[K]context.Context
 }
 
 // NewInProgressSession returns a new InProgressSession
-func NewInProgressSession() *InProgressSession {
-	return &InProgressSession{
+func NewInProgressSession[K comparable]() *InProgressSession[K] {
+	return &InProgressSession[K]{
 		mu:      new(sync.Mutex),
-		session: make(map[define.DimChunk]context.Context),
+		session: make(map[K]context.Context),
 	}
 }
 
@@ -34,7 +34,7 @@ func NewInProgressSession() *InProgressSession {
 // thread could start to using them.
 // If you get Require returned false, then that means the underlying
 // database is closed.
-func (i *InProgressSession) Require(pos define.DimChunk) (releaseFunc func(), success bool) {
+func (i *InProgressSession[K]) Require(pos K) (releaseFunc func(), success bool) {
 	var cancelFunc context.CancelFunc
 
 	for {
@@ -68,17 +68,43 @@ func (i *InProgressSession) Require(pos define.DimChunk) (releaseFunc func(), su
 		break
 	}
 
+	return i.releaseFunc(pos, cancelFunc), true
+}
+
+// TryRequire is like Require, but it will not blocking when
+// there is one thread is using the target timeline.
+//
+// If the target timeline is in use, then returned ErrBusy.
+// If the underlying database is closed, then returned ErrClosed.
+func (i *InProgressSession[K]) TryRequire(pos K) (releaseFunc func(), err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.closed {
+		return nil, ErrClosed
+	}
+	if _, ok := i.session[pos]; ok {
+		return nil, ErrBusy
+	}
+
+	ctx, cancelFunc := context.WithCancel(context.Background())
+	i.session[pos] = ctx
+
+	return i.releaseFunc(pos, cancelFunc), nil
+}
+
+// releaseFunc is an internal implement detail.
+func (i *InProgressSession[K]) releaseFunc(pos K, cancelFunc context.CancelFunc) func() {
 	release := func() {
 		i.mu.Lock()
 		{
 			cancelFunc()
 			delete(i.session, pos)
-			newMapping := make(map[define.DimChunk]context.Context)
+			newMapping := make(map[K]context.Context)
 			maps.Copy(newMapping, i.session)
 			i.session = newMapping
 		}
 		i.mu.Unlock()
 	}
-
-	return sync.OnceFunc(release), true
+	return sync.OnceFunc(release)
 }
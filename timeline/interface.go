@@ -2,7 +2,8 @@ package timeline
 
 import (
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
-	"go.etcd.io/bbolt"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
 )
 
 // DatabaseOperation represents some basic
@@ -14,33 +15,89 @@ type DatabaseOperation interface {
 	Put(key []byte, value []byte) (err error)
 }
 
+// checkedReader is implemented by the databases (and their
+// transactions and buckets) whose value could exist but
+// can't be read, e.g. the encrypted database.
+//
+// The Get of them returns nil for such a value, which looks
+// like the key is not exist. So getValue is used to read
+// the values that timeline needs.
+type checkedReader interface {
+	getChecked(key []byte) (value []byte, err error)
+}
+
+// Bucket represents a named key space in the database.
+// All the keys in a bucket are independent to the keys
+// in other buckets.
+type Bucket interface {
+	DatabaseOperation
+	ForEach(fn func(key []byte, value []byte) error) error
+}
+
+// BucketProvider represents something that could
+// provide buckets by their name.
+type BucketProvider interface {
+	Bucket(name []byte) Bucket
+}
+
 // Transaction represents a transaction in database.
 type Transaction interface {
 	DatabaseOperation
+	BucketProvider
 	Commit() error
 	Discard() error
 }
 
 // DB represent to a database that implements some basic funtions.
+//
+// Note that the DatabaseOperation that DB implements should operate
+// on the root bucket, that is, Bucket(DatabaseKeyRoot).
 type DB interface {
 	DatabaseOperation
+	BucketProvider
 	OpenTransaction() (Transaction, error)
 	Close() error
 }
 
 // Timeline is the function that timeline database should to implement.
 type Timeline interface {
+	BlockRegistry() *define.BlockRegistry
+	ChunkCount() uint32
+	Codec() *utils.Codec
+	CustomBlocks() (states []operator_define.BlockState, err error)
 	DeleteChunkTimeline(pos define.DimChunk) error
+	DeletePlayerTimeline(id string) error
+	ForEachChunkTimeline(fn func(pos define.DimChunk) error) error
+	ForEachPlayerTimeline(fn func(id string) error) error
+	GCSharedPalette() (removed int, err error)
+	HasChunkTimeline(pos define.DimChunk) bool
+	HasPlayerTimeline(id string) bool
 	LoadLatestTimePointUnixTime(pos define.DimChunk) (timeStamp int64)
 	NewChunkTimeline(pos define.DimChunk, readOnly bool) (result *ChunkTimeline, err error)
+	NewPlayerTimeline(id string, readOnly bool) (result *PlayerTimeline, err error)
+	RegisterCustomBlocks(states ...operator_define.BlockState) (blockRuntimeIDs []uint32, err error)
+	RewriteChunkTimeline(pos define.DimChunk) error
+	RewritePlayerTimeline(id string) error
+	RotateEncryptionKey() error
 	SaveLatestTimePointUnixTime(pos define.DimChunk, timeStamp int64) error
+	SearchBlocks(positions []define.DimChunk, predicate BlockPredicate) (result map[define.DimChunk][]BlockEvent, err error)
+	SetCompression(compression utils.Compression) error
+	SetSharedPalette(enabled bool) error
+	SetSubChunkDedup(enabled bool) error
+	SharedPaletteEnabled() bool
+	SubChunkDedupEnabled() bool
+	SubChunkHashes(pos define.DimChunk) (hashes []uint64, err error)
+	TrainDictionary(maxSamples int, maxSize int, level int) (version uint32, err error)
+	TryNewChunkTimeline(pos define.DimChunk, readOnly bool) (result *ChunkTimeline, err error)
+	TryNewPlayerTimeline(id string, readOnly bool) (result *PlayerTimeline, err error)
+	UseDictionary(version uint32) error
 }
 
 // TimelineDatabase wrapper and implements all features from Timeline,
-// and as a provider to provide timeline of chunk related functions.
+// and as a provider to provide timeline of chunk (and player) related functions.
 type TimelineDatabase interface {
 	DB
 	Timeline
-	UnderlyingDatabase() *bbolt.DB
+	UnderlyingDatabase() DB
 	CloseTimelineDB() error
 }
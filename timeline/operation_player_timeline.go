@@ -0,0 +1,339 @@
+package timeline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// ID returns the identifier of the player that this timeline belongs to.
+func (s *PlayerTimeline) ID() string {
+	return s.id
+}
+
+// Empty returns whether this timeline is empty or not.
+// If is empty, then calling Save will result in no operation.
+func (s *PlayerTimeline) Empty() bool {
+	return s.isEmpty
+}
+
+// ReadOnly returns whether this timeline is read only or not.
+// If is read only, then calling any function that will modify
+// underlying timeline will result in no operation.
+func (s *PlayerTimeline) ReadOnly() bool {
+	return s.isReadOnly
+}
+
+// AllTimePoint returns a slice that holds the unix time of all time points
+// this timeline have. Granted the returned array is non-decreasing.
+// Note that it's unsafe to modify the returned slice.
+func (s *PlayerTimeline) AllTimePoint() []int64 {
+	return s.timelineUnixTime
+}
+
+// AllTimePointLen returns the length of the time point that this timeline have.
+func (s *PlayerTimeline) AllTimePointLen() int {
+	return len(s.timelineUnixTime)
+}
+
+// SetMaxLimit sets the timeline could record how many time point.
+// It is the same as ChunkTimeline.SetMaxLimit.
+func (s *PlayerTimeline) SetMaxLimit(maxLimit uint) error {
+	if s.isReadOnly {
+		return nil
+	}
+
+	s.maxLimit = max(maxLimit, 1)
+
+	for s.barrierRight-s.barrierLeft+1 > s.maxLimit {
+		if err := s.Pop(); err != nil {
+			return fmt.Errorf("(s *PlayerTimeline) SetMaxLimit: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Append tries append the new data of this player to the timeline,
+// and player is the NBT data of this player (e.g. the value of the
+// "player_server_<UUID>" key in the game saves).
+// Only the difference to the latest time point is saved, which
+// is computed in the same way as the block NBTs.
+//
+// If NOPWhenNoChange is true, then if their is no change between
+// the one that want to append and the latest one, then finally
+// will result in NOP.
+//
+// The same as ChunkTimeline.Append, calling Append will make sure
+// there is exist at least one empty space to place the new time point
+// by calling Pop, and the poped time points must be the most earliest one.
+//
+// If current timeline is read only, then calling Append will do no operation.
+func (s *PlayerTimeline) Append(player map[string]any, NOPWhenNoChange bool) error {
+	var success bool
+
+	if s.isReadOnly {
+		return nil
+	}
+
+	for s.barrierRight-s.barrierLeft+1 >= s.maxLimit {
+		if err := s.Pop(); err != nil {
+			return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+		}
+	}
+
+	newerPlayer, err := define.PlayerRestore(player, define.DiffPlayer{})
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+	playerDiff, err := define.PlayerDifference(s.latestPlayer, newerPlayer)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+
+	// NOP Check
+	if !s.isEmpty && NOPWhenNoChange && define.PlayerNoChange(playerDiff) {
+		return nil
+	}
+
+	transaction, err := s.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = transaction.Discard()
+		}
+	}()
+	bucket := transaction.Bucket(DatabaseKeyPlayer)
+
+	// Put delta update
+	payload, err := marshal.DiffPlayerBytes(playerDiff, s.codec)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+	err = bucket.Put(define.IndexPlayerDu(s.id, s.barrierRight+1), payload)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+
+	// Update Latest Player
+	payload, err = marshal.PlayerBytes(newerPlayer, s.codec)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+	err = bucket.Put(define.SumPlayer(s.id, []byte(define.KeyLatestPlayer)...), payload)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Append: %w", err)
+	}
+	success = true
+
+	s.latestPlayer = newerPlayer
+	s.barrierRight++
+	s.timelineUnixTime = append(s.timelineUnixTime, time.Now().Unix())
+
+	if s.isEmpty {
+		s.barrierLeft = s.barrierRight
+		s.isEmpty = false
+	}
+
+	return nil
+}
+
+// Pop tries to delete the first time point from this timeline.
+// If current timeline is empty, or it is read only, or there is
+// only one time point, then we will do no operation.
+func (s *PlayerTimeline) Pop() error {
+	var success bool
+
+	if s.isEmpty || s.isReadOnly || s.barrierLeft == s.barrierRight {
+		return nil
+	}
+
+	transaction, err := s.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = transaction.Discard()
+		}
+	}()
+	bucket := transaction.Bucket(DatabaseKeyPlayer)
+
+	// Setp 1: Get element 1 and element 2 from timeline
+	dst, err := s.restorePlayer(bucket, s.barrierLeft, nil)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+	dst, err = s.restorePlayer(bucket, s.barrierLeft+1, dst)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+
+	// Setp 2: Pop
+	err = bucket.Delete(define.IndexPlayerDu(s.id, s.barrierLeft))
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+
+	newDiff, err := define.PlayerDifference(nil, dst)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+	payload, err := marshal.DiffPlayerBytes(newDiff, s.codec)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+	err = bucket.Put(define.IndexPlayerDu(s.id, s.barrierLeft+1), payload)
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Pop: %w", err)
+	}
+	success = true
+
+	s.barrierLeft++
+	s.timelineUnixTime = s.timelineUnixTime[1:]
+	return nil
+}
+
+// JumpTo gets the data of this player at the time point who is in index.
+// JumpTo don't change this timeline, so it could be called many times.
+//
+// Time complexity: O(C×(index+1)).
+// C is relevant to the size of the player data (see define.PlayerRestore).
+func (s *PlayerTimeline) JumpTo(index uint) (player map[string]any, updateUnixTime int64, err error) {
+	if s.isEmpty {
+		return nil, 0, fmt.Errorf("(s *PlayerTimeline) JumpTo: %w", ErrEmpty)
+	}
+
+	idx := s.barrierLeft + index
+	if idx > s.barrierRight {
+		return nil, 0, fmt.Errorf("(s *PlayerTimeline) JumpTo: %w (index %d is out of index %d)", ErrOutOfRange, index, s.barrierRight-s.barrierLeft)
+	}
+
+	bucket := s.db.Bucket(DatabaseKeyPlayer)
+	for keyIndex := s.barrierLeft; keyIndex <= idx; keyIndex++ {
+		player, err = s.restorePlayer(bucket, keyIndex, player)
+		if err != nil {
+			return nil, 0, fmt.Errorf("(s *PlayerTimeline) JumpTo: %w", err)
+		}
+	}
+
+	return player, s.timelineUnixTime[index], nil
+}
+
+// Last gets the data of this player at the latest time point.
+// Time complexity: O(C), C is relevant to the size of the player data.
+func (s *PlayerTimeline) Last() (player map[string]any, updateUnixTime int64, err error) {
+	if s.isEmpty {
+		return nil, 0, fmt.Errorf("(s *PlayerTimeline) Last: %w", ErrEmpty)
+	}
+
+	player, err = define.PlayerRestore(s.latestPlayer, define.DiffPlayer{})
+	if err != nil {
+		return nil, 0, fmt.Errorf("(s *PlayerTimeline) Last: %w", err)
+	}
+
+	return player, s.timelineUnixTime[len(s.timelineUnixTime)-1], nil
+}
+
+// Save saves current timeline into the underlying database,
+// and also release current timeline.
+// It is the same as ChunkTimeline.Save.
+func (s *PlayerTimeline) Save() error {
+	var success bool
+
+	if s.isEmpty || s.isReadOnly {
+		s.releaseFunc()
+		return nil
+	}
+
+	tran, err := s.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+	bucket := tran.Bucket(DatabaseKeyPlayer)
+
+	// Player Index
+	err = tran.Bucket(DatabaseKeyPlayerIndex).Put(define.IndexPlayer(s.id), []byte(s.id))
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+	}
+
+	// Save global data
+	{
+		compressedBytes, err := s.codec.Encode(s.encodeGlobalData())
+		if err != nil {
+			return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+		}
+		err = bucket.Put(
+			define.SumPlayer(s.id, []byte(define.KeyPlayerGlobalData)...),
+			compressedBytes,
+		)
+		if err != nil {
+			return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+		}
+	}
+
+	// Latest Player
+	{
+		payload, err := marshal.PlayerBytes(s.latestPlayer, s.codec)
+		if err != nil {
+			return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+		}
+		err = bucket.Put(
+			define.SumPlayer(s.id, []byte(define.KeyLatestPlayer)...),
+			payload,
+		)
+		if err != nil {
+			return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("(s *PlayerTimeline) Save: %w", err)
+	}
+	success = true
+	s.releaseFunc()
+
+	return nil
+}
+
+// restorePlayer computes the player data of the time point whose key index
+// is keyIndex, by given the player data of the previous time point (current).
+// The player data delta is read from reader.
+func (s *PlayerTimeline) restorePlayer(reader DatabaseOperation, keyIndex uint, current map[string]any) (result map[string]any, err error) {
+	var diff define.DiffPlayer
+	payload, err := getValue(reader, define.IndexPlayerDu(s.id, keyIndex))
+	if err == nil {
+		diff, err = marshal.BytesToDiffPlayer(payload, s.codec)
+	}
+	if err == nil {
+		result, err = define.PlayerRestore(current, diff)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(
+			"restorePlayer: Player data delta of player %s at time index %d is broken: %w",
+			s.id, keyIndex-s.barrierLeft, corruptError(err),
+		)
+	}
+	return result, nil
+}
@@ -2,6 +2,7 @@ package timeline
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
@@ -17,34 +18,34 @@ func (s *ChunkTimeline) appendBlocks(
 ) error {
 	for s.barrierRight-s.barrierLeft+1 >= s.maxLimit {
 		if err := s.Pop(); err != nil {
-			return fmt.Errorf("appendBlocks: %v", err)
+			return fmt.Errorf("appendBlocks: %w", err)
 		}
 	}
 
 	// Put delta update
-	payload, err := marshal.ChunkDiffMatrixToBytes(chunkDiff)
+	payload, err := marshal.ChunkDiffMatrixToBytes(chunkDiff, s.codec)
 	if err != nil {
-		return fmt.Errorf("appendBlocks: %v", err)
+		return fmt.Errorf("appendBlocks: %w", err)
 	}
 	err = transaction.Put(
 		define.IndexBlockDu(s.pos, s.barrierRight+1),
 		payload,
 	)
 	if err != nil {
-		return fmt.Errorf("appendBlocks: %v", err)
+		return fmt.Errorf("appendBlocks: %w", err)
 	}
 
 	// Update Latest Chunk
-	payload, err = marshal.ChunkMatrixToBytes(newerChunk)
+	payload, err = s.encodeLatestChunk(transaction, newerChunk)
 	if err != nil {
-		return fmt.Errorf("appendBlocks: %v", err)
+		return fmt.Errorf("appendBlocks: %w", err)
 	}
 	err = transaction.Put(
 		define.Sum(s.pos, define.KeyLatestChunk),
 		payload,
 	)
 	if err != nil {
-		return fmt.Errorf("appendBlocks: %v", err)
+		return fmt.Errorf("appendBlocks: %w", err)
 	}
 
 	return nil
@@ -58,47 +59,59 @@ func (s *ChunkTimeline) appendNBTs(
 ) error {
 	for s.barrierRight-s.barrierLeft+1 >= s.maxLimit {
 		if err := s.Pop(); err != nil {
-			return fmt.Errorf("appendNBTs: %v", err)
+			return fmt.Errorf("appendNBTs: %w", err)
 		}
 	}
 
 	// Put delta update
-	payload, err := marshal.MultipleDiffNBTBytes(nbtDiff)
+	payload, err := marshal.MultipleDiffNBTBytes(nbtDiff, s.codec)
 	if err != nil {
-		return fmt.Errorf("appendNBTs: %v", err)
+		return fmt.Errorf("appendNBTs: %w", err)
 	}
 	err = transaction.Put(
 		define.IndexNBTDu(s.pos, s.barrierRight+1),
 		payload,
 	)
 	if err != nil {
-		return fmt.Errorf("appendNBTs: %v", err)
+		return fmt.Errorf("appendNBTs: %w", err)
 	}
 
 	// Update Latest NBT
-	payload, err = marshal.BlockNBTBytes(newerNBTs)
+	payload, err = marshal.BlockNBTBytes(newerNBTs, s.codec)
 	if err != nil {
-		return fmt.Errorf("appendNBTs: %v", err)
+		return fmt.Errorf("appendNBTs: %w", err)
 	}
 	err = transaction.Put(
 		define.Sum(s.pos, []byte(define.KeyLatestNBT)...),
 		payload,
 	)
 	if err != nil {
-		return fmt.Errorf("appendNBTs: %v", err)
+		return fmt.Errorf("appendNBTs: %w", err)
 	}
 
 	return nil
 }
 
 // Append tries append a new chunk with block
-// NBT data to the timeline of current chunk.
+// NBT data and entities to the timeline of
+// current chunk.
+//
+// The entities are matched by their unique ID
+// (see define.FromChunkEntities), so only the
+// changed entities are saved as delta update.
+// The biomes of c are also saved in the same
+// way as the blocks.
 //
 // If NOPWhenNoChange is true, then if their
 // is no change between the one that want to
 // append and the latest one, then finally will
 // result in NOP.
 //
+// The sub chunks whose content hash is the same
+// as the latest one (see SubChunkHashes) are not
+// diffed again, so appending an unchanged chunk
+// is cheap.
+//
 // Calling Append will make sure there is exist
 // at least one empty space to place the new time
 // point, whether new time point will be added in
@@ -111,12 +124,138 @@ func (s *ChunkTimeline) appendNBTs(
 // If current timeline is read only, then calling
 // Append will do no operation.
 func (s *ChunkTimeline) Append(
-	c *chunk.Chunk, nbts []map[string]any,
+	c *chunk.Chunk, nbts []map[string]any, entities []map[string]any,
+	NOPWhenNoChange bool,
+) error {
+	if len(c.Sub()) != len(s.latestChunk) {
+		return fmt.Errorf("(s *ChunkTimeline) Append: %w (chunk have %d sub chunks but expected %d)", ErrOutOfRange, len(c.Sub()), len(s.latestChunk))
+	}
+
+	err := s.appendTimePoint(func() (define.ChunkMatrix, define.ChunkDiffMatrix, []define.NBTWithIndex, []uint64, error) {
+		newerNBTs := define.FromChunkNBT(s.pos.ChunkPos, nbts)
+		hashes := define.HashChunk(c, newerNBTs)
+
+		newerChunk := make(define.ChunkMatrix, len(s.latestChunk))
+		chunkDiff := make(define.ChunkDiffMatrix, len(s.latestChunk))
+		for index, subChunk := range c.Sub() {
+			// The sub chunks that not changed are
+			// not converted and diffed again.
+			if s.subChunkUnchanged(index, hashes[index]) {
+				newerChunk[index] = s.latestChunk[index]
+				chunkDiff[index] = make(define.LayersDiff, len(s.latestChunk[index]))
+				continue
+			}
+			newerChunk[index] = define.SubChunkToLayers(subChunk, s.blockPalette)
+			chunkDiff[index] = define.LayerDifference(s.latestChunk[index], newerChunk[index])
+		}
+
+		return newerChunk, chunkDiff, newerNBTs, hashes, nil
+	}, define.FromChunkEntities(entities), define.ChunkToBiomeMatrix(c), NOPWhenNoChange)
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Append: %w", err)
+	}
+	return nil
+}
+
+// AppendSubChunks is the same as Append, but only the sub chunks
+// in subChunks are changed, and the others are treated as unchanged
+// (the same as the latest time point). So only these sub chunks are
+// diffed, which is much faster than Append if you know exactly which
+// sub chunks are changed (e.g. from the SubChunk packets).
+//
+// The key of subChunks is the Y index of the sub chunk (e.g. -4 is
+// the lowest sub chunk of Overworld), and if it is out of the range
+// of this chunk, then returns an error that wraps ErrOutOfRange.
+// The value of subChunks can't be nil, use an empty sub chunk for
+// the sub chunk that full of air.
+//
+// nbts is the block entities in these sub chunks. The block entities
+// in the other sub chunks are kept, and the ones in nbts that not in
+// these sub chunks are ignored.
+//
+// For an empty timeline, the sub chunks that not in subChunks
+// are treated as air. The entities and biomes are always kept
+// the same as the latest time point.
+func (s *ChunkTimeline) AppendSubChunks(
+	subChunks map[int16]*chunk.SubChunk, nbts []map[string]any,
+	NOPWhenNoChange bool,
+) error {
+	minSubChunk := int16(s.pos.Dimension.Range()[0] >> 4)
+
+	for y, subChunk := range subChunks {
+		if index := int(y - minSubChunk); index < 0 || index >= len(s.latestChunk) {
+			return fmt.Errorf("(s *ChunkTimeline) AppendSubChunks: %w (sub chunk %d is not in chunk range)", ErrOutOfRange, y)
+		}
+		if subChunk == nil {
+			return fmt.Errorf("(s *ChunkTimeline) AppendSubChunks: Sub chunk %d is nil", y)
+		}
+	}
+
+	err := s.appendTimePoint(func() (define.ChunkMatrix, define.ChunkDiffMatrix, []define.NBTWithIndex, []uint64, error) {
+		newerChunk := make(define.ChunkMatrix, len(s.latestChunk))
+		chunkDiff := make(define.ChunkDiffMatrix, len(s.latestChunk))
+		hashes := make([]uint64, len(s.latestChunk))
+		copy(newerChunk, s.latestChunk)
+		copy(hashes, s.latestHash)
+
+		// The difference of the unchanged sub chunks must still have
+		// the same layers, or they will be cleared when restoring.
+		for index, layers := range s.latestChunk {
+			chunkDiff[index] = make(define.LayersDiff, len(layers))
+		}
+
+		newerNBTs := make([]define.NBTWithIndex, 0, len(s.latestNBT)+len(nbts))
+		for _, value := range s.latestNBT {
+			if _, ok := subChunks[value.Index.Y()>>4]; !ok {
+				newerNBTs = append(newerNBTs, value)
+			}
+		}
+		for _, value := range define.FromChunkNBT(s.pos.ChunkPos, nbts) {
+			if _, ok := subChunks[value.Index.Y()>>4]; ok {
+				newerNBTs = append(newerNBTs, value)
+			}
+		}
+
+		groups := define.GroupNBTBySubChunk(newerNBTs, s.pos.Dimension.Range())
+		for y, subChunk := range subChunks {
+			index := int(y - minSubChunk)
+			hashes[index] = define.HashSubChunk(subChunk, groups[index])
+			if s.subChunkUnchanged(index, hashes[index]) {
+				continue
+			}
+			newerChunk[index] = define.SubChunkToLayers(subChunk, s.blockPalette)
+			chunkDiff[index] = define.LayerDifference(s.latestChunk[index], newerChunk[index])
+		}
+
+		return newerChunk, chunkDiff, newerNBTs, hashes, nil
+	}, s.latestEntities, s.latestBiomes, NOPWhenNoChange)
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) AppendSubChunks: %w", err)
+	}
+	return nil
+}
+
+// appendTimePoint appends a new time point that computed by
+// build, which returns the newer chunk matrix, the difference
+// of blocks, the newer block NBTs and the hash of each sub chunk.
+// newerEntities and newerBiomes are the entities and biomes
+// of this new time point.
+//
+// The block NBTs in the sub chunks whose hash is not changed
+// are not diffed, and if all the hashes, entities and biomes
+// are not changed, then NOPWhenNoChange could skip this time point
+// directly.
+//
+// build is called after the earliest time points are poped
+// (if needed), and appendTimePoint is an internal implement
+// detail of Append and AppendSubChunks.
+func (s *ChunkTimeline) appendTimePoint(
+	build func() (define.ChunkMatrix, define.ChunkDiffMatrix, []define.NBTWithIndex, []uint64, error),
+	newerEntities []define.EntityWithID,
+	newerBiomes define.ChunkBiomeMatrix,
 	NOPWhenNoChange bool,
 ) error {
 	var success bool
-	var newerChunk define.ChunkMatrix
-	var newerNBTs []define.NBTWithIndex
 
 	if s.isReadOnly {
 		return nil
@@ -124,36 +263,41 @@ func (s *ChunkTimeline) Append(
 
 	for s.barrierRight-s.barrierLeft+1 >= s.maxLimit {
 		if err := s.Pop(); err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Append: %v", err)
+			return fmt.Errorf("appendTimePoint: %w", err)
 		}
 	}
 
 	transaction, err := s.db.OpenTransaction()
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Append: %v", err)
+		return fmt.Errorf("appendTimePoint: %w", err)
 	}
 	defer func() {
 		if !success {
 			_ = transaction.Discard()
-			return
 		}
-		_ = transaction.Commit()
 	}()
 
-	// Blocks
-	newerChunk = define.ChunkToMatrix(c, s.blockPalette)
-	chunkDiff := define.ChunkDifference(s.latestChunk, newerChunk)
-
-	// NBTs
-	newerNBTs = define.FromChunkNBT(s.pos.ChunkPos, nbts)
-	nbtDiff, err := define.NBTDifference(s.latestNBT, newerNBTs)
+	// Blocks, NBTs, entities and biomes
+	newerChunk, chunkDiff, newerNBTs, hashes, err := build()
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	entityDiff, err := define.EntityDifference(s.latestEntities, newerEntities)
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	biomeDiff := define.BiomeDifference(s.latestBiomes, newerBiomes)
+	if !s.isEmpty && NOPWhenNoChange && slices.Equal(hashes, s.latestHash) && define.EntityNoChange(*entityDiff) && define.BiomeNoChange(biomeDiff) {
+		return nil
+	}
+	nbtDiff, err := define.NBTDifference(s.changedNBTs(s.latestNBT, hashes), s.changedNBTs(newerNBTs, hashes))
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Append: %v", err)
+		return fmt.Errorf("appendTimePoint: %w", err)
 	}
 
 	// NOP Check
 	if !s.isEmpty {
-		if NOPWhenNoChange && define.ChunkNoChange(chunkDiff) && define.NBTNoChange(*nbtDiff) {
+		if NOPWhenNoChange && define.ChunkNoChange(chunkDiff) && define.NBTNoChange(*nbtDiff) && define.EntityNoChange(*entityDiff) && define.BiomeNoChange(biomeDiff) {
 			return nil
 		}
 	}
@@ -161,18 +305,46 @@ func (s *ChunkTimeline) Append(
 	// Append
 	err = s.appendBlocks(newerChunk, chunkDiff, transaction)
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Append: %v", err)
+		return fmt.Errorf("appendTimePoint: %w", err)
 	}
 	err = s.appendNBTs(newerNBTs, *nbtDiff, transaction)
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Append: %v", err)
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	err = s.appendEntities(newerEntities, *entityDiff, transaction)
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	err = s.appendBiomes(newerBiomes, biomeDiff, transaction)
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	err = s.putSubChunkHashes(transaction, hashes)
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	err = s.putSummary(transaction, s.barrierRight+1, define.NewTimePointSummary(chunkDiff, *nbtDiff))
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	err = s.putNBTSnapshot(transaction, s.barrierRight+1, newerNBTs)
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
 	}
 
+	err = transaction.Commit()
+	if err != nil {
+		return fmt.Errorf("appendTimePoint: %w", err)
+	}
+	success = true
+
 	s.latestChunk = newerChunk
 	s.latestNBT = newerNBTs
+	s.latestEntities = newerEntities
+	s.latestBiomes = newerBiomes
+	s.latestHash = hashes
 	s.barrierRight++
 	s.timelineUnixTime = append(s.timelineUnixTime, time.Now().Unix())
-	success = true
 
 	if s.isEmpty {
 		s.barrierLeft = s.barrierRight
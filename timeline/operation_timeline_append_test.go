@@ -0,0 +1,80 @@
+package timeline
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+)
+
+func TestAppendSubChunks(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1)
+
+	// Only the sub chunk that have gold blocks and the chest is changed
+	c, nbts := testChunk(t, 5)
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = tl.AppendSubChunks(map[int16]*chunk.SubChunk{20: c.Sub()[1]}, nbts, false)
+	if !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("AppendSubChunks: expected ErrOutOfRange, but got %v", err)
+	}
+	if err = tl.AppendSubChunks(map[int16]*chunk.SubChunk{-3: nil}, nbts, false); err == nil {
+		t.Fatal("AppendSubChunks: expected an error for the nil sub chunk")
+	}
+	if err = tl.AppendSubChunks(map[int16]*chunk.SubChunk{-3: c.Sub()[1]}, nbts, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	testCheckChunks(t, tl, 1, 5)
+	_, result, _, _, err := tl.JumpTo(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := testChestCount(t, result); count != 5 {
+		t.Fatalf("JumpTo: expected 5 items in the chest, but got %d", count)
+	}
+	if !slices.Equal(tl.SubChunkHashes(), define.HashChunk(c, define.FromChunkNBT(testPos.ChunkPos, nbts))) {
+		t.Fatal("expected the hashes are the same as the one of the full chunk")
+	}
+
+	diff, err := marshal.BytesToChunkDiffMatrix(raw.Get(define.IndexBlockDu(testPos, tl.barrierRight)), testPos.Dimension.Range(), db.Codec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, layersDiff := range diff {
+		if index != 1 && !define.LayerNoChange(layersDiff) {
+			t.Fatalf("expected only sub chunk 1 is changed, but sub chunk %d is also changed", index)
+		}
+	}
+}
+
+// testChestCount returns the item count of the chest in nbts.
+func testChestCount(t *testing.T, nbts []map[string]any) byte {
+	t.Helper()
+	for _, value := range nbts {
+		if value["id"] == "Chest" {
+			return value["Items"].([]any)[0].(map[string]any)["Count"].(byte)
+		}
+	}
+	t.Fatal("chest is not found")
+	return 0
+}
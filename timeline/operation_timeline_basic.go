@@ -30,6 +30,14 @@ func (s *ChunkTimeline) ResetPointer() {
 	s.ptr = s.barrierLeft
 	s.currentChunk = make(define.ChunkMatrix, s.pos.Dimension.Height()>>4)
 	s.currentNBT = nil
+	s.currentEntities = nil
+	s.currentBiomes = make(define.ChunkBiomeMatrix, s.pos.Dimension.Height()>>4)
+}
+
+// BlockRegistry returns the block registry that the block palette of this
+// timeline used, which could find the block states of the custom blocks.
+func (s *ChunkTimeline) BlockRegistry() *define.BlockRegistry {
+	return s.blockPalette.Registry()
 }
 
 // AllTimePoint returns a slice that holds the unix time of all time points
@@ -65,7 +73,7 @@ func (s *ChunkTimeline) SetMaxLimit(maxLimit uint) error {
 
 	for s.barrierRight-s.barrierLeft+1 > s.maxLimit {
 		if err := s.Pop(); err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) SetMaxLimit: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) SetMaxLimit: %w", err)
 		}
 	}
 
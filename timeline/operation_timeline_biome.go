@@ -0,0 +1,81 @@
+package timeline
+
+import (
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// "appendBiomes" is an internal implement detail.
+func (s *ChunkTimeline) appendBiomes(
+	newerBiomes define.ChunkBiomeMatrix,
+	biomeDiff define.ChunkBiomeDiffMatrix,
+	transaction Transaction,
+) error {
+	// Put delta update
+	payload, err := marshal.ChunkBiomeDiffMatrixToBytes(biomeDiff, s.codec)
+	if err != nil {
+		return fmt.Errorf("appendBiomes: %w", err)
+	}
+	err = transaction.Put(
+		define.IndexBiomeDu(s.pos, s.barrierRight+1),
+		payload,
+	)
+	if err != nil {
+		return fmt.Errorf("appendBiomes: %w", err)
+	}
+
+	// Update Latest Biomes
+	payload, err = marshal.ChunkBiomeMatrixToBytes(newerBiomes, s.codec)
+	if err != nil {
+		return fmt.Errorf("appendBiomes: %w", err)
+	}
+	err = transaction.Put(
+		define.Sum(s.pos, []byte(define.KeyLatestBiome)...),
+		payload,
+	)
+	if err != nil {
+		return fmt.Errorf("appendBiomes: %w", err)
+	}
+
+	return nil
+}
+
+// restoreBiomes computes the biomes of the time point whose key index
+// is keyIndex, by given the biomes of the previous time point (current).
+// The biome delta is read from reader, and current is modified in place.
+//
+// If bestEffort is true, then the broken biome delta is ignored,
+// and the biomes of the previous time point are returned.
+func (s *ChunkTimeline) restoreBiomes(
+	reader DatabaseOperation, keyIndex uint,
+	current define.ChunkBiomeMatrix, bestEffort bool,
+) (result define.ChunkBiomeMatrix, err error) {
+	var diff define.ChunkBiomeDiffMatrix
+	payload, err := getValue(reader, define.IndexBiomeDu(s.pos, keyIndex))
+	if err == nil {
+		diff, err = marshal.BytesToChunkBiomeDiffMatrix(payload, s.pos.Dimension.Range(), s.codec)
+	}
+	if err == nil && len(diff) != len(current) {
+		err = fmt.Errorf("%w (delta have %d sub chunks but expected %d)", define.ErrMalformed, len(diff), len(current))
+	}
+	if err != nil {
+		if bestEffort {
+			s.ignoredDeltas++
+			return current, nil
+		}
+		return nil, fmt.Errorf("restoreBiomes: %w", s.biomeDeltaError(keyIndex, err))
+	}
+	return define.BiomeRestore(current, diff), nil
+}
+
+// biomeDeltaError wraps err that occurred when restoring the
+// biomes of the time point whose key index is keyIndex, so
+// the returned error could report where the broken payload is.
+func (s *ChunkTimeline) biomeDeltaError(keyIndex uint, err error) error {
+	return fmt.Errorf(
+		"Biome delta of chunk (%d, %d) in dim %d at time index %d is broken: %w",
+		s.pos.ChunkPos[0], s.pos.ChunkPos[1], s.pos.Dimension, keyIndex-s.barrierLeft, corruptError(err),
+	)
+}
@@ -0,0 +1,110 @@
+package timeline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+)
+
+// testBiomeChunk returns the chunk of testChunk(t, 1), but
+// the biome of the sub chunks under y = -32 is biome.
+func testBiomeChunk(t *testing.T, biome uint32) *chunk.Chunk {
+	t.Helper()
+
+	c, _ := testChunk(t, 1)
+	for x := range uint8(16) {
+		for z := range uint8(16) {
+			for y := int16(-64); y < -32; y++ {
+				c.SetBiome(x, y, z, biome)
+			}
+		}
+	}
+	return c
+}
+
+// testCheckBiome checks all the biomes of c under
+// y = -32 are biome, and the others are biome 0.
+func testCheckBiome(t *testing.T, c *chunk.Chunk, biome uint32) {
+	t.Helper()
+
+	r := testPos.Dimension.Range()
+	for y := int16(r[0]); y <= int16(r[1]); y += 4 {
+		expected := biome
+		if y >= -32 {
+			expected = 0
+		}
+		if got := c.Biome(7, y, 9); got != expected {
+			t.Fatalf("expected biome %d at y = %d, but got %d", expected, y, got)
+		}
+	}
+}
+
+func TestBiomeTimeline(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	biomes := []uint32{0, 5, 7}
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, biome := range biomes {
+		if err = tl.Append(testBiomeChunk(t, biome), nil, nil, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the biomes are changed, so the time
+	// points are not skipped by NOPWhenNoChange
+	if tl.AllTimePointLen() != len(biomes) {
+		t.Fatalf("expected %d time points, but got %d", len(biomes), tl.AllTimePointLen())
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, biome := range biomes {
+		c, _, _, _, err := tl.JumpTo(uint(index))
+		if err != nil {
+			t.Fatalf("JumpTo: %v", err)
+		}
+		testCheckBiome(t, c, biome)
+	}
+	c, _, _, _, err := tl.Last()
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+	testCheckBiome(t, c, biomes[len(biomes)-1])
+	_ = tl.Save()
+
+	// A broken biome delta
+	testTamper(t, raw, define.IndexBiomeDu(testPos, tl.barrierLeft+1))
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	_, _, _, _, err = tl.JumpTo(1)
+	if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "Biome delta") {
+		t.Fatalf("JumpTo: expected a broken biome delta, but got %v", err)
+	}
+	tl.SetBestEffort(true)
+	c, _, _, _, err = tl.JumpTo(1)
+	if err != nil {
+		t.Fatalf("JumpTo: %v", err)
+	}
+	testCheckBiome(t, c, biomes[0])
+	if tl.IgnoredDeltas() != 1 {
+		t.Fatalf("expected 1 ignored delta, but got %d", tl.IgnoredDeltas())
+	}
+}
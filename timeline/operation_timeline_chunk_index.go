@@ -0,0 +1,110 @@
+package timeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// addChunkIndex adds pos to the chunk index
+// that comes from provider, and then update
+// the chunk count.
+// If pos is already exist, then do no operation.
+func addChunkIndex(provider BucketProvider, pos define.DimChunk) error {
+	keyBytes := define.Index(pos)
+	bucket := provider.Bucket(DatabaseKeyChunkIndex)
+
+	if bucket.Has(keyBytes) {
+		return nil
+	}
+
+	countBytes, err := getValue(bucket, DatabaseKeyChunkCount)
+	if err != nil {
+		return fmt.Errorf("addChunkIndex: %w", err)
+	}
+	err = bucket.Put(DatabaseKeyChunkCount, utils.Uint32BinaryAdd(countBytes, make([]byte, 4), 1))
+	if err != nil {
+		return fmt.Errorf("addChunkIndex: %w", err)
+	}
+
+	err = bucket.Put(keyBytes, keyBytes)
+	if err != nil {
+		return fmt.Errorf("addChunkIndex: %w", err)
+	}
+
+	return nil
+}
+
+// removeChunkIndex removes pos from the chunk
+// index that comes from provider, and then update
+// the chunk count.
+// If pos is not exist, then do no operation.
+func removeChunkIndex(provider BucketProvider, pos define.DimChunk) error {
+	keyBytes := define.Index(pos)
+	bucket := provider.Bucket(DatabaseKeyChunkIndex)
+
+	if !bucket.Has(keyBytes) {
+		return nil
+	}
+
+	countBytes, err := getValue(bucket, DatabaseKeyChunkCount)
+	if err != nil {
+		return fmt.Errorf("removeChunkIndex: %w", err)
+	}
+	err = bucket.Put(DatabaseKeyChunkCount, utils.Uint32BinaryAdd(countBytes, []byte{1, 0, 0, 0}, -1))
+	if err != nil {
+		return fmt.Errorf("removeChunkIndex: %w", err)
+	}
+
+	err = bucket.Delete(keyBytes)
+	if err != nil {
+		return fmt.Errorf("removeChunkIndex: %w", err)
+	}
+
+	return nil
+}
+
+// ChunkCount returns the count of chunks
+// that have timeline in this database.
+//
+// The chunk count is checked when the timeline database
+// is opened, so if it can't be read here (e.g. it is
+// tampered after that), then return 0.
+func (t *TimelineDB) ChunkCount() uint32 {
+	countBytes, err := getValue(t.Bucket(DatabaseKeyChunkIndex), DatabaseKeyChunkCount)
+	if err != nil || len(countBytes) < 4 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(countBytes)
+}
+
+// HasChunkTimeline reports whether the chunk
+// who at pos have timeline in this database.
+func (t *TimelineDB) HasChunkTimeline(pos define.DimChunk) bool {
+	return t.Bucket(DatabaseKeyChunkIndex).Has(define.Index(pos))
+}
+
+// ForEachChunkTimeline calls fn for each chunk that
+// have timeline in this database.
+// If fn returns an error then the iteration is stopped
+// and the error is returned to the caller.
+//
+// Note that it's unsafe to modify the timeline of any
+// chunk when iterating, but you can read them.
+func (t *TimelineDB) ForEachChunkTimeline(fn func(pos define.DimChunk) error) error {
+	return t.Bucket(DatabaseKeyChunkIndex).ForEach(func(key []byte, value []byte) error {
+		if bytes.Equal(key, DatabaseKeyChunkCount) {
+			return nil
+		}
+		// The chunk position is also saved in the value,
+		// because the key could be hashed when encrypted.
+		// Old databases only have the position in the key.
+		if len(value) == len(key) {
+			return fn(define.IndexInv(value))
+		}
+		return fn(define.IndexInv(key))
+	})
+}
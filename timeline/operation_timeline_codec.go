@@ -0,0 +1,290 @@
+package timeline
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// loadCompression loads the compression algorithm that
+// the database db selected. If not selected, then return
+// the default one.
+func loadCompression(db DB) (compression utils.Compression, err error) {
+	payload, err := getValue(db.Bucket(DatabaseKeyMeta), DatabaseKeyCodec)
+	if err != nil {
+		return nil, fmt.Errorf("loadCompression: %w", err)
+	}
+	if len(payload) == 0 {
+		return utils.DefaultCompression(), nil
+	}
+	if len(payload) < 5 {
+		return nil, fmt.Errorf("loadCompression: %w (codec setting is broken, only get %d bytes but expected 5)", ErrCorrupt, len(payload))
+	}
+
+	compression, err = utils.NewCompression(payload[0], int(int32(binary.LittleEndian.Uint32(payload[1:]))))
+	if err != nil {
+		return nil, fmt.Errorf("loadCompression: %w", err)
+	}
+	return compression, nil
+}
+
+// Codec returns the codec that this database used
+// to compress and decompress the stored values.
+func (t *TimelineDB) Codec() *utils.Codec {
+	return t.codec
+}
+
+// SetCompression sets the compression algorithm that used to compress
+// the new values, and save this setting into the underlying database.
+//
+// Values that compressed by other algorithms could still be decoded,
+// and use RewriteChunkTimeline (or RewritePlayerTimeline)
+// to re-encode them if needed.
+func (t *TimelineDB) SetCompression(compression utils.Compression) error {
+	payload := make([]byte, 5)
+	payload[0] = compression.ID()
+	binary.LittleEndian.PutUint32(payload[1:], uint32(int32(compression.Level())))
+
+	err := t.Bucket(DatabaseKeyMeta).Put(DatabaseKeyCodec, payload)
+	if err != nil {
+		return fmt.Errorf("SetCompression: %w", err)
+	}
+
+	t.codec.SetCompression(compression)
+	return nil
+}
+
+// RewriteChunkTimeline re-encodes all the values of the timeline
+// of chunk who at pos by the compression algorithm that currently
+// selected. Deltas will be re-encoded by the dictionary if have.
+// The block palette will also be migrated to the shared palette
+// or back to the block states, and the layers of the latest chunk
+// will be deduplicated or saved directly, depends on the current
+// settings.
+// If timeline is not exist, then do no operation.
+//
+// Time complexity: O(n).
+// n is the time point that this chunk have.
+func (t *TimelineDB) RewriteChunkTimeline(pos define.DimChunk) error {
+	var success bool
+	var globalData []byte
+	var pendingEntries []sharedPaletteEntry
+
+	timeline, err := t.NewChunkTimeline(pos, false)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+	defer func() {
+		timeline.releaseFunc()
+	}()
+
+	if timeline.isEmpty {
+		return nil
+	}
+
+	sharedPaletteEnabled, unlock := t.palette.lock()
+	defer unlock()
+
+	tran, err := t.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+
+	// The global data is encoded again, so the block
+	// palette will be migrated to the current setting.
+	globalData, pendingEntries, err = timeline.encodeGlobalData(tran, sharedPaletteEnabled)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+	payload, err := t.codec.Encode(globalData)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+	err = tran.Put(define.Sum(pos, []byte(define.KeyChunkGlobalData)...), payload)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+
+	// The latest chunk is encoded again, so the layers will be
+	// deduplicated or saved directly, depends on the current setting.
+	payload, err = timeline.encodeLatestChunk(tran, timeline.latestChunk)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+	err = tran.Put(define.Sum(pos, define.KeyLatestChunk), payload)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+
+	// The hashes of the latest time point are also saved,
+	// so the timelines that saved by older versions could
+	// be migrated.
+	err = timeline.putSubChunkHashes(tran, timeline.latestHash)
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+
+	type rewriteKey struct {
+		key         []byte
+		isDelta     bool
+		hasChecksum bool
+	}
+
+	keys := []rewriteKey{
+		{key: define.Sum(pos, []byte(define.KeyLatestNBT)...)},
+		{key: define.Sum(pos, []byte(define.KeyLatestEntity)...)},
+		{key: define.Sum(pos, []byte(define.KeyLatestBiome)...), hasChecksum: true},
+	}
+	for i := timeline.barrierLeft; i <= timeline.barrierRight; i++ {
+		keys = append(
+			keys,
+			rewriteKey{key: define.IndexBlockDu(pos, i), isDelta: true, hasChecksum: true},
+			rewriteKey{key: define.IndexNBTDu(pos, i), isDelta: true},
+			rewriteKey{key: define.IndexNBTSnapshot(pos, i)},
+			rewriteKey{key: define.IndexEntityDu(pos, i), isDelta: true},
+			rewriteKey{key: define.IndexBiomeDu(pos, i), isDelta: true, hasChecksum: true},
+		)
+	}
+
+	for _, value := range keys {
+		payload, err := getValue(tran, value.key)
+		if err != nil {
+			return fmt.Errorf("RewriteChunkTimeline: %w", err)
+		}
+		if len(payload) == 0 {
+			continue
+		}
+
+		originBytes, err := t.codec.Decode(payload)
+		if err != nil {
+			return fmt.Errorf("RewriteChunkTimeline: %w", corruptError(err))
+		}
+		if value.isDelta {
+			payload, err = t.codec.EncodeDelta(originBytes)
+		} else {
+			payload, err = t.codec.Encode(originBytes)
+		}
+		if err != nil {
+			return fmt.Errorf("RewriteChunkTimeline: %w", err)
+		}
+		if value.hasChecksum {
+			payload = utils.WithChecksum(payload)
+		}
+
+		err = tran.Put(value.key, payload)
+		if err != nil {
+			return fmt.Errorf("RewriteChunkTimeline: %w", err)
+		}
+	}
+
+	// The size of the deltas are changed
+	for i := timeline.barrierLeft; i <= timeline.barrierRight; i++ {
+		err = timeline.refreshSummarySize(tran, i)
+		if err != nil {
+			return fmt.Errorf("RewriteChunkTimeline: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("RewriteChunkTimeline: %w", err)
+	}
+	success = true
+	t.palette.commit(pendingEntries)
+	return nil
+}
+
+// RewritePlayerTimeline re-encodes all the values of the timeline of
+// the player whose identifier is id by the compression algorithm that
+// currently selected. It is the same as RewriteChunkTimeline, and the
+// deltas will be re-encoded by the dictionary if have.
+// If timeline is not exist, then do no operation.
+//
+// Time complexity: O(n).
+// n is the time point that this player have.
+func (t *TimelineDB) RewritePlayerTimeline(id string) error {
+	var success bool
+
+	timeline, err := t.NewPlayerTimeline(id, false)
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+	defer func() {
+		timeline.releaseFunc()
+	}()
+
+	if timeline.isEmpty {
+		return nil
+	}
+
+	tran, err := t.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+	bucket := tran.Bucket(DatabaseKeyPlayer)
+
+	// Global data
+	payload, err := t.codec.Encode(timeline.encodeGlobalData())
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+	err = bucket.Put(define.SumPlayer(id, []byte(define.KeyPlayerGlobalData)...), payload)
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+
+	// Latest Player
+	payload, err = marshal.PlayerBytes(timeline.latestPlayer, t.codec)
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+	err = bucket.Put(define.SumPlayer(id, []byte(define.KeyLatestPlayer)...), payload)
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+
+	// Each delta update
+	for i := timeline.barrierLeft; i <= timeline.barrierRight; i++ {
+		payload, err := getValue(bucket, define.IndexPlayerDu(id, i))
+		if err != nil {
+			return fmt.Errorf("RewritePlayerTimeline: %w", err)
+		}
+		if len(payload) == 0 {
+			continue
+		}
+
+		originBytes, err := t.codec.Decode(payload)
+		if err != nil {
+			return fmt.Errorf("RewritePlayerTimeline: %w", corruptError(err))
+		}
+		payload, err = t.codec.EncodeDelta(originBytes)
+		if err != nil {
+			return fmt.Errorf("RewritePlayerTimeline: %w", err)
+		}
+
+		err = bucket.Put(define.IndexPlayerDu(id, i), payload)
+		if err != nil {
+			return fmt.Errorf("RewritePlayerTimeline: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("RewritePlayerTimeline: %w", err)
+	}
+	success = true
+	return nil
+}
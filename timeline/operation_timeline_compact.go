@@ -30,7 +30,7 @@ func (s *ChunkTimeline) Compact() error {
 
 	for s.barrierRight-s.barrierLeft+1 > s.maxLimit {
 		if err := s.Pop(); err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Compact: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Compact: %w", err)
 		}
 	}
 
@@ -50,9 +50,9 @@ func (s *ChunkTimeline) Compact() error {
 	for {
 		index := s.ptr - s.barrierLeft
 
-		allTimePoint[index], _, _, _, err = s.next()
+		allTimePoint[index], _, _, _, _, _, err = s.next(true)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Compact: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Compact: %w", err)
 		}
 
 		if s.ptr == originPtr {
@@ -60,7 +60,7 @@ func (s *ChunkTimeline) Compact() error {
 		}
 	}
 
-	newBlockPalette := define.NewBlockPalette()
+	newBlockPalette := define.NewBlockPaletteWithRegistry(s.blockPalette.Registry())
 	newAllTimePoint := make([]define.ChunkMatrix, length)
 	for index := range newAllTimePoint {
 		newAllTimePoint[index] = make(define.ChunkMatrix, s.pos.Dimension.Height()>>4)
@@ -101,7 +101,7 @@ func (s *ChunkTimeline) Compact() error {
 
 	transaction, err := s.db.OpenTransaction()
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Compact: %v", err)
+		return fmt.Errorf("(s *ChunkTimeline) Compact: %w", err)
 	}
 
 	// Rollback prepare
@@ -130,10 +130,15 @@ func (s *ChunkTimeline) Compact() error {
 			transaction,
 		)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Compact: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Compact: %w", err)
 		}
 		s.latestChunk = value
 		s.barrierRight++
+
+		err = s.refreshSummarySize(transaction, s.barrierRight)
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Compact: %w", err)
+		}
 	}
 
 	s.blockPalette = newBlockPalette
@@ -0,0 +1,137 @@
+package timeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// RegisterCustomBlocks registers the custom block states (e.g. the
+// blocks that come from resource pack) to the timeline database,
+// and returns their block runtime IDs.
+//
+// The registered block states are saved into the underlying database,
+// and they are registered again when the database is opened, so the
+// chunks that contains custom blocks could be diffed, saved and restored
+// correctly, even if the database is opened on another machine.
+// They are only known by the block registry of this database (see
+// BlockRegistry), but not the other databases in the same process.
+//
+// The block runtime ID of a custom block is the hash of its name and
+// states, which is also the network runtime ID of this block, so it
+// is stable across different machines.
+// If the version of a block state is 0, then chunk.CurrentBlockVersion
+// is used.
+func (t *TimelineDB) RegisterCustomBlocks(states ...operator_define.BlockState) (blockRuntimeIDs []uint32, err error) {
+	var success bool
+
+	tran, err := t.OpenTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("RegisterCustomBlocks: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+
+	normalized := make([]operator_define.BlockState, 0, len(states))
+	for index, state := range states {
+		if len(state.Name) == 0 {
+			return nil, fmt.Errorf("RegisterCustomBlocks: The name of block state %d is empty", index)
+		}
+		if state.Properties == nil {
+			state.Properties = make(map[string]any)
+		}
+		if state.Version == 0 {
+			state.Version = chunk.CurrentBlockVersion
+		}
+		normalized = append(normalized, state)
+
+		buf := bytes.NewBuffer(nil)
+		utils.MarshalNBT(buf, map[string]any{
+			"name":    state.Name,
+			"states":  state.Properties,
+			"version": state.Version,
+		}, "")
+
+		key := binary.LittleEndian.AppendUint32(nil, block.ComputeBlockHash(state.Name, state.Properties))
+		err = tran.Bucket(DatabaseKeyCustomBlock).Put(key, buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("RegisterCustomBlocks: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return nil, fmt.Errorf("RegisterCustomBlocks: %w", err)
+	}
+	success = true
+
+	blockRuntimeIDs = make([]uint32, 0, len(normalized))
+	for _, state := range normalized {
+		blockRuntimeIDs = append(blockRuntimeIDs, t.blocks.Register(state))
+	}
+	return blockRuntimeIDs, nil
+}
+
+// CustomBlocks returns all the custom block states that
+// registered to this timeline database.
+func (t *TimelineDB) CustomBlocks() (states []operator_define.BlockState, err error) {
+	err = t.Bucket(DatabaseKeyCustomBlock).ForEach(func(key []byte, value []byte) error {
+		state, err := decodeCustomBlock(value)
+		if err != nil {
+			return err
+		}
+		states = append(states, state)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("CustomBlocks: %w", err)
+	}
+	return states, nil
+}
+
+// loadCustomBlocks registers the custom block states
+// that saved in db to registry.
+func loadCustomBlocks(db DB, registry *define.BlockRegistry) error {
+	err := db.Bucket(DatabaseKeyCustomBlock).ForEach(func(key []byte, value []byte) error {
+		state, err := decodeCustomBlock(value)
+		if err != nil {
+			return err
+		}
+		registry.Register(state)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("loadCustomBlocks: %w", err)
+	}
+	return nil
+}
+
+// decodeCustomBlock decodes the custom block state that saved as
+// little endian NBT by RegisterCustomBlocks.
+func decodeCustomBlock(payload []byte) (state operator_define.BlockState, err error) {
+	m, err := define.ReadNBT(bytes.NewBuffer(payload))
+	if err != nil {
+		return state, fmt.Errorf("decodeCustomBlock: %w", corruptError(err))
+	}
+
+	state.Name, _ = m["name"].(string)
+	state.Properties, _ = m["states"].(map[string]any)
+	state.Version, _ = m["version"].(int32)
+	if len(state.Name) == 0 {
+		return state, fmt.Errorf("decodeCustomBlock: %w: %w (block name is missing)", ErrCorrupt, define.ErrMalformed)
+	}
+	if state.Properties == nil {
+		state.Properties = make(map[string]any)
+	}
+
+	return state, nil
+}
@@ -0,0 +1,84 @@
+package timeline
+
+import (
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+func TestCustomBlocks(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := operator_define.BlockState{Name: "test:custom_block", Properties: map[string]any{"facing": int32(2)}}
+	if _, err = db.RegisterCustomBlocks(operator_define.BlockState{}); err == nil {
+		t.Fatal("RegisterCustomBlocks: expected an error for the empty block name")
+	}
+	blockRuntimeIDs, err := db.RegisterCustomBlocks(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, nbts := testChunk(t, 1)
+	c.SetBlock(5, 0, 5, 0, blockRuntimeIDs[0])
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Append(c, nbts, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states, err := db.CustomBlocks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(states) != 1 || states[0].Name != state.Name || states[0].Version != chunk.CurrentBlockVersion {
+		t.Fatalf("CustomBlocks: expected %v with the current block version, but got %v", state, states)
+	}
+	if states[0].Properties["facing"] != int32(2) {
+		t.Fatalf("CustomBlocks: expected the block states %v, but got %v", state.Properties, states[0].Properties)
+	}
+
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	result, _, _, _, err := tl.Last()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if blockRuntimeID := result.Block(5, 0, 5, 0); blockRuntimeID != blockRuntimeIDs[0] {
+		t.Fatalf("Last: expected the custom block %d, but got %d", blockRuntimeIDs[0], blockRuntimeID)
+	}
+	if name, _, found := tl.BlockRegistry().RuntimeIDToState(blockRuntimeIDs[0]); !found || name != state.Name {
+		t.Fatalf("RuntimeIDToState: expected %s, but got %s (found = %v)", state.Name, name, found)
+	}
+
+	// The custom blocks are only known by the database that registered them.
+	other, err := OpenWithDB(OpenMemoryDB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.CloseTimelineDB()
+	if _, found := other.BlockRegistry().ExtraBlockState(blockRuntimeIDs[0]); found {
+		t.Fatal("ExtraBlockState: the custom block of another database is found")
+	}
+	if _, _, found := block.RuntimeIDToState(blockRuntimeIDs[0]); found {
+		t.Fatal("block.RuntimeIDToState: the custom block is found by the whole process")
+	}
+}
@@ -0,0 +1,207 @@
+package timeline
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// loadDictionaries loads all the dictionaries that
+// saved in db and add them to codec. The dictionary
+// that db selected will also be set to codec.
+func loadDictionaries(db DB, codec *utils.Codec) error {
+	dictionaries := make(map[uint32]*utils.ZstdDictionary)
+
+	err := db.Bucket(DatabaseKeyDictionary).ForEach(func(key []byte, value []byte) error {
+		if len(key) != 4 || len(value) < 4 {
+			return fmt.Errorf("%w (dictionary %v is broken)", ErrCorrupt, key)
+		}
+
+		dictionary, err := utils.NewZstdDictionary(
+			binary.LittleEndian.Uint32(key),
+			append([]byte(nil), value[4:]...),
+			int(int32(binary.LittleEndian.Uint32(value))),
+		)
+		if err != nil {
+			return err
+		}
+
+		dictionaries[dictionary.Version()] = dictionary
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("loadDictionaries: %w", err)
+	}
+
+	for _, dictionary := range dictionaries {
+		codec.AddDictionary(dictionary)
+	}
+
+	currentVersion, err := getValue(db.Bucket(DatabaseKeyMeta), DatabaseKeyDictionary)
+	if err != nil {
+		return fmt.Errorf("loadDictionaries: %w", err)
+	}
+	if len(currentVersion) < 4 {
+		return nil
+	}
+
+	version := binary.LittleEndian.Uint32(currentVersion)
+	if version == 0 {
+		return nil
+	}
+	if dictionaries[version] == nil {
+		return fmt.Errorf("loadDictionaries: Selected dictionary %d is not found", version)
+	}
+
+	codec.SetDictionary(dictionaries[version])
+	return nil
+}
+
+// sampleDeltas is an internal implement detail.
+func (t *TimelineDB) sampleDeltas(maxSamples int) (samples [][]byte, err error) {
+	allPos := make([]define.DimChunk, 0)
+	err = t.ForEachChunkTimeline(func(pos define.DimChunk) error {
+		allPos = append(allPos, pos)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sampleDeltas: %w", err)
+	}
+
+	for _, pos := range allPos {
+		timeline, err := t.NewChunkTimeline(pos, true)
+		if err != nil {
+			return nil, fmt.Errorf("sampleDeltas: %w", err)
+		}
+
+		for i := timeline.barrierLeft; i <= timeline.barrierRight && !timeline.isEmpty; i++ {
+			for _, key := range [][]byte{define.IndexBlockDu(pos, i), define.IndexNBTDu(pos, i), define.IndexEntityDu(pos, i), define.IndexBiomeDu(pos, i)} {
+				if len(samples) >= maxSamples {
+					break
+				}
+
+				payload, err := getValue(t.DB, key)
+				if err != nil {
+					timeline.releaseFunc()
+					return nil, fmt.Errorf("sampleDeltas: %w", err)
+				}
+				if len(payload) == 0 {
+					continue
+				}
+
+				originBytes, err := t.codec.Decode(payload)
+				if err != nil {
+					timeline.releaseFunc()
+					return nil, fmt.Errorf("sampleDeltas: %w", corruptError(err))
+				}
+				samples = append(samples, originBytes)
+			}
+		}
+
+		timeline.releaseFunc()
+		if len(samples) >= maxSamples {
+			break
+		}
+	}
+
+	return samples, nil
+}
+
+// TrainDictionary trains a new zstd dictionary from the
+// deltas that already in this database, and then use it
+// to compress the new deltas.
+//
+// maxSamples is the max count of deltas that used to train,
+// maxSize is the max size of the dictionary, and level is the
+// zstd compression level (ranging from 1 to 22).
+//
+// The trained dictionary will be saved into the database with
+// a new version, and the dictionaries that trained before are
+// still kept so the old values could still be decoded.
+//
+// Time complexity: O(n).
+// n is the count of sampled deltas.
+func (t *TimelineDB) TrainDictionary(maxSamples int, maxSize int, level int) (version uint32, err error) {
+	samples, err := t.sampleDeltas(maxSamples)
+	if err != nil {
+		return 0, fmt.Errorf("TrainDictionary: %w", err)
+	}
+
+	err = t.Bucket(DatabaseKeyDictionary).ForEach(func(key []byte, value []byte) error {
+		if len(key) == 4 {
+			version = max(version, binary.LittleEndian.Uint32(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("TrainDictionary: %w", err)
+	}
+	version++
+
+	dictionary, err := utils.TrainZstdDictionary(samples, version, maxSize, level)
+	if err != nil {
+		return 0, fmt.Errorf("TrainDictionary: %w", err)
+	}
+
+	payload := make([]byte, 4+len(dictionary.Bytes()))
+	binary.LittleEndian.PutUint32(payload, uint32(int32(dictionary.Level())))
+	copy(payload[4:], dictionary.Bytes())
+
+	key := make([]byte, 4)
+	binary.LittleEndian.PutUint32(key, version)
+
+	err = t.Bucket(DatabaseKeyDictionary).Put(key, payload)
+	if err != nil {
+		return 0, fmt.Errorf("TrainDictionary: %w", err)
+	}
+	t.codec.AddDictionary(dictionary)
+
+	err = t.UseDictionary(version)
+	if err != nil {
+		return 0, fmt.Errorf("TrainDictionary: %w", err)
+	}
+
+	return version, nil
+}
+
+// UseDictionary selects the dictionary whose version is version
+// to compress the new deltas, and save this setting into the
+// underlying database.
+//
+// If version is 0, then the new deltas will not be compressed
+// by dictionary but the compression algorithm that selected.
+func (t *TimelineDB) UseDictionary(version uint32) error {
+	var dictionary *utils.ZstdDictionary
+
+	if version != 0 {
+		key := make([]byte, 4)
+		binary.LittleEndian.PutUint32(key, version)
+
+		payload, err := getValue(t.Bucket(DatabaseKeyDictionary), key)
+		if err != nil {
+			return fmt.Errorf("UseDictionary: %w", err)
+		}
+		if len(payload) < 4 {
+			return fmt.Errorf("UseDictionary: Dictionary %d is not found", version)
+		}
+
+		d, err := utils.NewZstdDictionary(version, payload[4:], int(int32(binary.LittleEndian.Uint32(payload))))
+		if err != nil {
+			return fmt.Errorf("UseDictionary: %w", err)
+		}
+		dictionary = d
+	}
+
+	versionBytes := make([]byte, 4)
+	binary.LittleEndian.PutUint32(versionBytes, version)
+
+	err := t.Bucket(DatabaseKeyMeta).Put(DatabaseKeyDictionary, versionBytes)
+	if err != nil {
+		return fmt.Errorf("UseDictionary: %w", err)
+	}
+
+	t.codec.SetDictionary(dictionary)
+	return nil
+}
@@ -0,0 +1,355 @@
+package timeline
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-world-operator/block"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+)
+
+// BlockDiff is a single changed block in ChunkDiff.
+type BlockDiff struct {
+	// X, Y and Z is the position of this block,
+	// where X and Z are the relative coordinates
+	// to this chunk, and Y is the world height.
+	X     uint8
+	Y     int16
+	Z     uint8
+	Layer uint8
+	// OldBlock and NewBlock are the block runtime
+	// ID of this block before and after the change.
+	OldBlock uint32
+	NewBlock uint32
+}
+
+// NBTDiff is a single changed block NBT in ChunkDiff.
+type NBTDiff struct {
+	// X, Y and Z is the position of this block,
+	// just like the one in BlockDiff.
+	X uint8
+	Y int16
+	Z uint8
+	// OldNBT and NewNBT are the block NBT before
+	// and after the change, and nil means missing.
+	OldNBT map[string]any
+	NewNBT map[string]any
+}
+
+// ChunkDiff is the difference of a chunk between two states,
+// which returned by DiffBetween and DiffLatest.
+type ChunkDiff struct {
+	// Blocks is sorted by the sub chunk, the layer
+	// and then the position in the sub chunk.
+	Blocks []BlockDiff
+	// AddedNBT, RemovedNBT and ModifiedNBT are sorted by
+	// their position. The OldNBT of the added ones and the
+	// NewNBT of the removed ones are always nil.
+	AddedNBT    []NBTDiff
+	RemovedNBT  []NBTDiff
+	ModifiedNBT []NBTDiff
+}
+
+// layerChanges is the composed block changes of
+// a single layer, which used by DiffBetween.
+type layerChanges struct {
+	// cleared is true if this layer was cleared by
+	// a block delta (see define.LayerRestore), and
+	// then the blocks that not in changed are air.
+	cleared bool
+	changed [define.MatrixSize]bool
+	blocks  [define.MatrixSize]uint32
+}
+
+// DiffBetween returns the difference of this chunk between the time
+// point i and j, which are the index of the time points just like the
+// one used by JumpTo. If i is bigger than j, then the returned one is
+// the difference from the newer to the older.
+//
+// Only the time point min(i, j) is restored as chunk matrix, and the
+// block deltas after it are composed to the changed blocks directly,
+// so the full chunks are never built.
+// DiffBetween don't move the pointer of this timeline.
+//
+// Time complexity: O(4096×n×m + C).
+//   - n is the sub chunk count of this chunk.
+//   - m is the average layer count of each sub chunk.
+//   - C is relevant to the changes of all the time points until max(i, j).
+func (s *ChunkTimeline) DiffBetween(i uint, j uint) (result *ChunkDiff, err error) {
+	if s.isEmpty {
+		return nil, fmt.Errorf("(s *ChunkTimeline) DiffBetween: %w", ErrEmpty)
+	}
+
+	older, newer := min(i, j), max(i, j)
+	if s.barrierLeft+newer > s.barrierRight {
+		return nil, fmt.Errorf("(s *ChunkTimeline) DiffBetween: %w (index %d is out of index %d)", ErrOutOfRange, newer, s.barrierRight-s.barrierLeft)
+	}
+
+	r := s.pos.Dimension.Range()
+	base := make(define.ChunkMatrix, (r.Height()>>4)+1)
+	changes := make([][]*layerChanges, len(base))
+	layersLen := make([]int, len(base))
+
+	var currentNBT, baseNBT []define.NBTWithIndex
+	for index := s.barrierLeft; index <= s.barrierLeft+newer; index++ {
+		// Blocks
+		var diff define.ChunkDiffMatrix
+		payload, err := getValue(s.db, define.IndexBlockDu(s.pos, index))
+		if err == nil {
+			diff, err = s.decodeBlockDelta(payload)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) DiffBetween: %w", s.blockDeltaError(index, err))
+		}
+
+		if index <= s.barrierLeft+older {
+			base = define.ChunkRestore(base, diff)
+			for subChunkIndex, layers := range base {
+				layersLen[subChunkIndex] = len(layers)
+			}
+		} else {
+			for subChunkIndex, layersDiff := range diff {
+				changes[subChunkIndex] = composeLayersDiff(changes[subChunkIndex], layersLen[subChunkIndex], layersDiff)
+				layersLen[subChunkIndex] = len(layersDiff)
+			}
+		}
+
+		// NBTs
+		currentNBT, err = s.restoreNBT(s.db, index, currentNBT, s.bestEffort)
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) DiffBetween: %w", err)
+		}
+		if index == s.barrierLeft+older {
+			baseNBT = currentNBT
+		}
+	}
+
+	result = new(ChunkDiff)
+	for subChunkIndex, layers := range changes {
+		for layer, lc := range layers {
+			if lc == nil {
+				continue
+			}
+			oldMatrix := base[subChunkIndex].Layer(layer)
+
+			for ptr := range define.MatrixSize {
+				if !lc.changed[ptr] && !lc.cleared {
+					continue
+				}
+
+				var oldID, newID uint32
+				if !define.BlockMatrixIsEmpty(oldMatrix) {
+					oldID = oldMatrix[ptr]
+				}
+				if lc.changed[ptr] {
+					newID = lc.blocks[ptr]
+				}
+
+				oldBlock, newBlock := s.blockPalette.BlockRuntimeID(oldID), s.blockPalette.BlockRuntimeID(newID)
+				if oldBlock != newBlock {
+					result.appendBlock(r[0], subChunkIndex, layer, ptr, oldBlock, newBlock)
+				}
+			}
+		}
+	}
+	result.compareNBT(baseNBT, currentNBT)
+
+	if i > j {
+		result.reverse()
+	}
+	return result, nil
+}
+
+// DiffLatest returns the difference between the latest time point of
+// this timeline and c, which could be a live chunk that not appended.
+// nbts is the block NBTs of c.
+//
+// The sub chunks whose content hash is the same as the latest one (see
+// SubChunkHashes) are skipped, and c is not changed or appended.
+//
+// Time complexity: O(4096×n×m + C).
+//   - n is the sub chunk count of the changed sub chunks.
+//   - m is the average layer count of these sub chunks.
+//   - C is relevant to the count of the block NBTs.
+func (s *ChunkTimeline) DiffLatest(c *chunk.Chunk, nbts []map[string]any) (result *ChunkDiff, err error) {
+	if s.isEmpty {
+		return nil, fmt.Errorf("(s *ChunkTimeline) DiffLatest: %w", ErrEmpty)
+	}
+	if len(c.Sub()) != len(s.latestChunk) {
+		return nil, fmt.Errorf("(s *ChunkTimeline) DiffLatest: %w (chunk have %d sub chunks but expected %d)", ErrOutOfRange, len(c.Sub()), len(s.latestChunk))
+	}
+
+	r := s.pos.Dimension.Range()
+	newerNBTs := define.FromChunkNBT(s.pos.ChunkPos, nbts)
+	hashes := define.HashChunk(c, newerNBTs)
+
+	result = new(ChunkDiff)
+	for subChunkIndex, subChunk := range c.Sub() {
+		if s.subChunkUnchanged(subChunkIndex, hashes[subChunkIndex]) {
+			continue
+		}
+
+		layers := s.latestChunk[subChunkIndex]
+		for layer := range max(len(layers), len(subChunk.Layers())) {
+			var oldMatrix define.BlockMatrix
+			if layer < len(layers) {
+				oldMatrix = layers[layer]
+			}
+			var newLayer *chunk.PalettedStorage
+			if layer < len(subChunk.Layers()) {
+				newLayer = subChunk.Layers()[layer]
+			}
+
+			ptr := 0
+			for x := range uint8(16) {
+				for y := range uint8(16) {
+					for z := range uint8(16) {
+						oldBlock, newBlock := block.AirRuntimeID, block.AirRuntimeID
+						if !define.BlockMatrixIsEmpty(oldMatrix) {
+							oldBlock = s.blockPalette.BlockRuntimeID(oldMatrix[ptr])
+						}
+						if newLayer != nil {
+							newBlock = newLayer.At(x, y, z)
+						}
+						if oldBlock != newBlock {
+							result.appendBlock(r[0], subChunkIndex, layer, ptr, oldBlock, newBlock)
+						}
+						ptr++
+					}
+				}
+			}
+		}
+	}
+
+	olderNBTs, err := define.NBTDeepCopy(s.latestNBT)
+	if err != nil {
+		return nil, fmt.Errorf("(s *ChunkTimeline) DiffLatest: %w", err)
+	}
+	result.compareNBT(olderNBTs, newerNBTs)
+
+	return result, nil
+}
+
+// composeLayersDiff applies the block delta of a sub chunk, which is
+// layersDiff, to the composed changes of this sub chunk, and returns
+// the new one. layersLen is the layer count of this sub chunk before
+// this block delta.
+func composeLayersDiff(changes []*layerChanges, layersLen int, layersDiff define.LayersDiff) []*layerChanges {
+	for len(changes) < max(layersLen, len(layersDiff)) {
+		changes = append(changes, nil)
+	}
+
+	for layer := range changes {
+		if layer >= len(layersDiff) {
+			// The layers that not in the block delta
+			// are cleared (see define.LayerRestore).
+			if layer < layersLen {
+				changes[layer] = &layerChanges{cleared: true}
+			}
+			continue
+		}
+
+		diff := layersDiff[layer]
+		if len(diff) == 0 {
+			continue
+		}
+		if changes[layer] == nil {
+			changes[layer] = new(layerChanges)
+		}
+		lc := changes[layer]
+
+		index := uint32(0)
+		for _, value := range diff {
+			index += value.IndexDelta
+			for ptr := index; ptr <= index+value.RunLength; ptr++ {
+				lc.changed[ptr] = true
+				lc.blocks[ptr] = value.NewPaletteID
+			}
+			index += value.RunLength
+		}
+	}
+
+	return changes
+}
+
+// appendBlock appends a changed block to d. minY is the min
+// height of this chunk, and ptr is the index of this block
+// in the block matrix of this layer.
+func (d *ChunkDiff) appendBlock(minY int, subChunkIndex int, layer int, ptr int, oldBlock uint32, newBlock uint32) {
+	blockIndex := define.BlockIndex(ptr)
+	d.Blocks = append(d.Blocks, BlockDiff{
+		X:        blockIndex.X(),
+		Y:        int16((subChunkIndex+minY>>4)<<4 + int(blockIndex.Y())),
+		Z:        blockIndex.Z(),
+		Layer:    uint8(layer),
+		OldBlock: oldBlock,
+		NewBlock: newBlock,
+	})
+}
+
+// compareNBT compares the block NBTs older and newer,
+// and appends the added, removed and modified ones to d.
+func (d *ChunkDiff) compareNBT(older []define.NBTWithIndex, newer []define.NBTWithIndex) {
+	olderSet := make(map[define.ChunkBlockIndex]map[string]any)
+	newerSet := make(map[define.ChunkBlockIndex]map[string]any)
+	indexes := make([]define.ChunkBlockIndex, 0, len(older)+len(newer))
+
+	for _, value := range older {
+		olderSet[value.Index] = value.NBT
+		indexes = append(indexes, value.Index)
+	}
+	for _, value := range newer {
+		if _, ok := olderSet[value.Index]; !ok {
+			indexes = append(indexes, value.Index)
+		}
+		newerSet[value.Index] = value.NBT
+	}
+
+	slices.SortFunc(indexes, func(a define.ChunkBlockIndex, b define.ChunkBlockIndex) int {
+		if a.Y() != b.Y() {
+			return int(a.Y()) - int(b.Y())
+		}
+		if a.X() != b.X() {
+			return int(a.X()) - int(b.X())
+		}
+		return int(a.Z()) - int(b.Z())
+	})
+	indexes = slices.Compact(indexes)
+
+	for _, index := range indexes {
+		oldNBT, newNBT := olderSet[index], newerSet[index]
+		nbtDiff := NBTDiff{
+			X:      index.X(),
+			Y:      index.Y(),
+			Z:      index.Z(),
+			OldNBT: oldNBT,
+			NewNBT: newNBT,
+		}
+
+		switch {
+		case oldNBT == nil:
+			d.AddedNBT = append(d.AddedNBT, nbtDiff)
+		case newNBT == nil:
+			d.RemovedNBT = append(d.RemovedNBT, nbtDiff)
+		case !reflect.DeepEqual(oldNBT, newNBT):
+			d.ModifiedNBT = append(d.ModifiedNBT, nbtDiff)
+		}
+	}
+}
+
+// reverse swaps the old one and the new one of d.
+func (d *ChunkDiff) reverse() {
+	for index := range d.Blocks {
+		value := &d.Blocks[index]
+		value.OldBlock, value.NewBlock = value.NewBlock, value.OldBlock
+	}
+	for _, nbts := range [][]NBTDiff{d.AddedNBT, d.RemovedNBT, d.ModifiedNBT} {
+		for index := range nbts {
+			value := &nbts[index]
+			value.OldNBT, value.NewNBT = value.NewNBT, value.OldNBT
+		}
+	}
+	d.AddedNBT, d.RemovedNBT = d.RemovedNBT, d.AddedNBT
+}
@@ -0,0 +1,71 @@
+package timeline
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDiffBetween(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 3, 2)
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	air, gold := testRuntimeID(t, "minecraft:air"), testRuntimeID(t, "minecraft:gold_block")
+
+	for _, expected := range []struct {
+		i, j     uint
+		block    BlockDiff
+		old, new byte
+	}{
+		{0, 2, BlockDiff{X: 1, Y: -40, OldBlock: air, NewBlock: gold}, 1, 2},
+		{2, 0, BlockDiff{X: 1, Y: -40, OldBlock: gold, NewBlock: air}, 2, 1},
+		{1, 2, BlockDiff{X: 2, Y: -40, OldBlock: gold, NewBlock: air}, 3, 2},
+	} {
+		diff, err := tl.DiffBetween(expected.i, expected.j)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(diff.Blocks) != 1 || diff.Blocks[0] != expected.block {
+			t.Fatalf("DiffBetween(%d, %d): expected block change %+v, but got %+v", expected.i, expected.j, expected.block, diff.Blocks)
+		}
+		if len(diff.AddedNBT) != 0 || len(diff.RemovedNBT) != 0 || len(diff.ModifiedNBT) != 1 {
+			t.Fatalf("DiffBetween(%d, %d): expected only the chest is modified, but got %+v", expected.i, expected.j, diff)
+		}
+		nbt := diff.ModifiedNBT[0]
+		if testChestCount(t, []map[string]any{nbt.OldNBT}) != expected.old || testChestCount(t, []map[string]any{nbt.NewNBT}) != expected.new {
+			t.Fatalf("DiffBetween(%d, %d): got chest change %+v", expected.i, expected.j, nbt)
+		}
+	}
+
+	diff, err := tl.DiffBetween(1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Blocks) != 0 || len(diff.ModifiedNBT) != 0 {
+		t.Fatalf("DiffBetween(1, 1): expected no change, but got %+v", diff)
+	}
+	if _, err = tl.DiffBetween(0, 3); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("DiffBetween(0, 3): expected ErrOutOfRange, but got %v", err)
+	}
+
+	// The live chunk that not appended
+	c, nbts := testChunk(t, 4)
+	diff, err = tl.DiffLatest(c, nbts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Blocks) != 2 || diff.Blocks[0].X != 2 || diff.Blocks[1].X != 3 || diff.Blocks[1].NewBlock != gold {
+		t.Fatalf("DiffLatest: expected 2 gold blocks are placed, but got %+v", diff.Blocks)
+	}
+	if len(diff.ModifiedNBT) != 1 || testChestCount(t, []map[string]any{diff.ModifiedNBT[0].NewNBT}) != 4 {
+		t.Fatalf("DiffLatest: expected the chest is modified, but got %+v", diff.ModifiedNBT)
+	}
+}
@@ -0,0 +1,26 @@
+package timeline
+
+import "fmt"
+
+// RotateEncryptionKey re-encrypts all the values in the
+// database by the key that currently provided by the key
+// provider, so the old keys could be dropped after that.
+//
+// It's unsafe to modify any timeline when rotating.
+// If the database is not encrypted, then returns an error.
+//
+// Time complexity: O(n).
+// n is the count of values in the database.
+func (t *TimelineDB) RotateEncryptionKey() error {
+	edb, ok := t.DB.(*encryptedDatabase)
+	if !ok {
+		return fmt.Errorf("RotateEncryptionKey: Database is not encrypted")
+	}
+
+	err := edb.Rotate(databaseBuckets...)
+	if err != nil {
+		return fmt.Errorf("RotateEncryptionKey: %w", err)
+	}
+
+	return nil
+}
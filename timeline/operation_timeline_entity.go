@@ -0,0 +1,81 @@
+package timeline
+
+import (
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// "appendEntities" is an internal implement detail.
+func (s *ChunkTimeline) appendEntities(
+	newerEntities []define.EntityWithID,
+	entityDiff define.MultipleDiffEntity,
+	transaction Transaction,
+) error {
+	// Put delta update
+	payload, err := marshal.MultipleDiffEntityBytes(entityDiff, s.codec)
+	if err != nil {
+		return fmt.Errorf("appendEntities: %w", err)
+	}
+	err = transaction.Put(
+		define.IndexEntityDu(s.pos, s.barrierRight+1),
+		payload,
+	)
+	if err != nil {
+		return fmt.Errorf("appendEntities: %w", err)
+	}
+
+	// Update Latest Entities
+	payload, err = marshal.EntitiesBytes(newerEntities, s.codec)
+	if err != nil {
+		return fmt.Errorf("appendEntities: %w", err)
+	}
+	err = transaction.Put(
+		define.Sum(s.pos, []byte(define.KeyLatestEntity)...),
+		payload,
+	)
+	if err != nil {
+		return fmt.Errorf("appendEntities: %w", err)
+	}
+
+	return nil
+}
+
+// restoreEntities computes the entities of the time point whose key index
+// is keyIndex, by given the entities of the previous time point (current).
+// The entity delta is read from reader.
+//
+// If bestEffort is true, then the broken entity delta is ignored,
+// and the entities of the previous time point are returned.
+func (s *ChunkTimeline) restoreEntities(
+	reader DatabaseOperation, keyIndex uint,
+	current []define.EntityWithID, bestEffort bool,
+) (result []define.EntityWithID, err error) {
+	var diff define.MultipleDiffEntity
+	payload, err := getValue(reader, define.IndexEntityDu(s.pos, keyIndex))
+	if err == nil {
+		diff, err = marshal.BytesToMultipleDiffEntity(payload, s.codec)
+	}
+	if err == nil {
+		result, err = define.EntityRestore(current, diff)
+	}
+	if err != nil {
+		if bestEffort {
+			s.ignoredDeltas++
+			return define.EntityDeepCopy(current)
+		}
+		return nil, fmt.Errorf("restoreEntities: %w", s.entityDeltaError(keyIndex, err))
+	}
+	return result, nil
+}
+
+// entityDeltaError wraps err that occurred when restoring the
+// entities of the time point whose key index is keyIndex, so
+// the returned error could report where the broken payload is.
+func (s *ChunkTimeline) entityDeltaError(keyIndex uint, err error) error {
+	return fmt.Errorf(
+		"Entity delta of chunk (%d, %d) in dim %d at time index %d is broken: %w",
+		s.pos.ChunkPos[0], s.pos.ChunkPos[1], s.pos.Dimension, keyIndex-s.barrierLeft, corruptError(err),
+	)
+}
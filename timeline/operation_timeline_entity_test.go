@@ -0,0 +1,104 @@
+package timeline
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+)
+
+// testEntities returns the entities of each time point that
+// used by the tests. The zombie is moved at the second time
+// point, and the cow is replaced by a painting that have no
+// unique ID. At last, all the entities are removed.
+func testEntities() [][]map[string]any {
+	zombie := func(x float32) map[string]any {
+		return map[string]any{"identifier": "minecraft:zombie", "UniqueID": int64(1), "Pos": []any{x, float32(-40), float32(2)}}
+	}
+	return [][]map[string]any{
+		{zombie(1), {"identifier": "minecraft:cow", "UniqueID": int64(2), "Pos": []any{float32(3), float32(-40), float32(3)}}},
+		{zombie(5), {"identifier": "minecraft:painting", "Motive": "Kebab"}},
+		nil,
+	}
+}
+
+// testEqualEntities reports whether
+// a and b have the same entities.
+func testEqualEntities(a []map[string]any, b []map[string]any) bool {
+	key := func(entity map[string]any) string { return entity["identifier"].(string) }
+	sorted := func(entities []map[string]any) []map[string]any {
+		return slices.SortedFunc(slices.Values(entities), func(x, y map[string]any) int {
+			return strings.Compare(key(x), key(y))
+		})
+	}
+	return len(a) == len(b) && reflect.DeepEqual(sorted(a), sorted(b))
+}
+
+func TestEntityTimeline(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, nbts := testChunk(t, 1)
+	for _, entities := range testEntities() {
+		if err = tl.Append(c, nbts, entities, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Only the entities are changed, so the
+	// time points are not skipped by NOPWhenNoChange
+	if tl.AllTimePointLen() != 3 {
+		t.Fatalf("expected 3 time points, but got %d", tl.AllTimePointLen())
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, expected := range testEntities() {
+		_, _, entities, _, err := tl.JumpTo(uint(index))
+		if err != nil {
+			t.Fatalf("JumpTo: %v", err)
+		}
+		if !testEqualEntities(entities, expected) {
+			t.Fatalf("JumpTo: expected entities %v at time point %d, but got %v", expected, index, entities)
+		}
+	}
+	_ = tl.Save()
+
+	// A broken entity delta
+	err = raw.Put(define.IndexEntityDu(testPos, tl.barrierLeft+1), []byte{0xff, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	_, _, _, _, err = tl.JumpTo(1)
+	if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "Entity delta") {
+		t.Fatalf("JumpTo: expected a broken entity delta, but got %v", err)
+	}
+	tl.SetBestEffort(true)
+	_, _, entities, _, err := tl.JumpTo(1)
+	if err != nil {
+		t.Fatalf("JumpTo: %v", err)
+	}
+	if !testEqualEntities(entities, testEntities()[0]) || tl.IgnoredDeltas() != 1 {
+		t.Fatalf("JumpTo: expected the entities of the previous time point, but got %v (%d ignored)", entities, tl.IgnoredDeltas())
+	}
+}
@@ -0,0 +1,116 @@
+package timeline
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+)
+
+// SubChunkHashes returns the content hash of each sub chunk of the
+// latest time point of this timeline (see define.HashChunk), which
+// covers both the blocks and the block NBTs in this sub chunk.
+//
+// You can compare them with the one that computed by define.HashChunk
+// to know which sub chunks are changed. For an empty timeline, the
+// returned hashes are the hashes of the sub chunks that full of air.
+func (s *ChunkTimeline) SubChunkHashes() []uint64 {
+	return slices.Clone(s.latestHash)
+}
+
+// SubChunkHashes returns the content hash of each sub chunk of the
+// latest time point of the chunk who at pos, without loading the
+// whole timeline. If timeline is not exist, then return nil.
+//
+// For the timelines that saved by older versions, the hashes are
+// computed by loading the timeline as read only, and they will be
+// saved when the timeline is saved or rewritten next time.
+func (t *TimelineDB) SubChunkHashes(pos define.DimChunk) (hashes []uint64, err error) {
+	payload, err := getValue(t.DB, define.Sum(pos, []byte(define.KeyLatestHash)...))
+	if err != nil {
+		return nil, fmt.Errorf("SubChunkHashes: %w", err)
+	}
+	hashes, ok := decodeSubChunkHashes(payload, pos.Dimension.Height()>>4)
+	if ok {
+		return hashes, nil
+	}
+	if !t.HasChunkTimeline(pos) {
+		return nil, nil
+	}
+
+	timeline, err := t.NewChunkTimeline(pos, true)
+	if err != nil {
+		return nil, fmt.Errorf("SubChunkHashes: %w", err)
+	}
+	defer timeline.releaseFunc()
+
+	return timeline.SubChunkHashes(), nil
+}
+
+// loadSubChunkHashes loads the hashes of the latest time point of this
+// timeline. If not exist (or broken), then they are computed again.
+// If they can't be read (e.g. failed to be decrypted), then returns
+// an error that matches ErrCorrupt.
+func (s *ChunkTimeline) loadSubChunkHashes() error {
+	payload, err := getValue(s.db, define.Sum(s.pos, []byte(define.KeyLatestHash)...))
+	if err != nil {
+		return fmt.Errorf("loadSubChunkHashes: %w", err)
+	}
+
+	hashes, ok := decodeSubChunkHashes(payload, len(s.latestChunk))
+	if !ok {
+		hashes = define.HashChunkMatrix(s.latestChunk, s.pos.Dimension.Range(), s.blockPalette, s.latestNBT)
+	}
+	s.latestHash = hashes
+	return nil
+}
+
+// putSubChunkHashes writes hashes as the hashes
+// of the latest time point of this timeline by tran.
+func (s *ChunkTimeline) putSubChunkHashes(tran Transaction, hashes []uint64) error {
+	payload := make([]byte, 0, len(hashes)*8)
+	for _, value := range hashes {
+		payload = binary.LittleEndian.AppendUint64(payload, value)
+	}
+
+	err := tran.Put(define.Sum(s.pos, []byte(define.KeyLatestHash)...), payload)
+	if err != nil {
+		return fmt.Errorf("putSubChunkHashes: %w", err)
+	}
+	return nil
+}
+
+// decodeSubChunkHashes decodes the hashes of count sub chunks
+// from payload. ok is false if payload is not match.
+func decodeSubChunkHashes(payload []byte, count int) (hashes []uint64, ok bool) {
+	if count == 0 || len(payload) != count*8 {
+		return nil, false
+	}
+
+	hashes = make([]uint64, 0, count)
+	for ; len(payload) > 0; payload = payload[8:] {
+		hashes = append(hashes, binary.LittleEndian.Uint64(payload))
+	}
+	return hashes, true
+}
+
+// subChunkUnchanged reports whether the sub chunk at index is not
+// changed compared with the latest time point, by its new hash.
+func (s *ChunkTimeline) subChunkUnchanged(index int, hash uint64) bool {
+	return index < len(s.latestHash) && s.latestHash[index] == hash
+}
+
+// changedNBTs returns the NBTs in nbts that in the sub
+// chunks whose hash in hashes is changed, so the NBTs
+// in the unchanged sub chunks are not diffed again.
+func (s *ChunkTimeline) changedNBTs(nbts []define.NBTWithIndex, hashes []uint64) (result []define.NBTWithIndex) {
+	r := s.pos.Dimension.Range()
+	for _, value := range nbts {
+		index := define.SubChunkIndexOf(value.Index.Y(), r)
+		if index >= len(hashes) || !s.subChunkUnchanged(index, hashes[index]) {
+			result = append(result, value)
+		}
+	}
+	return
+}
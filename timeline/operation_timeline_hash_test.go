@@ -0,0 +1,52 @@
+package timeline
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-world-operator/chunk"
+)
+
+func TestAppendUnchangedHashes(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1)
+
+	hashes, err := db.SubChunkHashes(testPos)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, nbts := testChunk(t, 1)
+	if !slices.Equal(hashes, define.HashChunk(c, define.FromChunkNBT(testPos.ChunkPos, nbts))) {
+		t.Fatalf("SubChunkHashes: got %v", hashes)
+	}
+
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Append(c, nbts, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.AppendSubChunks(map[int16]*chunk.SubChunk{-3: c.Sub()[1]}, nbts, true); err != nil {
+		t.Fatal(err)
+	}
+	if tl.AllTimePointLen() != 1 {
+		t.Fatalf("expected unchanged chunk is skipped, but got %d time points", tl.AllTimePointLen())
+	}
+
+	// Changed block NBT also changes the hash
+	nbts[0]["Items"] = []any{map[string]any{"Name": "minecraft:apple", "Count": byte(2)}}
+	if err = tl.Append(c, nbts, nil, true); err != nil {
+		t.Fatal(err)
+	}
+	if tl.AllTimePointLen() != 2 || slices.Equal(tl.SubChunkHashes(), hashes) {
+		t.Fatal("expected the time point of changed block NBT is appended")
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
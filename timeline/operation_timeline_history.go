@@ -0,0 +1,211 @@
+package timeline
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// BlockChange is the change of a single block
+// at a time point, which returned by BlockHistory.
+type BlockChange struct {
+	// TimePoint is the index of the time point that this
+	// change happened, which could be used by JumpTo.
+	TimePoint      uint
+	UpdateUnixTime int64
+	// OldBlocks and NewBlocks are the block runtime ID
+	// of each layer of this block before and after this
+	// time point. They always have the same length.
+	OldBlocks []uint32
+	NewBlocks []uint32
+	// OldNBT and NewNBT are the block NBT of this block
+	// before and after this time point, and nil means
+	// this block have no NBT.
+	OldNBT map[string]any
+	NewNBT map[string]any
+}
+
+// BlockHistory returns all the changes of the block at (x, y, z) of this
+// timeline, from the earliest time point to the latest one. x and z are
+// the relative coordinates to this chunk, and y is the world height.
+//
+// Only the block deltas of the sub chunk that this block in are decoded,
+// and the full chunks are never built, so BlockHistory is much faster than
+// restoring each time point by Next.
+//
+// The first time point is compared with an empty chunk (full of air), so
+// a non-air block or a block NBT at the first time point is also returned
+// as a change.
+// BlockHistory don't move the pointer of this timeline.
+//
+// Time complexity: O(n×(S+C)).
+//   - n is the count of time points.
+//   - S is relevant to the size of each block delta.
+//   - C is relevant to the changes of the block NBT of this block.
+func (s *ChunkTimeline) BlockHistory(x uint8, y int16, z uint8) (changes []BlockChange, err error) {
+	if s.isEmpty {
+		return nil, fmt.Errorf("(s *ChunkTimeline) BlockHistory: %w", ErrEmpty)
+	}
+
+	r := s.pos.Dimension.Range()
+	if x > 15 || z > 15 || int(y) < r[0] || int(y) > r[1] {
+		return nil, fmt.Errorf("(s *ChunkTimeline) BlockHistory: %w (block (%d, %d, %d) is not in this chunk)", ErrOutOfRange, x, y, z)
+	}
+
+	var target define.ChunkBlockIndex
+	target.UpdateIndex(x, y, z)
+
+	var blockIndex define.BlockIndex
+	blockIndex.UpdateIndex(x, uint8(y&15), z)
+
+	subChunkIndex := define.SubChunkIndexOf(y, r)
+	blocks := []uint32{0}
+	var currentNBT map[string]any
+
+	for i := s.barrierLeft; i <= s.barrierRight; i++ {
+		// Blocks
+		var layersDiff define.LayersDiff
+		payload, err := getValue(s.db, define.IndexBlockDu(s.pos, i))
+		if err == nil {
+			layersDiff, err = marshal.BytesToSubChunkDiff(payload, subChunkIndex, s.codec)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) BlockHistory: %w", s.blockDeltaError(i, err))
+		}
+		for _, diffMatrix := range layersDiff {
+			define.RemapDiffMatrix(diffMatrix, s.paletteRemap)
+		}
+
+		oldBlocks := slices.Clone(blocks)
+		for len(oldBlocks) < len(layersDiff) {
+			oldBlocks = append(oldBlocks, 0)
+		}
+		newBlocks := make([]uint32, len(oldBlocks))
+		for layer := range newBlocks {
+			// The layers that not in the block delta
+			// are cleared (see define.LayerRestore).
+			if layer >= len(layersDiff) {
+				continue
+			}
+			newPaletteID, changed := define.DiffMatrixBlock(layersDiff[layer], uint32(blockIndex))
+			if !changed {
+				newPaletteID = oldBlocks[layer]
+			}
+			if int(newPaletteID) > s.blockPalette.BlockPaletteLen() {
+				return nil, fmt.Errorf(
+					"(s *ChunkTimeline) BlockHistory: %w",
+					s.blockDeltaError(i, fmt.Errorf("%w (block palette index %d is out of range)", define.ErrMalformed, newPaletteID)),
+				)
+			}
+			newBlocks[layer] = newPaletteID
+		}
+
+		// NBTs
+		newNBT, nbtChanged, err := s.blockNBTAt(i, target, currentNBT)
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) BlockHistory: %w", err)
+		}
+
+		if nbtChanged || !slices.Equal(oldBlocks, newBlocks) {
+			change := BlockChange{
+				TimePoint:      i - s.barrierLeft,
+				UpdateUnixTime: s.timelineUnixTime[i-s.barrierLeft],
+				OldBlocks:      make([]uint32, len(oldBlocks)),
+				NewBlocks:      make([]uint32, len(newBlocks)),
+				OldNBT:         currentNBT,
+				NewNBT:         newNBT,
+			}
+			for layer := range oldBlocks {
+				change.OldBlocks[layer] = s.blockPalette.BlockRuntimeID(oldBlocks[layer])
+				change.NewBlocks[layer] = s.blockPalette.BlockRuntimeID(newBlocks[layer])
+			}
+			changes = append(changes, change)
+		}
+
+		blocks = newBlocks
+		currentNBT = newNBT
+	}
+
+	return changes, nil
+}
+
+// blockNBTAt applies the NBT delta of the time point whose key index is
+// keyIndex to the block NBT at target, whose old NBT is old (nil means
+// have no NBT), and returns the new one.
+// changed is false if the NBT at target is not changed at this time point.
+//
+// If this time point has a full block NBT snapshot, then the NBT at target
+// is read from it directly. In best effort mode, the NBT that failed to
+// restore is treated as removed.
+func (s *ChunkTimeline) blockNBTAt(keyIndex uint, target define.ChunkBlockIndex, old map[string]any) (
+	result map[string]any, changed bool, err error,
+) {
+	if s.hasNBTSnapshot(s.db, keyIndex) {
+		var nbts []define.NBTWithIndex
+		payload, err := getValue(s.db, define.IndexNBTSnapshot(s.pos, keyIndex))
+		if err == nil {
+			nbts, err = marshal.BytesToNBTSnapshot(payload, s.codec)
+		}
+		if err == nil {
+			for _, value := range nbts {
+				if value.Index == target {
+					result = value.NBT
+				}
+			}
+			return result, !reflect.DeepEqual(result, old), nil
+		}
+		if !s.bestEffort {
+			return nil, false, fmt.Errorf("blockNBTAt: %w", s.nbtSnapshotError(keyIndex, err))
+		}
+		s.ignoredDeltas++
+	}
+
+	var diff define.MultipleDiffNBT
+	payload, err := getValue(s.db, define.IndexNBTDu(s.pos, keyIndex))
+	if err == nil {
+		diff, err = marshal.BytesToMultipleDiffNBT(payload, s.codec)
+	}
+	if err != nil {
+		if s.bestEffort {
+			s.ignoredDeltas++
+			return old, false, nil
+		}
+		return nil, false, fmt.Errorf("blockNBTAt: %w", s.nbtDeltaError(keyIndex, err))
+	}
+
+	result, changed = old, false
+	if slices.Contains(diff.Removed, target) {
+		result, changed = nil, true
+	}
+	for _, value := range diff.Added {
+		if value.Index == target {
+			result, changed = value.NBT, true
+		}
+	}
+	for _, value := range diff.Modified {
+		if value.Index != target {
+			continue
+		}
+		if old == nil {
+			if s.bestEffort {
+				s.skippedNBTs++
+				return nil, false, nil
+			}
+			return nil, false, fmt.Errorf("blockNBTAt: %w (modified block NBT at time index %d have no older one)", ErrCorrupt, keyIndex-s.barrierLeft)
+		}
+		newer, err := value.Restore(define.NBTWithIndex{Index: target, NBT: old})
+		if err != nil {
+			if s.bestEffort {
+				s.skippedNBTs++
+				return nil, true, nil
+			}
+			return nil, false, fmt.Errorf("blockNBTAt: %w", corruptError(err))
+		}
+		result, changed = newer.NBT, true
+	}
+
+	return result, changed, nil
+}
@@ -0,0 +1,69 @@
+package timeline
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestBlockHistory(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 3, 2)
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	air, gold := testRuntimeID(t, "minecraft:air"), testRuntimeID(t, "minecraft:gold_block")
+
+	// The gold block that placed at the second
+	// time point and removed at the third one
+	changes, err := tl.BlockHistory(2, -40, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("BlockHistory: expected 2 changes, but got %v", changes)
+	}
+	for index, expected := range []struct {
+		timePoint uint
+		old, new  uint32
+	}{{1, air, gold}, {2, gold, air}} {
+		change := changes[index]
+		if change.TimePoint != expected.timePoint || !slices.Equal(change.OldBlocks, []uint32{expected.old}) || !slices.Equal(change.NewBlocks, []uint32{expected.new}) {
+			t.Fatalf("BlockHistory: change %d is %+v", index, change)
+		}
+	}
+
+	// The first time point is compared with air
+	changes, err = tl.BlockHistory(0, -64, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].TimePoint != 0 || changes[0].OldBlocks[0] != air || changes[0].NewBlocks[0] != testRuntimeID(t, "minecraft:stone") {
+		t.Fatalf("BlockHistory: expected the stone is placed at the first time point, but got %+v", changes)
+	}
+
+	// The chest is only changed by its block NBT
+	changes, err = tl.BlockHistory(1, -40, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 3 || changes[0].OldNBT != nil {
+		t.Fatalf("BlockHistory: expected 3 changes of the chest, but got %+v", changes)
+	}
+	for index, count := range []byte{1, 3, 2} {
+		if got := testChestCount(t, []map[string]any{changes[index].NewNBT}); got != count {
+			t.Fatalf("BlockHistory: expected %d items at time point %d, but got %d", count, index, got)
+		}
+	}
+
+	if _, err = tl.BlockHistory(0, 400, 0); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("BlockHistory: expected ErrOutOfRange, but got %v", err)
+	}
+}
@@ -4,50 +4,60 @@ import (
 	"fmt"
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
-	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
 	"github.com/TriM-Organization/bedrock-world-operator/chunk"
 )
 
 // "next" is an internal implement detail.
 // The feature of "next" is like one progress of prefix sum.
-func (s *ChunkTimeline) next() (
-	oriChunk define.ChunkMatrix, oriNBTs []define.NBTWithIndex, updateUnixTime int64,
-	isLastElement bool, err error,
+//
+// If withNBT is false, then the block NBTs are not restored and
+// oriNBTs is nil. In this case, the block NBTs of the following
+// time points could only be restored from a full block NBT snapshot.
+// The entities and biomes are always restored.
+func (s *ChunkTimeline) next(withNBT bool) (
+	oriChunk define.ChunkMatrix, oriNBTs []define.NBTWithIndex,
+	oriEntities []define.EntityWithID, oriBiomes define.ChunkBiomeMatrix,
+	updateUnixTime int64, isLastElement bool, err error,
 ) {
 	if s.isEmpty {
-		return nil, nil, 0, false, fmt.Errorf("next: Current chunk timeline is empty")
+		return nil, nil, nil, nil, 0, false, fmt.Errorf("next: %w", ErrEmpty)
 	}
 	isLastElement = (s.ptr == s.barrierRight)
 
 	// Blocks
 	{
-		payload := s.db.Get(
+		var diff define.ChunkDiffMatrix
+		payload, err := getValue(s.db,
 			define.IndexBlockDu(s.pos, s.ptr),
 		)
-
-		diff, err := marshal.BytesToChunkDiffMatrix(payload, s.pos.Dimension.Range())
+		if err == nil {
+			diff, err = s.decodeBlockDelta(payload)
+		}
 		if err != nil {
-			return nil, nil, 0, false, fmt.Errorf("next: %v", err)
+			return nil, nil, nil, nil, 0, false, fmt.Errorf("next: %w", s.blockDeltaError(s.ptr, err))
 		}
 
 		oriChunk = define.ChunkRestore(s.currentChunk, diff)
 	}
 
 	// NBTs
-	{
-		payload := s.db.Get(
-			define.IndexNBTDu(s.pos, s.ptr),
-		)
-
-		diff, err := marshal.BytesToMultipleDiffNBT(payload)
+	if withNBT {
+		oriNBTs, err = s.restoreNBT(s.db, s.ptr, s.currentNBT, s.bestEffort)
 		if err != nil {
-			return nil, nil, 0, false, fmt.Errorf("next: %v", err)
+			return nil, nil, nil, nil, 0, false, fmt.Errorf("next: %w", err)
 		}
+	}
 
-		oriNBTs, err = define.NBTRestore(s.currentNBT, diff)
-		if err != nil {
-			return nil, nil, 0, false, fmt.Errorf("next: %v", err)
-		}
+	// Entities
+	oriEntities, err = s.restoreEntities(s.db, s.ptr, s.currentEntities, s.bestEffort)
+	if err != nil {
+		return nil, nil, nil, nil, 0, false, fmt.Errorf("next: %w", err)
+	}
+
+	// Biomes
+	oriBiomes, err = s.restoreBiomes(s.db, s.ptr, s.currentBiomes, s.bestEffort)
+	if err != nil {
+		return nil, nil, nil, nil, 0, false, fmt.Errorf("next: %w", err)
 	}
 
 	// Timeline Unix Time
@@ -55,16 +65,18 @@ func (s *ChunkTimeline) next() (
 
 	s.currentChunk = oriChunk
 	s.currentNBT = oriNBTs
+	s.currentEntities = oriEntities
+	s.currentBiomes = oriBiomes
 	s.ptr++
 
 	if s.ptr > s.barrierRight {
 		s.ResetPointer()
 	}
 
-	return oriChunk, oriNBTs, updateUnixTime, isLastElement, nil
+	return oriChunk, oriNBTs, oriEntities, oriBiomes, updateUnixTime, isLastElement, nil
 }
 
-// Next gets the next time point of current chunk and the NBT blocks in it.
+// Next gets the next time point of current chunk and the NBT blocks and entities in it.
 //
 // With the call to Next, we granted that the returned time keeps increasing until
 // the entire time series is traversed.
@@ -82,24 +94,27 @@ func (s *ChunkTimeline) next() (
 // n is the sub chunk count of this chunk.
 // C is relevant to the average changes between last time point and the next one.
 func (s *ChunkTimeline) Next() (
-	c *chunk.Chunk, nbts []map[string]any, updateUnixTime int64,
-	isLastElement bool, err error,
+	c *chunk.Chunk, nbts []map[string]any, entities []map[string]any,
+	updateUnixTime int64, isLastElement bool, err error,
 ) {
 	var oriChunk define.ChunkMatrix
 	var oriNBTs []define.NBTWithIndex
+	var oriEntities []define.EntityWithID
+	var oriBiomes define.ChunkBiomeMatrix
 
 	if s.isEmpty {
-		return nil, nil, 0, false, fmt.Errorf("(s *ChunkTimeline) Next: Current chunk timeline is empty")
+		return nil, nil, nil, 0, false, fmt.Errorf("(s *ChunkTimeline) Next: %w", ErrEmpty)
 	}
 
-	oriChunk, oriNBTs, updateUnixTime, isLastElement, err = s.next()
+	oriChunk, oriNBTs, oriEntities, oriBiomes, updateUnixTime, isLastElement, err = s.next(true)
 	if err != nil {
 		s.ResetPointer()
-		return nil, nil, 0, false, fmt.Errorf("(s *ChunkTimeline) Next: %v", err)
+		return nil, nil, nil, 0, false, fmt.Errorf("(s *ChunkTimeline) Next: %w", err)
 	}
 
-	c = define.MatrixToChunk(oriChunk, s.pos.Dimension.Range(), s.blockPalette)
+	c = define.MatrixToChunk(oriChunk, oriBiomes, s.pos.Dimension.Range(), s.blockPalette)
 	nbts = define.ToChunkNBT(oriNBTs)
+	entities = define.ToChunkEntities(oriEntities)
 
 	return
 }
@@ -109,6 +124,10 @@ func (s *ChunkTimeline) Next() (
 // JumpTo is a very useful replacement of Next when you are trying to jump
 // to a specific time point and no need to get the information of other time point.
 //
+// The block NBTs are restored from the nearest full block NBT snapshot (see
+// NBTSnapshotInterval) before index, so the broken block NBT deltas that before
+// this snapshot will not affect the result.
+//
 // Note that if JumpTo returned non-nil error, then the underlying pointer will back
 // to the firest time point due to when an error occurs, some of the underlying data
 // maybe is inconsistent.
@@ -117,26 +136,44 @@ func (s *ChunkTimeline) Next() (
 //   - n is the sub chunk count of this chunk.
 //   - d is the distance between index and current pointer.
 //   - C is relevant to the average changes of all these time point.
-func (s *ChunkTimeline) JumpTo(index uint) (c *chunk.Chunk, nbts []map[string]any, updateUnixTime int64, err error) {
+func (s *ChunkTimeline) JumpTo(index uint) (
+	c *chunk.Chunk, nbts []map[string]any, entities []map[string]any,
+	updateUnixTime int64, err error,
+) {
 	var oriChunk define.ChunkMatrix
 	var oriNBTs []define.NBTWithIndex
+	var oriEntities []define.EntityWithID
+	var oriBiomes define.ChunkBiomeMatrix
 
 	if s.isEmpty {
-		return nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) JumpTo: Current chunk timeline is empty")
+		return nil, nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) JumpTo: %w", ErrEmpty)
 	}
 
 	idx := s.barrierLeft + index
 	if idx > s.barrierRight {
-		return nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) JumpTo: index %d is out of index %d", index, s.barrierRight-s.barrierLeft)
+		return nil, nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) JumpTo: %w (index %d is out of index %d)", ErrOutOfRange, index, s.barrierRight-s.barrierLeft)
+	}
+
+	if idx < s.ptr {
+		s.ResetPointer()
+	}
+
+	// The block NBTs before the nearest snapshot are not needed
+	nbtStart := s.ptr
+	for keyIndex := idx; keyIndex > s.ptr; keyIndex-- {
+		if s.hasNBTSnapshot(s.db, keyIndex) {
+			nbtStart = keyIndex
+			break
+		}
 	}
 
 	for {
 		couldBreak := (s.ptr == idx)
 
-		oriChunk, oriNBTs, updateUnixTime, _, err = s.next()
+		oriChunk, oriNBTs, oriEntities, oriBiomes, updateUnixTime, _, err = s.next(s.ptr >= nbtStart)
 		if err != nil {
 			s.ResetPointer()
-			return nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) JumpTo: %v", err)
+			return nil, nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) JumpTo: %w", err)
 		}
 
 		if couldBreak {
@@ -144,32 +181,39 @@ func (s *ChunkTimeline) JumpTo(index uint) (c *chunk.Chunk, nbts []map[string]an
 		}
 	}
 
-	c = define.MatrixToChunk(oriChunk, s.pos.Dimension.Range(), s.blockPalette)
+	c = define.MatrixToChunk(oriChunk, oriBiomes, s.pos.Dimension.Range(), s.blockPalette)
 	nbts = define.ToChunkNBT(oriNBTs)
+	entities = define.ToChunkEntities(oriEntities)
 
 	return
 }
 
-// Last gets the latest time point of current chunk and the NBT blocks in it.
+// Last gets the latest time point of current chunk and the NBT blocks and entities in it.
 // Time complexity: O(4096×n).
 // n is the sub chunk count of this chunk.
 func (s *ChunkTimeline) Last() (
 	c *chunk.Chunk,
 	nbts []map[string]any,
+	entities []map[string]any,
 	updateUnixTime int64,
 	err error,
 ) {
 	if s.isEmpty {
-		return nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) Last: Current chunk timeline is empty")
+		return nil, nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) Last: %w", ErrEmpty)
 	}
 
 	oriNBTsCopyOne, err := define.NBTDeepCopy(s.latestNBT)
 	if err != nil {
-		return nil, nil, 0, fmt.Errorf("Last: %v", err)
+		return nil, nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) Last: %w", err)
+	}
+	oriEntitiesCopyOne, err := define.EntityDeepCopy(s.latestEntities)
+	if err != nil {
+		return nil, nil, nil, 0, fmt.Errorf("(s *ChunkTimeline) Last: %w", err)
 	}
 
-	c = define.MatrixToChunk(s.latestChunk, s.pos.Dimension.Range(), s.blockPalette)
+	c = define.MatrixToChunk(s.latestChunk, s.latestBiomes, s.pos.Dimension.Range(), s.blockPalette)
 	nbts = define.ToChunkNBT(oriNBTsCopyOne)
+	entities = define.ToChunkEntities(oriEntitiesCopyOne)
 
-	return c, nbts, s.timelineUnixTime[len(s.timelineUnixTime)-1], nil
+	return c, nbts, entities, s.timelineUnixTime[len(s.timelineUnixTime)-1], nil
 }
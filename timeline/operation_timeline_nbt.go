@@ -0,0 +1,118 @@
+package timeline
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// NBTModification is the modification of a single block
+// NBT at a time point, which returned by NBTModifications.
+type NBTModification struct {
+	// X, Y and Z is the position of this block,
+	// where X and Z are the relative coordinates
+	// to this chunk, and Y is the world height.
+	X uint8
+	Y int16
+	Z uint8
+	// Kind is the kind of the stored delta,
+	// e.g. define.ModifiedNBTStructural.
+	Kind byte
+	// Patches change the block NBT from the
+	// previous time point to this time point.
+	Patches []define.NBTPatch
+}
+
+// NBTModifications returns the block NBTs that modified at the time point
+// whose index is index, and the patches of each of them.
+// The added and removed block NBTs are not included (see DiffBetween).
+//
+// The time points saved by the older versions may hold bsdiff deltas that
+// could not be inspected directly. For them, the block NBTs are restored to
+// the previous time point, and then the patches are computed again.
+// NBTModifications don't move the pointer of this timeline.
+//
+// Time complexity: O(k) if there is no bsdiff delta, or O(C) otherwise.
+//   - k is relevant to the size of the block NBT delta at index.
+//   - C is relevant to the changes of all the time points until index.
+func (s *ChunkTimeline) NBTModifications(index uint) (result []NBTModification, err error) {
+	if s.isEmpty {
+		return nil, fmt.Errorf("(s *ChunkTimeline) NBTModifications: %w", ErrEmpty)
+	}
+	if s.barrierLeft+index > s.barrierRight {
+		return nil, fmt.Errorf("(s *ChunkTimeline) NBTModifications: %w (index %d is out of index %d)", ErrOutOfRange, index, s.barrierRight-s.barrierLeft)
+	}
+
+	var nbtDiff define.MultipleDiffNBT
+	payload, err := getValue(s.db, define.IndexNBTDu(s.pos, s.barrierLeft+index))
+	if err == nil {
+		nbtDiff, err = marshal.BytesToMultipleDiffNBT(payload, s.codec)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("(s *ChunkTimeline) NBTModifications: %w", corruptError(err))
+	}
+
+	var olderSet map[define.ChunkBlockIndex]define.NBTWithIndex
+	for _, value := range nbtDiff.Modified {
+		modification := NBTModification{
+			X:    value.Index.X(),
+			Y:    value.Index.Y(),
+			Z:    value.Index.Z(),
+			Kind: value.Kind(),
+		}
+
+		if modification.Kind != define.ModifiedNBTBSDiff {
+			modification.Patches, err = value.Patches()
+			if err != nil {
+				return nil, fmt.Errorf("(s *ChunkTimeline) NBTModifications: %w", corruptError(err))
+			}
+			result = append(result, modification)
+			continue
+		}
+
+		if olderSet == nil {
+			olderSet, err = s.nbtBefore(index)
+			if err != nil {
+				return nil, fmt.Errorf("(s *ChunkTimeline) NBTModifications: %w", err)
+			}
+		}
+		older := olderSet[value.Index]
+		newer, err := value.Restore(older)
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) NBTModifications: %w", corruptError(err))
+		}
+		modification.Patches = define.NewStructuralDiffNBT(older.NBT, newer.NBT)
+		result = append(result, modification)
+	}
+
+	slices.SortFunc(result, func(a NBTModification, b NBTModification) int {
+		if a.Y != b.Y {
+			return int(a.Y) - int(b.Y)
+		}
+		if a.X != b.X {
+			return int(a.X) - int(b.X)
+		}
+		return int(a.Z) - int(b.Z)
+	})
+	return result, nil
+}
+
+// nbtBefore restores the block NBTs of the time
+// point that before the one whose index is index.
+func (s *ChunkTimeline) nbtBefore(index uint) (result map[define.ChunkBlockIndex]define.NBTWithIndex, err error) {
+	var current []define.NBTWithIndex
+	for keyIndex := s.barrierLeft; keyIndex < s.barrierLeft+index; keyIndex++ {
+		current, err = s.restoreNBT(s.db, keyIndex, current, s.bestEffort)
+		if err != nil {
+			return nil, fmt.Errorf("nbtBefore: %w", err)
+		}
+	}
+
+	result = make(map[define.ChunkBlockIndex]define.NBTWithIndex)
+	for _, value := range current {
+		result[value.Index] = value
+	}
+	return result, nil
+}
@@ -0,0 +1,282 @@
+package timeline
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// SharedPaletteEnabled returns whether the shared
+// palette is used when saving the chunk timelines.
+func (t *TimelineDB) SharedPaletteEnabled() bool {
+	t.palette.mu.Lock()
+	defer t.palette.mu.Unlock()
+	return t.palette.enabled
+}
+
+// SetSharedPalette sets whether to use the shared palette, and
+// save this setting into the underlying database.
+//
+// The shared palette maps the block states to stable global IDs
+// for the entire database, and the block palette of each chunk
+// timeline will only save these IDs but not the full block states.
+//
+// Existing chunk timelines are migrated when they are saved next
+// time, or use RewriteChunkTimeline to migrate them immediately.
+// This is also the same when disabling the shared palette.
+func (t *TimelineDB) SetSharedPalette(enabled bool) error {
+	t.palette.mu.Lock()
+	defer t.palette.mu.Unlock()
+
+	setting := []byte{0}
+	if enabled {
+		setting[0] = 1
+	}
+
+	err := t.Bucket(DatabaseKeyMeta).Put(DatabaseKeyPalette, setting)
+	if err != nil {
+		return fmt.Errorf("SetSharedPalette: %w", err)
+	}
+
+	t.palette.enabled = enabled
+	return nil
+}
+
+// GCSharedPalette removes the entries of the shared palette
+// that not used by any chunk timeline, and returns the count
+// of the removed entries.
+//
+// The chunk timelines that in use are not affected, because
+// the removed blocks will be added again when they are saved.
+//
+// Time complexity: O(n+m).
+//   - n is the count of chunk timelines.
+//   - m is the count of the entries in the shared palette.
+func (t *TimelineDB) GCSharedPalette() (removed int, err error) {
+	var success bool
+
+	t.palette.mu.Lock()
+	defer t.palette.mu.Unlock()
+
+	allPos := make([]define.DimChunk, 0)
+	err = t.ForEachChunkTimeline(func(pos define.DimChunk) error {
+		allPos = append(allPos, pos)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("GCSharedPalette: %w", err)
+	}
+
+	used := make(map[uint32]bool)
+	for _, pos := range allPos {
+		payload, err := getValue(t.DB, define.Sum(pos, []byte(define.KeyChunkGlobalData)...))
+		if err != nil {
+			return 0, fmt.Errorf("GCSharedPalette: %w", err)
+		}
+		if len(payload) == 0 {
+			continue
+		}
+
+		globalData, err := t.codec.Decode(payload)
+		if err != nil {
+			return 0, fmt.Errorf("GCSharedPalette: %w", corruptError(err))
+		}
+		ids, isShared, err := sharedPaletteIDs(globalData)
+		if err != nil {
+			return 0, fmt.Errorf("GCSharedPalette: %w", err)
+		}
+		if !isShared {
+			continue
+		}
+
+		for ; len(ids) > 0; ids = ids[4:] {
+			used[binary.LittleEndian.Uint32(ids)] = true
+		}
+	}
+
+	unused := make([][]byte, 0)
+	err = t.Bucket(DatabaseKeyPalette).ForEach(func(key []byte, value []byte) error {
+		if len(key) == 4 && !used[binary.LittleEndian.Uint32(key)] {
+			unused = append(unused, append([]byte(nil), key...))
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("GCSharedPalette: %w", err)
+	}
+	if len(unused) == 0 {
+		return 0, nil
+	}
+
+	tran, err := t.OpenTransaction()
+	if err != nil {
+		return 0, fmt.Errorf("GCSharedPalette: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+
+	for _, key := range unused {
+		err = tran.Bucket(DatabaseKeyPalette).Delete(key)
+		if err != nil {
+			return 0, fmt.Errorf("GCSharedPalette: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return 0, fmt.Errorf("GCSharedPalette: %w", err)
+	}
+	success = true
+
+	for _, key := range unused {
+		id := binary.LittleEndian.Uint32(key)
+		if blockRuntimeID, ok := t.palette.blocks[id]; ok && t.palette.ids[blockRuntimeID] == id {
+			delete(t.palette.ids, blockRuntimeID)
+		}
+		delete(t.palette.blocks, id)
+		delete(t.palette.outdated, id)
+	}
+
+	return len(unused), nil
+}
+
+// sharedPaletteIDs gets the global IDs of the shared palette
+// that saved in globalData, which is the global data of a
+// chunk timeline.
+//
+// If the block palette of this chunk timeline is not saved
+// as the shared palette, then isShared is false.
+func sharedPaletteIDs(globalData []byte) (ids []byte, isShared bool, err error) {
+	for i := range 2 {
+		if len(globalData) < 4 {
+			return nil, false, fmt.Errorf("sharedPaletteIDs: %w: %w (global data is truncated)", ErrCorrupt, define.ErrMalformed)
+		}
+
+		length := binary.LittleEndian.Uint32(globalData)
+		isShared = (i == 1 && length&sharedPaletteFlag != 0)
+		if i == 1 {
+			length &^= sharedPaletteFlag
+		}
+
+		if uint64(length)+4 > uint64(len(globalData)) {
+			return nil, false, fmt.Errorf("sharedPaletteIDs: %w: %w (global data is truncated)", ErrCorrupt, define.ErrMalformed)
+		}
+		ids = globalData[4 : 4+length]
+		globalData = globalData[4+length:]
+	}
+
+	if !isShared {
+		return nil, false, nil
+	}
+	return ids, true, nil
+}
+
+// decodeBlockDelta decodes payload as a block delta of this timeline.
+// The block palette indices in it are remapped by s.paletteRemap and
+// then checked, so they are always valid for the current block palette.
+func (s *ChunkTimeline) decodeBlockDelta(payload []byte) (diff define.ChunkDiffMatrix, err error) {
+	diff, err = marshal.BytesToChunkDiffMatrix(payload, s.pos.Dimension.Range(), s.codec)
+	if err != nil {
+		return nil, fmt.Errorf("decodeBlockDelta: %w", err)
+	}
+	define.RemapChunkDiffMatrix(diff, s.paletteRemap)
+
+	err = define.ValidateChunkDiffMatrix(diff, s.blockPalette.BlockPaletteLen())
+	if err != nil {
+		return nil, fmt.Errorf("decodeBlockDelta: %w", err)
+	}
+	return diff, nil
+}
+
+// rewriteBlockDeltas writes the block deltas of this timeline back
+// to the database with the block palette indices that remapped by
+// s.paletteRemap. The block palette and the latest chunk are also
+// written back, so the saved block palette will not have duplicate
+// entries anymore, and s.paletteRemap is set to nil.
+//
+// Time complexity: O(n).
+// n is the time point that this chunk have.
+func (s *ChunkTimeline) rewriteBlockDeltas() error {
+	var success bool
+
+	sharedPaletteEnabled, unlock := s.palette.lock()
+	defer unlock()
+
+	tran, err := s.db.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+
+	// Each block delta
+	for i := s.barrierLeft; i <= s.barrierRight; i++ {
+		payload, err := getValue(tran, define.IndexBlockDu(s.pos, i))
+		if err != nil {
+			return fmt.Errorf("rewriteBlockDeltas: %w", s.blockDeltaError(i, err))
+		}
+		if len(payload) == 0 {
+			continue
+		}
+
+		diff, err := s.decodeBlockDelta(payload)
+		if err != nil {
+			return fmt.Errorf("rewriteBlockDeltas: %w", s.blockDeltaError(i, err))
+		}
+		payload, err = marshal.ChunkDiffMatrixToBytes(diff, s.codec)
+		if err != nil {
+			return fmt.Errorf("rewriteBlockDeltas: %w", err)
+		}
+		err = tran.Put(define.IndexBlockDu(s.pos, i), payload)
+		if err != nil {
+			return fmt.Errorf("rewriteBlockDeltas: %w", err)
+		}
+
+		err = s.refreshSummarySize(tran, i)
+		if err != nil {
+			return fmt.Errorf("rewriteBlockDeltas: %w", err)
+		}
+	}
+
+	// Global data
+	globalData, pendingEntries, err := s.encodeGlobalData(tran, sharedPaletteEnabled)
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+	payload, err := s.codec.Encode(globalData)
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+	err = tran.Put(define.Sum(s.pos, []byte(define.KeyChunkGlobalData)...), payload)
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+
+	// Latest chunk
+	payload, err = s.encodeLatestChunk(tran, s.latestChunk)
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+	err = tran.Put(define.Sum(s.pos, define.KeyLatestChunk), payload)
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("rewriteBlockDeltas: %w", err)
+	}
+	s.palette.commit(pendingEntries)
+	s.paletteRemap = nil
+
+	success = true
+	return nil
+}
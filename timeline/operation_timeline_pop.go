@@ -19,16 +19,20 @@ func (s *ChunkTimeline) Pop() error {
 
 	transaction, err := s.db.OpenTransaction()
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+		return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 	}
 	defer func() {
 		if !success {
 			_ = transaction.Discard()
-			return
 		}
-		_ = transaction.Commit()
 	}()
 
+	// The new first time point is compared
+	// with an empty chunk, so its summary
+	// is also changed.
+	var summaryBlocks define.ChunkDiffMatrix
+	var summaryNBTs *define.MultipleDiffNBT
+
 	// Blocks
 	for range 1 {
 		var dst define.ChunkMatrix
@@ -36,13 +40,15 @@ func (s *ChunkTimeline) Pop() error {
 
 		// Step 1: Get element 1 from timeline
 		{
-			payload := transaction.Get(
+			var diff define.ChunkDiffMatrix
+			payload, err := getValue(transaction,
 				define.IndexBlockDu(s.pos, s.barrierLeft),
 			)
-
-			diff, err := marshal.BytesToChunkDiffMatrix(payload, s.pos.Dimension.Range())
+			if err == nil {
+				diff, err = s.decodeBlockDelta(payload)
+			}
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", s.blockDeltaError(s.barrierLeft, err))
 			}
 
 			dst = define.ChunkRestore(make(define.ChunkMatrix, len(diff)), diff)
@@ -50,20 +56,23 @@ func (s *ChunkTimeline) Pop() error {
 
 		// Setp 2: Get element 2 from timeline
 		{
-			payload := transaction.Get(
+			payload, err := getValue(transaction,
 				define.IndexBlockDu(s.pos, s.barrierLeft+1),
 			)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", s.blockDeltaError(s.barrierLeft+1, err))
+			}
 			if len(payload) == 0 {
 				err = transaction.Delete(define.IndexBlockDu(s.pos, s.barrierLeft))
 				if err != nil {
-					return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+					return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 				}
 				break
 			}
 
-			diff, err := marshal.BytesToChunkDiffMatrix(payload, s.pos.Dimension.Range())
+			diff, err := s.decodeBlockDelta(payload)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", s.blockDeltaError(s.barrierLeft+1, err))
 			}
 
 			dst = define.ChunkRestore(dst, diff)
@@ -74,19 +83,20 @@ func (s *ChunkTimeline) Pop() error {
 		{
 			err := transaction.Delete(define.IndexBlockDu(s.pos, s.barrierLeft))
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 
-			payload, err := marshal.ChunkDiffMatrixToBytes(newDiff)
+			summaryBlocks = newDiff
+			payload, err := marshal.ChunkDiffMatrixToBytes(newDiff, s.codec)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 			err = transaction.Put(
 				define.IndexBlockDu(s.pos, s.barrierLeft+1),
 				payload,
 			)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 		}
 	}
@@ -98,47 +108,36 @@ func (s *ChunkTimeline) Pop() error {
 
 		// Setp 1: Get element 1 from timeline
 		{
-			payload := transaction.Get(
-				define.IndexNBTDu(s.pos, s.barrierLeft),
-			)
-
-			diff, err := marshal.BytesToMultipleDiffNBT(payload)
-			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
-			}
-
-			dst, err = define.NBTRestore(nil, diff)
+			dst, err = s.restoreNBT(transaction, s.barrierLeft, nil, false)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 		}
 
 		// Setp 2: Get element 2 from timeline
 		{
-			payload := transaction.Get(
+			payload, err := getValue(transaction,
 				define.IndexNBTDu(s.pos, s.barrierLeft+1),
 			)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", s.nbtDeltaError(s.barrierLeft+1, err))
+			}
 			if len(payload) == 0 {
 				err = transaction.Delete(define.IndexNBTDu(s.pos, s.barrierLeft))
 				if err != nil {
-					return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+					return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 				}
 				break
 			}
 
-			diff, err := marshal.BytesToMultipleDiffNBT(payload)
+			dst, err = s.restoreNBT(transaction, s.barrierLeft+1, dst, false)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
-			}
-
-			dst, err = define.NBTRestore(dst, diff)
-			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 
 			newDiff, err = define.NBTDifference(nil, dst)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 		}
 
@@ -146,23 +145,137 @@ func (s *ChunkTimeline) Pop() error {
 		{
 			err := transaction.Delete(define.IndexNBTDu(s.pos, s.barrierLeft))
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 
-			payload, err := marshal.MultipleDiffNBTBytes(*newDiff)
+			summaryNBTs = newDiff
+			payload, err := marshal.MultipleDiffNBTBytes(*newDiff, s.codec)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 			err = transaction.Put(
 				define.IndexNBTDu(s.pos, s.barrierLeft+1),
 				payload,
 			)
 			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Pop: %v", err)
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+		}
+	}
+
+	// Entities
+	for range 1 {
+		var dst []define.EntityWithID
+		var newDiff *define.MultipleDiffEntity
+
+		// Setp 1: Get element 1 from timeline
+		{
+			dst, err = s.restoreEntities(transaction, s.barrierLeft, nil, false)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+		}
+
+		// Setp 2: Get element 2 from timeline
+		{
+			dst, err = s.restoreEntities(transaction, s.barrierLeft+1, dst, false)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+
+			newDiff, err = define.EntityDifference(nil, dst)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+		}
+
+		// Setp 3: Pop
+		{
+			err := transaction.Delete(define.IndexEntityDu(s.pos, s.barrierLeft))
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+
+			payload, err := marshal.MultipleDiffEntityBytes(*newDiff, s.codec)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+			err = transaction.Put(
+				define.IndexEntityDu(s.pos, s.barrierLeft+1),
+				payload,
+			)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
 			}
 		}
 	}
 
+	// Biomes
+	for range 1 {
+		var dst define.ChunkBiomeMatrix
+		var newDiff define.ChunkBiomeDiffMatrix
+
+		// Setp 1: Get element 1 from timeline
+		{
+			dst, err = s.restoreBiomes(transaction, s.barrierLeft, make(define.ChunkBiomeMatrix, s.pos.Dimension.Height()>>4), false)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+		}
+
+		// Setp 2: Get element 2 from timeline
+		{
+			dst, err = s.restoreBiomes(transaction, s.barrierLeft+1, dst, false)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+
+			newDiff = define.BiomeDifference(make(define.ChunkBiomeMatrix, len(dst)), dst)
+		}
+
+		// Setp 3: Pop
+		{
+			err := transaction.Delete(define.IndexBiomeDu(s.pos, s.barrierLeft))
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+
+			payload, err := marshal.ChunkBiomeDiffMatrixToBytes(newDiff, s.codec)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+			err = transaction.Put(
+				define.IndexBiomeDu(s.pos, s.barrierLeft+1),
+				payload,
+			)
+			if err != nil {
+				return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+			}
+		}
+	}
+
+	// Summaries and snapshots
+	err = transaction.Delete(define.IndexSummary(s.pos, s.barrierLeft))
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+	}
+	err = transaction.Delete(define.IndexNBTSnapshot(s.pos, s.barrierLeft))
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+	}
+	if summaryBlocks != nil && summaryNBTs != nil {
+		err = s.putSummary(transaction, s.barrierLeft+1, define.NewTimePointSummary(summaryBlocks, *summaryNBTs))
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Pop: %w", err)
+	}
+	success = true
+
 	s.barrierLeft++
 	s.timelineUnixTime = s.timelineUnixTime[1:]
 
@@ -170,9 +283,9 @@ func (s *ChunkTimeline) Pop() error {
 		s.ptr = s.barrierLeft
 		s.currentChunk = make(define.ChunkMatrix, s.pos.Dimension.Height()>>4)
 		s.currentNBT = nil
+		s.currentEntities = nil
+		s.currentBiomes = make(define.ChunkBiomeMatrix, s.pos.Dimension.Height()>>4)
 	}
 
-	success = true
-
 	return nil
 }
@@ -7,9 +7,6 @@ import (
 
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
-	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
-	"github.com/TriM-Organization/bedrock-world-operator/block"
-	"github.com/TriM-Organization/bedrock-world-operator/chunk"
 )
 
 // Save saves current timeline into the underlying database,
@@ -36,115 +33,50 @@ import (
 // otherwise, the timeline will not be able to maintain data consistency.
 func (s *ChunkTimeline) Save() error {
 	var success bool
+	var pendingEntries []sharedPaletteEntry
 
 	if s.isEmpty || s.isReadOnly {
 		s.releaseFunc()
 		return nil
 	}
 
+	sharedPaletteEnabled, unlock := s.palette.lock()
+	defer unlock()
+
 	tran, err := s.db.OpenTransaction()
 	if err != nil {
-		return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+		return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 	}
 	defer func() {
 		if !success {
 			_ = tran.Discard()
-			return
 		}
-		_ = tran.Commit()
-		s.releaseFunc()
 	}()
 
-	globalData := bytes.NewBuffer(nil)
-
 	// Chunk Index
-	{
-		keyBytes := define.Index(s.pos)
-		bucket := tran.(*transaction).tx.Bucket(DatabaseKeyChunkIndex)
-
-		if bucket.Get(keyBytes) == nil {
-			err = bucket.Put(
-				DatabaseKeyChunkCount,
-				utils.Uint32BinaryAdd(bucket.Get(DatabaseKeyChunkCount), make([]byte, 4), 1),
-			)
-			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
-			}
-
-			err = bucket.Put(keyBytes, []byte{1})
-			if err != nil {
-				return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
-			}
-		}
-	}
-
-	// Timeline Unix Time
-	{
-		buf := bytes.NewBuffer(nil)
-
-		for _, value := range s.timelineUnixTime {
-			temp := make([]byte, 8)
-			binary.LittleEndian.PutUint64(temp, uint64(value))
-			buf.Write(temp)
-		}
-
-		lengthBytes := make([]byte, 4)
-		binary.LittleEndian.PutUint32(lengthBytes, uint32(buf.Len()))
-
-		globalData.Write(lengthBytes)
-		globalData.Write(buf.Bytes())
+	err = addChunkIndex(tran, s.pos)
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 	}
 
-	// Block Palette
+	// Save global data
 	{
-		buf := bytes.NewBuffer(nil)
-
-		for _, value := range s.blockPalette.BlockPalette() {
-			name, states, found := block.RuntimeIDToState(value)
-			if !found {
-				name = "minecraft:unknown"
-			}
-			utils.MarshalNBT(
-				buf,
-				map[string]any{
-					"name":    name,
-					"states":  states,
-					"version": chunk.CurrentBlockVersion,
-				},
-				"",
-			)
+		globalData, pending, err := s.encodeGlobalData(tran, sharedPaletteEnabled)
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
+		pendingEntries = pending
 
-		lengthBytes := make([]byte, 4)
-		binary.LittleEndian.PutUint32(lengthBytes, uint32(buf.Len()))
-
-		globalData.Write(lengthBytes)
-		globalData.Write(buf.Bytes())
-	}
-
-	// Barrier and Max limit
-	{
-		result := make([]byte, 12)
-
-		binary.LittleEndian.PutUint32(result, uint32(s.barrierLeft))
-		binary.LittleEndian.PutUint32(result[4:], uint32(s.barrierRight))
-		binary.LittleEndian.PutUint32(result[8:], uint32(s.maxLimit))
-
-		globalData.Write(result)
-	}
-
-	// Save global data
-	{
-		gzipBytes, err := utils.Gzip(globalData.Bytes())
+		compressedBytes, err := s.codec.Encode(globalData)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 		err = tran.Put(
 			define.Sum(s.pos, []byte(define.KeyChunkGlobalData)...),
-			gzipBytes,
+			compressedBytes,
 		)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 	}
 
@@ -160,40 +92,151 @@ func (s *ChunkTimeline) Save() error {
 			latestTimePointUnixTimeBytes,
 		)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 	}
 
 	// Latest Chunk
 	{
-		payload, err := marshal.ChunkMatrixToBytes(s.latestChunk)
+		payload, err := s.encodeLatestChunk(tran, s.latestChunk)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 		err = tran.Put(
 			define.Sum(s.pos, define.KeyLatestChunk),
 			payload,
 		)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 	}
 
 	// Latest NBT
 	{
-		payload, err := marshal.BlockNBTBytes(s.latestNBT)
+		payload, err := marshal.BlockNBTBytes(s.latestNBT, s.codec)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 		err = tran.Put(
 			define.Sum(s.pos, []byte(define.KeyLatestNBT)...),
 			payload,
 		)
 		if err != nil {
-			return fmt.Errorf("(s *ChunkTimeline) Save: %v", err)
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
+		}
+	}
+
+	// Latest Entities
+	{
+		payload, err := marshal.EntitiesBytes(s.latestEntities, s.codec)
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
+		}
+		err = tran.Put(
+			define.Sum(s.pos, []byte(define.KeyLatestEntity)...),
+			payload,
+		)
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
+		}
+	}
+
+	// Latest Biomes
+	{
+		payload, err := marshal.ChunkBiomeMatrixToBytes(s.latestBiomes, s.codec)
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
+		}
+		err = tran.Put(
+			define.Sum(s.pos, []byte(define.KeyLatestBiome)...),
+			payload,
+		)
+		if err != nil {
+			return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
 		}
 	}
 
+	// Latest Hash
+	err = s.putSubChunkHashes(tran, s.latestHash)
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("(s *ChunkTimeline) Save: %w", err)
+	}
 	success = true
+	s.palette.commit(pendingEntries)
+	s.releaseFunc()
 	return nil
 }
+
+// encodeGlobalData encodes the global data of this timeline.
+// If sharedPalette is true, then the block palette is encoded
+// as the global IDs of the shared palette, and the new entries
+// are added by tran and returned as pending.
+//
+// The caller must hold the lock of the shared palette
+// if sharedPalette is true.
+func (s *ChunkTimeline) encodeGlobalData(tran Transaction, sharedPalette bool) (
+	payload []byte, pending []sharedPaletteEntry, err error,
+) {
+	globalData := bytes.NewBuffer(nil)
+
+	// Timeline Unix Time
+	{
+		buf := bytes.NewBuffer(nil)
+
+		for _, value := range s.timelineUnixTime {
+			temp := make([]byte, 8)
+			binary.LittleEndian.PutUint64(temp, uint64(value))
+			buf.Write(temp)
+		}
+
+		lengthBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(lengthBytes, uint32(buf.Len()))
+
+		globalData.Write(lengthBytes)
+		globalData.Write(buf.Bytes())
+	}
+
+	// Block Palette
+	{
+		var flag uint32
+		buf := bytes.NewBuffer(nil)
+
+		if sharedPalette {
+			ids, newEntries, err := s.palette.encode(tran, s.blockPalette.BlockPalette())
+			if err != nil {
+				return nil, nil, fmt.Errorf("encodeGlobalData: %w", err)
+			}
+			buf.Write(ids)
+			pending = newEntries
+			flag = sharedPaletteFlag
+		} else {
+			for _, value := range s.blockPalette.BlockPalette() {
+				encodeBlockState(buf, s.blockPalette.Registry(), value)
+			}
+		}
+
+		lengthBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(lengthBytes, uint32(buf.Len())|flag)
+
+		globalData.Write(lengthBytes)
+		globalData.Write(buf.Bytes())
+	}
+
+	// Barrier and Max limit
+	{
+		result := make([]byte, 12)
+
+		binary.LittleEndian.PutUint32(result, uint32(s.barrierLeft))
+		binary.LittleEndian.PutUint32(result[4:], uint32(s.barrierRight))
+		binary.LittleEndian.PutUint32(result[8:], uint32(s.maxLimit))
+
+		globalData.Write(result)
+	}
+
+	return globalData.Bytes(), pending, nil
+}
@@ -0,0 +1,122 @@
+package timeline
+
+import (
+	"errors"
+	"testing"
+)
+
+// errTestCommit is returned by the transactions
+// of testFailingDB when they are committed.
+var errTestCommit = errors.New("commit failed")
+
+// testFailingDB is a DB whose transactions
+// can't be committed if fail is true.
+type testFailingDB struct {
+	DB
+	fail bool
+}
+
+func (db *testFailingDB) OpenTransaction() (Transaction, error) {
+	tran, err := db.DB.OpenTransaction()
+	if err != nil {
+		return nil, err
+	}
+	return &testFailingTransaction{Transaction: tran, db: db}, nil
+}
+
+// testFailingTransaction is a transaction of testFailingDB.
+type testFailingTransaction struct {
+	Transaction
+	db *testFailingDB
+}
+
+func (t *testFailingTransaction) Commit() error {
+	if t.db.fail {
+		_ = t.Transaction.Discard()
+		return errTestCommit
+	}
+	return t.Transaction.Commit()
+}
+
+func TestSaveCommitFailed(t *testing.T) {
+	raw := &testFailingDB{DB: OpenMemoryDB()}
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, nbts := testChunk(t, 1)
+	if err = tl.Append(c, nbts, nil, false); err != nil {
+		t.Fatal(err)
+	}
+
+	raw.fail = true
+	if err = tl.Append(c, nbts, nil, false); !errors.Is(err, errTestCommit) {
+		t.Fatalf("Append: expected the commit error, but got %v", err)
+	}
+	if err = tl.Save(); !errors.Is(err, errTestCommit) {
+		t.Fatalf("Save: expected the commit error, but got %v", err)
+	}
+	if db.HasChunkTimeline(testPos) {
+		t.Fatal("expected the chunk index is not saved")
+	}
+
+	// The timeline is not released, so it could be saved again
+	if _, err = db.TryNewChunkTimeline(testPos, true); !errors.Is(err, ErrBusy) {
+		t.Fatalf("TryNewChunkTimeline: expected ErrBusy, but got %v", err)
+	}
+	raw.fail = false
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if !db.HasChunkTimeline(testPos) {
+		t.Fatal("expected the chunk index is saved")
+	}
+
+	raw.fail = true
+	if err = db.DeleteChunkTimeline(testPos); !errors.Is(err, errTestCommit) {
+		t.Fatalf("DeleteChunkTimeline: expected the commit error, but got %v", err)
+	}
+	if err = db.RewriteChunkTimeline(testPos); !errors.Is(err, errTestCommit) {
+		t.Fatalf("RewriteChunkTimeline: expected the commit error, but got %v", err)
+	}
+}
+
+func TestPlayerSaveCommitFailed(t *testing.T) {
+	raw := &testFailingDB{DB: OpenMemoryDB()}
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppendPlayer(t, db, DefaultMaxLimit, 1)
+
+	tl, err := db.NewPlayerTimeline(testPlayerID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw.fail = true
+	if err = tl.Append(testPlayer(2), false); !errors.Is(err, errTestCommit) {
+		t.Fatalf("Append: expected the commit error, but got %v", err)
+	}
+	if err = tl.Save(); !errors.Is(err, errTestCommit) {
+		t.Fatalf("Save: expected the commit error, but got %v", err)
+	}
+	raw.fail = false
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	raw.fail = true
+	if err = db.DeletePlayerTimeline(testPlayerID); !errors.Is(err, errTestCommit) {
+		t.Fatalf("DeletePlayerTimeline: expected the commit error, but got %v", err)
+	}
+	if err = db.RewritePlayerTimeline(testPlayerID); !errors.Is(err, errTestCommit) {
+		t.Fatalf("RewritePlayerTimeline: expected the commit error, but got %v", err)
+	}
+	raw.fail = false
+	testCheckPlayer(t, db, 1)
+}
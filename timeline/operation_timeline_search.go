@@ -0,0 +1,246 @@
+package timeline
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// BlockPredicate reports whether the block whose
+// block state is state is the one that searching for.
+type BlockPredicate func(state operator_define.BlockState) bool
+
+// BlockEvent is a matching block that appeared or disappeared
+// at a time point, which returned by SearchBlocks.
+type BlockEvent struct {
+	// TimePoint is the index of the time point that
+	// this event happened, which could be used by JumpTo.
+	TimePoint      uint
+	UpdateUnixTime int64
+	// X, Y and Z is the position of this block,
+	// where X and Z are the relative coordinates
+	// to this chunk, and Y is the world height.
+	X     uint8
+	Y     int16
+	Z     uint8
+	Layer uint8
+	// Appeared is true if the matching block appeared
+	// at this time point, or false if it disappeared.
+	Appeared bool
+	// OldBlock and NewBlock are the block runtime ID of this
+	// block before and after this time point. If both of them
+	// are matching blocks, then two events are returned.
+	OldBlock uint32
+	NewBlock uint32
+}
+
+// MatchBlockName returns a BlockPredicate that matches the blocks
+// whose name is one of names. The "minecraft:" prefix could be omitted.
+func MatchBlockName(names ...string) BlockPredicate {
+	set := make(map[string]bool)
+	for _, name := range names {
+		set[normalizeBlockName(name)] = true
+	}
+	return func(state operator_define.BlockState) bool {
+		return set[normalizeBlockName(state.Name)]
+	}
+}
+
+// MatchBlockStates returns a BlockPredicate that matches the blocks
+// who matches one of states. A block matches a state if they have the
+// same name, and all the properties in this state are the same as the
+// block, so the properties that not given are not compared.
+func MatchBlockStates(states ...operator_define.BlockState) BlockPredicate {
+	return func(state operator_define.BlockState) bool {
+		name := normalizeBlockName(state.Name)
+		for _, value := range states {
+			if normalizeBlockName(value.Name) != name {
+				continue
+			}
+
+			matched := true
+			for key, property := range value.Properties {
+				if !reflect.DeepEqual(state.Properties[key], property) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// normalizeBlockName adds the "minecraft:"
+// prefix to name if it has no namespace.
+func normalizeBlockName(name string) string {
+	if !strings.Contains(name, ":") {
+		return "minecraft:" + name
+	}
+	return name
+}
+
+// SearchBlocks returns all the time points and positions where the
+// blocks that matches predicate appeared or disappeared, from the
+// earliest time point to the latest one.
+//
+// The block palette is checked first, so a timeline that never have
+// any matching block returns directly. Otherwise, only the block deltas
+// are walked and the full chunks are never built. Note that the first
+// time point is compared with an empty chunk (full of air).
+// SearchBlocks don't move the pointer of this timeline.
+//
+// Time complexity: O(p + n×L).
+//   - p is the length of the block palette.
+//   - n is the count of time points.
+//   - L is relevant to the size of each block delta.
+func (s *ChunkTimeline) SearchBlocks(predicate BlockPredicate) (events []BlockEvent, err error) {
+	if s.isEmpty {
+		return nil, nil
+	}
+
+	matches := make([]bool, s.blockPalette.BlockPaletteLen()+1)
+	var anyMatch bool
+	for index := range matches {
+		matches[index] = predicate(s.blockPalette.BlockState(uint32(index)))
+		anyMatch = anyMatch || matches[index]
+	}
+	if !anyMatch {
+		return nil, nil
+	}
+
+	r := s.pos.Dimension.Range()
+	current := make(define.ChunkMatrix, (r.Height()>>4)+1)
+	for index := s.barrierLeft; index <= s.barrierRight; index++ {
+		var diff define.ChunkDiffMatrix
+		payload, err := getValue(s.db, define.IndexBlockDu(s.pos, index))
+		if err == nil {
+			diff, err = s.decodeBlockDelta(payload)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) SearchBlocks: %w", s.blockDeltaError(index, err))
+		}
+
+		timePoint := index - s.barrierLeft
+		for subChunkIndex, layersDiff := range diff {
+			layers := current[subChunkIndex]
+
+			for layer := range max(len(layers), len(layersDiff)) {
+				var oldMatrix define.BlockMatrix
+				if layer < len(layers) {
+					oldMatrix = layers[layer]
+				}
+				oldAt := func(ptr uint32) uint32 {
+					if define.BlockMatrixIsEmpty(oldMatrix) {
+						return 0
+					}
+					return oldMatrix[ptr]
+				}
+
+				// The layers that not in the block delta
+				// are cleared (see define.LayerRestore).
+				if layer >= len(layersDiff) {
+					for ptr := range uint32(define.MatrixSize) {
+						events = s.appendBlockEvents(events, matches, timePoint, subChunkIndex, layer, ptr, oldAt(ptr), 0)
+					}
+					continue
+				}
+
+				ptr := uint32(0)
+				for _, value := range layersDiff[layer] {
+					ptr += value.IndexDelta
+					for end := ptr + value.RunLength; ptr <= end; ptr++ {
+						events = s.appendBlockEvents(events, matches, timePoint, subChunkIndex, layer, ptr, oldAt(ptr), value.NewPaletteID)
+					}
+					ptr--
+				}
+			}
+		}
+
+		current = define.ChunkRestore(current, diff)
+	}
+
+	return events, nil
+}
+
+// appendBlockEvents appends the events of the block at ptr of layer in
+// the sub chunk at subChunkIndex, whose block palette index is changed
+// from oldID to newID at timePoint. matches holds whether each block
+// palette index is a matching block.
+func (s *ChunkTimeline) appendBlockEvents(
+	events []BlockEvent, matches []bool,
+	timePoint uint, subChunkIndex int, layer int, ptr uint32,
+	oldID uint32, newID uint32,
+) []BlockEvent {
+	if oldID == newID || (!matches[oldID] && !matches[newID]) {
+		return events
+	}
+
+	blockIndex := define.BlockIndex(ptr)
+	event := BlockEvent{
+		TimePoint:      timePoint,
+		UpdateUnixTime: s.timelineUnixTime[timePoint],
+		X:              blockIndex.X(),
+		Y:              int16((subChunkIndex+s.pos.Dimension.Range()[0]>>4)<<4 + int(blockIndex.Y())),
+		Z:              blockIndex.Z(),
+		Layer:          uint8(layer),
+		OldBlock:       s.blockPalette.BlockRuntimeID(oldID),
+		NewBlock:       s.blockPalette.BlockRuntimeID(newID),
+	}
+
+	if matches[oldID] {
+		events = append(events, event)
+	}
+	if matches[newID] {
+		event.Appeared = true
+		events = append(events, event)
+	}
+	return events
+}
+
+// SearchBlocks returns the events of the blocks that matches predicate
+// (see ChunkTimeline.SearchBlocks) of each chunk in positions, and the
+// chunks that have no event are not in the returned map.
+// If positions is empty, then all the chunk timelines are searched.
+//
+// The timelines are opened as read only one by one, so SearchBlocks
+// will be blocked if some of them are using by other threads.
+func (t *TimelineDB) SearchBlocks(positions []define.DimChunk, predicate BlockPredicate) (result map[define.DimChunk][]BlockEvent, err error) {
+	if len(positions) == 0 {
+		err = t.ForEachChunkTimeline(func(pos define.DimChunk) error {
+			positions = append(positions, pos)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("SearchBlocks: %w", err)
+		}
+	}
+
+	result = make(map[define.DimChunk][]BlockEvent)
+	for _, pos := range positions {
+		if !t.HasChunkTimeline(pos) {
+			continue
+		}
+
+		timeline, err := t.NewChunkTimeline(pos, true)
+		if err != nil {
+			return nil, fmt.Errorf("SearchBlocks: %w", err)
+		}
+		events, err := timeline.SearchBlocks(predicate)
+		timeline.releaseFunc()
+		if err != nil {
+			return nil, fmt.Errorf("SearchBlocks: %w", err)
+		}
+
+		if len(events) > 0 {
+			result[pos] = events
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,52 @@
+package timeline
+
+import (
+	"testing"
+)
+
+func TestSearchBlocks(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 3, 2)
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := tl.SearchBlocks(MatchBlockName("gold_block"))
+	_ = tl.Save()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type event struct {
+		timePoint uint
+		x         uint8
+		appeared  bool
+	}
+	expected := []event{{0, 0, true}, {1, 1, true}, {1, 2, true}, {2, 2, false}}
+	if len(events) != len(expected) {
+		t.Fatalf("SearchBlocks: expected %d events, but got %+v", len(expected), events)
+	}
+	for index, value := range events {
+		got := event{value.TimePoint, value.X, value.Appeared}
+		if got != expected[index] || value.Y != -40 || value.Z != 0 {
+			t.Fatalf("SearchBlocks: event %d is %+v, but expected %+v", index, value, expected[index])
+		}
+	}
+
+	// Search all the chunk timelines
+	result, err := db.SearchBlocks(nil, MatchBlockName("minecraft:gold_block", "diamond_block"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || len(result[testPos]) != len(expected) {
+		t.Fatalf("SearchBlocks: expected the events of testPos, but got %v", result)
+	}
+	result, err = db.SearchBlocks(nil, MatchBlockName("diamond_block"))
+	if err != nil || len(result) != 0 {
+		t.Fatalf("SearchBlocks: expected no event, but got %v and %v", result, err)
+	}
+}
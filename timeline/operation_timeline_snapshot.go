@@ -0,0 +1,158 @@
+package timeline
+
+import (
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// NBTSnapshotInterval is the interval of the full block NBT snapshots.
+// The time point whose key index is a multiple of NBTSnapshotInterval
+// saves all the block NBTs of it, in addition to the block NBT delta.
+//
+// When restoring, the block NBTs are loaded from the snapshot directly,
+// so a broken block NBT delta only affects the time points that before
+// the next snapshot.
+const NBTSnapshotInterval = 16
+
+// BestEffort returns whether this timeline is in best effort mode.
+func (s *ChunkTimeline) BestEffort() bool {
+	return s.bestEffort
+}
+
+// SetBestEffort sets whether this timeline is in best effort mode.
+// It is disabled by default.
+//
+// In best effort mode, the block NBTs that failed to restore are
+// skipped instead of failing the whole chunk, and the broken block
+// NBT deltas are ignored. The skipped block NBTs come back when
+// there is a full block NBT snapshot (see NBTSnapshotInterval).
+// The blocks are always restored strictly.
+//
+// Note that best effort mode only used when reading, the
+// data that saved to the database is never affected.
+//
+// Calling SetBestEffort also resets the counts that returned
+// by SkippedNBTs and IgnoredDeltas.
+func (s *ChunkTimeline) SetBestEffort(enabled bool) {
+	s.bestEffort = enabled
+	s.skippedNBTs, s.ignoredDeltas = 0, 0
+}
+
+// SkippedNBTs returns how many block NBTs are skipped because they
+// failed to restore in best effort mode. The block NBTs that lost
+// with an ignored delta are not counted (see IgnoredDeltas).
+//
+// The count is accumulated by all the reading since this timeline
+// is loaded or SetBestEffort is called, so the same block NBT could
+// be counted more than once.
+func (s *ChunkTimeline) SkippedNBTs() int {
+	return s.skippedNBTs
+}
+
+// IgnoredDeltas returns how many broken deltas (or full block NBT
+// snapshots) are ignored in best effort mode, including the deltas
+// of block NBTs, entities and biomes.
+//
+// The count is accumulated in the same way as SkippedNBTs.
+func (s *ChunkTimeline) IgnoredDeltas() int {
+	return s.ignoredDeltas
+}
+
+// hasNBTSnapshot reports whether the time point whose key index
+// is keyIndex has a full block NBT snapshot in reader.
+func (s *ChunkTimeline) hasNBTSnapshot(reader DatabaseOperation, keyIndex uint) bool {
+	return keyIndex%NBTSnapshotInterval == 0 && reader.Has(define.IndexNBTSnapshot(s.pos, keyIndex))
+}
+
+// putNBTSnapshot saves nbts as the full block NBT snapshot of the time point
+// whose key index is keyIndex, if keyIndex is a multiple of NBTSnapshotInterval.
+func (s *ChunkTimeline) putNBTSnapshot(tran Transaction, keyIndex uint, nbts []define.NBTWithIndex) error {
+	if keyIndex%NBTSnapshotInterval != 0 {
+		return nil
+	}
+
+	payload, err := marshal.NBTSnapshotBytes(nbts, s.codec)
+	if err != nil {
+		return fmt.Errorf("putNBTSnapshot: %w", err)
+	}
+	err = tran.Put(define.IndexNBTSnapshot(s.pos, keyIndex), payload)
+	if err != nil {
+		return fmt.Errorf("putNBTSnapshot: %w", err)
+	}
+
+	return nil
+}
+
+// restoreNBT computes the block NBTs of the time point whose key index is
+// keyIndex, by given the block NBTs of the previous time point (current).
+// The snapshot and block NBT delta are read from reader.
+//
+// If this time point has a full block NBT snapshot, then current is not used.
+// If bestEffort is true, then the broken snapshot and block NBT delta are
+// ignored, and the block NBTs that failed to restore are skipped.
+func (s *ChunkTimeline) restoreNBT(
+	reader DatabaseOperation, keyIndex uint,
+	current []define.NBTWithIndex, bestEffort bool,
+) (result []define.NBTWithIndex, err error) {
+	if s.hasNBTSnapshot(reader, keyIndex) {
+		payload, err := getValue(reader, define.IndexNBTSnapshot(s.pos, keyIndex))
+		if err == nil {
+			result, err = marshal.BytesToNBTSnapshot(payload, s.codec)
+		}
+		if err == nil {
+			return result, nil
+		}
+		if !bestEffort {
+			return nil, fmt.Errorf("restoreNBT: %w", s.nbtSnapshotError(keyIndex, err))
+		}
+		s.ignoredDeltas++
+	}
+
+	var diff define.MultipleDiffNBT
+	payload, err := getValue(reader, define.IndexNBTDu(s.pos, keyIndex))
+	if err == nil {
+		diff, err = marshal.BytesToMultipleDiffNBT(payload, s.codec)
+	}
+	if err != nil {
+		if bestEffort {
+			s.ignoredDeltas++
+			return define.NBTDeepCopy(current)
+		}
+		return nil, fmt.Errorf("restoreNBT: %w", s.nbtDeltaError(keyIndex, err))
+	}
+
+	if bestEffort {
+		var broken []define.ChunkBlockIndex
+		result, broken, err = define.NBTRestoreBestEffort(current, diff)
+		s.skippedNBTs += len(broken)
+	} else {
+		result, err = define.NBTRestore(current, diff)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("restoreNBT: %w", s.nbtDeltaError(keyIndex, err))
+	}
+
+	return result, nil
+}
+
+// nbtDeltaError wraps err that occurred when restoring the block
+// NBTs of the time point whose key index is keyIndex, so the
+// returned error could report where the broken payload is.
+func (s *ChunkTimeline) nbtDeltaError(keyIndex uint, err error) error {
+	return fmt.Errorf(
+		"Block NBT delta of chunk (%d, %d) in dim %d at time index %d is broken: %w",
+		s.pos.ChunkPos[0], s.pos.ChunkPos[1], s.pos.Dimension, keyIndex-s.barrierLeft, corruptError(err),
+	)
+}
+
+// nbtSnapshotError is like nbtDeltaError, but it is used
+// when the full block NBT snapshot of the time point whose
+// key index is keyIndex is broken.
+func (s *ChunkTimeline) nbtSnapshotError(keyIndex uint, err error) error {
+	return fmt.Errorf(
+		"Block NBT snapshot of chunk (%d, %d) in dim %d at time index %d is broken: %w",
+		s.pos.ChunkPos[0], s.pos.ChunkPos[1], s.pos.Dimension, keyIndex-s.barrierLeft, corruptError(err),
+	)
+}
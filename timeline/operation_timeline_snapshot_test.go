@@ -0,0 +1,164 @@
+package timeline
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// testSnapshotTimeline returns a timeline database that have
+// 20 time points at testPos, and the chest in the time point
+// whose index is i have i+1 items. Also returns the underlying
+// database and the key index of the first time point.
+func testSnapshotTimeline(t *testing.T) (db TimelineDatabase, raw DB, barrierLeft uint) {
+	t.Helper()
+
+	raw = OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seeds := make([]int, 20)
+	for i := range seeds {
+		seeds[i] = i + 1
+	}
+	testAppend(t, db, seeds...)
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	return db, raw, tl.barrierLeft
+}
+
+// testSnapshotIndex returns the index of the first time point
+// that have a full block NBT snapshot.
+func testSnapshotIndex(barrierLeft uint) uint {
+	return (barrierLeft+NBTSnapshotInterval-1)/NBTSnapshotInterval*NBTSnapshotInterval - barrierLeft
+}
+
+func TestRestoreNBTResyncFromSnapshot(t *testing.T) {
+	db, raw, barrierLeft := testSnapshotTimeline(t)
+	snapshotIndex := testSnapshotIndex(barrierLeft)
+
+	err := raw.Put(define.IndexNBTDu(testPos, barrierLeft+2), []byte{0xff, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	_, _, _, _, err = tl.JumpTo(5)
+	if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "Block NBT delta") {
+		t.Fatalf("JumpTo: expected a broken block NBT delta, but got %v", err)
+	}
+
+	for _, index := range []uint{snapshotIndex, snapshotIndex + 2} {
+		_, nbts, _, _, err := tl.JumpTo(index)
+		if err != nil {
+			t.Fatalf("JumpTo: %v", err)
+		}
+		if count := testChestCount(t, nbts); count != byte(index+1) {
+			t.Fatalf("JumpTo: expected %d items at time point %d, but got %d", index+1, index, count)
+		}
+	}
+
+	tl.SetBestEffort(true)
+	if _, _, _, _, err = tl.JumpTo(5); err != nil {
+		t.Fatalf("JumpTo: %v", err)
+	}
+	if tl.IgnoredDeltas() != 1 || tl.SkippedNBTs() != 0 {
+		t.Fatalf("expected 1 ignored delta and 0 skipped NBT, but got %d and %d", tl.IgnoredDeltas(), tl.SkippedNBTs())
+	}
+
+	tl.SetBestEffort(true)
+	if tl.IgnoredDeltas() != 0 {
+		t.Fatalf("expected SetBestEffort resets the count, but got %d", tl.IgnoredDeltas())
+	}
+}
+
+func TestRestoreNBTBrokenSnapshot(t *testing.T) {
+	db, raw, barrierLeft := testSnapshotTimeline(t)
+	snapshotIndex := testSnapshotIndex(barrierLeft)
+
+	err := raw.Put(define.IndexNBTSnapshot(testPos, barrierLeft+snapshotIndex), []byte{0xff, 0xff})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	_, _, _, _, err = tl.JumpTo(snapshotIndex)
+	if !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "Block NBT snapshot") {
+		t.Fatalf("JumpTo: expected a broken block NBT snapshot, but got %v", err)
+	}
+
+	// The block NBTs before the snapshot are not restored, so
+	// the chest that modified by the block NBT delta is skipped.
+	tl.SetBestEffort(true)
+	_, nbts, _, _, err := tl.JumpTo(snapshotIndex)
+	if err != nil {
+		t.Fatalf("JumpTo: %v", err)
+	}
+	if len(nbts) != 0 {
+		t.Fatalf("JumpTo: expected no block NBT, but got %v", nbts)
+	}
+	if tl.IgnoredDeltas() != 1 || tl.SkippedNBTs() != 1 {
+		t.Fatalf("expected 1 ignored snapshot and 1 skipped NBT, but got %d and %d", tl.IgnoredDeltas(), tl.SkippedNBTs())
+	}
+}
+
+func TestRestoreNBTSkippedCount(t *testing.T) {
+	db, raw, barrierLeft := testSnapshotTimeline(t)
+
+	// The block at (0, 0, 0) have no block NBT,
+	// so the modification on it can't be restored.
+	var index define.ChunkBlockIndex
+	index.UpdateIndex(0, 0, 0)
+	diff, err := define.NewDiffNBT(
+		&define.NBTWithIndex{Index: index, NBT: map[string]any{"id": "Sign"}},
+		&define.NBTWithIndex{Index: index, NBT: map[string]any{"id": "Sign", "Text": "hello"}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := marshal.MultipleDiffNBTBytes(define.MultipleDiffNBT{Modified: []define.DiffNBTWithIndex{*diff}}, db.Codec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = raw.Put(define.IndexNBTDu(testPos, barrierLeft+3), payload); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	tl.SetBestEffort(true)
+	_, nbts, _, _, err := tl.JumpTo(3)
+	if err != nil {
+		t.Fatalf("JumpTo: %v", err)
+	}
+	if count := testChestCount(t, nbts); count != 3 {
+		t.Fatalf("JumpTo: expected the chest of the previous time point, but got %d items", count)
+	}
+	if tl.SkippedNBTs() != 1 || tl.IgnoredDeltas() != 0 {
+		t.Fatalf("expected 1 skipped NBT and 0 ignored delta, but got %d and %d", tl.SkippedNBTs(), tl.IgnoredDeltas())
+	}
+}
@@ -0,0 +1,40 @@
+package timeline
+
+import "fmt"
+
+// SubChunkDedupEnabled returns whether the sub chunk
+// layers of the latest chunks are deduplicated.
+func (t *TimelineDB) SubChunkDedupEnabled() bool {
+	return t.subChunks.Enabled()
+}
+
+// SetSubChunkDedup sets whether to deduplicate the sub chunk
+// layers of the latest chunks, and save this setting into the
+// underlying database.
+//
+// When enabled, each non-trivial layer of the latest chunk is
+// saved into a shared bucket by its content hash with a reference
+// count, and the latest chunk only holds the references of them.
+// This could shrink the database a lot for the large worlds that
+// most of the chunks are natural (e.g. underground stones).
+//
+// Existing chunk timelines are migrated when they are saved next
+// time, or use RewriteChunkTimeline to migrate them immediately.
+// This is also the same when disabling the deduplication.
+func (t *TimelineDB) SetSubChunkDedup(enabled bool) error {
+	t.subChunks.mu.Lock()
+	defer t.subChunks.mu.Unlock()
+
+	setting := []byte{0}
+	if enabled {
+		setting[0] = 1
+	}
+
+	err := t.Bucket(DatabaseKeyMeta).Put(DatabaseKeySubChunk, setting)
+	if err != nil {
+		return fmt.Errorf("SetSubChunkDedup: %w", err)
+	}
+
+	t.subChunks.enabled = enabled
+	return nil
+}
@@ -0,0 +1,130 @@
+package timeline
+
+import (
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// Summary is the summary of the changes of a
+// time point, which returned by Summaries.
+type Summary struct {
+	// TimePoint is the index of this time
+	// point, which could be used by JumpTo.
+	TimePoint      uint
+	UpdateUnixTime int64
+	define.TimePointSummary
+}
+
+// Summaries returns the summary of each time point of this timeline,
+// from the earliest one to the latest one, which includes the count of
+// changed blocks of each sub chunk, the count of added, removed and
+// modified block NBTs, and the size of the stored deltas.
+//
+// The summaries are saved when appending, so Summaries don't need to
+// decode any delta. For the time points that saved by older versions,
+// their summaries are computed by decoding their deltas.
+//
+// Note that the first time point is compared with an empty chunk.
+// For an empty timeline, return nil.
+func (s *ChunkTimeline) Summaries() (summaries []Summary, err error) {
+	if s.isEmpty {
+		return nil, nil
+	}
+
+	summaries = make([]Summary, 0, s.barrierRight-s.barrierLeft+1)
+	for index := s.barrierLeft; index <= s.barrierRight; index++ {
+		payload, err := getValue(s.db, define.IndexSummary(s.pos, index))
+		if err != nil {
+			return nil, fmt.Errorf("(s *ChunkTimeline) Summaries: %w", err)
+		}
+
+		summary, err := marshal.BytesToTimePointSummary(payload)
+		if err != nil {
+			summary, err = s.computeSummary(index)
+			if err != nil {
+				return nil, fmt.Errorf("(s *ChunkTimeline) Summaries: %w", err)
+			}
+		}
+
+		summaries = append(summaries, Summary{
+			TimePoint:        index - s.barrierLeft,
+			UpdateUnixTime:   s.timelineUnixTime[index-s.barrierLeft],
+			TimePointSummary: summary,
+		})
+	}
+
+	return summaries, nil
+}
+
+// computeSummary computes the summary of the time point whose
+// key index is keyIndex by decoding its block delta and NBT delta.
+func (s *ChunkTimeline) computeSummary(keyIndex uint) (summary define.TimePointSummary, err error) {
+	var chunkDiff define.ChunkDiffMatrix
+	blockPayload, err := getValue(s.db, define.IndexBlockDu(s.pos, keyIndex))
+	if err == nil {
+		chunkDiff, err = marshal.BytesToChunkDiffMatrix(blockPayload, s.pos.Dimension.Range(), s.codec)
+	}
+	if err != nil {
+		return summary, fmt.Errorf("computeSummary: %w", s.blockDeltaError(keyIndex, err))
+	}
+
+	var nbtDiff define.MultipleDiffNBT
+	nbtPayload, err := getValue(s.db, define.IndexNBTDu(s.pos, keyIndex))
+	if err == nil {
+		nbtDiff, err = marshal.BytesToMultipleDiffNBT(nbtPayload, s.codec)
+	}
+	if err != nil {
+		return summary, fmt.Errorf("computeSummary: %w", corruptError(err))
+	}
+
+	summary = define.NewTimePointSummary(chunkDiff, nbtDiff)
+	summary.BlockDeltaSize = uint32(len(blockPayload))
+	summary.NBTDeltaSize = uint32(len(nbtPayload))
+	return summary, nil
+}
+
+// putSummary writes summary as the summary of the time point whose
+// key index is keyIndex by tran. The size of the deltas are read from
+// tran, so the deltas of this time point must be written first.
+func (s *ChunkTimeline) putSummary(tran Transaction, keyIndex uint, summary define.TimePointSummary) error {
+	blockPayload, err := getValue(tran, define.IndexBlockDu(s.pos, keyIndex))
+	if err != nil {
+		return fmt.Errorf("putSummary: %w", err)
+	}
+	nbtPayload, err := getValue(tran, define.IndexNBTDu(s.pos, keyIndex))
+	if err != nil {
+		return fmt.Errorf("putSummary: %w", err)
+	}
+	summary.BlockDeltaSize = uint32(len(blockPayload))
+	summary.NBTDeltaSize = uint32(len(nbtPayload))
+
+	err = tran.Put(define.IndexSummary(s.pos, keyIndex), marshal.TimePointSummaryToBytes(summary))
+	if err != nil {
+		return fmt.Errorf("putSummary: %w", err)
+	}
+	return nil
+}
+
+// refreshSummarySize updates the size of the deltas in the summary of
+// the time point whose key index is keyIndex by tran, which is used
+// after the deltas are rewritten. If the summary is not exist (or is
+// broken), then do nothing, and it will be computed when reading.
+func (s *ChunkTimeline) refreshSummarySize(tran Transaction, keyIndex uint) error {
+	payload, err := getValue(tran, define.IndexSummary(s.pos, keyIndex))
+	if err != nil {
+		return fmt.Errorf("refreshSummarySize: %w", err)
+	}
+
+	summary, err := marshal.BytesToTimePointSummary(payload)
+	if err != nil {
+		return nil
+	}
+
+	err = s.putSummary(tran, keyIndex, summary)
+	if err != nil {
+		return fmt.Errorf("refreshSummarySize: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package timeline
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+)
+
+func TestSummaries(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 2, 4)
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	summaries, err := tl.Summaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	barrierLeft := tl.barrierLeft
+	tl.Save()
+
+	// The first time point is compared with an empty chunk,
+	// so all the stone and the gold block are changed.
+	expected := []struct {
+		blockChanges int
+		nbtAdded     uint32
+		nbtModified  uint32
+	}{
+		{16*16*24 + 1, 1, 0},
+		{1, 0, 1},
+		{2, 0, 1},
+	}
+	if len(summaries) != len(expected) {
+		t.Fatalf("expected %d summaries, but got %d", len(expected), len(summaries))
+	}
+	for index, value := range expected {
+		summary := summaries[index]
+		if summary.TimePoint != uint(index) {
+			t.Fatalf("expected time point %d, but got %d", index, summary.TimePoint)
+		}
+		if summary.TotalBlockChanges() != value.blockChanges || summary.NBTAdded != value.nbtAdded || summary.NBTModified != value.nbtModified {
+			t.Fatalf("time point %d: expected %+v, but got %+v", index, value, summary.TimePointSummary)
+		}
+		if summary.BlockDeltaSize == 0 || summary.NBTDeltaSize == 0 {
+			t.Fatalf("time point %d: the size of the stored deltas is 0", index)
+		}
+	}
+
+	// The summaries that saved by older versions are
+	// not exist, so they should be computed from the deltas.
+	for index := range summaries {
+		if err = raw.Delete(define.IndexSummary(testPos, barrierLeft+uint(index))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	computed, err := tl.Summaries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(computed, summaries) {
+		t.Fatalf("expected the computed summaries %+v, but got %+v", summaries, computed)
+	}
+}
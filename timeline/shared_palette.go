@@ -0,0 +1,190 @@
+package timeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// sharedPaletteFlag is set on the length of the block palette
+// in the global data of a chunk, when the block palette is saved
+// as the global IDs of the shared palette but not block states.
+const sharedPaletteFlag = 1 << 31
+
+// sharedPalette is the block palette that shared by all the
+// chunks in the same timeline database, which maps the block
+// states to stable global IDs.
+//
+// The global IDs are starting from 1, and will not be reused
+// unless the entry is removed by GC and the database reopened.
+//
+// The entries whose block state was upgraded when loading are
+// outdated, and they are written back when a chunk timeline
+// that using them is saved.
+type sharedPalette struct {
+	mu       *sync.Mutex
+	registry *define.BlockRegistry
+	enabled  bool
+	nextID   uint32
+	blocks   map[uint32]uint32
+	ids      map[uint32]uint32
+	outdated map[uint32]bool
+}
+
+// sharedPaletteEntry is an entry of the shared palette,
+// which is pending to be added.
+type sharedPaletteEntry struct {
+	id             uint32
+	blockRuntimeID uint32
+}
+
+// encodeBlockState writes the block state of the block whose block runtime
+// ID is blockRuntimeID to buf as little endian NBT, and the block state is
+// found from registry.
+func encodeBlockState(buf *bytes.Buffer, registry *define.BlockRegistry, blockRuntimeID uint32) {
+	utils.MarshalNBT(buf, registry.EncodeBlockState(blockRuntimeID), "")
+}
+
+// loadSharedPalette loads the shared palette that saved in db.
+// The block states of the entries are decoded by registry.
+func loadSharedPalette(db DB, registry *define.BlockRegistry) (result *sharedPalette, err error) {
+	result = &sharedPalette{
+		mu:       new(sync.Mutex),
+		registry: registry,
+		nextID:   1,
+		blocks:   make(map[uint32]uint32),
+		ids:      make(map[uint32]uint32),
+		outdated: make(map[uint32]bool),
+	}
+
+	setting, err := getValue(db.Bucket(DatabaseKeyMeta), DatabaseKeyPalette)
+	if err != nil {
+		return nil, fmt.Errorf("loadSharedPalette: %w", err)
+	}
+	result.enabled = len(setting) > 0 && setting[0] != 0
+
+	err = db.Bucket(DatabaseKeyPalette).ForEach(func(key []byte, value []byte) error {
+		if len(key) != 4 {
+			return fmt.Errorf("%w (shared palette entry %v is broken)", ErrCorrupt, key)
+		}
+		id := binary.LittleEndian.Uint32(key)
+
+		result.nextID = max(result.nextID, id+1)
+
+		// The entries that could not be decoded are not added, so
+		// only the chunks that using them will fail to be loaded.
+		m, err := define.ReadNBT(bytes.NewBuffer(value))
+		if err != nil {
+			return nil
+		}
+		blockRuntimeID, upgraded, err := registry.DecodeBlockState(m)
+		if err != nil {
+			return nil
+		}
+
+		if upgraded {
+			result.outdated[id] = true
+		}
+		result.blocks[id] = blockRuntimeID
+		if _, ok := result.ids[blockRuntimeID]; !ok {
+			result.ids[blockRuntimeID] = id
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loadSharedPalette: %w", err)
+	}
+
+	return result, nil
+}
+
+// lock locks p if the shared palette is enabled,
+// and returns the function to unlock it.
+// If not enabled, then p will not be locked.
+func (p *sharedPalette) lock() (enabled bool, unlock func()) {
+	p.mu.Lock()
+	if !p.enabled {
+		p.mu.Unlock()
+		return false, func() {}
+	}
+	return true, p.mu.Unlock
+}
+
+// encode encodes blockRuntimeIDs to the global IDs of the shared
+// palette. The blocks that not in the shared palette will be added
+// by tran, and they are returned as pending entries which should be
+// committed by calling p.commit after tran is committed.
+// The outdated entries that used are also written back by tran.
+//
+// The caller must hold the lock of p.
+func (p *sharedPalette) encode(tran Transaction, blockRuntimeIDs []uint32) (
+	payload []byte, pending []sharedPaletteEntry, err error,
+) {
+	payload = make([]byte, 0, len(blockRuntimeIDs)*4)
+	written := make(map[uint32]uint32)
+
+	for _, blockRuntimeID := range blockRuntimeIDs {
+		if id, ok := written[blockRuntimeID]; ok {
+			payload = binary.LittleEndian.AppendUint32(payload, id)
+			continue
+		}
+
+		id, ok := p.ids[blockRuntimeID]
+		if !ok || p.outdated[id] {
+			if !ok {
+				id = p.nextID
+				p.nextID++
+			}
+
+			buf := bytes.NewBuffer(nil)
+			encodeBlockState(buf, p.registry, blockRuntimeID)
+
+			err = tran.Bucket(DatabaseKeyPalette).Put(binary.LittleEndian.AppendUint32(nil, id), buf.Bytes())
+			if err != nil {
+				return nil, nil, fmt.Errorf("encode: %w", err)
+			}
+			pending = append(pending, sharedPaletteEntry{id: id, blockRuntimeID: blockRuntimeID})
+			written[blockRuntimeID] = id
+		}
+		payload = binary.LittleEndian.AppendUint32(payload, id)
+	}
+
+	return payload, pending, nil
+}
+
+// commit adds the pending entries to p.
+// The caller must hold the lock of p.
+func (p *sharedPalette) commit(pending []sharedPaletteEntry) {
+	for _, entry := range pending {
+		delete(p.outdated, entry.id)
+		p.blocks[entry.id] = entry.blockRuntimeID
+		p.ids[entry.blockRuntimeID] = entry.id
+	}
+}
+
+// decode decodes the global IDs in payload
+// to their block runtime IDs.
+func (p *sharedPalette) decode(payload []byte) (blockRuntimeIDs []uint32, err error) {
+	if len(payload)%4 != 0 {
+		return nil, fmt.Errorf("decode: %w (shared palette IDs is truncated)", ErrCorrupt)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	blockRuntimeIDs = make([]uint32, 0, len(payload)/4)
+	for ; len(payload) > 0; payload = payload[4:] {
+		id := binary.LittleEndian.Uint32(payload)
+		blockRuntimeID, ok := p.blocks[id]
+		if !ok {
+			return nil, fmt.Errorf("decode: %w (shared palette entry %d is not found)", ErrCorrupt, id)
+		}
+		blockRuntimeIDs = append(blockRuntimeIDs, blockRuntimeID)
+	}
+
+	return blockRuntimeIDs, nil
+}
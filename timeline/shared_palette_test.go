@@ -0,0 +1,138 @@
+package timeline
+
+import (
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+)
+
+// testPaletteShared reports whether the block palette of the
+// timeline of testPos in db is saved as the shared palette.
+func testPaletteShared(t *testing.T, db TimelineDatabase) bool {
+	t.Helper()
+
+	globalData, err := db.Codec().Decode(db.Get(define.Sum(testPos, []byte(define.KeyChunkGlobalData)...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, isShared, err := sharedPaletteIDs(globalData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return isShared
+}
+
+// testSharedPaletteLen returns the count of
+// the entries in the shared palette of db.
+func testSharedPaletteLen(t *testing.T, db TimelineDatabase) (count int) {
+	t.Helper()
+
+	err := db.Bucket(DatabaseKeyPalette).ForEach(func(key []byte, value []byte) error {
+		if len(key) == 4 {
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+// testCheckTimeline checks the timeline of testPos
+// in db have the chunks of each seed in seeds.
+func testCheckTimeline(t *testing.T, db TimelineDatabase, seeds ...int) {
+	t.Helper()
+
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+	testCheckChunks(t, tl, seeds...)
+}
+
+func TestSharedPaletteMigration(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 2)
+	if testPaletteShared(t, db) || testSharedPaletteLen(t, db) != 0 {
+		t.Fatal("expected the block palette is not shared by default")
+	}
+
+	// Existing timelines are migrated when they are rewritten
+	if err = db.SetSharedPalette(true); err != nil {
+		t.Fatal(err)
+	}
+	if testPaletteShared(t, db) {
+		t.Fatal("expected the block palette is not migrated before rewritten")
+	}
+	if err = db.RewriteChunkTimeline(testPos); err != nil {
+		t.Fatal(err)
+	}
+	if !testPaletteShared(t, db) || testSharedPaletteLen(t, db) != 2 {
+		t.Fatalf("expected the block palette is migrated, but got %d shared entries", testSharedPaletteLen(t, db))
+	}
+	testCheckTimeline(t, db, 1, 2)
+
+	// Or when they are saved next time
+	if err = db.SetSharedPalette(false); err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 3)
+	if testPaletteShared(t, db) {
+		t.Fatal("expected the block palette is migrated back after saved")
+	}
+
+	// The setting and the shared palette are loaded again
+	reopened, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.SharedPaletteEnabled() {
+		t.Fatal("expected the shared palette is disabled after reopened")
+	}
+	testCheckTimeline(t, reopened, 1, 2, 3)
+}
+
+func TestGCSharedPalette(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.SetSharedPalette(true); err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 2)
+
+	if removed, err := db.GCSharedPalette(); err != nil || removed != 0 {
+		t.Fatalf("GCSharedPalette: expected nothing is removed, but got %d and %v", removed, err)
+	}
+
+	if err = db.SetSharedPalette(false); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RewriteChunkTimeline(testPos); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := db.GCSharedPalette(); err != nil || removed != 2 {
+		t.Fatalf("GCSharedPalette: expected 2 entries are removed, but got %d and %v", removed, err)
+	}
+	if testSharedPaletteLen(t, db) != 0 {
+		t.Fatalf("expected the shared palette is empty, but got %d entries", testSharedPaletteLen(t, db))
+	}
+	testCheckTimeline(t, db, 1, 2)
+
+	// The removed entries are added again when used
+	if err = db.SetSharedPalette(true); err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 3)
+	if !testPaletteShared(t, db) || testSharedPaletteLen(t, db) != 2 {
+		t.Fatalf("expected 2 shared entries, but got %d", testSharedPaletteLen(t, db))
+	}
+	testCheckTimeline(t, db, 1, 2, 3)
+}
@@ -0,0 +1,235 @@
+package timeline
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// subChunkMinSize is the min size of a sub chunk layer that
+// could be deduplicated. The smaller layers (e.g. the layers
+// that only have one block) are saved directly, because their
+// references are not smaller than themselves.
+const subChunkMinSize = 64
+
+// subChunkStore holds the setting of the deduplication of the
+// sub chunk layers.
+//
+// When enabled, each non-trivial layer of the latest chunk is
+// saved into a shared bucket by its content hash with a reference
+// count, and the latest chunk only holds the references of them.
+// The layers are saved as block runtime IDs but not the index of
+// block palette, so the same layers in different chunks could be
+// deduplicated.
+type subChunkStore struct {
+	mu      *sync.Mutex
+	enabled bool
+}
+
+// loadSubChunkStore loads the setting of the
+// sub chunk deduplication that saved in db.
+func loadSubChunkStore(db DB) (result *subChunkStore, err error) {
+	setting, err := getValue(db.Bucket(DatabaseKeyMeta), DatabaseKeySubChunk)
+	if err != nil {
+		return nil, fmt.Errorf("loadSubChunkStore: %w", err)
+	}
+	return &subChunkStore{
+		mu:      new(sync.Mutex),
+		enabled: len(setting) > 0 && setting[0] != 0,
+	}, nil
+}
+
+// Enabled returns whether the sub chunk deduplication is enabled.
+func (s *subChunkStore) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.enabled
+}
+
+// encodeLatestChunk encodes chunkMatrix, which is the latest chunk of
+// this timeline. If the sub chunk deduplication is enabled, then the
+// layers are saved into the shared bucket by tran.
+//
+// The reference counts of the layers that the old latest chunk used
+// are also released by tran, so the returned payload must be written
+// as the new latest chunk by tran.
+func (s *ChunkTimeline) encodeLatestChunk(tran Transaction, chunkMatrix define.ChunkMatrix) (payload []byte, err error) {
+	counts := make(map[string]int)
+	contents := make(map[string][]byte)
+
+	var referencer marshal.LayerReferencer
+	if s.subChunks.Enabled() {
+		referencer = func(blockMatrix define.BlockMatrix) (ref []byte, err error) {
+			runtimeIDs := define.NewBlockMatrix()
+			for index, value := range blockMatrix {
+				runtimeIDs[index] = s.blockPalette.BlockRuntimeID(value)
+			}
+
+			buf := bytes.NewBuffer(nil)
+			marshal.BlockMatrixToBytes(buf, runtimeIDs)
+			if buf.Len() < subChunkMinSize {
+				return nil, nil
+			}
+
+			sum := sha256.Sum256(buf.Bytes())
+			ref = sum[:marshal.ReferenceSize]
+			counts[string(ref)]++
+			contents[string(ref)] = buf.Bytes()
+			return ref, nil
+		}
+	}
+
+	payload, err = marshal.ChunkMatrixToBytesWithReference(chunkMatrix, s.codec, referencer)
+	if err != nil {
+		return nil, fmt.Errorf("encodeLatestChunk: %w", err)
+	}
+
+	oldRefs, err := latestChunkReferences(tran, s.codec, s.pos)
+	if err != nil {
+		return nil, fmt.Errorf("encodeLatestChunk: %w", err)
+	}
+	for _, ref := range oldRefs {
+		counts[string(ref)]--
+	}
+
+	err = updateSubChunkReferences(tran, s.codec, counts, contents)
+	if err != nil {
+		return nil, fmt.Errorf("encodeLatestChunk: %w", err)
+	}
+	return payload, nil
+}
+
+// releaseLatestChunk releases the reference counts of the
+// layers that the latest chunk of chunk who at pos used.
+func releaseLatestChunk(tran Transaction, codec *utils.Codec, pos define.DimChunk) error {
+	oldRefs, err := latestChunkReferences(tran, codec, pos)
+	if err != nil {
+		return fmt.Errorf("releaseLatestChunk: %w", err)
+	}
+	if len(oldRefs) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	for _, ref := range oldRefs {
+		counts[string(ref)]--
+	}
+
+	err = updateSubChunkReferences(tran, codec, counts, nil)
+	if err != nil {
+		return fmt.Errorf("releaseLatestChunk: %w", err)
+	}
+	return nil
+}
+
+// latestChunkReferences returns the references of the layers that
+// the latest chunk of chunk who at pos used, which is read by tran.
+//
+// If the latest chunk is broken, then returns an error that matches
+// ErrCorrupt, because the reference counts of its layers can't be
+// released, and they would be kept forever by the garbage collection.
+func latestChunkReferences(tran Transaction, codec *utils.Codec, pos define.DimChunk) (refs [][]byte, err error) {
+	payload, err := getValue(tran, define.Sum(pos, define.KeyLatestChunk))
+	if err != nil {
+		return nil, fmt.Errorf("latestChunkReferences: %w", err)
+	}
+
+	refs, err = marshal.ChunkMatrixReferences(payload, codec)
+	if err != nil {
+		return nil, fmt.Errorf("latestChunkReferences: %w", corruptError(err))
+	}
+	return refs, nil
+}
+
+// updateSubChunkReferences adds counts to the reference count of each
+// layer by tran. The layers whose reference count become 0 are deleted,
+// and the new layers are saved with the content that in contents.
+//
+// Each layer is saved as its reference count (4 bytes) and then its
+// content that encoded by codec.
+func updateSubChunkReferences(tran Transaction, codec *utils.Codec, counts map[string]int, contents map[string][]byte) error {
+	bucket := tran.Bucket(DatabaseKeySubChunk)
+
+	for ref, delta := range counts {
+		if delta == 0 {
+			continue
+		}
+
+		var count int64
+		value, err := getValue(bucket, []byte(ref))
+		if err != nil {
+			return fmt.Errorf("updateSubChunkReferences: %w", err)
+		}
+		if len(value) >= 4 {
+			count = int64(binary.LittleEndian.Uint32(value))
+		}
+		count += int64(delta)
+
+		if count <= 0 {
+			if len(value) > 0 {
+				if err := bucket.Delete([]byte(ref)); err != nil {
+					return fmt.Errorf("updateSubChunkReferences: %w", err)
+				}
+			}
+			continue
+		}
+
+		if len(value) < 4 {
+			content, ok := contents[ref]
+			if !ok {
+				return fmt.Errorf("updateSubChunkReferences: %w (sub chunk layer %x is not found)", ErrCorrupt, ref)
+			}
+			encoded, err := codec.Encode(content)
+			if err != nil {
+				return fmt.Errorf("updateSubChunkReferences: %w", err)
+			}
+			value = append(make([]byte, 4), utils.WithChecksum(encoded)...)
+		} else {
+			value = bytes.Clone(value)
+		}
+
+		binary.LittleEndian.PutUint32(value, uint32(count))
+		if err := bucket.Put([]byte(ref), value); err != nil {
+			return fmt.Errorf("updateSubChunkReferences: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// subChunkResolver returns the resolver that used to resolve the
+// references of the layers in the latest chunk of this timeline.
+// The layers are converted to the index of the block palette of
+// this timeline.
+func (s *ChunkTimeline) subChunkResolver() marshal.LayerResolver {
+	return func(ref []byte) (blockMatrix define.BlockMatrix, err error) {
+		value, err := getValue(s.db.Bucket(DatabaseKeySubChunk), ref)
+		if err != nil {
+			return nil, fmt.Errorf("subChunkResolver: %w", err)
+		}
+		if len(value) < 4 {
+			return nil, fmt.Errorf("subChunkResolver: %w (sub chunk layer %x is not found)", ErrCorrupt, ref)
+		}
+
+		content, err := s.codec.Decode(value[4:])
+		if err != nil {
+			return nil, fmt.Errorf("subChunkResolver: %w", corruptError(err))
+		}
+		runtimeIDs, err := marshal.BytesToBlockMatrix(bytes.NewBuffer(content))
+		if err != nil || define.BlockMatrixIsEmpty(runtimeIDs) {
+			return nil, fmt.Errorf("subChunkResolver: %w (sub chunk layer %x is broken)", ErrCorrupt, ref)
+		}
+
+		blockMatrix = define.NewBlockMatrix()
+		for index, value := range runtimeIDs {
+			blockMatrix[index] = s.blockPalette.BlockPaletteIndex(value)
+		}
+		return blockMatrix, nil
+	}
+}
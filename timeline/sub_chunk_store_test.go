@@ -0,0 +1,137 @@
+package timeline
+
+import (
+	"encoding/binary"
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+	operator_define "github.com/TriM-Organization/bedrock-world-operator/define"
+)
+
+// testOtherPos is the chunk position that have
+// the same chunks as testPos in the tests.
+var testOtherPos = define.DimChunk{ChunkPos: operator_define.ChunkPos{4, -7}}
+
+// testCheckSubChunkRefs checks the reference count of each layer in
+// the shared bucket is the same as the count of the latest chunks that
+// use it, and returns these reference counts.
+func testCheckSubChunkRefs(t *testing.T, db TimelineDatabase) map[string]int {
+	t.Helper()
+
+	expected := make(map[string]int)
+	err := db.ForEachChunkTimeline(func(pos define.DimChunk) error {
+		refs, err := marshal.ChunkMatrixReferences(db.Get(define.Sum(pos, define.KeyLatestChunk)), db.Codec())
+		for _, ref := range refs {
+			expected[string(ref)]++
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts := make(map[string]int)
+	err = db.Bucket(DatabaseKeySubChunk).ForEach(func(key []byte, value []byte) error {
+		counts[string(key)] = int(binary.LittleEndian.Uint32(value))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !maps.Equal(counts, expected) {
+		t.Fatalf("expected the reference counts are %v, but got %v", expected, counts)
+	}
+	return counts
+}
+
+func TestSubChunkReferenceCount(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.SetSubChunkDedup(true); err != nil {
+		t.Fatal(err)
+	}
+
+	testAppend(t, db, 1, 2)
+	tl, err := db.NewChunkTimeline(testOtherPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, _ := testChunk(t, 2)
+	if err = tl.Append(c, nil, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both chunks use the same layers
+	counts := testCheckSubChunkRefs(t, db)
+	if len(counts) == 0 {
+		t.Fatal("expected some layers are deduplicated")
+	}
+	for ref, count := range counts {
+		if count != 2 {
+			t.Fatalf("expected layer %x is used twice, but got %d", ref, count)
+		}
+	}
+
+	// The layers of the older latest chunk are released
+	testAppend(t, db, 3)
+	testCheckSubChunkRefs(t, db)
+
+	// Pop don't change the latest chunk
+	tl, err = db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Pop(); err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+	testCheckSubChunkRefs(t, db)
+	testCheckTimeline(t, db, 2, 3)
+
+	if err = db.DeleteChunkTimeline(testPos); err != nil {
+		t.Fatal(err)
+	}
+	testCheckSubChunkRefs(t, db)
+	if err = db.DeleteChunkTimeline(testOtherPos); err != nil {
+		t.Fatal(err)
+	}
+	if counts = testCheckSubChunkRefs(t, db); len(counts) != 0 {
+		t.Fatalf("expected all layers are released, but got %v", counts)
+	}
+}
+
+func TestSubChunkBrokenLatestChunk(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.SetSubChunkDedup(true); err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1)
+
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The references of the broken latest chunk can't be released,
+	// so saving the timeline fails instead of leaking them.
+	if err = db.Put(define.Sum(testPos, define.KeyLatestChunk), []byte{0xff, 0xff}); err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.Save(); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("Save: expected ErrCorrupt, but got %v", err)
+	}
+}
@@ -8,9 +8,6 @@ import (
 	"github.com/TriM-Organization/bedrock-chunk-diff/define"
 	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
 	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
-	"github.com/TriM-Organization/bedrock-world-operator/chunk"
-	"github.com/sandertv/gophertunnel/minecraft/nbt"
-	"go.etcd.io/bbolt"
 )
 
 const DefaultMaxLimit = 7
@@ -30,25 +27,42 @@ const DefaultMaxLimit = 7
 // one thread is using this object.
 type ChunkTimeline struct {
 	db          DB
+	codec       *utils.Codec
+	palette     *sharedPalette
+	subChunks   *subChunkStore
 	pos         define.DimChunk
 	releaseFunc func()
 
 	isReadOnly bool
 	isEmpty    bool
+	bestEffort bool
+
+	skippedNBTs   int
+	ignoredDeltas int
 
 	timelineUnixTime []int64
 	blockPalette     *define.BlockPalette
+	// paletteRemap maps the block palette index that saved in the
+	// database to the one in blockPalette, and it is only non-nil
+	// for read only timelines whose saved block palette have duplicate
+	// entries (see define.BlockPalette.Deduplicate).
+	paletteRemap []uint32
 
 	ptr          uint
 	barrierLeft  uint
 	barrierRight uint
 	maxLimit     uint
 
-	currentChunk define.ChunkMatrix
-	currentNBT   []define.NBTWithIndex
+	currentChunk    define.ChunkMatrix
+	currentNBT      []define.NBTWithIndex
+	currentEntities []define.EntityWithID
+	currentBiomes   define.ChunkBiomeMatrix
 
-	latestChunk define.ChunkMatrix
-	latestNBT   []define.NBTWithIndex
+	latestChunk    define.ChunkMatrix
+	latestNBT      []define.NBTWithIndex
+	latestEntities []define.EntityWithID
+	latestBiomes   define.ChunkBiomeMatrix
+	latestHash     []uint64
 }
 
 // NewChunkTimeline gets the timeline of a chunk who is at pos.
@@ -75,13 +89,28 @@ type ChunkTimeline struct {
 //   - Returned ChunkTimeline can't shared with multiple threads, and it's your responsibility
 //     to ensure this thing.
 func (t *TimelineDB) NewChunkTimeline(pos define.DimChunk, readOnly bool) (result *ChunkTimeline, err error) {
-	var exist bool
-	var success bool
-
 	releaseFunc, succ := t.sessions.Require(pos)
 	if !succ {
-		return nil, fmt.Errorf("NewChunkTimeline: Underlying database is closed")
+		return nil, fmt.Errorf("NewChunkTimeline: %w", ErrClosed)
+	}
+	return t.newChunkTimeline(pos, readOnly, releaseFunc)
+}
+
+// TryNewChunkTimeline is like NewChunkTimeline, but it will not blocking
+// when there is still some threads are using target chunk. Instead, an
+// error that matches ErrBusy will be returned.
+func (t *TimelineDB) TryNewChunkTimeline(pos define.DimChunk, readOnly bool) (result *ChunkTimeline, err error) {
+	releaseFunc, err := t.sessions.TryRequire(pos)
+	if err != nil {
+		return nil, fmt.Errorf("TryNewChunkTimeline: %w", err)
 	}
+	return t.newChunkTimeline(pos, readOnly, releaseFunc)
+}
+
+// newChunkTimeline is an internal implement detail.
+// If failed, releaseFunc will be called.
+func (t *TimelineDB) newChunkTimeline(pos define.DimChunk, readOnly bool, releaseFunc func()) (result *ChunkTimeline, err error) {
+	var success bool
 
 	defer func() {
 		if !success {
@@ -91,46 +120,59 @@ func (t *TimelineDB) NewChunkTimeline(pos define.DimChunk, readOnly bool) (resul
 
 	result = &ChunkTimeline{
 		db:               t.DB,
+		codec:            t.codec,
+		palette:          t.palette,
+		subChunks:        t.subChunks,
 		pos:              pos,
 		releaseFunc:      releaseFunc,
 		isReadOnly:       readOnly,
 		isEmpty:          false,
 		timelineUnixTime: nil,
-		blockPalette:     define.NewBlockPalette(),
+		blockPalette:     define.NewBlockPaletteWithRegistry(t.blocks),
 		ptr:              0,
 		barrierLeft:      0,
 		barrierRight:     0,
 		maxLimit:         DefaultMaxLimit,
 		currentChunk:     make(define.ChunkMatrix, pos.Dimension.Height()>>4),
 		currentNBT:       nil,
+		currentEntities:  nil,
+		currentBiomes:    make(define.ChunkBiomeMatrix, pos.Dimension.Height()>>4),
 		latestChunk:      make(define.ChunkMatrix, pos.Dimension.Height()>>4),
 		latestNBT:        nil,
+		latestEntities:   nil,
+		latestBiomes:     make(define.ChunkBiomeMatrix, pos.Dimension.Height()>>4),
 	}
 
-	err = t.DB.(*database).bdb.View(func(tx *bbolt.Tx) error {
-		exist = (tx.Bucket(DatabaseKeyChunkIndex).Get(define.Index(pos)) != nil)
-		return nil
-	})
-	if err != nil {
-		return nil, fmt.Errorf("NewChunkTimeline: %v", err)
-	}
-	if !exist {
+	if !t.HasChunkTimeline(pos) {
+		err = result.loadSubChunkHashes()
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+		}
 		result.isEmpty = true
 		success = true
 		return result, nil
 	}
 
-	gzippedGlobalData := t.Get(
+	compressedGlobalData, err := getValue(t.DB,
 		define.Sum(pos, []byte(define.KeyChunkGlobalData)...),
 	)
-	globalData, err := utils.Ungzip(gzippedGlobalData)
 	if err != nil {
-		return nil, fmt.Errorf("NewChunkTimeline: %v", err)
+		return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+	}
+	globalData, err := t.codec.Decode(compressedGlobalData)
+	if err != nil {
+		return nil, fmt.Errorf("NewChunkTimeline: %w", corruptError(err))
 	}
 
 	// Timeline Unix Time
 	{
+		if len(globalData) < 4 || uint64(binary.LittleEndian.Uint32(globalData))+4 > uint64(len(globalData)) {
+			return nil, fmt.Errorf("NewChunkTimeline: %w: %w (timeline unix time is truncated)", ErrCorrupt, define.ErrMalformed)
+		}
 		length := binary.LittleEndian.Uint32(globalData)
+		if length%8 != 0 {
+			return nil, fmt.Errorf("NewChunkTimeline: %w: %w (timeline unix time is broken)", ErrCorrupt, define.ErrMalformed)
+		}
 		payload := globalData[4 : 4+length]
 		for len(payload) > 0 {
 			result.timelineUnixTime = append(result.timelineUnixTime, int64(binary.LittleEndian.Uint64(payload)))
@@ -141,22 +183,38 @@ func (t *TimelineDB) NewChunkTimeline(pos define.DimChunk, readOnly bool) (resul
 
 	// Block Palette
 	{
-		length := binary.LittleEndian.Uint32(globalData)
+		if len(globalData) < 4 || uint64(binary.LittleEndian.Uint32(globalData)&^sharedPaletteFlag)+4 > uint64(len(globalData)) {
+			return nil, fmt.Errorf("NewChunkTimeline: %w: %w (block palette is truncated)", ErrCorrupt, define.ErrMalformed)
+		}
+		length := binary.LittleEndian.Uint32(globalData) &^ sharedPaletteFlag
+		isShared := binary.LittleEndian.Uint32(globalData)&sharedPaletteFlag != 0
 		payload := globalData[4 : 4+length]
-		buf := bytes.NewBuffer(payload)
 
-		for buf.Len() > 0 {
-			var m map[string]any
-			if err := nbt.NewDecoderWithEncoding(buf, nbt.LittleEndian).Decode(&m); err != nil {
-				return nil, fmt.Errorf("NewChunkTimeline: error decoding block palette entry: %w", err)
-			}
-
-			blockRuntimeID, err := chunk.BlockPaletteEncoding.DecodeBlockState(m)
+		if isShared {
+			blockRuntimeIDs, err := t.palette.decode(payload)
 			if err != nil {
-				return nil, fmt.Errorf("NewChunkTimeline: %v", err)
+				return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+			}
+			for _, blockRuntimeID := range blockRuntimeIDs {
+				result.blockPalette.AppendBlock(blockRuntimeID)
+			}
+		} else {
+			buf := bytes.NewBuffer(payload)
+			for buf.Len() > 0 {
+				m, err := define.ReadNBT(buf)
+				if err != nil {
+					return nil, fmt.Errorf("NewChunkTimeline: error decoding block palette entry: %w", corruptError(err))
+				}
+
+				// The outdated block states are upgraded here, and
+				// they are written back when this timeline is saved.
+				blockRuntimeID, _, err := t.blocks.DecodeBlockState(m)
+				if err != nil {
+					return nil, fmt.Errorf("NewChunkTimeline: %w", corruptError(err))
+				}
+
+				result.blockPalette.AppendBlock(blockRuntimeID)
 			}
-
-			result.blockPalette.AddBlock(blockRuntimeID)
 		}
 
 		globalData = globalData[4+length:]
@@ -165,42 +223,119 @@ func (t *TimelineDB) NewChunkTimeline(pos define.DimChunk, readOnly bool) (resul
 	// Barrier and Max limit
 	{
 		if len(globalData) < 12 {
-			return nil, fmt.Errorf("NewChunkTimeline: Barrier and limit is broken (only get %d bytes but expected 12)", len(globalData))
+			return nil, fmt.Errorf("NewChunkTimeline: %w (barrier and limit is broken, only get %d bytes but expected 12)", ErrCorrupt, len(globalData))
 		}
 		result.barrierLeft = uint(binary.LittleEndian.Uint32(globalData))
 		result.ptr = result.barrierLeft
 		result.barrierRight = uint(binary.LittleEndian.Uint32(globalData[4:]))
 		result.maxLimit = uint(binary.LittleEndian.Uint32(globalData[8:]))
+
+		if result.barrierLeft > result.barrierRight || uint(len(result.timelineUnixTime)) != result.barrierRight-result.barrierLeft+1 {
+			return nil, fmt.Errorf(
+				"NewChunkTimeline: %w: %w (barrier [%d, %d] is not match the %d time points)",
+				ErrCorrupt, define.ErrMalformed, result.barrierLeft, result.barrierRight, len(result.timelineUnixTime),
+			)
+		}
 	}
 
 	// Latest Chunk
 	{
-		latestChunkBytes := t.Get(
+		latestChunkBytes, err := getValue(t.DB,
 			define.Sum(pos, define.KeyLatestChunk),
 		)
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+		}
 
-		chunkMatrix, err := marshal.BytesToChunkMatrix(latestChunkBytes, pos.Dimension.Range())
+		chunkMatrix, err := marshal.BytesToChunkMatrixWithResolver(
+			latestChunkBytes, pos.Dimension.Range(), t.codec, result.subChunkResolver(),
+		)
+		if err == nil {
+			err = define.ValidateChunkMatrix(chunkMatrix, result.blockPalette.BlockPaletteLen())
+		}
 		if err != nil {
-			return nil, fmt.Errorf("NewChunkTimeline: %v", err)
+			return nil, fmt.Errorf(
+				"NewChunkTimeline: Latest chunk of chunk (%d, %d) in dim %d is broken: %w",
+				pos.ChunkPos[0], pos.ChunkPos[1], pos.Dimension, corruptError(err),
+			)
 		}
 
 		result.latestChunk = chunkMatrix
 	}
 
+	// Duplicate block palette entries
+	{
+		// Different saved entries could be decoded to the same block (e.g. they
+		// are upgraded to the same block state), so they are merged here and the
+		// saved block palette indices are remapped.
+		result.paletteRemap = result.blockPalette.Deduplicate()
+		define.RemapChunkMatrix(result.latestChunk, result.paletteRemap)
+
+		if result.paletteRemap != nil && !readOnly {
+			err = result.rewriteBlockDeltas()
+			if err != nil {
+				return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+			}
+		}
+	}
+
 	// Latest NBT
 	{
-		latestNBTBytes := t.Get(
+		latestNBTBytes, err := getValue(t.DB,
 			define.Sum(pos, []byte(define.KeyLatestNBT)...),
 		)
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+		}
 
-		latestNBT, err := marshal.BytesToBlockNBT(latestNBTBytes)
+		latestNBT, err := marshal.BytesToBlockNBT(latestNBTBytes, t.codec)
 		if err != nil {
-			return nil, fmt.Errorf("NewChunkTimeline: %v", err)
+			return nil, fmt.Errorf("NewChunkTimeline: %w", corruptError(err))
 		}
 
 		result.latestNBT = latestNBT
 	}
 
+	// Latest Entities
+	{
+		latestEntitiesBytes, err := getValue(t.DB,
+			define.Sum(pos, []byte(define.KeyLatestEntity)...),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+		}
+
+		latestEntities, err := marshal.BytesToEntities(latestEntitiesBytes, t.codec)
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", corruptError(err))
+		}
+
+		result.latestEntities = latestEntities
+	}
+
+	// Latest Biomes
+	{
+		latestBiomesBytes, err := getValue(t.DB,
+			define.Sum(pos, []byte(define.KeyLatestBiome)...),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+		}
+
+		latestBiomes, err := marshal.BytesToChunkBiomeMatrix(latestBiomesBytes, pos.Dimension.Range(), t.codec)
+		if err != nil {
+			return nil, fmt.Errorf("NewChunkTimeline: %w", corruptError(err))
+		}
+
+		result.latestBiomes = latestBiomes
+	}
+
+	// Latest Hash
+	err = result.loadSubChunkHashes()
+	if err != nil {
+		return nil, fmt.Errorf("NewChunkTimeline: %w", err)
+	}
+
 	success = true
 	return result, nil
 }
@@ -215,7 +350,7 @@ func (t *TimelineDB) DeleteChunkTimeline(pos define.DimChunk) error {
 
 	timeline, err := t.NewChunkTimeline(pos, false)
 	if err != nil {
-		return fmt.Errorf("DeleteChunkTimeline: %v", err)
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
 	}
 	defer func() {
 		timeline.releaseFunc()
@@ -227,67 +362,105 @@ func (t *TimelineDB) DeleteChunkTimeline(pos define.DimChunk) error {
 
 	tran, err := t.OpenTransaction()
 	if err != nil {
-		return fmt.Errorf("DeleteChunkTimeline: %v", err)
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
 	}
 	defer func() {
 		if !success {
 			_ = tran.Discard()
-			return
 		}
-		_ = tran.Commit()
 	}()
 
 	// Global data
 	err = tran.Delete(define.Sum(pos, []byte(define.KeyChunkGlobalData)...))
 	if err != nil {
-		return fmt.Errorf("DeleteChunkTimeline: %v", err)
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
 	}
 
 	// Chunk Index
-	keyBytes := define.Index(pos)
-	bucket := tran.(*transaction).tx.Bucket(DatabaseKeyChunkIndex)
-	if bucket.Get(keyBytes) != nil {
-		err = bucket.Put(
-			DatabaseKeyChunkCount,
-			utils.Uint32BinaryAdd(bucket.Get(DatabaseKeyChunkCount), []byte{1, 0, 0, 0}, -1),
-		)
-		if err != nil {
-			return fmt.Errorf("DeleteChunkTimeline: %v", err)
-		}
-		err = bucket.Delete(keyBytes)
-		if err != nil {
-			return fmt.Errorf("DeleteChunkTimeline: %v", err)
-		}
-	}
-
+	err = removeChunkIndex(tran, pos)
 	if err != nil {
-		return fmt.Errorf("DeleteChunkTimeline: %v", err)
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
 	}
 
 	// Latest Chunk
+	err = releaseLatestChunk(tran, t.codec, pos)
+	if err != nil {
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
+	}
 	err = tran.Delete(define.Sum(pos, define.KeyLatestChunk))
 	if err != nil {
-		return fmt.Errorf("DeleteChunkTimeline: %v", err)
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
 	}
 
 	// Latest NBT
 	err = tran.Delete(define.Sum(pos, []byte(define.KeyLatestNBT)...))
 	if err != nil {
-		return fmt.Errorf("DeleteChunkTimeline: %v", err)
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
+	}
+
+	// Latest Entities
+	err = tran.Delete(define.Sum(pos, []byte(define.KeyLatestEntity)...))
+	if err != nil {
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
+	}
+
+	// Latest Biomes
+	err = tran.Delete(define.Sum(pos, []byte(define.KeyLatestBiome)...))
+	if err != nil {
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
+	}
+
+	// Latest Hash
+	err = tran.Delete(define.Sum(pos, []byte(define.KeyLatestHash)...))
+	if err != nil {
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
 	}
 
 	// Each delta update
 	for i := timeline.barrierLeft; i <= timeline.barrierRight; i++ {
 		err = tran.Delete(define.IndexBlockDu(pos, i))
 		if err != nil {
-			return fmt.Errorf("DeleteChunkTimeline: %v", err)
+			return fmt.Errorf("DeleteChunkTimeline: %w", err)
 		}
 		err = tran.Delete(define.IndexNBTDu(pos, i))
 		if err != nil {
-			return fmt.Errorf("DeleteChunkTimeline: %v", err)
+			return fmt.Errorf("DeleteChunkTimeline: %w", err)
+		}
+		err = tran.Delete(define.IndexSummary(pos, i))
+		if err != nil {
+			return fmt.Errorf("DeleteChunkTimeline: %w", err)
+		}
+		err = tran.Delete(define.IndexNBTSnapshot(pos, i))
+		if err != nil {
+			return fmt.Errorf("DeleteChunkTimeline: %w", err)
+		}
+		err = tran.Delete(define.IndexEntityDu(pos, i))
+		if err != nil {
+			return fmt.Errorf("DeleteChunkTimeline: %w", err)
+		}
+		err = tran.Delete(define.IndexBiomeDu(pos, i))
+		if err != nil {
+			return fmt.Errorf("DeleteChunkTimeline: %w", err)
 		}
 	}
 
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("DeleteChunkTimeline: %w", err)
+	}
 	success = true
 	return nil
 }
+
+// blockDeltaError wraps err that occurred when decoding the
+// block delta whose key index is keyIndex, so the returned
+// error could report where the broken payload is.
+//
+// The reported time index is the same as the one that
+// JumpTo used, that is, relative to the first time point.
+func (s *ChunkTimeline) blockDeltaError(keyIndex uint, err error) error {
+	return fmt.Errorf(
+		"Block delta of chunk (%d, %d) in dim %d at time index %d is broken: %w",
+		s.pos.ChunkPos[0], s.pos.ChunkPos[1], s.pos.Dimension, keyIndex-s.barrierLeft, corruptError(err),
+	)
+}
@@ -0,0 +1,109 @@
+package timeline
+
+import (
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+)
+
+// testDuplicatePalette makes the saved block palette of the
+// timeline of testPos have a duplicate gold block entry, and
+// the saved latest chunk and block deltas only use it.
+func testDuplicatePalette(t *testing.T, db TimelineDatabase, raw DB) {
+	t.Helper()
+
+	tl, err := db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	goldIndex := tl.blockPalette.BlockPaletteIndex(testRuntimeID(t, "minecraft:gold_block"))
+	tl.blockPalette.AppendBlock(testRuntimeID(t, "minecraft:gold_block"))
+
+	remap := make([]uint32, tl.blockPalette.BlockPaletteLen())
+	for index := range remap {
+		remap[index] = uint32(index)
+	}
+	remap[goldIndex] = uint32(len(remap))
+	define.RemapChunkMatrix(tl.latestChunk, remap)
+
+	for i := tl.barrierLeft; i <= tl.barrierRight; i++ {
+		diff, err := marshal.BytesToChunkDiffMatrix(raw.Get(define.IndexBlockDu(testPos, i)), testPos.Dimension.Range(), db.Codec())
+		if err != nil {
+			t.Fatal(err)
+		}
+		define.RemapChunkDiffMatrix(diff, remap)
+		payload, err := marshal.ChunkDiffMatrixToBytes(diff, db.Codec())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = raw.Put(define.IndexBlockDu(testPos, i), payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDuplicatePaletteEntries(t *testing.T) {
+	raw := OpenMemoryDB()
+	db, err := OpenWithDB(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppend(t, db, 1, 2)
+	testDuplicatePalette(t, db, raw)
+
+	// Read only timeline only remaps the saved indices
+	tl, err := db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tl.paletteRemap == nil || tl.blockPalette.BlockPaletteLen() != 2 {
+		t.Fatalf("expected the duplicate entry is remapped, but got palette %v", tl.blockPalette.BlockPalette())
+	}
+	testCheckChunks(t, tl, 1, 2)
+	_ = tl.Save()
+
+	// Otherwise, the deduplicated block palette is written back
+	tl, err = db.NewChunkTimeline(testPos, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tl.paletteRemap != nil {
+		t.Fatal("expected the remap is cleared after written back")
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tl.paletteRemap != nil || tl.blockPalette.BlockPaletteLen() != 2 {
+		t.Fatalf("expected the saved block palette is deduplicated, but got palette %v", tl.blockPalette.BlockPalette())
+	}
+	testCheckChunks(t, tl, 1, 2)
+	_ = tl.Save()
+
+	// The same chunk should not produce any block change
+	testAppend(t, db, 2)
+	tl, err = db.NewChunkTimeline(testPos, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	diff, err := tl.decodeBlockDelta(raw.Get(define.IndexBlockDu(testPos, tl.barrierRight)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !define.ChunkNoChange(diff) {
+		t.Fatalf("expected no block change, but got %v", diff)
+	}
+	testCheckChunks(t, tl, 1, 2, 2)
+}
@@ -4,23 +4,51 @@ import (
 	"context"
 	"fmt"
 
-	"go.etcd.io/bbolt"
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
 )
 
 var (
-	DatabaseKeyRoot       = []byte("root")
-	DatabaseKeyChunkIndex = []byte("chunk-index")
-	DatabaseKeyChunkCount = []byte("chunk-count")
+	DatabaseKeyRoot        = []byte("root")
+	DatabaseKeyChunkIndex  = []byte("chunk-index")
+	DatabaseKeyChunkCount  = []byte("chunk-count")
+	DatabaseKeyMeta        = []byte("meta")
+	DatabaseKeyCodec       = []byte("codec")
+	DatabaseKeyDictionary  = []byte("dictionary")
+	DatabaseKeyPalette     = []byte("palette")
+	DatabaseKeyCustomBlock = []byte("custom-block")
+	DatabaseKeySubChunk    = []byte("sub-chunk")
+	DatabaseKeyPlayer      = []byte("player")
+	DatabaseKeyPlayerIndex = []byte("player-index")
 )
 
+// databaseBuckets holds the name of all the
+// buckets that timeline database will use.
+var databaseBuckets = [][]byte{
+	DatabaseKeyRoot,
+	DatabaseKeyChunkIndex,
+	DatabaseKeyMeta,
+	DatabaseKeyDictionary,
+	DatabaseKeyPalette,
+	DatabaseKeyCustomBlock,
+	DatabaseKeySubChunk,
+	DatabaseKeyPlayer,
+	DatabaseKeyPlayerIndex,
+}
+
 // TimelineDB implements chunk timeline and
-// history record provider based on bbolt.
+// history record provider based on a DB.
 type TimelineDB struct {
 	DB
-	sessions *InProgressSession
+	codec          *utils.Codec
+	palette        *sharedPalette
+	blocks         *define.BlockRegistry
+	subChunks      *subChunkStore
+	sessions       *InProgressSession[define.DimChunk]
+	playerSessions *InProgressSession[string]
 }
 
-// Open open a level database that used for
+// Open open a bbolt database that used for
 // chunk delta update whose at path.
 // If not exist, then create a new database.
 //
@@ -41,39 +69,106 @@ type TimelineDB struct {
 //
 // THIS IS UNSAFE. PLEASE USE WITH CAUTION.
 func Open(path string, noGrowSync bool, noSync bool) (result TimelineDatabase, err error) {
+	db, err := OpenBBoltDB(path, noGrowSync, noSync)
+	if err != nil {
+		return nil, fmt.Errorf("Open: %w", err)
+	}
+	return OpenWithDB(db)
+}
+
+// OpenMemory opens a timeline database that holds
+// everything in memory. All the data will lost after
+// the timeline database is closed.
+func OpenMemory() (result TimelineDatabase, err error) {
+	return OpenWithDB(OpenMemoryDB())
+}
+
+// OpenLevel open a level database that used for
+// chunk delta update whose at path.
+// If not exist, then create a new database.
+func OpenLevel(path string) (result TimelineDatabase, err error) {
+	db, err := OpenLevelDB(path)
+	if err != nil {
+		return nil, fmt.Errorf("OpenLevel: %w", err)
+	}
+	return OpenWithDB(db)
+}
+
+// OpenEncrypted opens a timeline database that use db as its
+// underlying storage backend, and all the values written to it
+// are encrypted by the keys that options provided.
+//
+// If the keys are wrong, or db is not encrypted but already
+// have data, then returns an error.
+//
+// If failed, db will be closed.
+func OpenEncrypted(db DB, options EncryptionOptions) (result TimelineDatabase, err error) {
+	edb, err := NewEncryptedDB(db, options)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("OpenEncrypted: %w", err)
+	}
+	return OpenWithDB(edb)
+}
+
+// OpenWithDB opens a timeline database that
+// use db as its underlying storage backend.
+//
+// If failed, db will be closed.
+func OpenWithDB(db DB) (result TimelineDatabase, err error) {
 	timelineDB := &TimelineDB{
-		sessions: NewInProgressSession(),
+		DB:             db,
+		codec:          utils.NewCodec(utils.DefaultCompression()),
+		blocks:         define.NewBlockRegistry(),
+		sessions:       NewInProgressSession[define.DimChunk](),
+		playerSessions: NewInProgressSession[string](),
 	}
 
-	db, err := bbolt.Open(path, 0600, &bbolt.Options{
-		FreelistType: bbolt.FreelistMapType,
-		NoGrowSync:   noGrowSync,
-		NoSync:       noSync,
-	})
+	compression, err := loadCompression(db)
 	if err != nil {
-		return nil, fmt.Errorf("Open: %v", err)
+		_ = db.Close()
+		return nil, fmt.Errorf("OpenWithDB: %w", err)
 	}
+	timelineDB.codec.SetCompression(compression)
 
-	err = db.Update(func(tx *bbolt.Tx) error {
-		_, err = tx.CreateBucketIfNotExists(DatabaseKeyRoot)
-		if err != nil {
-			return err
-		}
-		bucket, err := tx.CreateBucketIfNotExists(DatabaseKeyChunkIndex)
-		if err != nil {
-			return err
-		}
-		if len(bucket.Get(DatabaseKeyChunkCount)) < 4 {
-			return bucket.Put(DatabaseKeyChunkCount, make([]byte, 4))
-		}
-		return nil
-	})
+	err = loadDictionaries(db, timelineDB.codec)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("OpenWithDB: %w", err)
+	}
+
+	err = loadCustomBlocks(db, timelineDB.blocks)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("OpenWithDB: %w", err)
+	}
+
+	timelineDB.palette, err = loadSharedPalette(db, timelineDB.blocks)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("OpenWithDB: %w", err)
+	}
+
+	timelineDB.subChunks, err = loadSubChunkStore(db)
 	if err != nil {
 		_ = db.Close()
-		return nil, fmt.Errorf("Open: %v", err)
+		return nil, fmt.Errorf("OpenWithDB: %w", err)
+	}
+
+	bucket := db.Bucket(DatabaseKeyChunkIndex)
+	countBytes, err := getValue(bucket, DatabaseKeyChunkCount)
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("OpenWithDB: %w", err)
+	}
+	if len(countBytes) < 4 {
+		err = bucket.Put(DatabaseKeyChunkCount, make([]byte, 4))
+		if err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("OpenWithDB: %w", err)
+		}
 	}
 
-	timelineDB.DB = &database{bdb: db}
 	return timelineDB, nil
 }
 
@@ -90,19 +185,38 @@ func (t *TimelineDB) CloseTimelineDB() error {
 	t.sessions.closed = true
 	t.sessions.mu.Unlock()
 
+	t.playerSessions.mu.Lock()
+	for _, value := range t.playerSessions.session {
+		allPendingCtx = append(allPendingCtx, value)
+	}
+	t.playerSessions.closed = true
+	t.playerSessions.mu.Unlock()
+
 	for _, value := range allPendingCtx {
 		<-value.Done()
 	}
 
 	err := t.Close()
 	if err != nil {
-		return fmt.Errorf("CloseTimelineDB: %v", err)
+		return fmt.Errorf("CloseTimelineDB: %w", err)
 	}
 	return nil
 }
 
 // UnderlyingDatabase returns the underlying database of this timeline database.
-// Only should be calling when need iter all timelines for all chunks.
-func (t *TimelineDB) UnderlyingDatabase() *bbolt.DB {
-	return t.DB.(*database).bdb
+func (t *TimelineDB) UnderlyingDatabase() DB {
+	return t.DB
+}
+
+// BlockRegistry returns the block registry of this timeline database,
+// which knows the standard block table and all the other block states
+// (e.g. custom blocks) that found in the block palettes of this database.
+//
+// The chunks that returned by the timelines of this database may contain
+// the runtime IDs of these blocks, so if you want to encode them by
+// bedrock-world-operator (e.g. write them to a world), then you could set
+// block.RuntimeIDToState and block.StateToRuntimeID to the functions of
+// the returned registry first.
+func (t *TimelineDB) BlockRegistry() *define.BlockRegistry {
+	return t.blocks
 }
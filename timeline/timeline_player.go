@@ -0,0 +1,292 @@
+package timeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/define"
+	"github.com/TriM-Organization/bedrock-chunk-diff/marshal"
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// PlayerTimeline records the timeline of a player,
+// and it contains the change logs about the data
+// (e.g. the inventory) of this player.
+//
+// Different from ChunkTimeline, the player timeline
+// is keyed by the identifier of the player (e.g. the
+// UUID of this player), but not a chunk position.
+//
+// Note that it's unsafe for multiple thread to access this
+// struct due to we don't use mutex to ensure the operation
+// is atomic.
+//
+// So, it's your responsibility to make ensure there is only
+// one thread is using this object.
+type PlayerTimeline struct {
+	db          DB
+	codec       *utils.Codec
+	id          string
+	releaseFunc func()
+
+	isReadOnly bool
+	isEmpty    bool
+
+	timelineUnixTime []int64
+
+	barrierLeft  uint
+	barrierRight uint
+	maxLimit     uint
+
+	latestPlayer map[string]any
+}
+
+// NewPlayerTimeline gets the timeline of a player whose identifier is id.
+//
+// The same as NewChunkTimeline, if the timeline of this player is not exist,
+// then we will return an empty one so you can modify it, and the timeline is
+// only created when you save a timeline that not empty to the database.
+//
+// If readOnly is true, then returned a timeline but only can read.
+// For a read only timeline, you also need use PlayerTimeline.Save to release it.
+//
+// Timeline of one player can't be using by multiple threads. Therefore, you will
+// get blocking when a thread calling NewPlayerTimeline but there is still some
+// threads are using target player.
+func (t *TimelineDB) NewPlayerTimeline(id string, readOnly bool) (result *PlayerTimeline, err error) {
+	releaseFunc, succ := t.playerSessions.Require(id)
+	if !succ {
+		return nil, fmt.Errorf("NewPlayerTimeline: %w", ErrClosed)
+	}
+	return t.newPlayerTimeline(id, readOnly, releaseFunc)
+}
+
+// TryNewPlayerTimeline is like NewPlayerTimeline, but it will not blocking
+// when there is still some threads are using target player. Instead, an
+// error that matches ErrBusy will be returned.
+func (t *TimelineDB) TryNewPlayerTimeline(id string, readOnly bool) (result *PlayerTimeline, err error) {
+	releaseFunc, err := t.playerSessions.TryRequire(id)
+	if err != nil {
+		return nil, fmt.Errorf("TryNewPlayerTimeline: %w", err)
+	}
+	return t.newPlayerTimeline(id, readOnly, releaseFunc)
+}
+
+// newPlayerTimeline is an internal implement detail.
+// If failed, releaseFunc will be called.
+func (t *TimelineDB) newPlayerTimeline(id string, readOnly bool, releaseFunc func()) (result *PlayerTimeline, err error) {
+	var success bool
+
+	defer func() {
+		if !success {
+			releaseFunc()
+		}
+	}()
+
+	if len(id) == 0 || len(id) > 0xffff {
+		return nil, fmt.Errorf("NewPlayerTimeline: %w (the length of player identifier %d is invalid)", ErrOutOfRange, len(id))
+	}
+
+	result = &PlayerTimeline{
+		db:               t.DB,
+		codec:            t.codec,
+		id:               id,
+		releaseFunc:      releaseFunc,
+		isReadOnly:       readOnly,
+		isEmpty:          false,
+		timelineUnixTime: nil,
+		barrierLeft:      0,
+		barrierRight:     0,
+		maxLimit:         DefaultMaxLimit,
+		latestPlayer:     nil,
+	}
+
+	if !t.HasPlayerTimeline(id) {
+		result.isEmpty = true
+		success = true
+		return result, nil
+	}
+
+	bucket := t.Bucket(DatabaseKeyPlayer)
+	compressedGlobalData, err := getValue(bucket, define.SumPlayer(id, []byte(define.KeyPlayerGlobalData)...))
+	if err != nil {
+		return nil, fmt.Errorf("NewPlayerTimeline: %w", err)
+	}
+	globalData, err := t.codec.Decode(compressedGlobalData)
+	if err != nil {
+		return nil, fmt.Errorf("NewPlayerTimeline: %w", corruptError(err))
+	}
+
+	// Timeline Unix Time
+	{
+		if len(globalData) < 4 || uint64(binary.LittleEndian.Uint32(globalData))+4 > uint64(len(globalData)) {
+			return nil, fmt.Errorf("NewPlayerTimeline: %w: %w (timeline unix time is truncated)", ErrCorrupt, define.ErrMalformed)
+		}
+		length := binary.LittleEndian.Uint32(globalData)
+		if length%8 != 0 {
+			return nil, fmt.Errorf("NewPlayerTimeline: %w: %w (timeline unix time is broken)", ErrCorrupt, define.ErrMalformed)
+		}
+		payload := globalData[4 : 4+length]
+		for len(payload) > 0 {
+			result.timelineUnixTime = append(result.timelineUnixTime, int64(binary.LittleEndian.Uint64(payload)))
+			payload = payload[8:]
+		}
+		globalData = globalData[4+length:]
+	}
+
+	// Barrier and Max limit
+	{
+		if len(globalData) < 12 {
+			return nil, fmt.Errorf("NewPlayerTimeline: %w (barrier and limit is broken, only get %d bytes but expected 12)", ErrCorrupt, len(globalData))
+		}
+		result.barrierLeft = uint(binary.LittleEndian.Uint32(globalData))
+		result.barrierRight = uint(binary.LittleEndian.Uint32(globalData[4:]))
+		result.maxLimit = uint(binary.LittleEndian.Uint32(globalData[8:]))
+
+		if result.barrierLeft > result.barrierRight || uint(len(result.timelineUnixTime)) != result.barrierRight-result.barrierLeft+1 {
+			return nil, fmt.Errorf(
+				"NewPlayerTimeline: %w: %w (barrier [%d, %d] is not match the %d time points)",
+				ErrCorrupt, define.ErrMalformed, result.barrierLeft, result.barrierRight, len(result.timelineUnixTime),
+			)
+		}
+	}
+
+	// Latest Player
+	{
+		latestPlayerBytes, err := getValue(bucket, define.SumPlayer(id, []byte(define.KeyLatestPlayer)...))
+		if err != nil {
+			return nil, fmt.Errorf("NewPlayerTimeline: %w", err)
+		}
+
+		latestPlayer, err := marshal.BytesToPlayer(latestPlayerBytes, t.codec)
+		if err != nil {
+			return nil, fmt.Errorf("NewPlayerTimeline: %w", corruptError(err))
+		}
+		result.latestPlayer = latestPlayer
+	}
+
+	success = true
+	return result, nil
+}
+
+// HasPlayerTimeline reports whether the player
+// whose identifier is id have timeline in this database.
+func (t *TimelineDB) HasPlayerTimeline(id string) bool {
+	return t.Bucket(DatabaseKeyPlayerIndex).Has(define.IndexPlayer(id))
+}
+
+// ForEachPlayerTimeline calls fn for each player that
+// have timeline in this database.
+// If fn returns an error then the iteration is stopped
+// and the error is returned to the caller.
+//
+// Note that it's unsafe to modify the timeline of any
+// player when iterating, but you can read them.
+func (t *TimelineDB) ForEachPlayerTimeline(fn func(id string) error) error {
+	return t.Bucket(DatabaseKeyPlayerIndex).ForEach(func(key []byte, value []byte) error {
+		// The player identifier is saved in the value,
+		// because the key could be hashed when encrypted.
+		return fn(string(value))
+	})
+}
+
+// DeletePlayerTimeline deletes the timeline of the player whose identifier is id.
+// If timeline is not exist, then do no operation.
+//
+// Time complexity: O(n).
+// n is the time point that this player have.
+func (t *TimelineDB) DeletePlayerTimeline(id string) error {
+	var success bool
+
+	timeline, err := t.NewPlayerTimeline(id, false)
+	if err != nil {
+		return fmt.Errorf("DeletePlayerTimeline: %w", err)
+	}
+	defer func() {
+		timeline.releaseFunc()
+	}()
+
+	if timeline.isEmpty {
+		return nil
+	}
+
+	tran, err := t.OpenTransaction()
+	if err != nil {
+		return fmt.Errorf("DeletePlayerTimeline: %w", err)
+	}
+	defer func() {
+		if !success {
+			_ = tran.Discard()
+		}
+	}()
+	bucket := tran.Bucket(DatabaseKeyPlayer)
+
+	// Player Index
+	err = tran.Bucket(DatabaseKeyPlayerIndex).Delete(define.IndexPlayer(id))
+	if err != nil {
+		return fmt.Errorf("DeletePlayerTimeline: %w", err)
+	}
+
+	// Global data
+	err = bucket.Delete(define.SumPlayer(id, []byte(define.KeyPlayerGlobalData)...))
+	if err != nil {
+		return fmt.Errorf("DeletePlayerTimeline: %w", err)
+	}
+
+	// Latest Player
+	err = bucket.Delete(define.SumPlayer(id, []byte(define.KeyLatestPlayer)...))
+	if err != nil {
+		return fmt.Errorf("DeletePlayerTimeline: %w", err)
+	}
+
+	// Each delta update
+	for i := timeline.barrierLeft; i <= timeline.barrierRight; i++ {
+		err = bucket.Delete(define.IndexPlayerDu(id, i))
+		if err != nil {
+			return fmt.Errorf("DeletePlayerTimeline: %w", err)
+		}
+	}
+
+	err = tran.Commit()
+	if err != nil {
+		return fmt.Errorf("DeletePlayerTimeline: %w", err)
+	}
+	success = true
+	return nil
+}
+
+// encodeGlobalData encodes the global data of this timeline.
+func (s *PlayerTimeline) encodeGlobalData() []byte {
+	globalData := bytes.NewBuffer(nil)
+
+	// Timeline Unix Time
+	{
+		buf := bytes.NewBuffer(nil)
+
+		for _, value := range s.timelineUnixTime {
+			temp := make([]byte, 8)
+			binary.LittleEndian.PutUint64(temp, uint64(value))
+			buf.Write(temp)
+		}
+
+		lengthBytes := make([]byte, 4)
+		binary.LittleEndian.PutUint32(lengthBytes, uint32(buf.Len()))
+
+		globalData.Write(lengthBytes)
+		globalData.Write(buf.Bytes())
+	}
+
+	// Barrier and Max limit
+	{
+		result := make([]byte, 12)
+
+		binary.LittleEndian.PutUint32(result, uint32(s.barrierLeft))
+		binary.LittleEndian.PutUint32(result[4:], uint32(s.barrierRight))
+		binary.LittleEndian.PutUint32(result[8:], uint32(s.maxLimit))
+
+		globalData.Write(result)
+	}
+
+	return globalData.Bytes()
+}
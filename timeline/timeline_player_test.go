@@ -0,0 +1,145 @@
+package timeline
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/TriM-Organization/bedrock-chunk-diff/utils"
+)
+
+// testPlayerID is the player identifier that used by the tests.
+const testPlayerID = "0b9a0c6e-1111-2222-3333-444455556666"
+
+// testPlayer returns the data of a player
+// whose inventory and position are decided by seed.
+func testPlayer(seed int) map[string]any {
+	inventory := make([]any, 0)
+	for i := range seed%5 + 1 {
+		inventory = append(inventory, map[string]any{
+			"Name":  fmt.Sprintf("minecraft:item_%d", i),
+			"Count": byte(seed + i),
+			"Slot":  byte(i),
+		})
+	}
+	return map[string]any{
+		"Inventory": inventory,
+		"Health":    float32(seed),
+		"Pos":       []any{float32(seed), float32(64), float32(-seed)},
+	}
+}
+
+// testAppendPlayer appends the player data that returned by
+// testPlayer for each seed in seeds to the timeline of testPlayerID.
+func testAppendPlayer(t *testing.T, db TimelineDatabase, maxLimit uint, seeds ...int) {
+	t.Helper()
+
+	tl, err := db.NewPlayerTimeline(testPlayerID, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = tl.SetMaxLimit(maxLimit); err != nil {
+		t.Fatal(err)
+	}
+	for _, seed := range seeds {
+		if err = tl.Append(testPlayer(seed), false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tl.Save(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testCheckPlayer checks the timeline of testPlayerID
+// in db have the player data of each seed in seeds.
+func testCheckPlayer(t *testing.T, db TimelineDatabase, seeds ...int) {
+	t.Helper()
+
+	tl, err := db.NewPlayerTimeline(testPlayerID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tl.Save()
+
+	if tl.AllTimePointLen() != len(seeds) {
+		t.Fatalf("expected %d time points, but got %d", len(seeds), tl.AllTimePointLen())
+	}
+	for index, seed := range seeds {
+		player, _, err := tl.JumpTo(uint(index))
+		if err != nil {
+			t.Fatalf("JumpTo: %v", err)
+		}
+		if !reflect.DeepEqual(player, testPlayer(seed)) {
+			t.Fatalf("JumpTo: time point %d is %v, but expected %v", index, player, testPlayer(seed))
+		}
+	}
+
+	player, _, err := tl.Last()
+	if err != nil {
+		t.Fatalf("Last: %v", err)
+	}
+	if !reflect.DeepEqual(player, testPlayer(seeds[len(seeds)-1])) {
+		t.Fatalf("Last: got %v, but expected %v", player, testPlayer(seeds[len(seeds)-1]))
+	}
+}
+
+func TestPlayerTimeline(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testAppendPlayer(t, db, 4, 1, 2, 3, 4, 5, 6)
+	testCheckPlayer(t, db, 3, 4, 5, 6)
+
+	ids := make([]string, 0)
+	err = db.ForEachPlayerTimeline(func(id string) error {
+		ids = append(ids, id)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(ids, []string{testPlayerID}) {
+		t.Fatalf("ForEachPlayerTimeline: got %v and %v", ids, err)
+	}
+
+	if err = db.DeletePlayerTimeline(testPlayerID); err != nil {
+		t.Fatal(err)
+	}
+	if db.HasPlayerTimeline(testPlayerID) {
+		t.Fatal("timeline is still exist after deleted")
+	}
+	if n := len(db.UnderlyingDatabase().(*memoryDatabase).snapshot(DatabaseKeyPlayer)); n != 0 {
+		t.Fatalf("expected no player data after deleted, but got %d values", n)
+	}
+}
+
+func TestRewritePlayerTimeline(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	testAppendPlayer(t, db, DefaultMaxLimit, 1, 2, 3)
+
+	if err = db.SetCompression(utils.NoneCompression{}); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.RewritePlayerTimeline(testPlayerID); err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.UnderlyingDatabase().Bucket(DatabaseKeyPlayer).ForEach(func(key []byte, value []byte) error {
+		if value[0] != utils.CompressionNone {
+			return fmt.Errorf("value of %v is compressed by %d", key, value[0])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCheckPlayer(t, db, 1, 2, 3)
+
+	// Do nothing for the player that have no timeline
+	if err = db.RewritePlayerTimeline("not exist"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+// ErrChecksumMismatch is returned when the
+// checksum of a stored value is not match.
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
+// checksumFlag is set on the codec tag of
+// the values that have a checksum trailer.
+const checksumFlag = 0x80
+
+// WithChecksum sets the checksum flag of encoded (which is returned
+// by Codec.Encode or Codec.EncodeDelta), and appends its xxhash64 as
+// the trailer. Codec.Decode will check this trailer automatically.
+//
+// If encoded is empty, then return encoded directly.
+func WithChecksum(encoded []byte) []byte {
+	if len(encoded) == 0 {
+		return encoded
+	}
+
+	result := make([]byte, len(encoded), len(encoded)+8)
+	copy(result, encoded)
+	result[0] |= checksumFlag
+
+	return binary.LittleEndian.AppendUint64(result, xxhash.Sum64(result))
+}
+
+// verifyChecksum checks the checksum trailer of in,
+// and returns the codec tag and the payload in it.
+func verifyChecksum(in []byte) (tag byte, payload []byte, err error) {
+	if len(in) < 9 {
+		return 0, nil, ErrChecksumMismatch
+	}
+
+	content, trailer := in[:len(in)-8], in[len(in)-8:]
+	if xxhash.Sum64(content) != binary.LittleEndian.Uint64(trailer) {
+		return 0, nil, ErrChecksumMismatch
+	}
+
+	return content[0] &^ checksumFlag, content[1:], nil
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	codec := NewCodec(DefaultCompression())
+	encoded, err := codec.Encode(testPayload(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value := WithChecksum(encoded)
+
+	result, err := codec.Decode(value)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !bytes.Equal(result, testPayload(1)) {
+		t.Fatal("Decode: value is not the same as the original one")
+	}
+
+	for index := range value {
+		flipped := bytes.Clone(value)
+		flipped[index] ^= 0x10
+		if _, err = codec.Decode(flipped); !errors.Is(err, ErrChecksumMismatch) {
+			t.Fatalf("Decode: expected ErrChecksumMismatch when byte %d is flipped, but got %v", index, err)
+		}
+	}
+
+	if _, err = codec.Decode(value[:8]); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Decode: expected ErrChecksumMismatch for truncated value, but got %v", err)
+	}
+	if result := WithChecksum(nil); len(result) != 0 {
+		t.Fatalf("WithChecksum: expected empty result, but got %v", result)
+	}
+}
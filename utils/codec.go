@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"compress/gzip"
+	"fmt"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// The ID of each compression algorithm.
+// Each compressed value will be prefixed
+// by one of them, which is the codec tag.
+const (
+	CompressionNone byte = iota
+	CompressionGzip
+	CompressionZstd
+	CompressionSnappy
+	CompressionZstdDict
+)
+
+// Values that written before codec tag is introduced
+// are always compressed by gzip, and could be found
+// by the magic number of gzip.
+const (
+	legacyGzipMagic0 = 0x1f
+	legacyGzipMagic1 = 0x8b
+)
+
+// Compression is a compression algorithm
+// that used to compress the stored values.
+type Compression interface {
+	// ID returns the ID of this compression algorithm,
+	// and will be used as the codec tag.
+	ID() byte
+	// Level returns the compression level.
+	Level() int
+	// Compress compresses in and returns the result.
+	Compress(in []byte) (result []byte, err error)
+	// Decompress decompresses in and returns the result.
+	Decompress(in []byte) (result []byte, err error)
+}
+
+// NewCompression returns the compression algorithm whose ID
+// is id and use level as its compression level.
+// level is ignored for algorithms that have no levels.
+func NewCompression(id byte, level int) (result Compression, err error) {
+	switch id {
+	case CompressionNone:
+		return NoneCompression{}, nil
+	case CompressionGzip:
+		if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+			return nil, fmt.Errorf("NewCompression: Invalid gzip level %d", level)
+		}
+		return GzipCompression{level: level}, nil
+	case CompressionZstd:
+		return NewZstdCompression(level)
+	case CompressionSnappy:
+		return SnappyCompression{}, nil
+	}
+	return nil, fmt.Errorf("NewCompression: Unknown compression algorithm %d", id)
+}
+
+// NoneCompression is a compression
+// algorithm that do no compression.
+type NoneCompression struct{}
+
+func (NoneCompression) ID() byte {
+	return CompressionNone
+}
+
+func (NoneCompression) Level() int {
+	return 0
+}
+
+func (NoneCompression) Compress(in []byte) (result []byte, err error) {
+	return in, nil
+}
+
+func (NoneCompression) Decompress(in []byte) (result []byte, err error) {
+	return in, nil
+}
+
+// GzipCompression is the gzip compression algorithm.
+type GzipCompression struct {
+	level int
+}
+
+func (g GzipCompression) ID() byte {
+	return CompressionGzip
+}
+
+func (g GzipCompression) Level() int {
+	return g.level
+}
+
+func (g GzipCompression) Compress(in []byte) (result []byte, err error) {
+	return GzipWithLevel(in, g.level)
+}
+
+func (g GzipCompression) Decompress(in []byte) (result []byte, err error) {
+	return Ungzip(in)
+}
+
+// ZstdCompression is the zstd compression algorithm.
+// It is safe to use ZstdCompression concurrently.
+type ZstdCompression struct {
+	level   int
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewZstdCompression returns a new zstd compression algorithm.
+// level is the standard zstd compression level, and is ranging
+// from 1 to 22.
+func NewZstdCompression(level int) (result *ZstdCompression, err error) {
+	if level < 1 || level > 22 {
+		return nil, fmt.Errorf("NewZstdCompression: Invalid zstd level %d", level)
+	}
+
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	if err != nil {
+		return nil, fmt.Errorf("NewZstdCompression: %v", err)
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(0))
+	if err != nil {
+		return nil, fmt.Errorf("NewZstdCompression: %v", err)
+	}
+
+	return &ZstdCompression{
+		level:   level,
+		encoder: encoder,
+		decoder: decoder,
+	}, nil
+}
+
+func (z *ZstdCompression) ID() byte {
+	return CompressionZstd
+}
+
+func (z *ZstdCompression) Level() int {
+	return z.level
+}
+
+func (z *ZstdCompression) Compress(in []byte) (result []byte, err error) {
+	return z.encoder.EncodeAll(in, nil), nil
+}
+
+func (z *ZstdCompression) Decompress(in []byte) (result []byte, err error) {
+	result, err = z.decoder.DecodeAll(in, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Decompress: %v", err)
+	}
+	return
+}
+
+// SnappyCompression is the snappy compression algorithm.
+type SnappyCompression struct{}
+
+func (SnappyCompression) ID() byte {
+	return CompressionSnappy
+}
+
+func (SnappyCompression) Level() int {
+	return 0
+}
+
+func (SnappyCompression) Compress(in []byte) (result []byte, err error) {
+	return snappy.Encode(nil, in), nil
+}
+
+func (SnappyCompression) Decompress(in []byte) (result []byte, err error) {
+	result, err = snappy.Decode(nil, in)
+	if err != nil {
+		return nil, fmt.Errorf("Decompress: %v", err)
+	}
+	return
+}
+
+// DefaultCompression returns the default compression
+// algorithm, which is gzip with best compression.
+func DefaultCompression() Compression {
+	return GzipCompression{level: gzip.BestCompression}
+}
+
+// Codec compresses values by the selected compression
+// algorithm, and prefix each value with a codec tag.
+//
+// Therefore, values that compressed by different algorithms
+// could be decoded by the same Codec, and this makes mixed
+// databases decode.
+//
+// It is safe to use Codec concurrently.
+type Codec struct {
+	mu           *sync.RWMutex
+	compression  Compression
+	decoders     map[byte]Compression
+	dictionary   *ZstdDictionary
+	dictionaries map[uint32]*ZstdDictionary
+}
+
+// NewCodec returns a new Codec that
+// use compression to encode values.
+func NewCodec(compression Compression) *Codec {
+	c := &Codec{
+		mu:          new(sync.RWMutex),
+		compression: compression,
+		decoders: map[byte]Compression{
+			CompressionNone:   NoneCompression{},
+			CompressionGzip:   DefaultCompression(),
+			CompressionSnappy: SnappyCompression{},
+		},
+		dictionaries: make(map[uint32]*ZstdDictionary),
+	}
+	c.decoders[compression.ID()] = compression
+	return c
+}
+
+// Compression returns the compression
+// algorithm that used to encode values.
+func (c *Codec) Compression() Compression {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.compression
+}
+
+// SetCompression sets the compression algorithm that used
+// to encode values. Values that encoded before could still
+// be decoded.
+func (c *Codec) SetCompression(compression Compression) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.compression = compression
+	c.decoders[compression.ID()] = compression
+}
+
+// decoder is an internal implement detail.
+func (c *Codec) decoder(id byte) (result Compression, err error) {
+	c.mu.RLock()
+	result = c.decoders[id]
+	c.mu.RUnlock()
+
+	if result != nil {
+		return result, nil
+	}
+
+	result, err = NewCompression(id, 3)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if existing := c.decoders[id]; existing != nil {
+		result = existing
+	} else {
+		c.decoders[id] = result
+	}
+	c.mu.Unlock()
+
+	return result, nil
+}
+
+// Encode compresses in and prefix the result with the codec tag.
+// If in is empty, then return nil.
+func (c *Codec) Encode(in []byte) (result []byte, err error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	compression := c.Compression()
+	payload, err := compression.Compress(in)
+	if err != nil {
+		return nil, fmt.Errorf("Encode: %v", err)
+	}
+
+	result = make([]byte, 1+len(payload))
+	result[0] = compression.ID()
+	copy(result[1:], payload)
+
+	return result, nil
+}
+
+// Decode decompresses in by the algorithm that its codec tag refers to.
+// Values that have no codec tag are treated as compressed by gzip.
+// If in have a checksum trailer (see WithChecksum), then it will be
+// checked, and ErrChecksumMismatch is wrapped in the returned error
+// if it is not match.
+// If in is empty, then return nil.
+func (c *Codec) Decode(in []byte) (result []byte, err error) {
+	if len(in) == 0 {
+		return nil, nil
+	}
+
+	if len(in) >= 2 && in[0] == legacyGzipMagic0 && in[1] == legacyGzipMagic1 {
+		result, err = Ungzip(in)
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %v", err)
+		}
+		return result, nil
+	}
+
+	tag, payload := in[0], in[1:]
+	if tag&checksumFlag != 0 {
+		tag, payload, err = verifyChecksum(in)
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %w", err)
+		}
+	}
+
+	if tag == CompressionZstdDict {
+		result, err = c.decodeByDictionary(payload)
+		if err != nil {
+			return nil, fmt.Errorf("Decode: %v", err)
+		}
+		return result, nil
+	}
+
+	decoder, err := c.decoder(tag)
+	if err != nil {
+		return nil, fmt.Errorf("Decode: %v", err)
+	}
+
+	result, err = decoder.Decompress(payload)
+	if err != nil {
+		return nil, fmt.Errorf("Decode: %v", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ZstdDictionary is the zstd compression algorithm
+// that use a trained dictionary.
+//
+// Each compressed value is prefixed by the version of
+// the dictionary (uint32, little endian), so the value
+// could always find its dictionary when decoding.
+//
+// It is safe to use ZstdDictionary concurrently.
+type ZstdDictionary struct {
+	version uint32
+	level   int
+	dict    []byte
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// TrainZstdDictionary trains a new zstd dictionary from samples.
+// version is the version of this dictionary and must not be 0.
+// maxSize is the max size of the dictionary, and level is the
+// zstd compression level (ranging from 1 to 22) that the returned
+// dictionary use.
+func TrainZstdDictionary(samples [][]byte, version uint32, maxSize int, level int) (result *ZstdDictionary, err error) {
+	if version == 0 {
+		return nil, fmt.Errorf("TrainZstdDictionary: Dictionary version must not be 0")
+	}
+	if level < 1 || level > 22 {
+		return nil, fmt.Errorf("TrainZstdDictionary: Invalid zstd level %d", level)
+	}
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("TrainZstdDictionary: No sample is given")
+	}
+
+	dictBytes, err := dict.BuildZstdDict(samples, dict.Options{
+		MaxDictSize: maxSize,
+		HashBytes:   6,
+		ZstdDictID:  version,
+		ZstdLevel:   zstd.EncoderLevelFromZstd(level),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("TrainZstdDictionary: %v", err)
+	}
+
+	result, err = NewZstdDictionary(version, dictBytes, level)
+	if err != nil {
+		return nil, fmt.Errorf("TrainZstdDictionary: %v", err)
+	}
+	return result, nil
+}
+
+// NewZstdDictionary returns a new zstd compression algorithm
+// that use dictBytes as its dictionary. version is the version
+// of this dictionary, and level is the zstd compression level
+// that ranging from 1 to 22.
+func NewZstdDictionary(version uint32, dictBytes []byte, level int) (result *ZstdDictionary, err error) {
+	if level < 1 || level > 22 {
+		return nil, fmt.Errorf("NewZstdDictionary: Invalid zstd level %d", level)
+	}
+
+	encoder, err := zstd.NewWriter(
+		nil,
+		zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+		zstd.WithEncoderDict(dictBytes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("NewZstdDictionary: %v", err)
+	}
+	decoder, err := zstd.NewReader(
+		nil,
+		zstd.WithDecoderConcurrency(0),
+		zstd.WithDecoderDicts(dictBytes),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("NewZstdDictionary: %v", err)
+	}
+
+	return &ZstdDictionary{
+		version: version,
+		level:   level,
+		dict:    dictBytes,
+		encoder: encoder,
+		decoder: decoder,
+	}, nil
+}
+
+func (z *ZstdDictionary) ID() byte {
+	return CompressionZstdDict
+}
+
+func (z *ZstdDictionary) Level() int {
+	return z.level
+}
+
+// Version returns the version of this dictionary.
+func (z *ZstdDictionary) Version() uint32 {
+	return z.version
+}
+
+// Bytes returns the raw bytes of this dictionary.
+func (z *ZstdDictionary) Bytes() []byte {
+	return z.dict
+}
+
+func (z *ZstdDictionary) Compress(in []byte) (result []byte, err error) {
+	result = make([]byte, 4, 4+len(in))
+	binary.LittleEndian.PutUint32(result, z.version)
+	return z.encoder.EncodeAll(in, result), nil
+}
+
+func (z *ZstdDictionary) Decompress(in []byte) (result []byte, err error) {
+	if len(in) < 4 {
+		return nil, fmt.Errorf("Decompress: Dictionary version is missing")
+	}
+	if version := binary.LittleEndian.Uint32(in); version != z.version {
+		return nil, fmt.Errorf("Decompress: Dictionary version mismatch (expected %d but got %d)", z.version, version)
+	}
+
+	result, err = z.decoder.DecodeAll(in[4:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("Decompress: %v", err)
+	}
+	return
+}
+
+// Dictionary returns the dictionary that used to
+// encode deltas. If not set, then return nil.
+func (c *Codec) Dictionary() *ZstdDictionary {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.dictionary
+}
+
+// AddDictionary adds dictionary to this codec, so
+// values that compressed by it could be decoded.
+func (c *Codec) AddDictionary(dictionary *ZstdDictionary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dictionaries[dictionary.Version()] = dictionary
+}
+
+// SetDictionary sets the dictionary that used to encode
+// deltas. If dictionary is nil, then deltas will be
+// encoded by the current compression algorithm.
+//
+// Dictionaries that used before are still kept,
+// so old values could still be decoded.
+func (c *Codec) SetDictionary(dictionary *ZstdDictionary) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dictionary = dictionary
+	if dictionary != nil {
+		c.dictionaries[dictionary.Version()] = dictionary
+	}
+}
+
+// EncodeDelta is like Encode, but in is a delta and is
+// compressed by the dictionary if it is set.
+// If in is empty, then return nil.
+func (c *Codec) EncodeDelta(in []byte) (result []byte, err error) {
+	dictionary := c.Dictionary()
+	if dictionary == nil || len(in) == 0 {
+		return c.Encode(in)
+	}
+
+	payload, err := dictionary.Compress(in)
+	if err != nil {
+		return nil, fmt.Errorf("EncodeDelta: %v", err)
+	}
+
+	result = make([]byte, 1+len(payload))
+	result[0] = CompressionZstdDict
+	copy(result[1:], payload)
+
+	return result, nil
+}
+
+// decodeByDictionary is an internal implement detail.
+func (c *Codec) decodeByDictionary(in []byte) (result []byte, err error) {
+	if len(in) < 4 {
+		return nil, fmt.Errorf("decodeByDictionary: Dictionary version is missing")
+	}
+	version := binary.LittleEndian.Uint32(in)
+
+	c.mu.RLock()
+	dictionary := c.dictionaries[version]
+	c.mu.RUnlock()
+
+	if dictionary == nil {
+		return nil, fmt.Errorf("decodeByDictionary: Dictionary %d is not found", version)
+	}
+	return dictionary.Decompress(in)
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// testDictionary trains a zstd dictionary whose version is version.
+func testDictionary(t *testing.T, version uint32) *ZstdDictionary {
+	t.Helper()
+
+	samples := make([][]byte, 0)
+	for i := range 512 {
+		samples = append(samples, fmt.Appendf(nil, "minecraft:chest{Items:[{Name:apple,Count:%d},{Slot:%d}]}", i%64, i%27))
+	}
+
+	dictionary, err := TrainZstdDictionary(samples, version, 4096, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dictionary
+}
+
+func TestCodecDictionary(t *testing.T) {
+	delta := []byte("minecraft:chest{Items:[{Name:apple,Count:7},{Slot:3}]}")
+
+	codec := NewCodec(DefaultCompression())
+	plain, err := codec.EncodeDelta(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain[0] != CompressionGzip {
+		t.Fatalf("EncodeDelta: expected codec tag %d without dictionary, but got %d", CompressionGzip, plain[0])
+	}
+
+	codec.SetDictionary(testDictionary(t, 1))
+	older, err := codec.EncodeDelta(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if older[0] != CompressionZstdDict {
+		t.Fatalf("EncodeDelta: expected codec tag %d, but got %d", CompressionZstdDict, older[0])
+	}
+
+	// Values that encoded by the older
+	// dictionary could still be decoded.
+	codec.SetDictionary(testDictionary(t, 2))
+	newer, err := codec.EncodeDelta(delta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range [][]byte{plain, older, newer} {
+		result, err := codec.Decode(value)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if !bytes.Equal(result, delta) {
+			t.Fatal("Decode: delta is not the same as the original one")
+		}
+	}
+
+	// The codec that not have the dictionary can't decode
+	_, err = NewCodec(DefaultCompression()).Decode(newer)
+	if err == nil {
+		t.Fatal("Decode: expected an error when the dictionary is not found")
+	}
+	other := NewCodec(DefaultCompression())
+	other.AddDictionary(testDictionary(t, 2))
+	if _, err = other.Decode(newer); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	if _, err = TrainZstdDictionary([][]byte{delta}, 0, 4096, 3); err == nil {
+		t.Fatal("TrainZstdDictionary: expected an error for version 0")
+	}
+}
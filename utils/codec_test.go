@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+)
+
+// testPayload returns a payload that could be compressed well.
+func testPayload(seed int) []byte {
+	return bytes.Repeat([]byte{byte(seed), 'b', 'e', 'd', 'r', 'o', 'c', 'k'}, 64+seed)
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	zstd, err := NewZstdCompression(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codec := NewCodec(DefaultCompression())
+	encoded := make([][]byte, 0)
+	for index, compression := range []Compression{NoneCompression{}, DefaultCompression(), zstd, SnappyCompression{}} {
+		codec.SetCompression(compression)
+
+		result, err := codec.Encode(testPayload(index))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result[0] != compression.ID() {
+			t.Fatalf("Encode: expected codec tag %d, but got %d", compression.ID(), result[0])
+		}
+		encoded = append(encoded, result)
+	}
+
+	// Values that encoded by other algorithms are
+	// still decoded, even by a new codec.
+	for _, c := range []*Codec{codec, NewCodec(NoneCompression{})} {
+		for index, value := range encoded {
+			result, err := c.Decode(value)
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !bytes.Equal(result, testPayload(index)) {
+				t.Fatalf("Decode: value %d is not the same as the original one", index)
+			}
+		}
+	}
+
+	if result, err := codec.Encode(nil); result != nil || err != nil {
+		t.Fatalf("Encode: expected nil result and error for empty input, but got %v and %v", result, err)
+	}
+	if _, err := codec.Decode([]byte{0x7f, 1, 2, 3}); err == nil {
+		t.Fatal("Decode: expected an error for unknown codec tag")
+	}
+}
+
+func TestCodecLegacyGzip(t *testing.T) {
+	legacy, err := Gzip(testPayload(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := NewCodec(NoneCompression{}).Decode(legacy)
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !bytes.Equal(result, testPayload(1)) {
+		t.Fatal("Decode: legacy value is not the same as the original one")
+	}
+}
@@ -9,9 +9,14 @@ import (
 
 // Gzip ..
 func Gzip(in []byte) (result []byte, err error) {
+	return GzipWithLevel(in, gzip.BestCompression)
+}
+
+// GzipWithLevel ..
+func GzipWithLevel(in []byte, level int) (result []byte, err error) {
 	buf := bytes.NewBuffer(nil)
 
-	w, err := gzip.NewWriterLevel(buf, gzip.BestCompression)
+	w, err := gzip.NewWriterLevel(buf, level)
 	if err != nil {
 		return nil, fmt.Errorf("Gzip: %v", err)
 	}